@@ -1,17 +1,14 @@
 package app
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
-	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -21,14 +18,18 @@ import (
 	"github.com/lacymorrow/lash/internal/db"
 	"github.com/lacymorrow/lash/internal/format"
 	"github.com/lacymorrow/lash/internal/history"
+	"github.com/lacymorrow/lash/internal/inputhistory"
 	"github.com/lacymorrow/lash/internal/llm/agent"
+	"github.com/lacymorrow/lash/internal/llm/tools"
 	"github.com/lacymorrow/lash/internal/log"
 	"github.com/lacymorrow/lash/internal/pubsub"
 
 	"github.com/lacymorrow/lash/internal/lsp"
 	"github.com/lacymorrow/lash/internal/message"
 	"github.com/lacymorrow/lash/internal/permission"
+	"github.com/lacymorrow/lash/internal/router"
 	"github.com/lacymorrow/lash/internal/session"
+	"github.com/lacymorrow/lash/internal/shell"
 )
 
 const subscriberSendTimeout = 2 * time.Second
@@ -39,7 +40,18 @@ type App struct {
 	History     history.Service
 	Permissions permission.Service
 
-	CoderAgent agent.Service
+	// CoderAgent runs each session with the agent it uses.
+	CoderAgent *agent.Agents
+
+	// Router decides whether Auto mode input goes to the shell or the agent.
+	Router *router.Router
+
+	// Shells holds the agent and user shells of each session.
+	Shells *shell.Manager
+
+	// shellRequests tracks cancel functions of running shell commands by
+	// session ID.
+	shellRequests *csync.Map[string, context.CancelFunc]
 
 	LSPClients map[string]*lsp.Client
 
@@ -65,7 +77,7 @@ type App struct {
 	// InputHistory stores a global list of user-entered prompts across all
 	// sessions for convenient navigation with Up/Down arrows, similar to a shell.
 	// Most recent entries are appended at the end.
-	InputHistory []string
+	InputHistory *inputhistory.Store
 }
 
 // New initializes a new applcation instance.
@@ -90,6 +102,17 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		allowedTools = append(allowedTools, "bash", "bash:execute")
 	}
 
+	shells := shell.NewManager(cfg.WorkingDir(), shellStateStore{q})
+	if cfg.Lash != nil {
+		shells.SetSyncPolicy(shell.SyncPolicy(cfg.Lash.Shell.Sync))
+		if cfg.Lash.Sandbox.Enabled {
+			profile := sandboxProfile(cfg)
+			if err := profile.Check(); err != nil {
+				return nil, fmt.Errorf("the sandbox is enabled but cannot be used: %w", err)
+			}
+			shells.SetSandbox(profile)
+		}
+	}
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
@@ -97,6 +120,10 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools),
 		LSPClients:  make(map[string]*lsp.Client),
 
+		shellRequests: csync.NewMap[string, context.CancelFunc](),
+		Router:        newRouter(cfg, shells),
+		Shells:        shells,
+
 		globalCtx: ctx,
 
 		config: cfg,
@@ -108,8 +135,6 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		tuiWG:           &sync.WaitGroup{},
 
 		Mode: cfg.ActiveMode(),
-
-		InputHistory: make([]string, 0, 128),
 	}
 
 	app.setupEvents()
@@ -140,74 +165,53 @@ func (app *App) historyFilePath() string {
 }
 
 // LoadInputHistory loads the global input history from disk, if present.
-// Entries are stored as JSON strings, one per line.
 func (app *App) LoadInputHistory() error {
-	path := app.historyFilePath()
-	f, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-	defer f.Close()
-
-	var loaded []string
-	scanner := bufio.NewScanner(f)
-	// Increase the buffer in case of long multi-line prompts
-	buf := make([]byte, 0, 1024*64)
-	scanner.Buffer(buf, 1024*1024)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		var s string
-		if err := json.Unmarshal(line, &s); err != nil {
-			// Skip malformed lines
-			continue
-		}
-		if s == "" {
-			continue
-		}
-		loaded = append(loaded, s)
-	}
-	if err := scanner.Err(); err != nil {
-		return err
-	}
-	if len(loaded) > 0 {
-		app.InputHistory = append(app.InputHistory, loaded...)
-	}
-	return nil
+	store, err := inputhistory.Open(app.historyFilePath())
+	app.InputHistory = store
+	return err
 }
 
-// AppendInputHistory appends an entry to the in-memory and on-disk history.
-// It skips consecutive duplicates. Entries are stored as JSONL for safe multiline support.
-func (app *App) AppendInputHistory(entry string) error {
-	entry = strings.TrimSpace(entry)
-	if entry == "" {
-		return nil
-	}
-	if n := len(app.InputHistory); n > 0 && app.InputHistory[n-1] == entry {
-		return nil
-	}
-	app.InputHistory = append(app.InputHistory, entry)
+// AppendInputHistory appends an entry to the in-memory and on-disk history,
+// along with the current mode and the working directory of the user's shell
+// in the session. It skips consecutive duplicates.
+func (app *App) AppendInputHistory(sessionID, entry string) error {
+	return app.InputHistory.Add(inputhistory.Entry{
+		Text: entry,
+		Mode: app.Mode,
+		Cwd:  app.shellWorkingDir(sessionID),
+	})
+}
 
-	// Ensure data directory exists
-	if err := os.MkdirAll(app.config.Options.DataDirectory, 0o700); err != nil {
-		return err
-	}
-	// Append as JSON string + newline
-	b, err := json.Marshal(entry)
-	if err != nil {
-		return err
+// SuggestInput returns the history entry that best completes prefix, preferring
+// entries from the working directory of the session's shell, or an empty
+// string.
+func (app *App) SuggestInput(sessionID, prefix string) string {
+	return app.InputHistory.Suggest(prefix, app.shellWorkingDir(sessionID), app.Mode)
+}
+
+// SearchInputHistory returns up to limit history entries containing query,
+// most relevant first.
+func (app *App) SearchInputHistory(sessionID, query string, limit int) []inputhistory.Entry {
+	return app.InputHistory.Search(query, app.shellWorkingDir(sessionID), limit)
+}
+
+// ImportShellHistory adds the commands of the user's bash, zsh and fish
+// histories to the input history and returns how many were added.
+func (app *App) ImportShellHistory() (int, error) {
+	var entries []inputhistory.Entry
+	for _, h := range inputhistory.ShellHistories() {
+		read, err := h.Read()
+		if err != nil {
+			slog.Warn("Failed to read shell history", "shell", h.Shell, "path", h.Path, "error", err)
+			continue
+		}
+		entries = append(entries, read...)
 	}
-	f, err := os.OpenFile(app.historyFilePath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	n, err := app.InputHistory.Import(entries)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("failed to import shell history: %w", err)
 	}
-	defer f.Close()
-	if _, err := f.Write(append(b, '\n')); err != nil {
-		return err
-	}
-	return nil
+	return n, nil
 }
 
 // Config returns the application configuration.
@@ -313,7 +317,11 @@ func (app *App) UpdateAgentModel() error {
 	if app.CoderAgent == nil {
 		return fmt.Errorf("coder agent is not initialized")
 	}
-	return app.CoderAgent.UpdateModel()
+	if err := app.CoderAgent.UpdateModel(); err != nil {
+		return err
+	}
+	app.updateRouterClassifier()
+	return nil
 }
 
 func (app *App) setupEvents() {
@@ -326,6 +334,10 @@ func (app *App) setupEvents() {
 	setupSubscriber(ctx, app.serviceEventsWG, "history", app.History.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "mcp", agent.SubscribeMCPEvents, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "lsp", SubscribeLSPEvents, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "jobs", app.Shells.SubscribeJobs, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "tool-output", tools.SubscribeOutput, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "sub-agents", agent.SubscribeSubAgents, app.events)
+	app.cleanUpDeletedSessions(ctx)
 	cleanupFunc := func() {
 		cancel()
 		app.serviceEventsWG.Wait()
@@ -333,6 +345,25 @@ func (app *App) setupEvents() {
 	app.cleanupFuncs = append(app.cleanupFuncs, cleanupFunc)
 }
 
+// cleanUpDeletedSessions drops what is kept for a session once it is
+// deleted: the tool outputs saved for read_output, and its shells.
+func (app *App) cleanUpDeletedSessions(ctx context.Context) {
+	events := app.Sessions.Subscribe(ctx)
+	app.serviceEventsWG.Add(1)
+	go func() {
+		defer app.serviceEventsWG.Done()
+		for event := range events {
+			if event.Type != pubsub.DeletedEvent {
+				continue
+			}
+			if err := tools.RemoveSessionOutputs(event.Payload.ID); err != nil {
+				slog.Warn("Failed to remove session outputs", "session_id", event.Payload.ID, "error", err)
+			}
+			app.Shells.Remove(event.Payload.ID)
+		}
+	}()
+}
+
 func setupSubscriber[T any](
 	ctx context.Context,
 	wg *sync.WaitGroup,
@@ -369,18 +400,19 @@ func setupSubscriber[T any](
 }
 
 func (app *App) InitCoderAgent() error {
-	coderAgentCfg := app.config.Agents["coder"]
-	if coderAgentCfg.ID == "" {
-		return fmt.Errorf("coder agent configuration is missing")
+	defaultAgent := cmp.Or(app.config.Options.DefaultAgent, "coder")
+	if app.config.Agents[defaultAgent].ID == "" {
+		return fmt.Errorf("%s agent configuration is missing", defaultAgent)
 	}
 	var err error
-	app.CoderAgent, err = agent.NewAgent(
+	app.CoderAgent, err = agent.NewAgents(
 		app.globalCtx,
-		coderAgentCfg,
+		defaultAgent,
 		app.Permissions,
 		app.Sessions,
 		app.Messages,
 		app.History,
+		app.Shells,
 		app.LSPClients,
 	)
 	if err != nil {
@@ -392,9 +424,27 @@ func (app *App) InitCoderAgent() error {
 	app.cleanupFuncs = append(app.cleanupFuncs, agent.CloseMCPClients)
 
 	setupSubscriber(app.eventsCtx, app.serviceEventsWG, "coderAgent", app.CoderAgent.Subscribe, app.events)
+	setupSubscriber(app.eventsCtx, app.serviceEventsWG, "prompt-queue", app.CoderAgent.SubscribeQueue, app.events)
+	app.updateRouterClassifier()
 	return nil
 }
 
+// SelectAgent switches a session to the agent with the given ID, or, without
+// a session, makes it the agent of new sessions.
+func (app *App) SelectAgent(ctx context.Context, sessionID, id string) error {
+	if app.CoderAgent == nil {
+		return fmt.Errorf("coder agent is not initialized")
+	}
+	if sessionID == "" {
+		if err := app.CoderAgent.SetDefault(id); err != nil {
+			return err
+		}
+		app.config.Options.DefaultAgent = id
+		return nil
+	}
+	return app.CoderAgent.SetSessionAgent(ctx, sessionID, id)
+}
+
 // Subscribe sends events to the TUI as tea.Msgs.
 func (app *App) Subscribe(program *tea.Program) {
 	defer log.RecoverPanic("app.Subscribe", func() {
@@ -431,6 +481,10 @@ func (app *App) Shutdown() {
 	if app.CoderAgent != nil {
 		app.CoderAgent.CancelAll()
 	}
+	for cancel := range app.shellRequests.Seq() {
+		cancel()
+	}
+	app.Shells.Close()
 
 	for cancel := range app.watcherCancelFuncs.Seq() {
 		cancel()
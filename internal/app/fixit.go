@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lacymorrow/lash/internal/llm/agent"
+	"github.com/lacymorrow/lash/internal/message"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+const (
+	// fixOutputLines is how many trailing lines of each output stream are
+	// sent with a fix request. Errors are usually at the end.
+	fixOutputLines = 60
+	// fixOutputChars caps each stream regardless of line count.
+	fixOutputChars = 6000
+)
+
+// fixEnvVars are environment variables that commonly explain why a command
+// failed. Variables the command itself refers to are sent as well.
+var fixEnvVars = []string{
+	"PATH", "SHELL", "HOME", "USER", "LANG",
+	"GOPATH", "GOROOT", "GOFLAGS", "GO111MODULE",
+	"NODE_ENV", "NVM_DIR",
+	"VIRTUAL_ENV", "CONDA_DEFAULT_ENV", "PYTHONPATH",
+	"JAVA_HOME", "CARGO_HOME", "RUSTUP_TOOLCHAIN",
+}
+
+// secretPattern matches names of variables whose values must not be sent to
+// the model.
+var secretPattern = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|API_?KEY|PRIVATE)`)
+
+// ErrNothingToFix is returned when the session has no failed shell command
+// to hand to the agent.
+var ErrNothingToFix = errors.New("no failed shell command to fix")
+
+// LastFailedShellExecution returns the most recent shell execution of the
+// session if it exited with a non-zero status.
+func (app *App) LastFailedShellExecution(ctx context.Context, sessionID string) (message.ShellExecution, bool, error) {
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return message.ShellExecution{}, false, fmt.Errorf("failed to list messages: %w", err)
+	}
+	for _, msg := range slices.Backward(msgs) {
+		executions := msg.ShellExecutions()
+		if len(executions) == 0 {
+			continue
+		}
+		exec := executions[len(executions)-1]
+		return exec, IsFailedShellExecution(exec), nil
+	}
+	return message.ShellExecution{}, false, nil
+}
+
+// IsFailedShellExecution reports whether exec finished with a non-zero exit
+// status. Canceled commands don't count as failures.
+func IsFailedShellExecution(exec message.ShellExecution) bool {
+	return exec.IsFinished() && !exec.Canceled && exec.ExitCode != 0
+}
+
+// FixShell starts a coder agent run primed with a failed shell execution:
+// the command, its working directory, exit code, trimmed output and the
+// relevant environment. The agent is asked to end its reply with the command
+// that fixes the failure; FixCommand extracts it from the reply.
+func (app *App) FixShell(ctx context.Context, sessionID string, exec message.ShellExecution) (<-chan agent.AgentEvent, error) {
+	if app.CoderAgent == nil {
+		return nil, errors.New("coder agent is not initialized")
+	}
+	if !IsFailedShellExecution(exec) {
+		return nil, ErrNothingToFix
+	}
+	env := app.Shells.User(sessionID).GetEnv()
+	return app.CoderAgent.Run(ctx, sessionID, fixPrompt(exec, fixEnv(exec.Command, env)))
+}
+
+// fixPrompt returns the request sent to the agent to fix exec.
+func fixPrompt(exec message.ShellExecution, env []string) string {
+	exec.Stdout = tailOutput(exec.Stdout)
+	exec.Stderr = tailOutput(exec.Stderr)
+
+	var sb strings.Builder
+	sb.WriteString("The shell command below failed. Find out why and propose a fix.\n\n")
+	sb.WriteString(exec.String())
+	sb.WriteString("\n")
+	if len(env) > 0 {
+		sb.WriteString("<environment>\n")
+		for _, kv := range env {
+			sb.WriteString(kv + "\n")
+		}
+		sb.WriteString("</environment>\n")
+	}
+	sb.WriteString(`
+You may read files and run read-only commands to investigate, but do not run the fix yourself; the user will review and run it.
+Keep the explanation short. If a shell command fixes the problem, end your reply with exactly one ` + "```sh" + ` code block containing only that command, to be run from the directory above. If no command can fix it, say so and don't include a code block.`)
+	return sb.String()
+}
+
+// fixEnv returns the variables of env that are worth sending with a fix
+// request for command. Values of variables that look like secrets are
+// redacted.
+func fixEnv(command string, env []string) []string {
+	names := slices.Clone(fixEnvVars)
+	if file, err := syntax.NewParser().Parse(strings.NewReader(command), ""); err == nil {
+		syntax.Walk(file, func(node syntax.Node) bool {
+			if pe, ok := node.(*syntax.ParamExp); ok && pe.Param != nil && !slices.Contains(names, pe.Param.Value) {
+				names = append(names, pe.Param.Value)
+			}
+			return true
+		})
+	}
+
+	values := make(map[string]string, len(env))
+	for _, kv := range env {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			values[name] = value
+		}
+	}
+	var out []string
+	for _, name := range names {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if secretPattern.MatchString(name) {
+			value = "<redacted>"
+		}
+		out = append(out, name+"="+value)
+	}
+	return out
+}
+
+// tailOutput keeps the end of a command's output, where errors usually are.
+func tailOutput(s string) string {
+	return message.TailOutput(s, fixOutputLines, fixOutputChars)
+}
+
+// FixCommand returns the command proposed in the agent's reply to a fix
+// request: the contents of the last shell or untagged code block, or an empty
+// string if there is none.
+func FixCommand(reply string) string {
+	commands := message.ShellCommandsWithUntagged(reply)
+	if len(commands) == 0 {
+		return ""
+	}
+	return commands[len(commands)-1]
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/lacymorrow/lash/internal/llm/agent"
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/session"
+)
+
+// ForkSession starts a new session from the messages of a session up to the
+// message messageID, along with the versions of the files they changed. The
+// results of the tool calls of the message are kept with it. The original
+// session is left as is.
+func (app *App) ForkSession(ctx context.Context, sessionID, messageID string) (session.Session, error) {
+	return app.fork(ctx, sessionID, messageID, false)
+}
+
+// ForkSessionBefore is ForkSession without the message messageID itself, so
+// that a prompt can be edited and sent again in the new session.
+func (app *App) ForkSessionBefore(ctx context.Context, sessionID, messageID string) (session.Session, error) {
+	return app.fork(ctx, sessionID, messageID, true)
+}
+
+func (app *App) fork(ctx context.Context, sessionID, messageID string, before bool) (session.Session, error) {
+	if app.IsSessionBusy(sessionID) {
+		return session.Session{}, agent.ErrSessionBusy
+	}
+	parent, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	i := slices.IndexFunc(msgs, func(m message.Message) bool { return m.ID == messageID })
+	if i < 0 {
+		return session.Session{}, fmt.Errorf("message %s not found in session %s", messageID, sessionID)
+	}
+	kept := msgs[:forkEnd(msgs, i, before)]
+
+	fork, err := app.Sessions.CreateForkSession(ctx, parent, messageID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	for _, msg := range kept {
+		copied, err := app.Messages.Copy(ctx, fork.ID, msg)
+		if err != nil {
+			return session.Session{}, fmt.Errorf("failed to copy message: %w", err)
+		}
+		if msg.ID == parent.SummaryMessageID {
+			fork.SummaryMessageID = copied.ID
+		}
+	}
+	if fork.SummaryMessageID != "" {
+		if fork, err = app.Sessions.Save(ctx, fork); err != nil {
+			return session.Session{}, fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+
+	if len(kept) == 0 {
+		return fork, nil
+	}
+	// File versions are made while the messages they belong to are written,
+	// so those of the kept messages are not newer than the last one.
+	last := kept[len(kept)-1].UpdatedAt
+	files, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list files: %w", err)
+	}
+	for _, file := range files {
+		if file.CreatedAt > last {
+			continue
+		}
+		if _, err := app.History.Copy(ctx, fork.ID, file); err != nil {
+			return session.Session{}, fmt.Errorf("failed to copy file history: %w", err)
+		}
+	}
+	return fork, nil
+}
+
+// forkEnd returns how many messages a fork at msgs[i] keeps: those before
+// it, and unless before is set, the message itself with the results of its
+// tool calls.
+func forkEnd(msgs []message.Message, i int, before bool) int {
+	if before {
+		return i
+	}
+	end := i + 1
+	for end < len(msgs) && msgs[end].Role == message.Tool {
+		end++
+	}
+	return end
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+// Jobs returns the background jobs of the user's shell in a session, oldest
+// first.
+func (app *App) Jobs(sessionID string) []shell.JobInfo {
+	return app.Shells.User(sessionID).Jobs()
+}
+
+// JobOutputAttachment returns the end of the output of a background job as
+// a text attachment for an agent prompt.
+func (app *App) JobOutputAttachment(sessionID string, id int) (message.Attachment, error) {
+	job, ok := app.Shells.User(sessionID).Job(id)
+	if !ok {
+		return message.Attachment{}, fmt.Errorf("no such job: %%%d", id)
+	}
+	info := job.Info()
+
+	var sb strings.Builder
+	sb.WriteString("The user started this command in the background of their shell. It was not run by you.\n")
+	fmt.Fprintf(&sb, "<cwd>%s</cwd>\n", info.Cwd)
+	fmt.Fprintf(&sb, "<command>%s</command>\n", info.Command)
+	if info.Finished() {
+		fmt.Fprintf(&sb, "<status>%s</status>\n", strings.ToLower(info.Status()))
+	} else {
+		sb.WriteString("<status>still running</status>\n")
+	}
+	if output := tailOutput(job.Output()); output != "" {
+		fmt.Fprintf(&sb, "<output>\n%s\n</output>\n", output)
+	}
+	return message.Attachment{
+		FilePath: info.Command,
+		FileName: fmt.Sprintf("job %d", info.ID),
+		MimeType: "text/plain",
+		Content:  []byte(sb.String()),
+	}, nil
+}
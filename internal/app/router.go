@@ -0,0 +1,62 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/llm/prompt"
+	"github.com/lacymorrow/lash/internal/llm/provider"
+	"github.com/lacymorrow/lash/internal/router"
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+// newRouter creates the Auto mode router from the lash.auto config.
+func newRouter(cfg *config.Config, shells *shell.Manager) *router.Router {
+	var opts router.Options
+	if cfg.Lash != nil {
+		opts = router.Options{
+			ShellPrefix:   cfg.Lash.Auto.ShellPrefix,
+			AgentPrefix:   cfg.Lash.Auto.AgentPrefix,
+			ShellCommands: cfg.Lash.Auto.ShellCommands,
+			AgentCommands: cfg.Lash.Auto.AgentCommands,
+		}
+	}
+	opts.Dir = func() string {
+		return shells.WorkingDir("")
+	}
+	return router.New(opts)
+}
+
+// updateRouterClassifier points the router's tiebreaker at the current small
+// model, if lash.auto.use_model is enabled.
+func (app *App) updateRouterClassifier() {
+	if app.config.Lash == nil || !app.config.Lash.Auto.UseModel {
+		app.Router.SetClassifier(nil)
+		return
+	}
+	classifier, err := newModelClassifier(app.config)
+	if err != nil {
+		slog.Warn("Failed to create Auto mode classifier", "error", err)
+		app.Router.SetClassifier(nil)
+		return
+	}
+	app.Router.SetClassifier(classifier)
+}
+
+func newModelClassifier(cfg *config.Config) (router.Classifier, error) {
+	providerCfg := cfg.GetProviderForModel(config.SelectedModelTypeSmall)
+	if providerCfg == nil {
+		return nil, fmt.Errorf("provider for the small model not found in config")
+	}
+	p, err := provider.NewProvider(
+		*providerCfg,
+		provider.WithModel(config.SelectedModelTypeSmall),
+		provider.WithSystemMessage(prompt.GetPrompt(prompt.PromptRouter, providerCfg.ID)),
+		provider.WithMaxTokens(10),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create router provider: %w", err)
+	}
+	return router.NewModelClassifier(p), nil
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/fsext"
+	"github.com/lacymorrow/lash/internal/sandbox"
+)
+
+// sandboxProfile returns the sandbox profile of the configuration: the
+// working directory, the temporary directory and the configured paths are
+// writable.
+func sandboxProfile(cfg *config.Config) *sandbox.Profile {
+	profile := &sandbox.Profile{Network: cfg.Lash.Sandbox.Network}
+	paths := append([]string{cfg.WorkingDir(), os.TempDir()}, cfg.Lash.Sandbox.Writable...)
+	for _, path := range paths {
+		expanded, err := fsext.Expand(path)
+		if err != nil {
+			slog.Warn("Invalid sandbox writable path", "path", path, "error", err)
+			continue
+		}
+		if !filepath.IsAbs(expanded) {
+			expanded = filepath.Join(cfg.WorkingDir(), expanded)
+		}
+		// Writes are checked against resolved paths.
+		if resolved, err := filepath.EvalSymlinks(expanded); err == nil {
+			expanded = resolved
+		}
+		profile.Writable = append(profile.Writable, expanded)
+	}
+	return profile
+}
@@ -0,0 +1,366 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/shell"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// ErrShellBusy is returned when a shell command is already running in a session.
+var ErrShellBusy = errors.New("shell is busy running a command")
+
+// shellFlushInterval is how often streamed shell output is written back to
+// the session message while a command is running.
+const shellFlushInterval = 100 * time.Millisecond
+
+// shellOutputLimit is how much of the end of each output stream of a shell
+// command is kept, so that commands printing without end don't fill the
+// memory and the session.
+const shellOutputLimit = 256 * 1024
+
+// shellOutput collects the end of stdout and stderr of a running command. It
+// is safe for concurrent use.
+type shellOutput struct {
+	mu     sync.Mutex
+	stdout tailBuffer
+	stderr tailBuffer
+	dirty  bool
+}
+
+// shellStream is the writer for one of the streams of a shellOutput.
+type shellStream struct {
+	out *shellOutput
+	buf *tailBuffer
+}
+
+func (s shellStream) Write(p []byte) (int, error) {
+	s.out.mu.Lock()
+	defer s.out.mu.Unlock()
+	s.out.dirty = true
+	s.buf.write(p)
+	return len(p), nil
+}
+
+// snapshot returns the output collected so far and whether it changed since
+// the last call.
+func (o *shellOutput) snapshot() (stdout, stderr string, changed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	changed = o.dirty
+	o.dirty = false
+	return o.stdout.String(), o.stderr.String(), changed
+}
+
+// tailBuffer keeps about the last shellOutputLimit bytes written to it, cut
+// at a line boundary, and counts the lines it dropped.
+type tailBuffer struct {
+	buf     []byte
+	omitted int
+}
+
+func (t *tailBuffer) write(p []byte) {
+	t.buf = append(t.buf, p...)
+	// Trimming only once the buffer doubled keeps writes cheap.
+	if len(t.buf) > 2*shellOutputLimit {
+		t.trim()
+	}
+}
+
+// trim drops all but the last shellOutputLimit bytes.
+func (t *tailBuffer) trim() {
+	if len(t.buf) <= shellOutputLimit {
+		return
+	}
+	cut := len(t.buf) - shellOutputLimit
+	if i := bytes.IndexByte(t.buf[cut:], '\n'); i >= 0 {
+		cut += i + 1
+	}
+	t.omitted += bytes.Count(t.buf[:cut], []byte{'\n'})
+	t.buf = append([]byte(nil), t.buf[cut:]...)
+}
+
+func (t *tailBuffer) String() string {
+	t.trim()
+	if t.omitted == 0 {
+		return string(t.buf)
+	}
+	return fmt.Sprintf("... [%d earlier lines omitted] ...\n%s", t.omitted, t.buf)
+}
+
+// RunShell runs command in the user's shell of a session in the background.
+// The command is recorded in the session as a user message carrying a
+// message.ShellExecution that is updated while output streams in.
+func (app *App) RunShell(ctx context.Context, sessionID, command string) error {
+	if app.CoderAgent != nil && app.CoderAgent.IsSessionBusy(sessionID) {
+		return ErrShellBusy
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	if !app.shellRequests.SetIfAbsent(sessionID, cancel) {
+		cancel()
+		return ErrShellBusy
+	}
+
+	sh := app.Shells.User(sessionID)
+	msg, exec, err := app.startShellExecution(ctx, sessionID, command, false)
+	if err != nil {
+		app.shellRequests.Del(sessionID)
+		cancel()
+		return err
+	}
+
+	go func() {
+		defer app.shellRequests.Del(sessionID)
+		defer cancel()
+
+		out := &shellOutput{}
+		done := make(chan error, 1)
+		go func() {
+			done <- sh.ExecStream(runCtx, command, shellStream{out, &out.stdout}, shellStream{out, &out.stderr})
+		}()
+
+		ticker := time.NewTicker(shellFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				stdout, stderr, changed := out.snapshot()
+				if !changed {
+					continue
+				}
+				exec.Stdout, exec.Stderr = stdout, stderr
+				msg.SetShellExecution(exec)
+				if err := app.Messages.Update(context.Background(), msg); err != nil {
+					slog.Error("Failed to update shell execution message", "error", err)
+				}
+			case err := <-done:
+				exec.Stdout, exec.Stderr, _ = out.snapshot()
+				app.finishShellExecution(msg, exec, err)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// startShellExecution records command in the session as a running shell
+// execution.
+func (app *App) startShellExecution(ctx context.Context, sessionID, command string, interactive bool) (message.Message, message.ShellExecution, error) {
+	exec := message.ShellExecution{
+		Command:     command,
+		Cwd:         app.Shells.User(sessionID).GetWorkingDir(),
+		Interactive: interactive,
+		StartedAt:   time.Now().UnixMilli(),
+	}
+	msg, err := app.Messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{exec},
+	})
+	if err != nil {
+		return message.Message{}, exec, fmt.Errorf("failed to create shell execution message: %w", err)
+	}
+	return msg, exec, nil
+}
+
+// finishShellExecution records how a shell execution ended.
+func (app *App) finishShellExecution(msg message.Message, exec message.ShellExecution, err error) {
+	exec.ExitCode = shell.ExitCode(err)
+	exec.Canceled = shell.IsInterrupt(err)
+	if err != nil && !exec.Canceled && !errors.As(err, new(interp.ExitStatus)) {
+		// Errors other than a non-zero exit, such as a parse error, are
+		// reported like the shell would on stderr.
+		if exec.Stderr != "" && !strings.HasSuffix(exec.Stderr, "\n") {
+			exec.Stderr += "\n"
+		}
+		exec.Stderr += err.Error() + "\n"
+	}
+	exec.FinishedAt = time.Now().UnixMilli()
+	if !exec.Canceled {
+		if err := app.InputHistory.SetExitCode(exec.Command, exec.ExitCode); err != nil {
+			slog.Warn("Failed to record exit status in input history", "error", err)
+		}
+	}
+	msg.SetShellExecution(exec)
+	if err := app.Messages.Update(context.Background(), msg); err != nil {
+		slog.Error("Failed to finish shell execution message", "error", err)
+	}
+}
+
+// NeedsTerminal reports whether command runs an interactive program, such as
+// an editor, a pager or a REPL, that must be attached to the real terminal.
+func (app *App) NeedsTerminal(sessionID, command string) bool {
+	var extra []string
+	if app.config.Lash != nil {
+		extra = app.config.Lash.Shell.InteractiveCommands
+	}
+	return app.Shells.User(sessionID).NeedsTerminal(command, extra)
+}
+
+// shellWorkingDir returns the working directory of the user's shell in a
+// session.
+func (app *App) shellWorkingDir(sessionID string) string {
+	return app.Shells.WorkingDir(sessionID)
+}
+
+// CompleteShell returns the completions of the last word of line in the
+// user's shell of a session, and the byte offset in line where that word
+// starts.
+func (app *App) CompleteShell(ctx context.Context, sessionID, line string) (int, []shell.Completion) {
+	return app.Shells.User(sessionID).Complete(ctx, line)
+}
+
+// ShellTerminalCommand runs a command in the user's shell of a session attached
+// to the terminal. It implements tea.ExecCommand, so the TUI can suspend
+// itself while the command runs; the result is recorded in the session like
+// any other shell execution.
+type ShellTerminalCommand struct {
+	app       *App
+	sessionID string
+	command   string
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	// ExitCode is the exit status of the command once Run returns.
+	ExitCode int
+}
+
+// NewShellTerminalCommand prepares command to run attached to the terminal.
+func (app *App) NewShellTerminalCommand(sessionID, command string) *ShellTerminalCommand {
+	return &ShellTerminalCommand{
+		app:       app,
+		sessionID: sessionID,
+		command:   command,
+		// Use the process's own files rather than whatever the TUI wraps
+		// them in, so programs see a TTY.
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+}
+
+func (c *ShellTerminalCommand) SetStdin(r io.Reader) {
+	if c.stdin == nil {
+		c.stdin = r
+	}
+}
+
+func (c *ShellTerminalCommand) SetStdout(w io.Writer) {
+	if c.stdout == nil {
+		c.stdout = w
+	}
+}
+
+func (c *ShellTerminalCommand) SetStderr(w io.Writer) {
+	if c.stderr == nil {
+		c.stderr = w
+	}
+}
+
+// Run runs the command and records its exit status in the session. A
+// non-zero exit is not an error.
+func (c *ShellTerminalCommand) Run() error {
+	app := c.app
+	if app.CoderAgent != nil && app.CoderAgent.IsSessionBusy(c.sessionID) {
+		return ErrShellBusy
+	}
+
+	ctx, cancel := context.WithCancel(app.globalCtx)
+	defer cancel()
+	if !app.shellRequests.SetIfAbsent(c.sessionID, cancel) {
+		return ErrShellBusy
+	}
+	defer app.shellRequests.Del(c.sessionID)
+
+	msg, exec, err := app.startShellExecution(ctx, c.sessionID, c.command, true)
+	if err != nil {
+		return err
+	}
+	sh := app.Shells.User(c.sessionID)
+	err = sh.ExecInteractive(ctx, c.command, c.stdin, c.stdout, c.stderr)
+	app.finishShellExecution(msg, exec, err)
+
+	c.ExitCode = shell.ExitCode(err)
+	if err != nil && !shell.IsInterrupt(err) && !errors.As(err, new(interp.ExitStatus)) {
+		return err
+	}
+	return nil
+}
+
+// LoadShellRC loads the configured rc files into the user's shells and
+// returns what could not be loaded. It does nothing when lash.shell.load_rc
+// is disabled.
+func (app *App) LoadShellRC(ctx context.Context) []shell.RCWarning {
+	cfg := app.config.Lash
+	if cfg != nil && cfg.Shell.LoadRC != nil && !*cfg.Shell.LoadRC {
+		return nil
+	}
+	files := shell.DefaultRCFiles()
+	if cfg != nil && len(cfg.Shell.RCFiles) > 0 {
+		files = cfg.Shell.RCFiles
+	}
+
+	warnings := app.Shells.LoadRC(ctx, files...)
+	for _, w := range warnings {
+		slog.Warn("Shell rc file not fully loaded", "warning", w.String())
+	}
+	return warnings
+}
+
+// CancelShell cancels the shell command running in the given session, if any.
+// The session stays busy until the command has exited.
+func (app *App) CancelShell(sessionID string) {
+	if cancel, ok := app.shellRequests.Get(sessionID); ok && cancel != nil {
+		slog.Info("Shell command cancellation initiated", "session_id", sessionID)
+		cancel()
+	}
+}
+
+// IsShellBusy reports whether a shell command is running in any session.
+func (app *App) IsShellBusy() bool {
+	return app.shellRequests.Len() > 0
+}
+
+// IsShellSessionBusy reports whether a shell command is running in the given
+// session.
+func (app *App) IsShellSessionBusy(sessionID string) bool {
+	_, busy := app.shellRequests.Get(sessionID)
+	return busy
+}
+
+// IsBusy reports whether the coder agent or a shell command is running.
+func (app *App) IsBusy() bool {
+	if app.CoderAgent != nil && app.CoderAgent.IsBusy() {
+		return true
+	}
+	return app.IsShellBusy()
+}
+
+// IsSessionBusy reports whether the coder agent or a shell command is running
+// in the given session.
+func (app *App) IsSessionBusy(sessionID string) bool {
+	if app.CoderAgent != nil && app.CoderAgent.IsSessionBusy(sessionID) {
+		return true
+	}
+	return app.IsShellSessionBusy(sessionID)
+}
+
+// Cancel cancels whatever is running in the given session, be it an agent
+// request or a shell command.
+func (app *App) Cancel(sessionID string) {
+	app.CancelShell(sessionID)
+	if app.CoderAgent != nil {
+		app.CoderAgent.Cancel(sessionID)
+	}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lacymorrow/lash/internal/db"
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+// shellStateStore keeps the state of session shells in the database.
+type shellStateStore struct {
+	q db.Querier
+}
+
+func (s shellStateStore) LoadShellState(ctx context.Context, sessionID string, kind shell.Kind) (shell.State, bool, error) {
+	row, err := s.q.GetSessionShell(ctx, db.GetSessionShellParams{
+		SessionID: sessionID,
+		Kind:      string(kind),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return shell.State{}, false, nil
+	}
+	if err != nil {
+		return shell.State{}, false, fmt.Errorf("failed to get shell state: %w", err)
+	}
+	state := shell.State{Cwd: row.Cwd}
+	if err := json.Unmarshal([]byte(row.Env), &state.Env); err != nil {
+		return shell.State{}, false, fmt.Errorf("failed to decode shell environment: %w", err)
+	}
+	return state, true, nil
+}
+
+func (s shellStateStore) SaveShellState(ctx context.Context, sessionID string, kind shell.Kind, state shell.State) error {
+	env, err := json.Marshal(state.Env)
+	if err != nil {
+		return fmt.Errorf("failed to encode shell environment: %w", err)
+	}
+	if state.Env == nil {
+		env = []byte("{}")
+	}
+	if err := s.q.UpsertSessionShell(ctx, db.UpsertSessionShellParams{
+		SessionID: sessionID,
+		Kind:      string(kind),
+		Cwd:       state.Cwd,
+		Env:       string(env),
+	}); err != nil {
+		return fmt.Errorf("failed to save shell state: %w", err)
+	}
+	return nil
+}
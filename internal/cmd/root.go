@@ -21,6 +21,7 @@ import (
 func init() {
 	rootCmd.PersistentFlags().StringP("cwd", "c", "", "Current working directory")
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Debug")
+	rootCmd.PersistentFlags().StringP("agent", "a", "", "Agent to use for new sessions")
 
 	rootCmd.Flags().BoolP("help", "h", false, "Help")
 	rootCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
@@ -52,6 +53,9 @@ lash run "Explain the use of context in Go"
 
 # Run in dangerous mode (auto-accept all permissions)
 lash -y
+
+# Start new sessions with the reviewer agent from lash.json
+lash --agent reviewer
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		app, err := setupApp(cmd)
@@ -95,6 +99,7 @@ func Execute() {
 func setupApp(cmd *cobra.Command) (*app.App, error) {
 	debug, _ := cmd.Flags().GetBool("debug")
 	yolo, _ := cmd.Flags().GetBool("yolo")
+	agentID, _ := cmd.Flags().GetString("agent")
 	ctx := cmd.Context()
 
 	cwd, err := ResolveCwd(cmd)
@@ -111,6 +116,9 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		cfg.Permissions = &config.Permissions{}
 	}
 	cfg.Permissions.SkipRequests = yolo
+	if agentID != "" {
+		cfg.Options.DefaultAgent = agentID
+	}
 
 	// Connect to DB; this will also run migrations.
 	conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
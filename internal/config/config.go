@@ -1,6 +1,7 @@
 package config
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
@@ -209,13 +210,95 @@ type LashSafety struct {
 	ConfirmAgentExec *bool `json:"confirm_agent_exec,omitempty" jsonschema:"description=Require confirmation before executing agent-suggested shell commands (bash:execute),default=true"`
 }
 
+// LashAuto configures how Auto mode decides between the shell and the agent.
+type LashAuto struct {
+	// UseModel asks the small model to break ties when input is ambiguous.
+	UseModel bool `json:"use_model,omitempty" jsonschema:"description=Ask the small model to classify ambiguous Auto mode input,default=false"`
+	// ShellPrefix forces a line to the shell.
+	ShellPrefix string `json:"shell_prefix,omitempty" jsonschema:"description=Prefix that forces Auto mode input to run in the shell,default=!"`
+	// AgentPrefix forces a line to the agent.
+	AgentPrefix string `json:"agent_prefix,omitempty" jsonschema:"description=Prefix that forces Auto mode input to go to the agent,default=?"`
+	// ShellCommands always run in the shell when they are the first word.
+	ShellCommands []string `json:"shell_commands,omitempty" jsonschema:"description=First words that always route Auto mode input to the shell,example=make,example=git"`
+	// AgentCommands always go to the agent when they are the first word.
+	AgentCommands []string `json:"agent_commands,omitempty" jsonschema:"description=First words that always route Auto mode input to the agent,example=explain,example=fix"`
+}
+
+// LashShell configures the interpreter behind Shell mode.
+type LashShell struct {
+	// LoadRC runs the rc files at startup so their aliases, functions and
+	// variables are available in Shell mode (default true).
+	LoadRC *bool `json:"load_rc,omitempty" jsonschema:"description=Load rc files into the Shell mode interpreter at startup,default=true"`
+	// RCFiles are the rc files to load, in order. Defaults to the rc file of
+	// $SHELL followed by ~/.lashrc.
+	RCFiles []string `json:"rc_files,omitempty" jsonschema:"description=RC files to load into the Shell mode interpreter; defaults to the rc file of $SHELL followed by ~/.lashrc,example=~/.bashrc,example=~/.lashrc"`
+	// InteractiveCommands always run attached to the terminal, in addition to
+	// the built-in list of editors, pagers and REPLs.
+	InteractiveCommands []string `json:"interactive_commands,omitempty" jsonschema:"description=Programs that always run attached to the terminal in Shell mode; editors, pagers and REPLs are detected automatically,example=mycli,example=ncdu"`
+	// HistoryImportOffered records that importing the bash, zsh and fish
+	// histories was offered, so it is only offered once.
+	HistoryImportOffered bool `json:"history_import_offered,omitempty" jsonschema:"description=Whether importing bash, zsh and fish history has been offered,default=false"`
+	// JobNotification is how to notify that a background job finished:
+	// "desktop", "bell" or "none" (default "desktop").
+	JobNotification string `json:"job_notification,omitempty" jsonschema:"description=How to notify that a background job finished,enum=desktop,enum=bell,enum=none,default=desktop"`
+	// Sync is what the agent's shell and the user's shell share within a
+	// session: "none", "cwd" or "env" (cwd and environment). Default "none".
+	Sync string `json:"sync,omitempty" jsonschema:"description=What the agent shell and the user shell share within a session,enum=none,enum=cwd,enum=env,default=none"`
+}
+
+// LashPolicyRule is a rule of the command policy. A rule applies to a simple
+// command when all of its matchers match; a rule without matchers applies to
+// every command.
+type LashPolicyRule struct {
+	// Name identifies the rule in denials and prompts.
+	Name string `json:"name,omitempty" jsonschema:"description=Name of the rule shown when it fires,example=no-force-push"`
+	// Action is "allow", "ask" or "deny".
+	Action string `json:"action" jsonschema:"required,description=What to do with matching commands,enum=allow,enum=ask,enum=deny"`
+	// Command is an argv pattern: each element is a glob matched against
+	// the argument at the same position, "**" matches any number of
+	// arguments, and the pattern matches the start of the argv.
+	Command []string `json:"command,omitempty" jsonschema:"description=Argv pattern; each element is a glob for one argument and ** matches any number of arguments,example=git,example=push,example=**,example=--force"`
+	// Paths are globs matched against the path arguments of the command,
+	// resolved from its directory. Globs without a slash match base names.
+	Paths []string `json:"paths,omitempty" jsonschema:"description=Globs matched against path arguments; globs without a slash match file names,example=~/.ssh/**,example=*.pem"`
+	// Env are globs matched against the names of the variables the command
+	// runs with that differ from the shell's environment.
+	Env []string `json:"env,omitempty" jsonschema:"description=Globs matched against the names of environment variables changed for the command,example=LD_PRELOAD,example=AWS_*"`
+	// Network matches commands that can reach the network.
+	Network bool `json:"network,omitempty" jsonschema:"description=Match commands that can reach the network,default=false"`
+	// Reason is shown along with the rule when it fires.
+	Reason string `json:"reason,omitempty" jsonschema:"description=Explanation shown when the rule fires"`
+}
+
+// LashPolicy is the policy the agent's shell commands are checked against.
+// When several rules apply, deny wins over ask and ask over allow.
+type LashPolicy struct {
+	Rules []LashPolicyRule `json:"rules,omitempty" jsonschema:"description=Command policy rules; deny wins over ask and ask over allow"`
+}
+
+// LashSandbox configures the sandbox the agent's commands run in. It is only
+// supported on Linux.
+type LashSandbox struct {
+	// Enabled runs the commands of the bash tool in the sandbox.
+	Enabled bool `json:"enabled,omitempty" jsonschema:"description=Run the agent's shell commands in a sandbox (Linux only),default=false"`
+	// Network lets sandboxed commands reach the network.
+	Network bool `json:"network,omitempty" jsonschema:"description=Allow sandboxed commands to reach the network,default=false"`
+	// Writable are the paths sandboxed commands may write to besides the
+	// working directory and the temporary directory. Defaults to ~/.cache.
+	Writable []string `json:"writable,omitempty" jsonschema:"description=Paths sandboxed commands may write to besides the working directory and the temporary directory,example=~/.cache,example=~/.npm"`
+}
+
 // LashConfig is the optional Lash-specific configuration namespace.
 type LashConfig struct {
 	// Mode persists the last selected app mode: Shell, Agent, or Auto
 	Mode string `json:"mode,omitempty" jsonschema:"description=Last selected app mode (Shell, Agent, or Auto),enum=Shell,enum=Agent,enum=Auto,default=Auto"`
 	// YOLO enables skipping all permission prompts (global auto-approve)
-	Yolo   bool       `json:"yolo,omitempty" jsonschema:"description=Skip all permission prompts (YOLO mode),default=false"`
-	Safety LashSafety `json:"safety,omitempty" jsonschema:"description=Lash-specific safety options"`
+	Yolo    bool        `json:"yolo,omitempty" jsonschema:"description=Skip all permission prompts (YOLO mode),default=false"`
+	Safety  LashSafety  `json:"safety,omitempty" jsonschema:"description=Lash-specific safety options"`
+	Auto    LashAuto    `json:"auto,omitempty" jsonschema:"description=Auto mode routing options"`
+	Shell   LashShell   `json:"shell,omitempty" jsonschema:"description=Shell mode interpreter options"`
+	Policy  LashPolicy  `json:"policy,omitempty" jsonschema:"description=Policy for the commands the agent runs"`
+	Sandbox LashSandbox `json:"sandbox,omitempty" jsonschema:"description=Sandbox for the commands the agent runs"`
 }
 
 type Options struct {
@@ -229,6 +312,10 @@ type Options struct {
 	RequestTimeoutSeconds int `json:"request_timeout_seconds,omitempty" jsonschema:"description=Max duration in seconds for a single agent request; when set, requests are canceled after this time"`
 	// Maximum duration for each individual tool call unless the tool specifies a shorter timeout. If 0, no extra per-tool cap is applied.
 	ToolCallTimeoutSeconds int `json:"tool_call_timeout_seconds,omitempty" jsonschema:"description=Max duration in seconds for each tool call unless the tool specifies a shorter timeout"`
+	// Maximum number of read-only tool calls of a message that run at the same time. If 0, 4 run at the same time.
+	MaxParallelToolCalls int `json:"max_parallel_tool_calls,omitempty" jsonschema:"description=Max number of read-only tool calls of a message that run at the same time; 1 runs them one at a time,default=4"`
+	// ID of the agent new sessions use. If empty, the coder agent is used.
+	DefaultAgent string `json:"default_agent,omitempty" jsonschema:"description=ID of the agent new sessions use,default=coder,example=reviewer"`
 }
 
 type MCPs map[string]MCPConfig
@@ -329,14 +416,20 @@ type Agent struct {
 	// This is the id of the system prompt used by the agent
 	Disabled bool `json:"disabled,omitempty"`
 
-	Model SelectedModelType `json:"model" jsonschema:"required,description=The model type to use for this agent,enum=large,enum=small,default=large"`
+	// The model of the agent: large, small, or the name of another entry of
+	// the models.
+	Model SelectedModelType `json:"model,omitempty" jsonschema:"description=The model to use for this agent: large, small or the name of another entry of models,default=large,example=large,example=small"`
+
+	// Path to a file with the system prompt of the agent, relative to the
+	// working directory. The built-in prompt is used if empty.
+	Prompt string `json:"prompt,omitempty" jsonschema:"description=Path to a file with the system prompt of the agent,example=.lash/agents/reviewer.md"`
 
 	// The available tools for the agent
 	//  if this is nil, all tools are available
 	AllowedTools []string `json:"allowed_tools,omitempty"`
 
 	// this tells us which MCPs are available for this agent
-	//  if this is empty all mcps are available
+	//  if this is nil all mcps are available
 	//  the string array is the list of tools from the AllowedMCP the agent has available
 	//  if the string array is nil, all tools from the AllowedMCP are available
 	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty"`
@@ -347,6 +440,20 @@ type Agent struct {
 
 	// Overrides the context paths for this agent
 	ContextPaths []string `json:"context_paths,omitempty"`
+
+	// The permissions the agent starts with
+	Permissions *AgentPermissions `json:"permissions,omitempty" jsonschema:"description=Permission defaults of the agent"`
+}
+
+// AgentPermissions are the permission defaults of an agent, on top of the
+// global permissions.
+type AgentPermissions struct {
+	// Tools, or tool:action pairs, the agent may use without asking
+	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=Tools or tool:action pairs the agent may use without asking,example=edit,example=bash:execute"`
+	// Glob patterns of the files the agent may change, relative to the
+	// working directory. Requests to change other files are denied. If
+	// empty, the agent may change any file.
+	WritablePaths []string `json:"writable_paths,omitempty" jsonschema:"description=Glob patterns of the files the agent may change; requests to change other files are denied,example=**/*.md"`
 }
 
 // Config holds the configuration for lash.
@@ -373,7 +480,7 @@ type Config struct {
 	// Internal
 	workingDir string `json:"-"`
 	// TODO: most likely remove this concept when I come back to it
-	Agents map[string]Agent `json:"-"`
+	Agents map[string]Agent `json:"agents,omitempty" jsonschema:"description=Agents to use for sessions by ID; coder and task are built in and can be overridden"`
 	// TODO: find a better way to do this this should probably not be part of the config
 	resolver       VariableResolver
 	dataConfigDir  string             `json:"-"`
@@ -555,6 +662,8 @@ func (c *Config) SetProviderAPIKey(providerID, apiKey string) error {
 	return nil
 }
 
+// SetupAgents adds the built-in agents to the agents of the configuration
+// and fills in the defaults of the others.
 func (c *Config) SetupAgents() {
 	agents := map[string]Agent{
 		"coder": {
@@ -575,6 +684,7 @@ func (c *Config) SetupAgents() {
 				"glob",
 				"grep",
 				"ls",
+				"read_output",
 				"sourcegraph",
 				"view",
 			},
@@ -583,9 +693,45 @@ func (c *Config) SetupAgents() {
 			AllowedLSP: []string{},
 		},
 	}
+	for id, agent := range c.Agents {
+		// Configured agents override the built-in ones field by field.
+		builtin := agents[id]
+		agent.ID = id
+		agent.Name = cmp.Or(agent.Name, builtin.Name, id)
+		agent.Description = cmp.Or(agent.Description, builtin.Description)
+		agent.Model = cmp.Or(agent.Model, builtin.Model, SelectedModelTypeLarge)
+		if agent.ContextPaths == nil {
+			agent.ContextPaths = c.Options.ContextPaths
+		}
+		if agent.AllowedTools == nil {
+			agent.AllowedTools = builtin.AllowedTools
+		}
+		if agent.AllowedMCP == nil {
+			agent.AllowedMCP = builtin.AllowedMCP
+		}
+		if agent.AllowedLSP == nil {
+			agent.AllowedLSP = builtin.AllowedLSP
+		}
+		agents[id] = agent
+	}
 	c.Agents = agents
 }
 
+// SessionAgents returns the agents sessions can use, sorted by name. The
+// task agent only runs as a sub-agent.
+func (c *Config) SessionAgents() []Agent {
+	var agents []Agent
+	for id, agent := range c.Agents {
+		if id != "task" && !agent.Disabled {
+			agents = append(agents, agent)
+		}
+	}
+	slices.SortFunc(agents, func(a, b Agent) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.ID, b.ID))
+	})
+	return agents
+}
+
 func (c *Config) Resolver() VariableResolver {
 	return c.resolver
 }
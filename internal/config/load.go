@@ -359,6 +359,13 @@ func (c *Config) setDefaults(workingDir string) {
 		v := false
 		c.Lash.Safety.ConfirmAgentExec = &v
 	}
+	if c.Lash.Shell.LoadRC == nil {
+		v := true
+		c.Lash.Shell.LoadRC = &v
+	}
+	if c.Lash.Sandbox.Writable == nil {
+		c.Lash.Sandbox.Writable = []string{"~/.cache"}
+	}
 
 	// Add the default context paths if they are not already present
 	c.Options.ContextPaths = append(defaultContextPaths, c.Options.ContextPaths...)
@@ -1184,3 +1184,35 @@ func TestConfig_configureSelectedModels(t *testing.T) {
 		require.Equal(t, int64(100), large.MaxTokens)
 	})
 }
+
+func TestConfig_SetupAgents(t *testing.T) {
+	loadedConfig, err := loadFromReaders([]io.Reader{strings.NewReader(`{
+		"agents": {
+			"reviewer": {"allowed_tools": ["view"], "permissions": {"writable_paths": ["**/*.md"]}},
+			"coder": {"model": "small"},
+			"off": {"disabled": true}
+		}
+	}`)})
+	require.NoError(t, err)
+	loadedConfig.setDefaults("/tmp")
+	loadedConfig.SetupAgents()
+
+	reviewer := loadedConfig.Agents["reviewer"]
+	require.Equal(t, "reviewer", reviewer.ID)
+	require.Equal(t, "reviewer", reviewer.Name)
+	require.Equal(t, SelectedModelTypeLarge, reviewer.Model)
+	require.Equal(t, []string{"view"}, reviewer.AllowedTools)
+	require.Equal(t, []string{"**/*.md"}, reviewer.Permissions.WritablePaths)
+	require.Equal(t, loadedConfig.Options.ContextPaths, reviewer.ContextPaths)
+
+	coder := loadedConfig.Agents["coder"]
+	require.Equal(t, "Coder", coder.Name)
+	require.Equal(t, SelectedModelTypeSmall, coder.Model)
+	require.Nil(t, coder.AllowedTools)
+
+	var ids []string
+	for _, agent := range loadedConfig.SessionAgents() {
+		ids = append(ids, agent.ID)
+	}
+	require.Equal(t, []string{"coder", "reviewer"}, ids)
+}
@@ -68,6 +68,18 @@ func (m *Map[K, V]) GetOrSet(key K, fn func() V) V {
 	return value
 }
 
+// SetIfAbsent sets the value for the specified key unless the key is already
+// in the map, and reports whether it was set.
+func (m *Map[K, V]) SetIfAbsent(key K, value V) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.inner[key]; ok {
+		return false
+	}
+	m.inner[key] = value
+	return true
+}
+
 // Take gets an item and then deletes it.
 func (m *Map[K, V]) Take(key K) (V, bool) {
 	m.mu.Lock()
@@ -64,6 +64,48 @@ func TestMap_GetOrSet(t *testing.T) {
 	require.Equal(t, 1, m.Len())
 }
 
+func TestMap_SetIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	m := NewMap[string, int]()
+
+	require.True(t, m.SetIfAbsent("key1", 42))
+	require.False(t, m.SetIfAbsent("key1", 99999))
+	value, ok := m.Get("key1")
+	require.True(t, ok)
+	require.Equal(t, 42, value)
+
+	m.Del("key1")
+	require.True(t, m.SetIfAbsent("key1", 7))
+	require.Equal(t, 1, m.Len())
+}
+
+func TestMap_SetIfAbsent_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	m := NewMap[string, int]()
+	const numGoroutines = 50
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	set := 0
+	for i := range numGoroutines {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if m.SetIfAbsent("key", i) {
+				mu.Lock()
+				set++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, set)
+	require.Equal(t, 1, m.Len())
+}
+
 func TestMap_Get(t *testing.T) {
 	t.Parallel()
 
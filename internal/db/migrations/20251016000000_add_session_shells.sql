@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Working directory and environment of the shells of each session
+CREATE TABLE IF NOT EXISTS session_shells (
+    session_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    cwd TEXT NOT NULL,
+    env TEXT NOT NULL DEFAULT '{}',
+    updated_at INTEGER NOT NULL,  -- Unix timestamp in milliseconds
+    PRIMARY KEY (session_id, kind),
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS session_shells;
+-- +goose StatementEnd
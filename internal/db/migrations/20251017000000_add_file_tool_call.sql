@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The tool call that made a file version, for changes made by commands
+ALTER TABLE files ADD COLUMN tool_call_id TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE files DROP COLUMN tool_call_id;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The agent a session uses, empty for the default agent
+ALTER TABLE sessions ADD COLUMN agent_id TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN agent_id;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The session and message a session was forked from, empty for other sessions
+ALTER TABLE sessions ADD COLUMN forked_from_session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN forked_from_message_id TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN forked_from_message_id;
+ALTER TABLE sessions DROP COLUMN forked_from_session_id;
+-- +goose StatementEnd
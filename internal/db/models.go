@@ -9,13 +9,14 @@ import (
 )
 
 type File struct {
-	ID        string `json:"id"`
-	SessionID string `json:"session_id"`
-	Path      string `json:"path"`
-	Content   string `json:"content"`
-	Version   int64  `json:"version"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
+	ID         string `json:"id"`
+	SessionID  string `json:"session_id"`
+	Path       string `json:"path"`
+	Content    string `json:"content"`
+	Version    int64  `json:"version"`
+	CreatedAt  int64  `json:"created_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+	ToolCallID string `json:"tool_call_id"`
 }
 
 type Message struct {
@@ -31,14 +32,25 @@ type Message struct {
 }
 
 type Session struct {
-	ID               string         `json:"id"`
-	ParentSessionID  sql.NullString `json:"parent_session_id"`
-	Title            string         `json:"title"`
-	MessageCount     int64          `json:"message_count"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	Cost             float64        `json:"cost"`
-	UpdatedAt        int64          `json:"updated_at"`
-	CreatedAt        int64          `json:"created_at"`
-	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	ID                  string         `json:"id"`
+	ParentSessionID     sql.NullString `json:"parent_session_id"`
+	Title               string         `json:"title"`
+	MessageCount        int64          `json:"message_count"`
+	PromptTokens        int64          `json:"prompt_tokens"`
+	CompletionTokens    int64          `json:"completion_tokens"`
+	Cost                float64        `json:"cost"`
+	UpdatedAt           int64          `json:"updated_at"`
+	CreatedAt           int64          `json:"created_at"`
+	SummaryMessageID    sql.NullString `json:"summary_message_id"`
+	AgentID             string         `json:"agent_id"`
+	ForkedFromSessionID string         `json:"forked_from_session_id"`
+	ForkedFromMessageID string         `json:"forked_from_message_id"`
+}
+
+type SessionShell struct {
+	SessionID string `json:"session_id"`
+	Kind      string `json:"kind"`
+	Cwd       string `json:"cwd"`
+	Env       string `json:"env"`
+	UpdatedAt int64  `json:"updated_at"`
 }
@@ -24,6 +24,22 @@ INSERT INTO messages (
 )
 RETURNING *;
 
+-- name: CopyMessage :one
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    provider,
+    created_at,
+    updated_at,
+    finished_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
 -- name: UpdateMessage :exec
 UPDATE messages
 SET
@@ -0,0 +1,19 @@
+-- name: GetSessionShell :one
+SELECT *
+FROM session_shells
+WHERE session_id = ? AND kind = ? LIMIT 1;
+
+-- name: UpsertSessionShell :exec
+INSERT INTO session_shells (
+    session_id,
+    kind,
+    cwd,
+    env,
+    updated_at
+) VALUES (
+    ?, ?, ?, ?, strftime('%s', 'now')
+)
+ON CONFLICT (session_id, kind) DO UPDATE SET
+    cwd = excluded.cwd,
+    env = excluded.env,
+    updated_at = excluded.updated_at;
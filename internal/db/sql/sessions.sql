@@ -8,6 +8,8 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    forked_from_session_id,
+    forked_from_message_id,
     updated_at,
     created_at
 ) VALUES (
@@ -19,6 +21,8 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
 ) RETURNING *;
@@ -41,7 +45,8 @@ SET
     prompt_tokens = ?,
     completion_tokens = ?,
     summary_message_id = ?,
-    cost = ?
+    cost = ?,
+    agent_id = ?
 WHERE id = ?
 RETURNING *;
 
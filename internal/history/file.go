@@ -23,12 +23,21 @@ type File struct {
 	Version   int64
 	CreatedAt int64
 	UpdatedAt int64
+	// ToolCallID is the tool call that made this version, when it was
+	// recorded from the changes of a command rather than by a file tool.
+	ToolCallID string
 }
 
 type Service interface {
 	pubsub.Suscriber[File]
 	Create(ctx context.Context, sessionID, path, content string) (File, error)
 	CreateVersion(ctx context.Context, sessionID, path, content string) (File, error)
+	// CreateToolCallVersion is CreateVersion for a change made by the tool
+	// call toolCallID.
+	CreateToolCallVersion(ctx context.Context, sessionID, toolCallID, path, content string) (File, error)
+	// Copy adds a copy of file to the session sessionID, keeping its version
+	// and times.
+	Copy(ctx context.Context, sessionID string, file File) (File, error)
 	Get(ctx context.Context, id string) (File, error)
 	GetByPathAndSession(ctx context.Context, path, sessionID string) (File, error)
 	ListBySession(ctx context.Context, sessionID string) ([]File, error)
@@ -52,10 +61,18 @@ func NewService(q *db.Queries, db *sql.DB) Service {
 }
 
 func (s *service) Create(ctx context.Context, sessionID, path, content string) (File, error) {
-	return s.createWithVersion(ctx, sessionID, path, content, InitialVersion)
+	return s.createWithVersion(ctx, sessionID, "", path, content, InitialVersion)
 }
 
 func (s *service) CreateVersion(ctx context.Context, sessionID, path, content string) (File, error) {
+	return s.createVersion(ctx, sessionID, "", path, content)
+}
+
+func (s *service) CreateToolCallVersion(ctx context.Context, sessionID, toolCallID, path, content string) (File, error) {
+	return s.createVersion(ctx, sessionID, toolCallID, path, content)
+}
+
+func (s *service) createVersion(ctx context.Context, sessionID, toolCallID, path, content string) (File, error) {
 	// Get the latest version for this path
 	files, err := s.q.ListFilesByPath(ctx, path)
 	if err != nil {
@@ -64,17 +81,17 @@ func (s *service) CreateVersion(ctx context.Context, sessionID, path, content st
 
 	if len(files) == 0 {
 		// No previous versions, create initial
-		return s.Create(ctx, sessionID, path, content)
+		return s.createWithVersion(ctx, sessionID, toolCallID, path, content, InitialVersion)
 	}
 
 	// Get the latest version
 	latestFile := files[0] // Files are ordered by version DESC, created_at DESC
 	nextVersion := latestFile.Version + 1
 
-	return s.createWithVersion(ctx, sessionID, path, content, nextVersion)
+	return s.createWithVersion(ctx, sessionID, toolCallID, path, content, nextVersion)
 }
 
-func (s *service) createWithVersion(ctx context.Context, sessionID, path, content string, version int64) (File, error) {
+func (s *service) createWithVersion(ctx context.Context, sessionID, toolCallID, path, content string, version int64) (File, error) {
 	// Maximum number of retries for transaction conflicts
 	const maxRetries = 3
 	var file File
@@ -93,11 +110,12 @@ func (s *service) createWithVersion(ctx context.Context, sessionID, path, conten
 
 		// Try to create the file within the transaction
 		dbFile, txErr := qtx.CreateFile(ctx, db.CreateFileParams{
-			ID:        uuid.New().String(),
-			SessionID: sessionID,
-			Path:      path,
-			Content:   content,
-			Version:   version,
+			ID:         uuid.New().String(),
+			SessionID:  sessionID,
+			Path:       path,
+			Content:    content,
+			Version:    version,
+			ToolCallID: toolCallID,
 		})
 		if txErr != nil {
 			// Rollback the transaction
@@ -127,6 +145,25 @@ func (s *service) createWithVersion(ctx context.Context, sessionID, path, conten
 	return file, err
 }
 
+func (s *service) Copy(ctx context.Context, sessionID string, file File) (File, error) {
+	dbFile, err := s.q.CopyFile(ctx, db.CopyFileParams{
+		ID:         uuid.New().String(),
+		SessionID:  sessionID,
+		Path:       file.Path,
+		Content:    file.Content,
+		Version:    file.Version,
+		ToolCallID: file.ToolCallID,
+		CreatedAt:  file.CreatedAt,
+		UpdatedAt:  file.UpdatedAt,
+	})
+	if err != nil {
+		return File{}, err
+	}
+	file = s.fromDBItem(dbFile)
+	s.Publish(pubsub.CreatedEvent, file)
+	return file, nil
+}
+
 func (s *service) Get(ctx context.Context, id string) (File, error) {
 	dbFile, err := s.q.GetFile(ctx, id)
 	if err != nil {
@@ -199,12 +236,13 @@ func (s *service) DeleteSessionFiles(ctx context.Context, sessionID string) erro
 
 func (s *service) fromDBItem(item db.File) File {
 	return File{
-		ID:        item.ID,
-		SessionID: item.SessionID,
-		Path:      item.Path,
-		Content:   item.Content,
-		Version:   item.Version,
-		CreatedAt: item.CreatedAt,
-		UpdatedAt: item.UpdatedAt,
+		ID:         item.ID,
+		SessionID:  item.SessionID,
+		Path:       item.Path,
+		Content:    item.Content,
+		Version:    item.Version,
+		CreatedAt:  item.CreatedAt,
+		UpdatedAt:  item.UpdatedAt,
+		ToolCallID: item.ToolCallID,
 	}
 }
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/fsext"
+)
+
+const (
+	// maxWorkspaceEntries is how many files and directories a workspace may
+	// have for its changes to be tracked, so that scanning it stays cheap.
+	maxWorkspaceEntries = 20000
+	// maxTrackedFileSize is the size of the largest file whose changes are
+	// tracked.
+	maxTrackedFileSize = 256 * 1024
+	// maxWorkspaceSize is how much file content a workspace may keep.
+	maxWorkspaceSize = 64 * 1024 * 1024
+)
+
+// Change is a change a command made to a file of a workspace. Before is ""
+// for created files and After is "" for deleted ones.
+type Change struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// Workspace keeps the content of the text files of a directory, skipping
+// those the ignore rules of fsext skip, so that the changes commands make to
+// them can be recorded. Changes are found by comparing modification times
+// and sizes, reading only the files that differ.
+type Workspace struct {
+	root string
+
+	mu    sync.Mutex
+	files map[string]workspaceFile
+	// tooLarge is set once the workspace is found too large to track, so
+	// that it is not scanned again.
+	tooLarge error
+	// running is how many commands started with Track are running, and
+	// overlapped is set once two of them ran at the same time.
+	running    int
+	overlapped bool
+}
+
+type workspaceFile struct {
+	modTime time.Time
+	size    int64
+	content string
+}
+
+func NewWorkspace(root string) *Workspace {
+	return &Workspace{root: root}
+}
+
+// workspaces holds the workspaces returned by SharedWorkspace by root.
+var workspaces sync.Map // map[string]*Workspace
+
+// SharedWorkspace returns the workspace of root that all its users share, so
+// that commands running in it at the same time are known to each other.
+func SharedWorkspace(root string) *Workspace {
+	w, _ := workspaces.LoadOrStore(root, NewWorkspace(root))
+	return w.(*Workspace)
+}
+
+// Track is called before a command runs in the workspace, and Finish with
+// its result once it ended. It takes a snapshot and reports whether the
+// changes of the command will be tracked, which they are not if another
+// command of the workspace is running: the changes of commands running at
+// the same time can not be told apart, so neither of them gets any.
+func (w *Workspace) Track() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running++
+	if w.running > 1 {
+		w.overlapped = true
+		return false, nil
+	}
+	w.overlapped = false
+	if _, err := w.scan(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Finish ends a command started with Track and returns the changes it made
+// if they were tracked and no other command ran meanwhile.
+func (w *Workspace) Finish(tracked bool) ([]Change, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running--
+	if !tracked || w.overlapped || w.files == nil {
+		return nil, nil
+	}
+	return w.scan()
+}
+
+// scan updates the files of the workspace and returns how they changed.
+func (w *Workspace) scan() ([]Change, error) {
+	if w.tooLarge != nil {
+		return nil, w.tooLarge
+	}
+	paths, truncated, err := fsext.ListDirectory(w.root, nil, maxWorkspaceEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace files: %w", err)
+	}
+	if truncated {
+		w.files = nil
+		w.tooLarge = fmt.Errorf("workspace has more than %d files", maxWorkspaceEntries)
+		return nil, w.tooLarge
+	}
+
+	first := w.files == nil
+	files := make(map[string]workspaceFile, len(paths))
+	var changes []Change
+	var size int64
+	for _, path := range paths {
+		if strings.HasSuffix(path, string(os.PathSeparator)) || strings.HasSuffix(path, "/") {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxTrackedFileSize {
+			continue
+		}
+		if size += info.Size(); size > maxWorkspaceSize {
+			w.files = nil
+			w.tooLarge = fmt.Errorf("workspace has more than %d bytes of files", maxWorkspaceSize)
+			return nil, w.tooLarge
+		}
+		old, known := w.files[path]
+		if known && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			files[path] = old
+			continue
+		}
+		content, ok := readText(path)
+		if !ok {
+			continue
+		}
+		files[path] = workspaceFile{modTime: info.ModTime(), size: info.Size(), content: content}
+		if !first && old.content != content {
+			changes = append(changes, Change{Path: path, Before: old.content, After: content})
+		}
+	}
+	for path, old := range w.files {
+		if _, ok := files[path]; !ok && old.content != "" {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				changes = append(changes, Change{Path: path, Before: old.content})
+			}
+		}
+	}
+	w.files = files
+	return changes, nil
+}
+
+// readText returns the content of the file at path if it is text.
+func readText(path string) (string, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return "", false
+	}
+	return string(content), true
+}
@@ -0,0 +1,108 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceChanges(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		// Make sure the modification time changes on coarse file systems.
+		later := time.Now().Add(time.Duration(len(content)+1) * time.Second)
+		require.NoError(t, os.Chtimes(path, later, later))
+	}
+	write("main.go", "package main\n")
+	write("gone.txt", "bye\n")
+	write("node_modules/dep.js", "ignored\n")
+	write("image.png", "\x89PNG\x00\x00")
+
+	w := NewWorkspace(root)
+	tracked, err := w.Track()
+	require.NoError(t, err)
+	require.True(t, tracked)
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("new.txt", "hello\n")
+	write("node_modules/dep.js", "still ignored\n")
+	write("image.png", "\x89PNG\x00\x01")
+	require.NoError(t, os.Remove(filepath.Join(root, "gone.txt")))
+
+	changes, err := w.Finish(tracked)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Change{
+		{Path: filepath.Join(root, "main.go"), Before: "package main\n", After: "package main\n\nfunc main() {}\n"},
+		{Path: filepath.Join(root, "new.txt"), After: "hello\n"},
+		{Path: filepath.Join(root, "gone.txt"), Before: "bye\n"},
+	}, changes)
+
+	// The next command only gets its own changes.
+	tracked, err = w.Track()
+	require.NoError(t, err)
+	changes, err = w.Finish(tracked)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestWorkspaceTrack(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	path := filepath.Join(root, "main.go")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		later := time.Now().Add(time.Duration(len(content)+1) * time.Second)
+		require.NoError(t, os.Chtimes(path, later, later))
+	}
+	write("package main\n")
+	w := NewWorkspace(root)
+
+	// A command running alone gets its changes.
+	tracked, err := w.Track()
+	require.NoError(t, err)
+	require.True(t, tracked)
+	write("package main\n\n// one\n")
+	changes, err := w.Finish(tracked)
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Path: path, Before: "package main\n", After: "package main\n\n// one\n"}}, changes)
+
+	// Commands running at the same time get none.
+	first, err := w.Track()
+	require.NoError(t, err)
+	require.True(t, first)
+	second, err := w.Track()
+	require.NoError(t, err)
+	require.False(t, second)
+	write("package main\n\n// three\n")
+	changes, err = w.Finish(second)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+	changes, err = w.Finish(first)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	// Nor are their changes given to the next command.
+	tracked, err = w.Track()
+	require.NoError(t, err)
+	require.True(t, tracked)
+	changes, err = w.Finish(tracked)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestSharedWorkspace(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.Same(t, SharedWorkspace(root), SharedWorkspace(root))
+	require.NotSame(t, SharedWorkspace(root), SharedWorkspace(t.TempDir()))
+}
@@ -0,0 +1,189 @@
+package inputhistory
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShellHistory is the history file of a shell.
+type ShellHistory struct {
+	Shell string
+	Path  string
+}
+
+// ShellHistories returns the bash, zsh and fish history files of the user
+// that exist.
+func ShellHistories() []ShellHistory {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	zsh := os.Getenv("HISTFILE")
+	if zsh == "" || !strings.Contains(filepath.Base(zsh), "zsh") {
+		zsh = filepath.Join(home, ".zsh_history")
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	candidates := []ShellHistory{
+		{Shell: "bash", Path: filepath.Join(home, ".bash_history")},
+		{Shell: "zsh", Path: zsh},
+		{Shell: "fish", Path: filepath.Join(dataHome, "fish", "fish_history")},
+	}
+	var found []ShellHistory
+	for _, h := range candidates {
+		if info, err := os.Stat(h.Path); err == nil && info.Size() > 0 {
+			found = append(found, h)
+		}
+	}
+	return found
+}
+
+// Read parses the history file into entries, oldest first.
+func (h ShellHistory) Read() ([]Entry, error) {
+	data, err := os.ReadFile(h.Path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	switch h.Shell {
+	case "zsh":
+		entries = parseZsh(data)
+	case "fish":
+		entries = parseFish(data)
+	default:
+		entries = parseBash(data)
+	}
+	for i := range entries {
+		entries[i].Mode = "Shell"
+		entries[i].Source = h.Shell
+	}
+	return entries, nil
+}
+
+// parseBash parses a bash history file. With HISTTIMEFORMAT set, bash
+// writes the time of each command on a "#<unix time>" line before it.
+func parseBash(data []byte) []Entry {
+	var entries []Entry
+	var when time.Time
+	for line := range strings.SplitSeq(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if ts, ok := strings.CutPrefix(line, "#"); ok {
+			if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				when = time.Unix(sec, 0)
+				continue
+			}
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entries = append(entries, Entry{Text: line, Time: when})
+		when = time.Time{}
+	}
+	return entries
+}
+
+// parseZsh parses a zsh history file, in the plain or the extended
+// ": <time>:<duration>;<command>" format. Multi-line commands end their
+// lines with a backslash.
+func parseZsh(data []byte) []Entry {
+	var entries []Entry
+	var cmd strings.Builder
+	var when time.Time
+	for line := range strings.SplitSeq(unmetafy(data), "\n") {
+		if cmd.Len() == 0 {
+			when = time.Time{}
+			if rest, ok := strings.CutPrefix(line, ": "); ok {
+				if meta, command, ok := strings.Cut(rest, ";"); ok {
+					ts, _, _ := strings.Cut(meta, ":")
+					if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+						when = time.Unix(sec, 0)
+						line = command
+					}
+				}
+			}
+		}
+		if cont, ok := strings.CutSuffix(line, "\\"); ok {
+			cmd.WriteString(cont + "\n")
+			continue
+		}
+		cmd.WriteString(line)
+		if text := strings.TrimSpace(cmd.String()); text != "" {
+			entries = append(entries, Entry{Text: text, Time: when})
+		}
+		cmd.Reset()
+	}
+	return entries
+}
+
+// unmetafy decodes the bytes zsh escapes in its history file: 0x83 is
+// followed by the original byte XOR 0x20.
+func unmetafy(data []byte) string {
+	const meta = 0x83
+	if bytes.IndexByte(data, meta) < 0 {
+		return string(data)
+	}
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == meta && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^0x20)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return string(out)
+}
+
+// parseFish parses fish's YAML-like history file:
+//
+//   - cmd: git status
+//     when: 1700000000
+func parseFish(data []byte) []Entry {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if cmd, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			entries = append(entries, Entry{Text: unescapeFish(cmd)})
+			continue
+		}
+		if ts, ok := strings.CutPrefix(line, "  when: "); ok && len(entries) > 0 {
+			if sec, err := strconv.ParseInt(strings.TrimSpace(ts), 10, 64); err == nil {
+				entries[len(entries)-1].Time = time.Unix(sec, 0)
+			}
+		}
+	}
+	return entries
+}
+
+// unescapeFish decodes the \n and \\ escapes of fish history commands.
+func unescapeFish(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n':
+				sb.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				sb.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
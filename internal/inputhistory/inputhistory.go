@@ -0,0 +1,316 @@
+// Package inputhistory stores the prompts and commands entered in the editor,
+// with the context they were entered in, and finds them again for
+// autosuggestions and history search.
+package inputhistory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a line entered in the editor.
+type Entry struct {
+	Text string `json:"text"`
+	// Mode is the input mode the entry was sent in: Shell, Agent or Auto.
+	Mode string `json:"mode,omitempty"`
+	// Cwd is the working directory of the user's shell at the time.
+	Cwd string `json:"cwd,omitempty"`
+	// ExitCode is the exit status of a shell command, once it finished.
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Time     time.Time `json:"time,omitzero"`
+	// Source names the shell the entry was imported from, if any.
+	Source string `json:"source,omitempty"`
+}
+
+// Failed reports whether the entry is a shell command that exited with a
+// non-zero status.
+func (e Entry) Failed() bool {
+	return e.ExitCode != nil && *e.ExitCode != 0
+}
+
+// key identifies the record of an entry in the history file. Records with
+// the same key update the entry instead of adding one.
+func (e Entry) key() string {
+	return e.Time.Format(time.RFC3339Nano) + "\x00" + e.Text
+}
+
+// parseLine decodes a line of the history file. Old history files store each
+// entry as a bare JSON string.
+func parseLine(line []byte) (Entry, bool) {
+	line = bytes.TrimSpace(line)
+	var e Entry
+	switch {
+	case len(line) == 0:
+		return e, false
+	case line[0] == '"':
+		if err := json.Unmarshal(line, &e.Text); err != nil {
+			return e, false
+		}
+	default:
+		if err := json.Unmarshal(line, &e); err != nil {
+			return e, false
+		}
+	}
+	return e, e.Text != ""
+}
+
+// Store is the input history, kept in memory and appended to a JSONL file.
+// It is safe for concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	entries []Entry
+}
+
+// Open loads the history stored at path. A missing file is an empty history.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	defer f.Close()
+
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	// Increase the buffer in case of long multi-line prompts
+	buf := make([]byte, 0, 1024*64)
+	scanner.Buffer(buf, 1024*1024)
+	for scanner.Scan() {
+		e, ok := parseLine(scanner.Bytes())
+		if !ok {
+			// Skip malformed lines
+			continue
+		}
+		if !e.Time.IsZero() {
+			if i, ok := index[e.key()]; ok {
+				s.entries[i] = e
+				continue
+			}
+			index[e.key()] = len(s.entries)
+		}
+		s.entries = append(s.entries, e)
+	}
+	return s, scanner.Err()
+}
+
+// Entries returns a copy of the history, oldest first.
+func (s *Store) Entries() []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.entries)
+}
+
+// Len returns the number of entries.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Add appends e to the history, unless it repeats the last entry. The time
+// defaults to now.
+func (s *Store) Add(e Entry) error {
+	e.Text = strings.TrimSpace(e.Text)
+	if e.Text == "" {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.entries); n > 0 && s.entries[n-1].Text == e.Text {
+		return nil
+	}
+	s.entries = append(s.entries, e)
+	return s.write(e)
+}
+
+// SetExitCode records the exit status of the most recent entry for the shell
+// command text that has none yet.
+func (s *Store) SetExitCode(text string, code int) error {
+	text = strings.TrimSpace(text)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range slices.Backward(s.entries) {
+		if e.Text != text {
+			continue
+		}
+		if e.ExitCode != nil {
+			return nil
+		}
+		e.ExitCode = &code
+		s.entries[i] = e
+		return s.write(e)
+	}
+	return nil
+}
+
+// write appends the record of e to the history file.
+func (s *Store) write(entries ...Entry) error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return encode(f, entries)
+}
+
+// encode writes entries as JSON lines.
+func encode(f *os.File, entries []Entry) error {
+	w := bufio.NewWriter(f)
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		w.Write(append(b, '\n')) //nolint:errcheck
+	}
+	return w.Flush()
+}
+
+// Import adds entries from another shell's history before the existing ones,
+// skipping commands already in the history. It rewrites the history file and
+// returns how many entries were added.
+func (s *Store) Import(entries []Entry) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool, len(s.entries))
+	for _, e := range s.entries {
+		seen[e.Text] = true
+	}
+	// Keep the most recent use of repeated commands.
+	var imported []Entry
+	for _, e := range slices.Backward(entries) {
+		e.Text = strings.TrimSpace(e.Text)
+		if e.Text == "" || seen[e.Text] {
+			continue
+		}
+		seen[e.Text] = true
+		imported = append(imported, e)
+	}
+	if len(imported) == 0 {
+		return 0, nil
+	}
+	slices.Reverse(imported)
+	slices.SortStableFunc(imported, func(a, b Entry) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	all := append(imported, s.entries...)
+	if s.path != "" {
+		if err := s.rewrite(all); err != nil {
+			return 0, err
+		}
+	}
+	s.entries = all
+	return len(imported), nil
+}
+
+// rewrite replaces the history file with entries.
+func (s *Store) rewrite(entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), ".input_history-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck
+	if err := encode(f, entries); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// score ranks an entry for the given working directory and mode: entries
+// from the same directory come first, then those that didn't fail, then
+// those from the same mode.
+func score(e Entry, cwd, mode string) int {
+	var n int
+	if cwd != "" && e.Cwd == cwd {
+		n += 4
+	}
+	if !e.Failed() {
+		n += 2
+	}
+	if mode != "" && e.Mode == mode {
+		n++
+	}
+	return n
+}
+
+// Suggest returns the entry that best completes prefix, or an empty string.
+// Among the entries that start with prefix, the best ranked one wins and
+// ties go to the most recent.
+func (s *Store) Suggest(prefix, cwd, mode string) string {
+	if strings.TrimSpace(prefix) == "" {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	best, bestScore := "", -1
+	for _, e := range slices.Backward(s.entries) {
+		if len(e.Text) <= len(prefix) || !strings.HasPrefix(e.Text, prefix) || strings.Contains(e.Text, "\n") {
+			continue
+		}
+		if sc := score(e, cwd, mode); sc > bestScore {
+			best, bestScore = e.Text, sc
+		}
+	}
+	return best
+}
+
+// Search returns up to limit distinct entries containing query, ignoring
+// case. Entries from cwd come first, then the others, each most recent
+// first.
+func (s *Store) Search(query, cwd string, limit int) []Entry {
+	query = strings.ToLower(query)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var here, elsewhere []Entry
+	seen := make(map[string]bool)
+	for _, e := range slices.Backward(s.entries) {
+		if seen[e.Text] || !strings.Contains(strings.ToLower(e.Text), query) {
+			continue
+		}
+		seen[e.Text] = true
+		if cwd != "" && e.Cwd == cwd {
+			here = append(here, e)
+		} else {
+			elsewhere = append(elsewhere, e)
+		}
+		if len(here) >= limit {
+			break
+		}
+	}
+	results := append(here, elsewhere...)
+	return results[:min(len(results), limit)]
+}
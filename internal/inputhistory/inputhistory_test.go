@@ -0,0 +1,131 @@
+package inputhistory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func texts(entries []Entry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Text
+	}
+	return out
+}
+
+func TestOpenReadsOldAndNewLines(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "input_history.jsonl")
+	content := `"old prompt"
+not json
+{"text":"ls -la","mode":"Shell","cwd":"/src","time":"2025-01-02T03:04:05Z"}
+{"text":"ls -la","mode":"Shell","cwd":"/src","exit_code":2,"time":"2025-01-02T03:04:05Z"}
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	s, err := Open(path)
+	require.NoError(t, err)
+	entries := s.Entries()
+	require.Equal(t, []string{"old prompt", "ls -la"}, texts(entries))
+	require.True(t, entries[1].Failed())
+	require.Equal(t, "/src", entries[1].Cwd)
+}
+
+func TestAddAndSetExitCodePersist(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "input_history.jsonl")
+	s, err := Open(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Add(Entry{Text: "make test", Mode: "Shell", Cwd: "/src"}))
+	require.NoError(t, s.Add(Entry{Text: "make test", Mode: "Shell", Cwd: "/src"}))
+	require.NoError(t, s.SetExitCode("make test", 1))
+	require.Equal(t, 1, s.Len())
+
+	reloaded, err := Open(path)
+	require.NoError(t, err)
+	entries := reloaded.Entries()
+	require.Len(t, entries, 1)
+	require.True(t, entries[0].Failed())
+	require.False(t, entries[0].Time.IsZero())
+}
+
+func TestSuggest(t *testing.T) {
+	t.Parallel()
+
+	ok, failed := 0, 1
+	s := &Store{entries: []Entry{
+		{Text: "go test ./internal/...", Cwd: "/src", ExitCode: &ok},
+		{Text: "go test ./...", Cwd: "/other", ExitCode: &ok},
+		{Text: "go test -run Foo", Cwd: "/src", ExitCode: &failed},
+		{Text: "go build", Cwd: "/src"},
+	}}
+
+	// Same directory and successful beats more recent.
+	require.Equal(t, "go test ./internal/...", s.Suggest("go t", "/src", ""))
+	require.Equal(t, "go test ./...", s.Suggest("go t", "/other", ""))
+	require.Equal(t, "go build", s.Suggest("go", "/src", ""))
+	require.Empty(t, s.Suggest("go build", "/src", ""))
+	require.Empty(t, s.Suggest(" ", "/src", ""))
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	s := &Store{entries: []Entry{
+		{Text: "git status", Cwd: "/src"},
+		{Text: "Git log", Cwd: "/other"},
+		{Text: "git status", Cwd: "/other"},
+		{Text: "ls", Cwd: "/src"},
+	}}
+	require.Equal(t, []string{"git status", "Git log"}, texts(s.Search("git", "/other", 10)))
+	require.Equal(t, []string{"git status"}, texts(s.Search("GIT", "/src", 1)))
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "input_history.jsonl")
+	s, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Add(Entry{Text: "npm start"}))
+
+	n, err := s.Import([]Entry{
+		{Text: "ls", Time: time.Unix(200, 0)},
+		{Text: "npm start", Time: time.Unix(100, 0)},
+		{Text: "cd /tmp", Time: time.Unix(100, 0)},
+		{Text: "ls", Time: time.Unix(300, 0)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{"cd /tmp", "ls", "npm start"}, texts(s.Entries()))
+
+	reloaded, err := Open(path)
+	require.NoError(t, err)
+	require.Equal(t, texts(s.Entries()), texts(reloaded.Entries()))
+}
+
+func TestParseShellHistories(t *testing.T) {
+	t.Parallel()
+
+	bash := parseBash([]byte("#1700000000\ngit status\nls\n\n"))
+	require.Equal(t, []string{"git status", "ls"}, texts(bash))
+	require.Equal(t, int64(1700000000), bash[0].Time.Unix())
+	require.True(t, bash[1].Time.IsZero())
+
+	zsh := parseZsh([]byte(": 1700000000:0;echo one\\\ntwo\n: 1700000001:3;make\nplain\n"))
+	require.Equal(t, []string{"echo one\ntwo", "make", "plain"}, texts(zsh))
+	require.Equal(t, int64(1700000001), zsh[1].Time.Unix())
+
+	fish := parseFish([]byte("- cmd: echo a\\nb\n  when: 1700000000\n  paths:\n    - a\n- cmd: ls\n"))
+	require.Equal(t, []string{"echo a\nb", "ls"}, texts(fish))
+	require.Equal(t, int64(1700000000), fish[0].Time.Unix())
+
+	require.Equal(t, "é", unmetafy([]byte{0xc3, 0x83, 0xa9 ^ 0x20}))
+}
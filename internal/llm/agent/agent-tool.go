@@ -4,16 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
     "github.com/lacymorrow/lash/internal/llm/tools"
     "github.com/lacymorrow/lash/internal/message"
+    "github.com/lacymorrow/lash/internal/pubsub"
     "github.com/lacymorrow/lash/internal/session"
+    "github.com/lacymorrow/lash/internal/shell"
 )
 
 type agentTool struct {
 	agent    Service
 	sessions session.Service
 	messages message.Service
+	shells   *shell.Manager
+
+	// costMu keeps sub-agents running at the same time from overwriting
+	// each other's cost in the parent session.
+	costMu sync.Mutex
 }
 
 const (
@@ -24,6 +32,28 @@ type AgentParams struct {
 	Prompt string `json:"prompt"`
 }
 
+// SubAgent is the progress of a running sub-agent, published as it runs.
+// Its session is a child of the parent session with the ID of the agent tool
+// call that started it.
+type SubAgent struct {
+	SessionID       string
+	ParentSessionID string
+	Prompt          string
+	// ToolCalls is how many tools the sub-agent called so far, and
+	// CurrentTool the last one.
+	ToolCalls   int
+	CurrentTool string
+	Cost        float64
+}
+
+var subAgentBroker = pubsub.NewBroker[SubAgent]()
+
+// SubscribeSubAgents returns a channel for the progress of running
+// sub-agents. A sub-agent is deleted when it finishes.
+func SubscribeSubAgents(ctx context.Context) <-chan pubsub.Event[SubAgent] {
+	return subAgentBroker.Subscribe(ctx)
+}
+
 func (b *agentTool) Name() string {
 	return AgentToolName
 }
@@ -31,7 +61,7 @@ func (b *agentTool) Name() string {
 func (b *agentTool) Info() tools.ToolInfo {
 	return tools.ToolInfo{
 		Name:        AgentToolName,
-		Description: "Launch a new agent that has access to the following tools: GlobTool, GrepTool, LS, View. When you are searching for a keyword or file and are not confident that you will find the right match on the first try, use the Agent tool to perform the search for you. For example:\n\n- If you are searching for a keyword like \"config\" or \"logger\", or for questions like \"which file does X?\", the Agent tool is strongly recommended\n- If you want to read a specific file path, use the View or GlobTool tool instead of the Agent tool, to find the match more quickly\n- If you are searching for a specific class definition like \"class Foo\", use the GlobTool tool instead, to find the match more quickly\n\nUsage notes:\n1. Launch multiple agents concurrently whenever possible, to maximize performance; to do that, use a single message with multiple tool uses\n2. When the agent is done, it will return a single message back to you. The result returned by the agent is not visible to the user. To show the user the result, you should send a text message back to the user with a concise summary of the result.\n3. Each agent invocation is stateless. You will not be able to send additional messages to the agent, nor will the agent be able to communicate with you outside of its final report. Therefore, your prompt should contain a highly detailed task description for the agent to perform autonomously and you should specify exactly what information the agent should return back to you in its final and only message to you.\n4. The agent's outputs should generally be trusted\n5. IMPORTANT: The agent can not use Bash, Replace, Edit, so can not modify files. If you want to use these tools, use them directly instead of going through the agent.",
+		Description: "Launch a new agent that has access to the following tools: GlobTool, GrepTool, LS, View. When you are searching for a keyword or file and are not confident that you will find the right match on the first try, use the Agent tool to perform the search for you. For example:\n\n- If you are searching for a keyword like \"config\" or \"logger\", or for questions like \"which file does X?\", the Agent tool is strongly recommended\n- If you want to read a specific file path, use the View or GlobTool tool instead of the Agent tool, to find the match more quickly\n- If you are searching for a specific class definition like \"class Foo\", use the GlobTool tool instead, to find the match more quickly\n\nUsage notes:\n1. Launch multiple agents concurrently whenever possible, to maximize performance; to do that, use a single message with multiple tool uses. For example, to investigate several packages, launch one agent per package. The agents of a message run in parallel and their results come back together\n2. When the agent is done, it will return a single message back to you. The result returned by the agent is not visible to the user. To show the user the result, you should send a text message back to the user with a concise summary of the result.\n3. Each agent invocation is stateless. You will not be able to send additional messages to the agent, nor will the agent be able to communicate with you outside of its final report. Therefore, your prompt should contain a highly detailed task description for the agent to perform autonomously and you should specify exactly what information the agent should return back to you in its final and only message to you.\n4. The agent's outputs should generally be trusted\n5. IMPORTANT: The agent can not use Bash, Replace, Edit, so can not modify files. If you want to use these tools, use them directly instead of going through the agent.",
 		Parameters: map[string]any{
 			"prompt": map[string]any{
 				"type":        "string",
@@ -42,7 +72,7 @@ func (b *agentTool) Info() tools.ToolInfo {
 	}
 }
 
-func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (response tools.ToolResponse, err error) {
 	var params AgentParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
 		return tools.NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
@@ -60,6 +90,32 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
 	}
+	// What the sub-agent spent counts for the parent even if it failed or
+	// was cancelled.
+	defer func() {
+		if costErr := b.rollUpCost(context.WithoutCancel(ctx), sessionID, session.ID); costErr != nil && err == nil {
+			response, err = tools.ToolResponse{}, costErr
+		}
+	}()
+	b.shells.Fork(sessionID, session.ID)
+
+	progress := SubAgent{
+		SessionID:       session.ID,
+		ParentSessionID: sessionID,
+		Prompt:          params.Prompt,
+	}
+	subAgentBroker.Publish(pubsub.CreatedEvent, progress)
+	progressCtx, stopProgress := context.WithCancel(ctx)
+	tracked := make(chan struct{})
+	go func() {
+		defer close(tracked)
+		b.trackProgress(progressCtx, progress)
+	}()
+	defer func() {
+		stopProgress()
+		<-tracked
+		subAgentBroker.Publish(pubsub.DeletedEvent, progress)
+	}()
 
 	done, err := b.agent.Run(ctx, session.ID, params.Prompt)
 	if err != nil {
@@ -70,37 +126,91 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 		return tools.ToolResponse{}, fmt.Errorf("error generating agent: %s", result.Error)
 	}
 
-	response := result.Message
-	if response.Role != message.Assistant {
+	reply := result.Message
+	if reply.Role != message.Assistant {
 		return tools.NewTextErrorResponse("no response"), nil
 	}
+	return tools.NewTextResponse(reply.Content().String()), nil
+}
 
-	updatedSession, err := b.sessions.Get(ctx, session.ID)
+// rollUpCost adds the cost of the session of a sub-agent to its parent
+// session.
+func (b *agentTool) rollUpCost(ctx context.Context, parentSessionID, sessionID string) error {
+	updatedSession, err := b.sessions.Get(ctx, sessionID)
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error getting session: %s", err)
+		return fmt.Errorf("error getting session: %s", err)
+	}
+	if updatedSession.Cost == 0 {
+		return nil
 	}
-	parentSession, err := b.sessions.Get(ctx, sessionID)
+	b.costMu.Lock()
+	defer b.costMu.Unlock()
+	parentSession, err := b.sessions.Get(ctx, parentSessionID)
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error getting parent session: %s", err)
+		return fmt.Errorf("error getting parent session: %s", err)
 	}
 
 	parentSession.Cost += updatedSession.Cost
 
 	_, err = b.sessions.Save(ctx, parentSession)
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error saving parent session: %s", err)
+		return fmt.Errorf("error saving parent session: %s", err)
+	}
+	return nil
+}
+
+// trackProgress publishes the progress of a sub-agent as its messages and
+// session change, until ctx is done.
+func (b *agentTool) trackProgress(ctx context.Context, progress SubAgent) {
+	messageEvents := b.messages.Subscribe(ctx)
+	sessionEvents := b.sessions.Subscribe(ctx)
+	toolCalls := make(map[string]struct{})
+	for {
+		select {
+		case event, ok := <-messageEvents:
+			if !ok {
+				return
+			}
+			if event.Payload.SessionID != progress.SessionID {
+				continue
+			}
+			changed := false
+			for _, toolCall := range event.Payload.ToolCalls() {
+				if _, ok := toolCalls[toolCall.ID]; !ok {
+					toolCalls[toolCall.ID] = struct{}{}
+					progress.CurrentTool = toolCall.Name
+					changed = true
+				}
+			}
+			if !changed {
+				continue
+			}
+			progress.ToolCalls = len(toolCalls)
+		case event, ok := <-sessionEvents:
+			if !ok {
+				return
+			}
+			if event.Payload.ID != progress.SessionID || event.Payload.Cost == progress.Cost {
+				continue
+			}
+			progress.Cost = event.Payload.Cost
+		case <-ctx.Done():
+			return
+		}
+		subAgentBroker.Publish(pubsub.UpdatedEvent, progress)
 	}
-	return tools.NewTextResponse(response.Content().String()), nil
 }
 
 func NewAgentTool(
 	agent Service,
 	sessions session.Service,
 	messages message.Service,
+	shells *shell.Manager,
 ) tools.BaseTool {
 	return &agentTool{
 		sessions: sessions,
 		messages: messages,
 		agent:    agent,
+		shells:   shells,
 	}
 }
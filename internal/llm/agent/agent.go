@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
@@ -29,6 +30,7 @@ import (
 var (
 	ErrRequestCancelled = errors.New("request canceled by user")
 	ErrSessionBusy      = errors.New("session is currently processing another request")
+	ErrSessionNotBusy   = errors.New("session is not processing a request")
 )
 
 type AgentEventType string
@@ -48,6 +50,10 @@ type AgentEvent struct {
 	SessionID string
 	Progress  string
 	Done      bool
+
+	// Steering holds the notes added with Steer that the request ended
+	// before sending, on its last event.
+	Steering []string
 }
 
 type Service interface {
@@ -55,9 +61,15 @@ type Service interface {
 	Model() catwalk.Model
 	Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error)
 	Cancel(sessionID string)
+	// CancelToolCall cancels a running tool call without cancelling the
+	// rest of the request.
+	CancelToolCall(toolCallID string)
 	CancelAll()
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
+	// Steer adds a note from the user to the running request of a session,
+	// without cancelling it.
+	Steer(sessionID string, content string) error
 	Summarize(ctx context.Context, sessionID string) error
 	UpdateModel() error
 }
@@ -67,6 +79,7 @@ type agent struct {
 	agentCfg config.Agent
 	sessions session.Service
 	messages message.Service
+	shells   *shell.Manager
 	mcpTools []McpTool
 
 	tools *csync.LazySlice[tools.BaseTool]
@@ -78,7 +91,13 @@ type agent struct {
 	summarizeProvider   provider.Provider
 	summarizeProviderID string
 
-	activeRequests *csync.Map[string, context.CancelFunc]
+	activeRequests  *csync.Map[string, context.CancelFunc]
+	activeToolCalls *csync.Map[string, context.CancelFunc]
+
+	// steering holds the notes for the running requests of sessions that
+	// have not been sent to the model yet.
+	steeringMu sync.Mutex
+	steering   map[string][]string
 }
 
 var agentPromptMap = map[string]prompt.PromptID{
@@ -94,22 +113,39 @@ func NewAgent(
 	sessions session.Service,
 	messages message.Service,
 	history history.Service,
+	shells *shell.Manager,
 	lspClients map[string]*lsp.Client,
 ) (Service, error) {
 	cfg := config.Get()
 
+	if agentCfg.Permissions != nil {
+		permissions = permission.WithDefaults(permissions, permission.Defaults{
+			AllowedTools:  agentCfg.Permissions.AllowedTools,
+			WritablePaths: agentCfg.Permissions.WritablePaths,
+		}, cfg.WorkingDir())
+	}
+	if agentCfg.AllowedLSP != nil {
+		allowedClients := make(map[string]*lsp.Client, len(agentCfg.AllowedLSP))
+		for name, client := range lspClients {
+			if slices.Contains(agentCfg.AllowedLSP, name) {
+				allowedClients[name] = client
+			}
+		}
+		lspClients = allowedClients
+	}
+
 	var agentTool tools.BaseTool
-	if agentCfg.ID == "coder" {
+	if agentCfg.ID != "task" && (agentCfg.AllowedTools == nil || slices.Contains(agentCfg.AllowedTools, AgentToolName)) {
 		taskAgentCfg := config.Get().Agents["task"]
 		if taskAgentCfg.ID == "" {
 			return nil, fmt.Errorf("task agent not found in config")
 		}
-		taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, lspClients)
+		taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, shells, lspClients)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create task agent: %w", err)
 		}
 
-		agentTool = NewAgentTool(taskAgent, sessions, messages)
+		agentTool = NewAgentTool(taskAgent, sessions, messages, shells)
 	}
 
 	providerCfg := config.Get().GetProviderForModel(agentCfg.Model)
@@ -122,13 +158,13 @@ func NewAgent(
 		return nil, fmt.Errorf("model not found for agent %s", agentCfg.Name)
 	}
 
-	promptID := agentPromptMap[agentCfg.ID]
-	if promptID == "" {
-		promptID = prompt.PromptDefault
+	systemMessage, err := systemPrompt(agentCfg, providerCfg.ID)
+	if err != nil {
+		return nil, err
 	}
 	opts := []provider.ProviderClientOption{
 		provider.WithModel(agentCfg.Model),
-		provider.WithSystemMessage(prompt.GetPrompt(promptID, providerCfg.ID, config.Get().Options.ContextPaths...)),
+		provider.WithSystemMessage(systemMessage),
 	}
 	agentProvider, err := provider.NewProvider(*providerCfg, opts...)
 	if err != nil {
@@ -177,7 +213,7 @@ func NewAgent(
 
 		cwd := cfg.WorkingDir()
 		allTools := []tools.BaseTool{
-			tools.NewBashTool(permissions, cwd),
+			tools.NewBashTool(permissions, shells, history, cwd),
 			tools.NewDownloadTool(permissions, cwd),
 			tools.NewEditTool(lspClients, permissions, history, cwd),
 			tools.NewMultiEditTool(lspClients, permissions, history, cwd),
@@ -185,16 +221,17 @@ func NewAgent(
 			tools.NewGlobTool(cwd),
 			tools.NewGrepTool(cwd),
 			tools.NewLsTool(permissions, cwd),
+			tools.NewProcessStartTool(permissions, shells, cwd),
+			tools.NewProcessOutputTool(shells),
+			tools.NewProcessSendTool(permissions, shells, cwd),
+			tools.NewProcessListTool(shells),
+			tools.NewProcessKillTool(shells),
+			tools.NewReadOutputTool(),
 			tools.NewSourcegraphTool(),
 			tools.NewViewTool(lspClients, permissions, cwd),
 			tools.NewWriteTool(lspClients, permissions, history, cwd),
 		}
 
-		mcpToolsOnce.Do(func() {
-			mcpTools = doGetMCPTools(ctx, permissions, cfg)
-		})
-		allTools = append(allTools, mcpTools...)
-
 		if len(lspClients) > 0 {
 			allTools = append(allTools, tools.NewDiagnosticsTool(lspClients))
 		}
@@ -203,17 +240,21 @@ func NewAgent(
 			allTools = append(allTools, agentTool)
 		}
 
-		if agentCfg.AllowedTools == nil {
-			return allTools
-		}
-
-		var filteredTools []tools.BaseTool
-		for _, tool := range allTools {
-			if slices.Contains(agentCfg.AllowedTools, tool.Name()) {
-				filteredTools = append(filteredTools, tool)
+		if agentCfg.AllowedTools != nil {
+			var filteredTools []tools.BaseTool
+			for _, tool := range allTools {
+				if slices.Contains(agentCfg.AllowedTools, tool.Name()) {
+					filteredTools = append(filteredTools, tool)
+				}
 			}
+			allTools = filteredTools
 		}
-		return filteredTools
+
+		// MCP tools are filtered by AllowedMCP instead.
+		mcpToolsOnce.Do(func() {
+			mcpTools = doGetMCPTools(ctx, permissions, cfg)
+		})
+		return append(allTools, agentMCPTools(agentCfg, permissions)...)
 	}
 
 	return &agent{
@@ -223,14 +264,30 @@ func NewAgent(
 		providerID:          string(providerCfg.ID),
 		messages:            messages,
 		sessions:            sessions,
+		shells:              shells,
 		titleProvider:       titleProvider,
 		summarizeProvider:   summarizeProvider,
 		summarizeProviderID: string(providerCfg.ID),
 		activeRequests:      csync.NewMap[string, context.CancelFunc](),
+		activeToolCalls:     csync.NewMap[string, context.CancelFunc](),
+		steering:            make(map[string][]string),
 		tools:               csync.NewLazySlice(toolFn),
 	}, nil
 }
 
+// systemPrompt returns the system prompt of the agent: the one in its prompt
+// file, or the built-in one.
+func systemPrompt(agentCfg config.Agent, providerID string) (string, error) {
+	if agentCfg.Prompt != "" {
+		return prompt.CustomPrompt(agentCfg.Prompt, agentCfg.ContextPaths...)
+	}
+	promptID := agentPromptMap[agentCfg.ID]
+	if promptID == "" {
+		promptID = prompt.PromptDefault
+	}
+	return prompt.GetPrompt(promptID, providerID, agentCfg.ContextPaths...), nil
+}
+
 func (a *agent) Model() catwalk.Model {
 	return *config.Get().GetModelByType(a.agentCfg.Model)
 }
@@ -249,6 +306,13 @@ func (a *agent) Cancel(sessionID string) {
 	}
 }
 
+func (a *agent) CancelToolCall(toolCallID string) {
+	if cancel, ok := a.activeToolCalls.Take(toolCallID); ok {
+		slog.Info("Tool call cancellation initiated", "tool_call_id", toolCallID)
+		cancel()
+	}
+}
+
 func (a *agent) IsBusy() bool {
 	var busy bool
 	for cancelFunc := range a.activeRequests.Seq() {
@@ -321,7 +385,24 @@ func (a *agent) err(err error) AgentEvent {
 	}
 }
 
+// inlineTextAttachments appends the text attachments to content, since
+// models only take images as attachments, and returns the other ones.
+func inlineTextAttachments(content string, attachments []message.Attachment) (string, []message.Attachment) {
+	var rest []message.Attachment
+	var sb strings.Builder
+	sb.WriteString(content)
+	for _, attachment := range attachments {
+		if !strings.HasPrefix(attachment.MimeType, "text/") {
+			rest = append(rest, attachment)
+			continue
+		}
+		fmt.Fprintf(&sb, "\n\n<attachment name=%q>\n%s\n</attachment>", attachment.FileName, strings.TrimRight(string(attachment.Content), "\n"))
+	}
+	return sb.String(), rest
+}
+
 func (a *agent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
+	content, attachments = inlineTextAttachments(content, attachments)
 	if !a.Model().SupportsImages && attachments != nil {
 		attachments = nil
 	}
@@ -355,7 +436,11 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 			slog.Error(result.Error.Error())
 		}
 		slog.Debug("Request completed", "sessionID", sessionID)
+		a.steeringMu.Lock()
+		result.Steering = a.steering[sessionID]
+		delete(a.steering, sessionID)
 		a.activeRequests.Del(sessionID)
+		a.steeringMu.Unlock()
 		cancel()
 		a.Publish(pubsub.CreatedEvent, result)
 		events <- result
@@ -428,8 +513,12 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			slog.Info("Result", "message", agentMessage.FinishReason(), "toolResults", toolResults)
 		}
 		if (agentMessage.FinishReason() == message.FinishReasonToolUse) && toolResults != nil {
-			// We are not done, we need to respond with the tool response
-			msgHistory = append(msgHistory, agentMessage, *toolResults)
+			// We are not done, we need to respond with the tool response,
+			// and the notes the user sent while the tools ran.
+			msgHistory, err = a.appendSteering(ctx, sessionID, append(msgHistory, agentMessage, *toolResults))
+			if err != nil {
+				return a.err(err)
+			}
 			continue
 		}
 		if agentMessage.FinishReason() == "" {
@@ -438,6 +527,18 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			_ = a.messages.Update(context.Background(), agentMessage)
 			return a.err(ErrRequestCancelled)
 		}
+		if agentMessage.FinishReason() == message.FinishReasonEndTurn {
+			// Notes sent after the last tool call keep the turn going.
+			history := append(msgHistory, agentMessage)
+			steered, err := a.appendSteering(ctx, sessionID, history)
+			if err != nil {
+				return a.err(err)
+			}
+			if len(steered) > len(history) {
+				msgHistory = steered
+				continue
+			}
+		}
 		return AgentEvent{
 			Type:    AgentEventTypeResponse,
 			Message: agentMessage,
@@ -497,105 +598,31 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		}
 	}
 
-	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
 	toolCalls := assistantMsg.ToolCalls()
-	for i, toolCall := range toolCalls {
-		select {
-		case <-ctx.Done():
-			a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled, "Request cancelled", "")
-			// Make all future tool calls cancelled
-			for j := i; j < len(toolCalls); j++ {
-				toolResults[j] = message.ToolResult{
-					ToolCallID: toolCalls[j].ID,
-					Content:    "Tool execution canceled by user",
-					IsError:    true,
-				}
-			}
-			goto out
-		default:
-			// Continue processing
-			var tool tools.BaseTool
-			for availableTool := range a.tools.Seq() {
-				if availableTool.Info().Name == toolCall.Name {
-					tool = availableTool
-					break
-				}
-			}
-
-			// Tool not found
-			if tool == nil {
-				toolResults[i] = message.ToolResult{
-					ToolCallID: toolCall.ID,
-					Content:    fmt.Sprintf("Tool not found: %s", toolCall.Name),
-					IsError:    true,
-				}
-				continue
-			}
-
-			// Run tool in goroutine to allow cancellation
-			type toolExecResult struct {
-				response tools.ToolResponse
-				err      error
-			}
-			resultChan := make(chan toolExecResult, 1)
-
-			go func() {
-				response, err := tool.Run(ctx, tools.ToolCall{
-					ID:    toolCall.ID,
-					Name:  toolCall.Name,
-					Input: toolCall.Input,
-				})
-				resultChan <- toolExecResult{response: response, err: err}
-			}()
-
-			var toolResponse tools.ToolResponse
-			var toolErr error
-
-			select {
-			case <-ctx.Done():
-				a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled, "Request cancelled", "")
-				// Mark remaining tool calls as cancelled
-				for j := i; j < len(toolCalls); j++ {
-					toolResults[j] = message.ToolResult{
-						ToolCallID: toolCalls[j].ID,
-						Content:    "Tool execution canceled by user",
-						IsError:    true,
-					}
-				}
-				goto out
-			case result := <-resultChan:
-				toolResponse = result.response
-				toolErr = result.err
-			}
-
-			if toolErr != nil {
-				slog.Error("Tool execution error", "toolCall", toolCall.ID, "error", toolErr)
-				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
-					toolResults[i] = message.ToolResult{
-						ToolCallID: toolCall.ID,
-						Content:    "Permission denied",
-						IsError:    true,
-					}
-					for j := i + 1; j < len(toolCalls); j++ {
-						toolResults[j] = message.ToolResult{
-							ToolCallID: toolCalls[j].ID,
-							Content:    "Tool execution canceled by user",
-							IsError:    true,
-						}
-					}
-					a.finishMessage(ctx, &assistantMsg, message.FinishReasonPermissionDenied, "Permission denied", "")
-					break
-				}
-			}
-			toolResults[i] = message.ToolResult{
-				ToolCallID: toolCall.ID,
-				Content:    toolResponse.Content,
-				Metadata:   toolResponse.Metadata,
-				IsError:    toolResponse.IsError,
+	toolResults := make([]message.ToolResult, len(toolCalls))
+	for start := 0; start < len(toolCalls); {
+		// Read-only tool calls that follow each other run together, the
+		// others one at a time.
+		end := start + 1
+		if isParallelTool(toolCalls[start].Name) {
+			for end < len(toolCalls) && isParallelTool(toolCalls[end].Name) {
+				end++
 			}
 		}
+		err := a.runToolCalls(ctx, toolCalls[start:end], toolResults[start:end])
+		if errors.Is(err, permission.ErrorPermissionDenied) {
+			cancelToolCalls(toolCalls, toolResults)
+			a.finishMessage(ctx, &assistantMsg, message.FinishReasonPermissionDenied, "Permission denied", "")
+			break
+		}
+		if err != nil {
+			a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled, "Request cancelled", "")
+			// Make all unfinished tool calls cancelled
+			cancelToolCalls(toolCalls, toolResults)
+			break
+		}
+		start = end
 	}
-out:
 	if len(toolResults) == 0 {
 		return assistantMsg, nil, nil
 	}
@@ -791,8 +818,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			a.Publish(pubsub.CreatedEvent, event)
 			return
 		}
-		shell := shell.GetPersistentShell(config.Get().WorkingDir())
-		summary += "\n\n**Current working directory of the persistent shell**\n\n" + shell.GetWorkingDir()
+		summary += "\n\n**Current working directory of the shell**\n\n" + a.shells.Agent(sessionID).GetWorkingDir()
 		event = AgentEvent{
 			Type:     AgentEventTypeSummarize,
 			Progress: "Creating new session...",
@@ -902,14 +928,14 @@ func (a *agent) UpdateModel() error {
 			return fmt.Errorf("model not found for agent %s", a.agentCfg.Name)
 		}
 
-		promptID := agentPromptMap[a.agentCfg.ID]
-		if promptID == "" {
-			promptID = prompt.PromptDefault
+		systemMessage, err := systemPrompt(a.agentCfg, currentProviderCfg.ID)
+		if err != nil {
+			return err
 		}
 
 		opts := []provider.ProviderClientOption{
 			provider.WithModel(a.agentCfg.Model),
-			provider.WithSystemMessage(prompt.GetPrompt(promptID, currentProviderCfg.ID, cfg.Options.ContextPaths...)),
+			provider.WithSystemMessage(systemMessage),
 		}
 
 		newProvider, err := provider.NewProvider(*currentProviderCfg, opts...)
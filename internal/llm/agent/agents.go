@@ -0,0 +1,283 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/history"
+	"github.com/lacymorrow/lash/internal/lsp"
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/permission"
+	"github.com/lacymorrow/lash/internal/pubsub"
+	"github.com/lacymorrow/lash/internal/session"
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+// Agents runs each session with the agent it uses, out of the agents of the
+// configuration. Sessions that have not picked an agent use the default one,
+// and keep it once they run. Agents are created the first time they are
+// used, and their events are published by Agents.
+type Agents struct {
+	*pubsub.Broker[AgentEvent]
+	ctx context.Context
+
+	permissions permission.Service
+	sessions    session.Service
+	messages    message.Service
+	history     history.Service
+	shells      *shell.Manager
+	lspClients  map[string]*lsp.Client
+
+	mu        sync.Mutex
+	defaultID string
+	agents    map[string]Service
+
+	queueMu     sync.Mutex
+	queues      map[string][]QueuedPrompt
+	queueBroker *pubsub.Broker[PromptQueue]
+}
+
+// NewAgents returns Agents with defaultID as the default agent, which is
+// created right away.
+func NewAgents(
+	ctx context.Context,
+	defaultID string,
+	permissions permission.Service,
+	sessions session.Service,
+	messages message.Service,
+	history history.Service,
+	shells *shell.Manager,
+	lspClients map[string]*lsp.Client,
+) (*Agents, error) {
+	a := &Agents{
+		Broker:      pubsub.NewBroker[AgentEvent](),
+		ctx:         ctx,
+		permissions: permissions,
+		sessions:    sessions,
+		messages:    messages,
+		history:     history,
+		shells:      shells,
+		lspClients:  lspClients,
+		defaultID:   defaultID,
+		agents:      make(map[string]Service),
+		queues:      make(map[string][]QueuedPrompt),
+		queueBroker: pubsub.NewBroker[PromptQueue](),
+	}
+	if _, err := a.agent(defaultID); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// agent returns the agent with the given ID, creating it if needed.
+func (a *Agents) agent(id string) (Service, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if agent, ok := a.agents[id]; ok {
+		return agent, nil
+	}
+
+	agentCfg, ok := config.Get().Agents[id]
+	if !ok || agentCfg.Disabled || id == "task" {
+		return nil, fmt.Errorf("agent %s not found in config", id)
+	}
+	agent, err := NewAgent(a.ctx, agentCfg, a.permissions, a.sessions, a.messages, a.history, a.shells, a.lspClients)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent %s: %w", id, err)
+	}
+	a.agents[id] = agent
+
+	events := agent.Subscribe(a.ctx)
+	go func() {
+		for event := range events {
+			a.Publish(event.Type, event.Payload)
+		}
+	}()
+	return agent, nil
+}
+
+// all returns the agents created so far.
+func (a *Agents) all() []Service {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	agents := make([]Service, 0, len(a.agents))
+	for _, agent := range a.agents {
+		agents = append(agents, agent)
+	}
+	return agents
+}
+
+// Default returns the ID of the agent of new sessions.
+func (a *Agents) Default() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.defaultID
+}
+
+// SetDefault makes the agent with the given ID the agent of new sessions.
+func (a *Agents) SetDefault(id string) error {
+	if _, err := a.agent(id); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.defaultID = id
+	return nil
+}
+
+// SessionAgent returns the ID of the agent of a session.
+func (a *Agents) SessionAgent(ctx context.Context, sessionID string) string {
+	if sessionID != "" {
+		if session, err := a.sessions.Get(ctx, sessionID); err == nil && session.AgentID != "" {
+			return session.AgentID
+		}
+	}
+	return a.Default()
+}
+
+// SetSessionAgent switches a session to the agent with the given ID.
+func (a *Agents) SetSessionAgent(ctx context.Context, sessionID, id string) error {
+	if a.IsSessionBusy(sessionID) {
+		return ErrSessionBusy
+	}
+	if _, err := a.agent(id); err != nil {
+		return err
+	}
+	session, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	session.AgentID = id
+	if _, err := a.sessions.Save(ctx, session); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// SessionModel returns the model of the agent of a session.
+func (a *Agents) SessionModel(ctx context.Context, sessionID string) catwalk.Model {
+	agent, err := a.agent(a.SessionAgent(ctx, sessionID))
+	if err != nil {
+		return a.Model()
+	}
+	return agent.Model()
+}
+
+// Model returns the model of the default agent.
+func (a *Agents) Model() catwalk.Model {
+	agent, err := a.agent(a.Default())
+	if err != nil {
+		return catwalk.Model{}
+	}
+	return agent.Model()
+}
+
+func (a *Agents) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
+	session, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	id := cmp.Or(session.AgentID, a.Default())
+	agent, err := a.agent(id)
+	if err != nil {
+		return nil, err
+	}
+	if session.AgentID == "" {
+		// Keep the agent the session started with, whatever the default
+		// becomes.
+		session.AgentID = id
+		if _, err := a.sessions.Save(ctx, session); err != nil {
+			return nil, fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	events, err := agent.Run(ctx, sessionID, content, attachments...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Once the request is done, run the prompts queued meanwhile, unless
+	// the user cancelled it. Steering notes the request ended before
+	// sending go first, so they are not lost.
+	out := make(chan AgentEvent, 1)
+	go func() {
+		var result AgentEvent
+		for event := range events {
+			result = event
+			out <- event
+		}
+		close(out)
+		if len(result.Steering) > 0 {
+			a.requeueSteering(sessionID, result.Steering)
+		}
+		if errors.Is(result.Error, ErrRequestCancelled) || errors.Is(result.Error, context.Canceled) {
+			return
+		}
+		a.runQueued(sessionID)
+	}()
+	return out, nil
+}
+
+func (a *Agents) Steer(sessionID string, content string) error {
+	agent, err := a.agent(a.SessionAgent(a.ctx, sessionID))
+	if err != nil {
+		return err
+	}
+	return agent.Steer(sessionID, content)
+}
+
+func (a *Agents) Cancel(sessionID string) {
+	for _, agent := range a.all() {
+		agent.Cancel(sessionID)
+	}
+}
+
+func (a *Agents) CancelToolCall(toolCallID string) {
+	for _, agent := range a.all() {
+		agent.CancelToolCall(toolCallID)
+	}
+}
+
+func (a *Agents) CancelAll() {
+	for _, agent := range a.all() {
+		agent.CancelAll()
+	}
+}
+
+func (a *Agents) IsSessionBusy(sessionID string) bool {
+	for _, agent := range a.all() {
+		if agent.IsSessionBusy(sessionID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Agents) IsBusy() bool {
+	for _, agent := range a.all() {
+		if agent.IsBusy() {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Agents) Summarize(ctx context.Context, sessionID string) error {
+	agent, err := a.agent(a.SessionAgent(ctx, sessionID))
+	if err != nil {
+		return err
+	}
+	return agent.Summarize(ctx, sessionID)
+}
+
+func (a *Agents) UpdateModel() error {
+	var errs []error
+	for _, agent := range a.all() {
+		errs = append(errs, agent.UpdateModel())
+	}
+	return errors.Join(errs...)
+}
@@ -230,7 +230,7 @@ func runTool(ctx context.Context, name, toolName string, input string) (tools.To
 		}
 	}
 
-	return tools.NewTextResponse(output.String()), nil
+	return tools.NewTextResponse(tools.SpillOutput(ctx, output.String())), nil
 }
 
 func (b *McpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
@@ -329,6 +329,28 @@ func CloseMCPClients() {
 	mcpBroker.Shutdown()
 }
 
+// agentMCPTools returns the MCP tools agentCfg allows, asking permissions
+// before running them.
+func agentMCPTools(agentCfg config.Agent, permissions permission.Service) []tools.BaseTool {
+	var result []tools.BaseTool
+	for _, tool := range mcpTools {
+		mcpTool, ok := tool.(*McpTool)
+		if !ok {
+			continue
+		}
+		if agentCfg.AllowedMCP != nil {
+			allowed, ok := agentCfg.AllowedMCP[mcpTool.mcpName]
+			if !ok || (allowed != nil && !slices.Contains(allowed, mcpTool.tool.Name)) {
+				continue
+			}
+		}
+		agentTool := *mcpTool
+		agentTool.permissions = permissions
+		result = append(result, &agentTool)
+	}
+	return result
+}
+
 var mcpInitRequest = mcp.InitializeRequest{
 	Params: mcp.InitializeParams{
 		ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/pubsub"
+)
+
+var ErrPromptNotQueued = errors.New("prompt is no longer queued")
+
+// QueuedPrompt is a prompt waiting for the running request of its session
+// to finish.
+type QueuedPrompt struct {
+	ID          string
+	Content     string
+	Attachments []message.Attachment
+}
+
+// PromptQueue is the queue of prompts of a session, published whenever it
+// changes.
+type PromptQueue struct {
+	SessionID string
+	Prompts   []QueuedPrompt
+}
+
+// SubscribeQueue returns a channel for the changes of the prompt queues of
+// sessions.
+func (a *Agents) SubscribeQueue(ctx context.Context) <-chan pubsub.Event[PromptQueue] {
+	return a.queueBroker.Subscribe(ctx)
+}
+
+// Enqueue adds a prompt to the queue of a session. Queued prompts run one
+// after another once the running request of the session finishes.
+func (a *Agents) Enqueue(sessionID, content string, attachments ...message.Attachment) QueuedPrompt {
+	prompt := QueuedPrompt{
+		ID:          uuid.NewString(),
+		Content:     content,
+		Attachments: attachments,
+	}
+	a.updateQueue(sessionID, func(prompts []QueuedPrompt) ([]QueuedPrompt, error) {
+		return append(prompts, prompt), nil
+	})
+	if !a.IsSessionBusy(sessionID) {
+		// The request finished while the prompt was being queued.
+		a.runQueued(sessionID)
+	}
+	return prompt
+}
+
+// Queue returns the queued prompts of a session, in the order they run.
+func (a *Agents) Queue(sessionID string) []QueuedPrompt {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	return slices.Clone(a.queues[sessionID])
+}
+
+// EditQueued changes the content of a queued prompt.
+func (a *Agents) EditQueued(sessionID, id, content string) error {
+	return a.updateQueue(sessionID, func(prompts []QueuedPrompt) ([]QueuedPrompt, error) {
+		i := slices.IndexFunc(prompts, func(p QueuedPrompt) bool { return p.ID == id })
+		if i < 0 {
+			return nil, ErrPromptNotQueued
+		}
+		prompts[i].Content = content
+		return prompts, nil
+	})
+}
+
+// MoveQueued moves a queued prompt offset places later in the queue, or
+// earlier if offset is negative.
+func (a *Agents) MoveQueued(sessionID, id string, offset int) error {
+	return a.updateQueue(sessionID, func(prompts []QueuedPrompt) ([]QueuedPrompt, error) {
+		i := slices.IndexFunc(prompts, func(p QueuedPrompt) bool { return p.ID == id })
+		if i < 0 {
+			return nil, ErrPromptNotQueued
+		}
+		prompt := prompts[i]
+		prompts = slices.Delete(prompts, i, i+1)
+		j := min(max(i+offset, 0), len(prompts))
+		return slices.Insert(prompts, j, prompt), nil
+	})
+}
+
+// RemoveQueued removes a prompt from the queue.
+func (a *Agents) RemoveQueued(sessionID, id string) error {
+	return a.updateQueue(sessionID, func(prompts []QueuedPrompt) ([]QueuedPrompt, error) {
+		i := slices.IndexFunc(prompts, func(p QueuedPrompt) bool { return p.ID == id })
+		if i < 0 {
+			return nil, ErrPromptNotQueued
+		}
+		return slices.Delete(prompts, i, i+1), nil
+	})
+}
+
+// updateQueue replaces the queue of a session with what update returns for
+// a copy of it, and publishes the change.
+func (a *Agents) updateQueue(sessionID string, update func([]QueuedPrompt) ([]QueuedPrompt, error)) error {
+	a.queueMu.Lock()
+	prompts, err := update(slices.Clone(a.queues[sessionID]))
+	if err != nil {
+		a.queueMu.Unlock()
+		return err
+	}
+	if len(prompts) == 0 {
+		delete(a.queues, sessionID)
+	} else {
+		a.queues[sessionID] = prompts
+	}
+	a.queueMu.Unlock()
+
+	a.queueBroker.Publish(pubsub.UpdatedEvent, PromptQueue{
+		SessionID: sessionID,
+		Prompts:   slices.Clone(prompts),
+	})
+	return nil
+}
+
+// requeueSteering puts the steering notes a request of a session ended
+// before sending at the front of its queue, as one prompt.
+func (a *Agents) requeueSteering(sessionID string, notes []string) {
+	slog.Info("Queueing steering notes the request did not send", "session_id", sessionID, "notes", len(notes))
+	prompt := QueuedPrompt{
+		ID:      uuid.NewString(),
+		Content: strings.Join(notes, "\n\n"),
+	}
+	a.updateQueue(sessionID, func(prompts []QueuedPrompt) ([]QueuedPrompt, error) {
+		return slices.Insert(prompts, 0, prompt), nil
+	})
+}
+
+// runQueued runs the next queued prompt of a session, if any.
+func (a *Agents) runQueued(sessionID string) {
+	var prompt QueuedPrompt
+	a.updateQueue(sessionID, func(prompts []QueuedPrompt) ([]QueuedPrompt, error) {
+		if len(prompts) == 0 {
+			return nil, ErrPromptNotQueued
+		}
+		prompt = prompts[0]
+		return prompts[1:], nil
+	})
+	if prompt.ID == "" {
+		return
+	}
+
+	_, err := a.Run(a.ctx, sessionID, prompt.Content, prompt.Attachments...)
+	if errors.Is(err, ErrSessionBusy) {
+		// Another request started first; the prompt runs after it.
+		a.updateQueue(sessionID, func(prompts []QueuedPrompt) ([]QueuedPrompt, error) {
+			return slices.Insert(prompts, 0, prompt), nil
+		})
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to run queued prompt", "session_id", sessionID, "error", err)
+		a.Publish(pubsub.CreatedEvent, AgentEvent{
+			Type:      AgentEventTypeError,
+			Error:     err,
+			SessionID: sessionID,
+		})
+	}
+}
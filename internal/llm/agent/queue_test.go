@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/pubsub"
+	"github.com/lacymorrow/lash/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent records the prompts it runs. A request runs until finish is
+// called, and the session is busy meanwhile.
+type fakeAgent struct {
+	Service
+
+	mu      sync.Mutex
+	busy    bool
+	reject  int
+	prompts []string
+	events  chan AgentEvent
+}
+
+func (f *fakeAgent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.busy || f.reject > 0 {
+		f.reject = max(f.reject-1, 0)
+		return nil, ErrSessionBusy
+	}
+	f.busy = true
+	f.prompts = append(f.prompts, content)
+	f.events = make(chan AgentEvent, 1)
+	return f.events, nil
+}
+
+func (f *fakeAgent) IsSessionBusy(sessionID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.busy
+}
+
+func (f *fakeAgent) setBusy(busy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.busy = busy
+}
+
+// finish ends the running request with event.
+func (f *fakeAgent) finish(event AgentEvent) {
+	f.mu.Lock()
+	events := f.events
+	f.busy = false
+	f.mu.Unlock()
+	events <- event
+	close(events)
+}
+
+func (f *fakeAgent) ran() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.prompts)
+}
+
+// fakeSessions returns sessions that already use the coder agent.
+type fakeSessions struct {
+	session.Service
+}
+
+func (fakeSessions) Get(ctx context.Context, id string) (session.Session, error) {
+	return session.Session{ID: id, AgentID: "coder"}, nil
+}
+
+func newQueueAgents(t *testing.T, agent *fakeAgent) *Agents {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Agents{
+		Broker:      pubsub.NewBroker[AgentEvent](),
+		ctx:         ctx,
+		sessions:    fakeSessions{},
+		defaultID:   "coder",
+		agents:      map[string]Service{"coder": agent},
+		queues:      make(map[string][]QueuedPrompt),
+		queueBroker: pubsub.NewBroker[PromptQueue](),
+	}
+}
+
+func queuedContents(prompts []QueuedPrompt) []string {
+	contents := make([]string, len(prompts))
+	for i, prompt := range prompts {
+		contents[i] = prompt.Content
+	}
+	return contents
+}
+
+func TestMoveQueued(t *testing.T) {
+	a := newQueueAgents(t, &fakeAgent{busy: true})
+	first := a.Enqueue("session", "first")
+	a.Enqueue("session", "second")
+	third := a.Enqueue("session", "third")
+
+	tests := []struct {
+		id     string
+		offset int
+		want   []string
+	}{
+		{first.ID, -1, []string{"first", "second", "third"}},
+		{first.ID, 1, []string{"second", "first", "third"}},
+		{first.ID, 10, []string{"second", "third", "first"}},
+		{first.ID, -10, []string{"first", "second", "third"}},
+		{third.ID, 1, []string{"first", "second", "third"}},
+		{third.ID, -2, []string{"third", "first", "second"}},
+	}
+	for _, tt := range tests {
+		require.NoError(t, a.MoveQueued("session", tt.id, tt.offset))
+		require.Equal(t, tt.want, queuedContents(a.Queue("session")), "moving by %d", tt.offset)
+	}
+	require.ErrorIs(t, a.MoveQueued("session", "unknown", 1), ErrPromptNotQueued)
+	require.ErrorIs(t, a.MoveQueued("other", first.ID, 1), ErrPromptNotQueued)
+}
+
+func TestQueuedPromptTaken(t *testing.T) {
+	agent := &fakeAgent{busy: true}
+	a := newQueueAgents(t, agent)
+	first := a.Enqueue("session", "first")
+	second := a.Enqueue("session", "second")
+
+	agent.setBusy(false)
+	a.runQueued("session")
+	require.Equal(t, []string{"first"}, agent.ran())
+
+	require.ErrorIs(t, a.EditQueued("session", first.ID, "edited"), ErrPromptNotQueued)
+	require.ErrorIs(t, a.RemoveQueued("session", first.ID), ErrPromptNotQueued)
+	require.ErrorIs(t, a.MoveQueued("session", first.ID, 1), ErrPromptNotQueued)
+	require.Equal(t, []QueuedPrompt{second}, a.Queue("session"))
+
+	require.NoError(t, a.EditQueued("session", second.ID, "edited"))
+	require.Equal(t, []string{"edited"}, queuedContents(a.Queue("session")))
+	require.NoError(t, a.RemoveQueued("session", second.ID))
+	require.Empty(t, a.Queue("session"))
+}
+
+func TestRunQueuedRequeuesWhenBusy(t *testing.T) {
+	agent := &fakeAgent{busy: true}
+	a := newQueueAgents(t, agent)
+	first := a.Enqueue("session", "first")
+	second := a.Enqueue("session", "second")
+
+	// Another request starts between the queue being taken from and the
+	// prompt being run.
+	agent.setBusy(false)
+	agent.reject = 1
+	a.runQueued("session")
+	require.Empty(t, agent.ran())
+	require.Equal(t, []QueuedPrompt{first, second}, a.Queue("session"))
+}
+
+func TestCancelledRequestKeepsQueue(t *testing.T) {
+	agent := &fakeAgent{}
+	a := newQueueAgents(t, agent)
+
+	out, err := a.Run(context.Background(), "session", "running")
+	require.NoError(t, err)
+	a.Enqueue("session", "queued")
+	agent.finish(AgentEvent{Type: AgentEventTypeError, Error: ErrRequestCancelled})
+	for range out {
+	}
+	require.Never(t, func() bool { return len(agent.ran()) > 1 }, 200*time.Millisecond, 10*time.Millisecond)
+	require.Equal(t, []string{"queued"}, queuedContents(a.Queue("session")))
+
+	// The queue runs once a request finishes.
+	out, err = a.Run(context.Background(), "session", "next")
+	require.NoError(t, err)
+	agent.finish(AgentEvent{Type: AgentEventTypeResponse})
+	for range out {
+	}
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"running", "next", "queued"}, agent.ran())
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Empty(t, a.Queue("session"))
+}
+
+func TestSteeringRequeuedAfterCancel(t *testing.T) {
+	agent := &fakeAgent{}
+	a := newQueueAgents(t, agent)
+
+	out, err := a.Run(context.Background(), "session", "running")
+	require.NoError(t, err)
+	a.Enqueue("session", "queued")
+	agent.finish(AgentEvent{Type: AgentEventTypeError, Error: context.Canceled, Steering: []string{"one", "two"}})
+	for range out {
+	}
+	require.Eventually(t, func() bool { return len(a.Queue("session")) == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"one\n\ntwo", "queued"}, queuedContents(a.Queue("session")))
+	require.Equal(t, []string{"running"}, agent.ran())
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/lacymorrow/lash/internal/message"
+)
+
+// Steer adds a note from the user to the running request of a session. The
+// note is sent to the model at the next tool-call boundary, after the
+// results of the tool calls that are running, without cancelling the
+// request. If the model ends its turn first, the request goes on with the
+// note.
+func (a *agent) Steer(sessionID string, content string) error {
+	a.steeringMu.Lock()
+	defer a.steeringMu.Unlock()
+	if !a.IsSessionBusy(sessionID) {
+		return ErrSessionNotBusy
+	}
+	a.steering[sessionID] = append(a.steering[sessionID], content)
+	return nil
+}
+
+// takeSteering returns the notes added to the request of a session since
+// the last call, and forgets them.
+func (a *agent) takeSteering(sessionID string) []string {
+	a.steeringMu.Lock()
+	defer a.steeringMu.Unlock()
+	notes := a.steering[sessionID]
+	delete(a.steering, sessionID)
+	return notes
+}
+
+// appendSteering saves the notes added to the request of a session as a user
+// message, and returns msgHistory with it. It returns msgHistory as is if
+// there are none.
+func (a *agent) appendSteering(ctx context.Context, sessionID string, msgHistory []message.Message) ([]message.Message, error) {
+	notes := a.takeSteering(sessionID)
+	if len(notes) == 0 {
+		return msgHistory, nil
+	}
+	slog.Info("Steering request", "session_id", sessionID, "notes", len(notes))
+	note, err := a.createUserMessage(ctx, sessionID, strings.Join(notes, "\n\n"), nil)
+	if err != nil {
+		return msgHistory, fmt.Errorf("failed to create steering message: %w", err)
+	}
+	return append(msgHistory, note), nil
+}
@@ -0,0 +1,25 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lacymorrow/lash/internal/csync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSteer(t *testing.T) {
+	a := &agent{
+		activeRequests: csync.NewMap[string, context.CancelFunc](),
+		steering:       make(map[string][]string),
+	}
+	require.ErrorIs(t, a.Steer("session", "note"), ErrSessionNotBusy)
+	require.Empty(t, a.takeSteering("session"))
+
+	a.activeRequests.Set("session", func() {})
+	require.NoError(t, a.Steer("session", "one"))
+	require.NoError(t, a.Steer("session", "two"))
+	require.Empty(t, a.takeSteering("other"))
+	require.Equal(t, []string{"one", "two"}, a.takeSteering("session"))
+	require.Empty(t, a.takeSteering("session"), "notes are taken once")
+}
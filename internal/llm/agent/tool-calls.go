@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/llm/tools"
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/permission"
+)
+
+// toolCancelGracePeriod is how long a cancelled tool call has to return
+// what it has so far before the agent moves on without it.
+const toolCancelGracePeriod = 5 * time.Second
+
+// defaultMaxParallelToolCalls is how many read-only tool calls run at the
+// same time unless configured otherwise.
+const defaultMaxParallelToolCalls = 4
+
+// parallelTools are the tools that only read, and so can run at the same
+// time as each other. view and ls ask for permission to read outside of the
+// working directory, which the permission service asks for one at a time.
+// Sub-agents run in sessions of their own, with the tools of the task agent.
+var parallelTools = []string{
+	AgentToolName,
+	tools.DiagnosticsToolName,
+	tools.GlobToolName,
+	tools.GrepToolName,
+	tools.LSToolName,
+	tools.ProcessListToolName,
+	tools.ProcessOutputToolName,
+	tools.ReadOutputToolName,
+	tools.SourcegraphToolName,
+	tools.ViewToolName,
+}
+
+func isParallelTool(name string) bool {
+	return slices.Contains(parallelTools, name)
+}
+
+func maxParallelToolCalls() int {
+	if cfg := config.Get(); cfg != nil && cfg.Options != nil && cfg.Options.MaxParallelToolCalls > 0 {
+		return cfg.Options.MaxParallelToolCalls
+	}
+	return defaultMaxParallelToolCalls
+}
+
+// cancelToolCalls marks the tool calls that have no result as cancelled.
+func cancelToolCalls(toolCalls []message.ToolCall, toolResults []message.ToolResult) {
+	for i, toolCall := range toolCalls {
+		if toolResults[i].ToolCallID == "" {
+			toolResults[i] = message.ToolResult{
+				ToolCallID: toolCall.ID,
+				Content:    "Tool execution canceled by user",
+				IsError:    true,
+			}
+		}
+	}
+}
+
+// runToolCalls runs toolCalls at the same time, up to the configured limit,
+// and stores their results in toolResults, in the same order. Calls that do
+// not finish because the request was cancelled are left without a result.
+// It returns the error of the request being cancelled, or
+// permission.ErrorPermissionDenied if the user denied a call.
+func (a *agent) runToolCalls(ctx context.Context, toolCalls []message.ToolCall, toolResults []message.ToolResult) error {
+	if len(toolCalls) == 1 {
+		result, err := a.runToolCall(ctx, toolCalls[0])
+		toolResults[0] = result
+		return err
+	}
+
+	errs := make([]error, len(toolCalls))
+	limit := make(chan struct{}, maxParallelToolCalls())
+	var wg sync.WaitGroup
+	for i, toolCall := range toolCalls {
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			toolResults[i], errs[i] = a.runToolCall(ctx, toolCall)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runToolCall runs a tool call. It returns the error of the request being
+// cancelled, with no result, or permission.ErrorPermissionDenied if the user
+// denied the call.
+func (a *agent) runToolCall(ctx context.Context, toolCall message.ToolCall) (message.ToolResult, error) {
+	if ctx.Err() != nil {
+		return message.ToolResult{}, ctx.Err()
+	}
+
+	var tool tools.BaseTool
+	for availableTool := range a.tools.Seq() {
+		if availableTool.Info().Name == toolCall.Name {
+			tool = availableTool
+			break
+		}
+	}
+
+	// Tool not found
+	if tool == nil {
+		return message.ToolResult{
+			ToolCallID: toolCall.ID,
+			Content:    fmt.Sprintf("Tool not found: %s", toolCall.Name),
+			IsError:    true,
+		}, nil
+	}
+
+	// Run tool in goroutine to allow cancellation
+	type toolExecResult struct {
+		response tools.ToolResponse
+		err      error
+	}
+	resultChan := make(chan toolExecResult, 1)
+
+	toolCtx, cancelTool := context.WithCancel(ctx)
+	defer cancelTool()
+	a.activeToolCalls.Set(toolCall.ID, cancelTool)
+	defer a.activeToolCalls.Del(toolCall.ID)
+	go func() {
+		response, err := tool.Run(toolCtx, tools.ToolCall{
+			ID:    toolCall.ID,
+			Name:  toolCall.Name,
+			Input: toolCall.Input,
+		})
+		resultChan <- toolExecResult{response: response, err: err}
+	}()
+
+	var toolResponse tools.ToolResponse
+	var toolErr error
+	select {
+	case <-toolCtx.Done():
+		if ctx.Err() != nil {
+			return message.ToolResult{}, ctx.Err()
+		}
+		// Only this tool call was cancelled; give the tool a moment to
+		// return what it has so far, then carry on with the rest.
+		content := "Tool execution canceled by user"
+		var metadata string
+		select {
+		case result := <-resultChan:
+			if result.err == nil && result.response.Content != "" {
+				content = result.response.Content + "\n\n" + content
+			}
+			metadata = result.response.Metadata
+		case <-time.After(toolCancelGracePeriod):
+		}
+		return message.ToolResult{
+			ToolCallID: toolCall.ID,
+			Content:    content,
+			Metadata:   metadata,
+			IsError:    true,
+		}, nil
+	case result := <-resultChan:
+		toolResponse = result.response
+		toolErr = result.err
+	}
+
+	if toolErr != nil {
+		slog.Error("Tool execution error", "toolCall", toolCall.ID, "error", toolErr)
+		if errors.Is(toolErr, permission.ErrorPermissionDenied) {
+			return message.ToolResult{
+				ToolCallID: toolCall.ID,
+				Content:    "Permission denied",
+				IsError:    true,
+			}, permission.ErrorPermissionDenied
+		}
+	}
+	return message.ToolResult{
+		ToolCallID: toolCall.ID,
+		Content:    toolResponse.Content,
+		Metadata:   toolResponse.Metadata,
+		IsError:    toolResponse.IsError,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/csync"
+	"github.com/lacymorrow/lash/internal/llm/tools"
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+// sleepTool sleeps for the duration given as its input, then answers with the
+// ID of the call. "deny" makes it fail as if the user denied the call, and
+// "block" makes it wait until it is cancelled.
+type sleepTool struct {
+	running, maxRunning atomic.Int32
+}
+
+func (s *sleepTool) Name() string {
+	return "sleep"
+}
+
+func (s *sleepTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "sleep"}
+}
+
+func (s *sleepTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	running := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		highest := s.maxRunning.Load()
+		if running <= highest || s.maxRunning.CompareAndSwap(highest, running) {
+			break
+		}
+	}
+
+	switch call.Input {
+	case "deny":
+		return tools.ToolResponse{}, permission.ErrorPermissionDenied
+	case "block":
+		<-ctx.Done()
+		return tools.ToolResponse{}, ctx.Err()
+	}
+	delay, err := time.ParseDuration(call.Input)
+	if err != nil {
+		return tools.NewTextErrorResponse(err.Error()), nil
+	}
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return tools.ToolResponse{}, ctx.Err()
+	}
+	return tools.NewTextResponse(call.ID), nil
+}
+
+// newToolAgent returns an agent that can only run tool calls.
+func newToolAgent(agentTools ...tools.BaseTool) *agent {
+	return &agent{
+		tools:           csync.NewLazySlice(func() []tools.BaseTool { return agentTools }),
+		activeToolCalls: csync.NewMap[string, context.CancelFunc](),
+	}
+}
+
+// sleepCalls returns a call of the sleep tool for each input, with its index
+// as ID.
+func sleepCalls(inputs ...string) []message.ToolCall {
+	calls := make([]message.ToolCall, len(inputs))
+	for i, input := range inputs {
+		calls[i] = message.ToolCall{ID: fmt.Sprint(i), Name: "sleep", Input: input, Finished: true}
+	}
+	return calls
+}
+
+func TestRunToolCallsKeepsOrder(t *testing.T) {
+	a := newToolAgent(&sleepTool{})
+	calls := sleepCalls("60ms", "0s", "30ms", "10ms", "50ms")
+	results := make([]message.ToolResult, len(calls))
+
+	require.NoError(t, a.runToolCalls(context.Background(), calls, results))
+	for i, result := range results {
+		require.Equal(t, calls[i].ID, result.ToolCallID)
+		require.Equal(t, calls[i].ID, result.Content)
+		require.False(t, result.IsError)
+	}
+}
+
+func TestRunToolCallsLimitsConcurrency(t *testing.T) {
+	tool := &sleepTool{}
+	a := newToolAgent(tool)
+	inputs := make([]string, 3*defaultMaxParallelToolCalls)
+	for i := range inputs {
+		inputs[i] = "20ms"
+	}
+	calls := sleepCalls(inputs...)
+	results := make([]message.ToolResult, len(calls))
+
+	require.NoError(t, a.runToolCalls(context.Background(), calls, results))
+	require.Equal(t, int32(defaultMaxParallelToolCalls), tool.maxRunning.Load())
+}
+
+func TestRunToolCallsCancelled(t *testing.T) {
+	a := newToolAgent(&sleepTool{})
+	// The first two calls finish, the next four are running when the request
+	// is cancelled, and the rest are waiting for their turn.
+	calls := sleepCalls("0s", "0s", "block", "block", "block", "block", "0s", "0s")
+	results := make([]message.ToolResult, len(calls))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- a.runToolCalls(ctx, calls, results) }()
+	require.Eventually(t, func() bool {
+		_, first := a.activeToolCalls.Get("0")
+		_, second := a.activeToolCalls.Get("1")
+		return a.activeToolCalls.Len() == defaultMaxParallelToolCalls && !first && !second
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errc:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("runToolCalls did not return after the request was cancelled")
+	}
+	cancelToolCalls(calls, results)
+	for i, result := range results {
+		require.Equal(t, calls[i].ID, result.ToolCallID)
+		if i < 2 {
+			require.Equal(t, calls[i].ID, result.Content)
+			require.False(t, result.IsError)
+			continue
+		}
+		require.Equal(t, "Tool execution canceled by user", result.Content, "call %d", i)
+		require.True(t, result.IsError)
+	}
+}
+
+func TestRunToolCallsPermissionDenied(t *testing.T) {
+	a := newToolAgent(&sleepTool{})
+	calls := sleepCalls("10ms", "deny", "0s")
+	results := make([]message.ToolResult, len(calls))
+
+	err := a.runToolCalls(context.Background(), calls, results)
+	require.ErrorIs(t, err, permission.ErrorPermissionDenied)
+	cancelToolCalls(calls, results)
+	require.Equal(t, message.ToolResult{ToolCallID: "1", Content: "Permission denied", IsError: true}, results[1])
+	require.Equal(t, "0", results[0].Content)
+	require.Equal(t, "2", results[2].Content)
+
+	t.Run("single call", func(t *testing.T) {
+		calls := sleepCalls("deny")
+		results := make([]message.ToolResult, 1)
+		err := a.runToolCalls(context.Background(), calls, results)
+		require.ErrorIs(t, err, permission.ErrorPermissionDenied)
+		require.Equal(t, "Permission denied", results[0].Content)
+	})
+}
@@ -41,7 +41,7 @@ func CoderPrompt(p string, contextFiles ...string) string {
 	}
 	// No explicit project context provided — bias toward shell when appropriate.
 	// Keep this concise to avoid adding token bloat.
-	noContextAddendum := `\n\n# When No Project Context Is Provided\n- If the user request looks like a local action (build, run, list files, edit configs, git ops), respond with the exact shell command(s) for this OS and current cwd.\n- Prefer a single safe command with flags. If multiple steps are required, list them as separate commands in order.\n- Do not execute commands yourself; they will be confirmed manually in the UI.\n- If the request is ambiguous, ask a single clarifying question before proceeding.`
+	noContextAddendum := `\n\n# When No Project Context Is Provided\n- If the user request looks like a local action (build, run, list files, edit configs, git ops), respond with the exact shell command(s) for this OS and current cwd.\n- Prefer a single safe command with flags. If multiple steps are required, list them as separate commands in order.\n- Put each command in its own fenced code block tagged sh; the UI turns these into cards the user can run, edit or copy.\n- Do not execute commands yourself; they will be confirmed manually in the UI.\n- If the request is ambiguous, ask a single clarifying question before proceeding.`
 	return basePrompt + noContextAddendum
 }
 
@@ -0,0 +1,31 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lacymorrow/lash/internal/config"
+)
+
+// CustomPrompt returns the system prompt of a user-defined agent: the content
+// of the file at path, followed by the environment information and the
+// project context.
+func CustomPrompt(path string, contextFiles ...string) (string, error) {
+	workingDir := config.Get().WorkingDir()
+	path = expandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read agent prompt: %w", err)
+	}
+
+	basePrompt := fmt.Sprintf("%s\n\n%s\n%s", content, getEnvironmentInfo(), lspInformation())
+	contextContent := getContextFromPaths(workingDir, contextFiles)
+	if contextContent != "" {
+		return fmt.Sprintf("%s\n\n# Project-Specific Context\n Make sure to follow the instructions in the context below\n%s", basePrompt, contextContent), nil
+	}
+	return basePrompt, nil
+}
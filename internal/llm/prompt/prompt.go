@@ -18,6 +18,7 @@ const (
 	PromptTitle      PromptID = "title"
 	PromptTask       PromptID = "task"
 	PromptSummarizer PromptID = "summarizer"
+	PromptRouter     PromptID = "router"
 	PromptDefault    PromptID = "default"
 )
 
@@ -32,6 +33,8 @@ func GetPrompt(promptID PromptID, provider string, contextPaths ...string) strin
 		basePrompt = TaskPrompt()
 	case PromptSummarizer:
 		basePrompt = SummarizerPrompt()
+	case PromptRouter:
+		basePrompt = RouterPrompt()
 	default:
 		basePrompt = "You are a helpful assistant"
 	}
@@ -0,0 +1,10 @@
+package prompt
+
+import _ "embed"
+
+//go:embed router.md
+var routerPrompt []byte
+
+func RouterPrompt() string {
+	return string(routerPrompt)
+}
@@ -109,14 +109,18 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 		}
 		switch msg.Role {
 		case message.User:
-			content := anthropic.NewTextBlock(msg.Content().String())
+			var contentBlocks []anthropic.ContentBlockParamUnion
+			for _, shellExec := range msg.ShellExecutions() {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(shellExec.String()))
+			}
+			if text := msg.Content().String(); text != "" || len(contentBlocks) == 0 {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(text))
+			}
 			if cache && !a.providerOptions.disableCache {
-				content.OfText.CacheControl = anthropic.CacheControlEphemeralParam{
+				contentBlocks[len(contentBlocks)-1].OfText.CacheControl = anthropic.CacheControlEphemeralParam{
 					Type: "ephemeral",
 				}
 			}
-			var contentBlocks []anthropic.ContentBlockParamUnion
-			contentBlocks = append(contentBlocks, content)
 			for _, binaryContent := range msg.BinaryContent() {
 				base64Image := binaryContent.String(catwalk.InferenceProviderAnthropic)
 				imageBlock := anthropic.NewImageBlockBase64(binaryContent.MIMEType, base64Image)
@@ -60,7 +60,12 @@ func (g *geminiClient) convertMessages(messages []message.Message) []*genai.Cont
 		switch msg.Role {
 		case message.User:
 			var parts []*genai.Part
-			parts = append(parts, &genai.Part{Text: msg.Content().String()})
+			for _, shellExec := range msg.ShellExecutions() {
+				parts = append(parts, &genai.Part{Text: shellExec.String()})
+			}
+			if text := msg.Content().String(); text != "" || len(parts) == 0 {
+				parts = append(parts, &genai.Part{Text: text})
+			}
 			for _, binaryContent := range msg.BinaryContent() {
 				imageFormat := strings.Split(binaryContent.MIMEType, "/")
 				parts = append(parts, &genai.Part{InlineData: &genai.Blob{
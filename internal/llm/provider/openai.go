@@ -98,7 +98,16 @@ func (o *openaiClient) convertMessages(messages []message.Message) (openaiMessag
 		case message.User:
 			var content []openai.ChatCompletionContentPartUnionParam
 
-			textBlock := openai.ChatCompletionContentPartTextParam{Text: msg.Content().String()}
+			var texts []string
+			for _, shellExec := range msg.ShellExecutions() {
+				texts = append(texts, shellExec.String())
+			}
+			if text := msg.Content().String(); text != "" || len(texts) == 0 {
+				texts = append(texts, text)
+			}
+			text := strings.Join(texts, "\n\n")
+
+			textBlock := openai.ChatCompletionContentPartTextParam{Text: text}
 			content = append(content, openai.ChatCompletionContentPartUnionParam{OfText: &textBlock})
 			hasBinaryContent := false
 			for _, binaryContent := range msg.BinaryContent() {
@@ -118,7 +127,7 @@ func (o *openaiClient) convertMessages(messages []message.Message) (openaiMessag
 			if hasBinaryContent || (isAnthropicModel && !o.providerOptions.disableCache) {
 				openaiMessages = append(openaiMessages, openai.UserMessage(content))
 			} else {
-				openaiMessages = append(openaiMessages, openai.UserMessage(msg.Content().String()))
+				openaiMessages = append(openaiMessages, openai.UserMessage(text))
 			}
 
 		case message.Assistant:
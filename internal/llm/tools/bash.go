@@ -3,12 +3,16 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/history"
 	"github.com/lacymorrow/lash/internal/permission"
+	"github.com/lacymorrow/lash/internal/policy"
 	"github.com/lacymorrow/lash/internal/shell"
 )
 
@@ -27,10 +31,26 @@ type BashResponseMetadata struct {
 	EndTime          int64  `json:"end_time"`
 	Output           string `json:"output"`
 	WorkingDirectory string `json:"working_directory"`
+	// FirstOutputTime is when the command first wrote output, zero if it
+	// wrote none.
+	FirstOutputTime int64 `json:"first_output_time,omitempty"`
 }
 type bashTool struct {
+	shells    *shell.Manager
+	guard     *commandGuard
+	files     history.Service
+	workspace *history.Workspace
+}
+
+// commandGuard decides whether the agent may run a command, from the command
+// policy of lash.json and by asking the user for permission.
+type commandGuard struct {
 	permissions permission.Service
 	workingDir  string
+	policy      *policy.Engine
+	// policyErr is why the command policy could not be loaded. No command
+	// runs until it is fixed.
+	policyErr error
 }
 
 const (
@@ -42,81 +62,7 @@ const (
 	BashNoOutput    = "no output"
 )
 
-var bannedCommands = []string{
-	// Network/Download tools
-	"alias",
-	"aria2c",
-	"axel",
-	"chrome",
-	"curl",
-	"curlie",
-	"firefox",
-	"http-prompt",
-	"httpie",
-	"links",
-	"lynx",
-	"nc",
-	"safari",
-	"scp",
-	"ssh",
-	"telnet",
-	"w3m",
-	"wget",
-	"xh",
-
-	// System administration
-	"doas",
-	"su",
-	"sudo",
-
-	// Package managers
-	"apk",
-	"apt",
-	"apt-cache",
-	"apt-get",
-	"dnf",
-	"dpkg",
-	"emerge",
-	"home-manager",
-	"makepkg",
-	"opkg",
-	"pacman",
-	"paru",
-	"pkg",
-	"pkg_add",
-	"pkg_delete",
-	"portage",
-	"rpm",
-	"yay",
-	"yum",
-	"zypper",
-
-	// System modification
-	"at",
-	"batch",
-	"chkconfig",
-	"crontab",
-	"fdisk",
-	"mkfs",
-	"mount",
-	"parted",
-	"service",
-	"systemctl",
-	"umount",
-
-	// Network configuration
-	"firewall-cmd",
-	"ifconfig",
-	"ip",
-	"iptables",
-	"netstat",
-	"pfctl",
-	"route",
-	"ufw",
-}
-
 func bashDescription() string {
-	bannedCommandsStr := strings.Join(bannedCommands, ", ")
 	return fmt.Sprintf(`Executes a given bash command in a persistent shell session with optional timeout, ensuring proper handling and security measures.
 
 CROSS-PLATFORM SHELL SUPPORT:
@@ -134,15 +80,14 @@ Before executing the command, please follow these steps:
  - For example, before running "mkdir foo/bar", first use LS to check that "foo" exists and is the intended parent directory
 
 2. Security Check:
- - For security and to limit the threat of a prompt injection attack, some commands are limited or banned. If you use a disallowed command, you will receive an error message explaining the restriction. Explain the error to the User.
- - Verify that the command is not one of the banned commands: %s.
+ - For security and to limit the threat of a prompt injection attack, commands are checked against the project's command policy, including those run by scripts and command substitutions. If a command is denied, you will receive an error message naming the policy rule that denied it. Explain the error to the User and do not try to work around the rule.
 
 3. Command Execution:
  - After ensuring proper quoting, execute the command.
  - Capture the output of the command.
 
 4. Output Processing:
- - If the output exceeds %d characters, output will be truncated before being returned to you.
+ - If the output exceeds %d characters, only its start and end are returned to you; the full output is saved and can be read with the read_output tool.
  - Prepare the output for display to the user.
 
 5. Return Result:
@@ -270,50 +215,36 @@ EOF
 
 Important:
 - Return an empty response - the user will see the gh output directly
-- Never update git config`, bannedCommandsStr, MaxOutputLength)
+- Never update git config`, MaxOutputLength)
 }
 
-func blockFuncs() []shell.BlockFunc {
-	return []shell.BlockFunc{
-		shell.CommandsBlocker(bannedCommands),
-		shell.ArgumentsBlocker([][]string{
-			// System package managers
-			{"apk", "add"},
-			{"apt", "install"},
-			{"apt-get", "install"},
-			{"dnf", "install"},
-			{"emerge"},
-			{"pacman", "-S"},
-			{"pkg", "install"},
-			{"yum", "install"},
-			{"zypper", "install"},
-
-			// Language-specific package managers
-			{"brew", "install"},
-			{"cargo", "install"},
-			{"gem", "install"},
-			{"go", "install"},
-			{"npm", "install", "-g"},
-			{"npm", "install", "--global"},
-			{"pip", "install", "--user"},
-			{"pip3", "install", "--user"},
-			{"pnpm", "add", "-g"},
-			{"pnpm", "add", "--global"},
-			{"yarn", "global", "add"},
-		}),
+func NewBashTool(permission permission.Service, shells *shell.Manager, files history.Service, workingDir string) BaseTool {
+	return &bashTool{
+		shells:    shells,
+		guard:     newCommandGuard(permission, workingDir),
+		files:     files,
+		workspace: history.SharedWorkspace(workingDir),
 	}
 }
 
-func NewBashTool(permission permission.Service, workingDir string) BaseTool {
+func newCommandGuard(permissions permission.Service, workingDir string) *commandGuard {
 	// Do not hard-block commands. Route all execution through the
-	// permissions service so the UI can approve/deny when needed.
-	// In YOLO mode, the permission service will auto-approve.
-	persistentShell := shell.GetPersistentShell(workingDir)
-	persistentShell.SetBlockFuncs([]shell.BlockFunc{})
-
-	return &bashTool{
-		permissions: permission,
+	// permissions service so the UI can approve/deny when needed, and
+	// through the command policy of lash.json. In YOLO mode, the permission
+	// service will auto-approve.
+	var rules []config.LashPolicyRule
+	if cfg := config.Get(); cfg != nil && cfg.Lash != nil {
+		rules = cfg.Lash.Policy.Rules
+	}
+	engine, err := policy.New(rules, workingDir)
+	if err != nil {
+		slog.Error("Invalid command policy, refusing to run commands", "error", err)
+	}
+	return &commandGuard{
+		permissions: permissions,
 		workingDir:  workingDir,
+		policy:      engine,
+		policyErr:   err,
 	}
 }
 
@@ -355,39 +286,13 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse("missing command"), nil
 	}
 
-	isSafeReadOnly := false
-	cmdLower := strings.ToLower(params.Command)
-
-	for _, safe := range safeCommands {
-		if strings.HasPrefix(cmdLower, safe) {
-			if len(cmdLower) == len(safe) || cmdLower[len(safe)] == ' ' || cmdLower[len(safe)] == '-' {
-				isSafeReadOnly = true
-				break
-			}
-		}
-	}
-
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
 	}
-	if !isSafeReadOnly {
-		p := b.permissions.Request(
-			permission.CreatePermissionRequest{
-				SessionID:   sessionID,
-				Path:        b.workingDir,
-				ToolCallID:  call.ID,
-				ToolName:    BashToolName,
-				Action:      "execute",
-				Description: fmt.Sprintf("Execute command: %s", params.Command),
-				Params: BashPermissionsParams{
-					Command: params.Command,
-				},
-			},
-		)
-		if !p {
-			return ToolResponse{}, permission.ErrorPermissionDenied
-		}
+	persistentShell := b.shells.Agent(sessionID)
+	if response, err := b.guard.check(sessionID, call.ID, params.Command, persistentShell); response != nil || err != nil {
+		return *response, err
 	}
 	startTime := time.Now()
 	if params.Timeout > 0 {
@@ -396,8 +301,26 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		defer cancel()
 	}
 
-	persistentShell := shell.GetPersistentShell(b.workingDir)
-	stdout, stderr, err := persistentShell.Exec(ctx, params.Command)
+	ctx = shell.WithPolicy(ctx, b.guard.checkCommand(sessionID, call.ID))
+	// Read-only commands change no files, so there is no need to scan the
+	// workspace for them.
+	track := !isReadOnlyCommand(params.Command, shadowedCommands(persistentShell))
+	tracked := false
+	if track {
+		var err error
+		if tracked, err = b.workspace.Track(); err != nil {
+			slog.Debug("Not tracking the file changes of the command", "error", err)
+		}
+	}
+	output := newLiveOutput(sessionID, call.ID)
+	err := persistentShell.ExecStream(ctx, params.Command, output.Stdout(), output.Stderr())
+	stdout, stderr := output.Close()
+	if track {
+		b.recordChanges(context.WithoutCancel(ctx), sessionID, call.ID, tracked)
+	}
+	if errors.Is(err, permission.ErrorPermissionDenied) {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
 
 	// Get the current working directory after command execution
 	currentWorkingDir := persistentShell.GetWorkingDir()
@@ -407,11 +330,17 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return ToolResponse{}, fmt.Errorf("error executing command: %w", err)
 	}
 
-	stdout = truncateOutput(stdout)
-	stderr = truncateOutput(stderr)
+	stdout = SpillOutput(ctx, stdout)
+	stderr = SpillOutput(ctx, stderr)
 
 	errorMessage := stderr
-	if errorMessage == "" && err != nil {
+	var denial *policyDenial
+	if errors.As(err, &denial) {
+		if errorMessage != "" {
+			errorMessage += "\n"
+		}
+		errorMessage += denial.Error()
+	} else if errorMessage == "" && err != nil {
 		errorMessage = err.Error()
 	}
 
@@ -443,6 +372,9 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		Output:           stdout,
 		WorkingDirectory: currentWorkingDir,
 	}
+	if first := output.FirstOutput(); !first.IsZero() {
+		metadata.FirstOutputTime = first.UnixMilli()
+	}
 	if stdout == "" {
 		return WithResponseMetadata(NewTextResponse(BashNoOutput), metadata), nil
 	}
@@ -450,6 +382,35 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	return WithResponseMetadata(NewTextResponse(stdout), metadata), nil
 }
 
+// recordChanges finishes the command of a tool call in the workspace and
+// records the changes it made to its files in the file history, like the
+// file tools do.
+func (b *bashTool) recordChanges(ctx context.Context, sessionID, toolCallID string, tracked bool) {
+	changes, err := b.workspace.Finish(tracked)
+	if err != nil {
+		slog.Debug("Error finding the file changes of the command", "error", err)
+		return
+	}
+	for _, change := range changes {
+		file, err := b.files.GetByPathAndSession(ctx, change.Path, sessionID)
+		if err != nil {
+			if _, err = b.files.Create(ctx, sessionID, change.Path, change.Before); err != nil {
+				slog.Debug("Error creating file history", "error", err)
+				continue
+			}
+		} else if file.Content != change.Before {
+			// The file changed since its last version, store an intermediate
+			// version
+			if _, err = b.files.CreateVersion(ctx, sessionID, change.Path, change.Before); err != nil {
+				slog.Debug("Error creating file history version", "error", err)
+			}
+		}
+		if _, err = b.files.CreateToolCallVersion(ctx, sessionID, toolCallID, change.Path, change.After); err != nil {
+			slog.Debug("Error creating file history version", "error", err)
+		}
+	}
+}
+
 func truncateOutput(content string) string {
 	if len(content) <= MaxOutputLength {
 		return content
@@ -469,3 +430,127 @@ func countLines(s string) int {
 	}
 	return len(strings.Split(s, "\n"))
 }
+
+// policyDenial is the error of a command denied by the command policy.
+type policyDenial struct {
+	decision policy.Decision
+	command  string
+}
+
+func (e *policyDenial) Error() string {
+	return fmt.Sprintf("%s was denied by %s", e.command, e.decision)
+}
+
+// check decides whether command may run in sh, before it runs. It returns a
+// response for the model if it may not, and ErrorPermissionDenied if the
+// user denied it.
+func (g *commandGuard) check(sessionID, toolCallID, command string, sh *shell.Shell) (*ToolResponse, error) {
+	if g.policyErr != nil {
+		response := NewTextErrorResponse(fmt.Sprintf("The command policy in lash.json is invalid, no command can run until it is fixed: %v", g.policyErr))
+		return &response, nil
+	}
+
+	decision, allowed := g.policy.EvaluateScript(command, sh.GetWorkingDir())
+	if decision.Action == policy.Deny {
+		response := NewTextErrorResponse(fmt.Sprintf("Command denied by %s", decision))
+		return &response, nil
+	}
+	if !allowed && !isReadOnlyCommand(command, shadowedCommands(sh)) {
+		p := g.permissions.Request(
+			permission.CreatePermissionRequest{
+				SessionID:   sessionID,
+				Path:        g.workingDir,
+				ToolCallID:  toolCallID,
+				ToolName:    BashToolName,
+				Action:      "execute",
+				Description: fmt.Sprintf("Execute command: %s", command),
+				Params: BashPermissionsParams{
+					Command: command,
+				},
+			},
+		)
+		if !p {
+			return &ToolResponse{}, permission.ErrorPermissionDenied
+		}
+	}
+	return nil, nil
+}
+
+// checkInput decides whether input may be written to the process called name
+// of sh. The process may be a shell or a REPL that runs whatever it reads, so
+// input goes through the command policy like a command, and the user is asked
+// unless the policy allows every command in it.
+func (g *commandGuard) checkInput(sessionID, toolCallID, name, input string, sh *shell.Shell) (*ToolResponse, error) {
+	if g.policyErr != nil {
+		response := NewTextErrorResponse(fmt.Sprintf("The command policy in lash.json is invalid, no input can be sent until it is fixed: %v", g.policyErr))
+		return &response, nil
+	}
+
+	decision, allowed := g.policy.EvaluateScript(input, sh.GetWorkingDir())
+	if decision.Action == policy.Deny {
+		response := NewTextErrorResponse(fmt.Sprintf("Input denied by %s", decision))
+		return &response, nil
+	}
+	if allowed {
+		return nil, nil
+	}
+	p := g.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        g.workingDir,
+			ToolCallID:  toolCallID,
+			ToolName:    BashToolName,
+			Action:      "send",
+			Description: fmt.Sprintf("Send input to process %q: %s", name, input),
+			Params: BashPermissionsParams{
+				Command: input,
+			},
+		},
+	)
+	if !p {
+		return &ToolResponse{}, permission.ErrorPermissionDenied
+	}
+	return nil, nil
+}
+
+// shadowedCommands returns the names the functions and aliases of sh give
+// new meanings to.
+func shadowedCommands(sh *shell.Shell) []string {
+	shadowed := sh.GetFunctions()
+	for name := range sh.GetAliases() {
+		shadowed = append(shadowed, name)
+	}
+	return shadowed
+}
+
+// checkCommand returns the policy every simple command run by a tool call
+// goes through, including those run by scripts and command substitutions.
+func (g *commandGuard) checkCommand(sessionID, toolCallID string) shell.Policy {
+	return func(ctx context.Context, req shell.ExecRequest) error {
+		decision := g.policy.Evaluate(req)
+		command := strings.Join(req.Args, " ")
+		switch decision.Action {
+		case policy.Deny:
+			return &policyDenial{decision: decision, command: command}
+		case policy.Ask:
+			granted := g.permissions.Request(
+				permission.CreatePermissionRequest{
+					SessionID:   sessionID,
+					Path:        req.Dir,
+					ToolCallID:  toolCallID,
+					ToolName:    BashToolName,
+					Action:      "policy",
+					Description: fmt.Sprintf("Execute command: %s (asked by %s)", command, decision),
+					Params: BashPermissionsParams{
+						Command: command,
+					},
+					IgnoreAllowlist: true,
+				},
+			)
+			if !granted {
+				return permission.ErrorPermissionDenied
+			}
+		}
+		return nil
+	}
+}
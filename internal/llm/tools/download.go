@@ -141,6 +141,7 @@ func (t *downloadTool) Run(ctx context.Context, call ToolCall) (ToolResponse, er
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        filePath,
+			FilePath:    filePath,
 			ToolName:    DownloadToolName,
 			Action:      "download",
 			Description: fmt.Sprintf("Download file from URL: %s to %s", params.URL, filePath),
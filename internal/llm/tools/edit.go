@@ -220,6 +220,7 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string,
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        fsext.PathOrPrefix(filePath, e.workingDir),
+			FilePath:    filePath,
 			ToolCallID:  call.ID,
 			ToolName:    EditToolName,
 			Action:      "write",
@@ -341,6 +342,7 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        fsext.PathOrPrefix(filePath, e.workingDir),
+			FilePath:    filePath,
 			ToolCallID:  call.ID,
 			ToolName:    EditToolName,
 			Action:      "write",
@@ -472,6 +474,7 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        fsext.PathOrPrefix(filePath, e.workingDir),
+			FilePath:    filePath,
 			ToolCallID:  call.ID,
 			ToolName:    EditToolName,
 			Action:      "write",
@@ -229,12 +229,7 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			content = "<html>\n<body>\n" + body + "\n</body>\n</html>"
 		}
 	}
-	// calculate byte size of content
-	contentSize := int64(len(content))
-	if contentSize > MaxReadSize {
-		content = content[:MaxReadSize]
-		content += fmt.Sprintf("\n\n[Content truncated to %d bytes]", MaxReadSize)
-	}
+	content = spillOutput(ctx, content, MaxReadSize, 0)
 
 	return NewTextResponse(content), nil
 }
@@ -93,6 +93,12 @@ type grepTool struct {
 }
 
 const (
+	// maxShownMatches is how many matches grep returns.
+	maxShownMatches = 100
+	// maxSavedMatches is how many matches grep saves for read_output when
+	// there are more than it returns.
+	maxSavedMatches = 10000
+
 	GrepToolName    = "grep"
 	grepDescription = `Fast content search tool that finds files containing specific text or patterns, returning matching file paths sorted by modification time (newest first).
 
@@ -120,7 +126,7 @@ COMMON INCLUDE PATTERN EXAMPLES:
 - '*.go' - Only search Go files
 
 LIMITATIONS:
-- Results are limited to 100 files (newest first)
+- Results are limited to 100 matches (newest files first); up to 10000 more are saved for the read_output tool
 - Performance depends on the number of files being searched
 - Very large binary files may be skipped
 - Hidden files (starting with '.') are skipped
@@ -211,7 +217,7 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		searchPath = g.workingDir
 	}
 
-	matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, params.Include, 100)
+	matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, params.Include, maxSavedMatches)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error searching files: %w", err)
 	}
@@ -220,27 +226,28 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if len(matches) == 0 {
 		output.WriteString("No files found")
 	} else {
-		fmt.Fprintf(&output, "Found %d matches\n", len(matches))
-
-		currentFile := ""
-		for _, match := range matches {
-			if currentFile != match.path {
-				if currentFile != "" {
-					output.WriteString("\n")
-				}
-				currentFile = match.path
-				fmt.Fprintf(&output, "%s:\n", match.path)
-			}
-			if match.lineNum > 0 {
-				fmt.Fprintf(&output, "  Line %d: %s\n", match.lineNum, match.lineText)
-			} else {
-				fmt.Fprintf(&output, "  %s\n", match.path)
-			}
+		shown := matches[:min(len(matches), maxShownMatches)]
+		fmt.Fprintf(&output, "Found %d matches\n", len(shown))
+		writeMatches(&output, shown)
+
+		// Save the matches that are not shown for read_output.
+		var id string
+		if len(matches) > len(shown) {
+			var all strings.Builder
+			writeMatches(&all, matches)
+			sessionID, _ := GetContextValues(ctx)
+			id, _ = saveOutput(sessionID, all.String())
 		}
-
-		if truncated {
+		switch {
+		case id != "" && truncated:
+			fmt.Fprintf(&output, "\n(Results are truncated. The first %d matches are saved as %s, read them with the %s tool, or use a more specific path or pattern.)", len(matches), id, ReadOutputToolName)
+		case id != "":
+			fmt.Fprintf(&output, "\n(Results are truncated. All %d matches are saved as %s, read them with the %s tool, or use a more specific path or pattern.)", len(matches), id, ReadOutputToolName)
+		case len(matches) > len(shown) || truncated:
 			output.WriteString("\n(Results are truncated. Consider using a more specific path or pattern.)")
 		}
+		truncated = truncated || len(matches) > len(shown)
+		matches = shown
 	}
 
 	return WithResponseMetadata(
@@ -252,6 +259,25 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	), nil
 }
 
+// writeMatches lists matches grouped by file.
+func writeMatches(output *strings.Builder, matches []grepMatch) {
+	currentFile := ""
+	for _, match := range matches {
+		if currentFile != match.path {
+			if currentFile != "" {
+				output.WriteString("\n")
+			}
+			currentFile = match.path
+			fmt.Fprintf(output, "%s:\n", match.path)
+		}
+		if match.lineNum > 0 {
+			fmt.Fprintf(output, "  Line %d: %s\n", match.lineNum, match.lineText)
+		} else {
+			fmt.Fprintf(output, "  %s\n", match.path)
+		}
+	}
+}
+
 func searchFiles(ctx context.Context, pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
 	matches, err := searchWithRipgrep(ctx, pattern, rootPath, include)
 	if err != nil {
@@ -254,6 +254,7 @@ func (m *multiEditTool) processMultiEditWithCreation(ctx context.Context, params
 	p := m.permissions.Request(permission.CreatePermissionRequest{
 		SessionID:   sessionID,
 		Path:        fsext.PathOrPrefix(params.FilePath, m.workingDir),
+		FilePath:    params.FilePath,
 		ToolCallID:  call.ID,
 		ToolName:    MultiEditToolName,
 		Action:      "write",
@@ -363,6 +364,7 @@ func (m *multiEditTool) processMultiEditExistingFile(ctx context.Context, params
 	p := m.permissions.Request(permission.CreatePermissionRequest{
 		SessionID:   sessionID,
 		Path:        fsext.PathOrPrefix(params.FilePath, m.workingDir),
+		FilePath:    params.FilePath,
 		ToolCallID:  call.ID,
 		ToolName:    MultiEditToolName,
 		Action:      "write",
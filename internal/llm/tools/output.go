@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/pubsub"
+)
+
+const (
+	// liveTailSize is how much of the end of a running command's output is
+	// published.
+	liveTailSize = 8 * 1024
+	// liveOutputInterval is how often the output of a running command is
+	// published, at most.
+	liveOutputInterval = 100 * time.Millisecond
+)
+
+// ToolOutput is the output a tool call has produced so far, published while
+// the tool runs.
+type ToolOutput struct {
+	SessionID  string
+	ToolCallID string
+	// Tail is the end of the output, stdout and stderr interleaved.
+	Tail string
+}
+
+var outputBroker = pubsub.NewBroker[ToolOutput]()
+
+// SubscribeOutput returns a channel for the output of running tool calls.
+func SubscribeOutput(ctx context.Context) <-chan pubsub.Event[ToolOutput] {
+	return outputBroker.Subscribe(ctx)
+}
+
+// liveOutput collects the output of a command and publishes its tail while
+// the command runs. Close it when the command exits.
+type liveOutput struct {
+	sessionID  string
+	toolCallID string
+
+	mu          sync.Mutex
+	stdout      bytes.Buffer
+	stderr      bytes.Buffer
+	tail        []byte
+	changed     bool
+	firstOutput time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newLiveOutput(sessionID, toolCallID string) *liveOutput {
+	o := &liveOutput{
+		sessionID:  sessionID,
+		toolCallID: toolCallID,
+		done:       make(chan struct{}),
+	}
+	o.wg.Add(1)
+	go o.publish()
+	return o
+}
+
+// Stdout returns the writer for the standard output of the command.
+func (o *liveOutput) Stdout() io.Writer {
+	return &outputWriter{output: o, buf: &o.stdout}
+}
+
+// Stderr returns the writer for the standard error of the command.
+func (o *liveOutput) Stderr() io.Writer {
+	return &outputWriter{output: o, buf: &o.stderr}
+}
+
+// Close stops publishing the output and returns everything the command
+// wrote to stdout and stderr.
+func (o *liveOutput) Close() (string, string) {
+	close(o.done)
+	o.wg.Wait()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.stdout.String(), o.stderr.String()
+}
+
+// FirstOutput returns when the command first wrote something, or the zero
+// time if it did not.
+func (o *liveOutput) FirstOutput() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.firstOutput
+}
+
+func (o *liveOutput) write(buf *bytes.Buffer, p []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(p) == 0 {
+		return
+	}
+	if o.firstOutput.IsZero() {
+		o.firstOutput = time.Now()
+	}
+	buf.Write(p)
+	o.tail = append(o.tail, p...)
+	if len(o.tail) > liveTailSize {
+		// Start at a line boundary rather than in the middle of a line.
+		start := len(o.tail) - liveTailSize
+		if i := bytes.IndexByte(o.tail[start:], '\n'); i >= 0 {
+			start += i + 1
+		}
+		o.tail = append(o.tail[:0], o.tail[start:]...)
+	}
+	o.changed = true
+}
+
+// publish publishes the tail when it changed, at most every
+// liveOutputInterval, so chatty commands do not flood subscribers.
+func (o *liveOutput) publish() {
+	defer o.wg.Done()
+	ticker := time.NewTicker(liveOutputInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-ticker.C:
+		}
+		o.mu.Lock()
+		if !o.changed {
+			o.mu.Unlock()
+			continue
+		}
+		tail := string(o.tail)
+		o.changed = false
+		o.mu.Unlock()
+		outputBroker.Publish(pubsub.UpdatedEvent, ToolOutput{
+			SessionID:  o.sessionID,
+			ToolCallID: o.toolCallID,
+			Tail:       tail,
+		})
+	}
+}
+
+type outputWriter struct {
+	output *liveOutput
+	buf    *bytes.Buffer
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.output.write(w.buf, p)
+	return len(p), nil
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveOutput(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := SubscribeOutput(ctx)
+
+	output := newLiveOutput("session", "live-output-call")
+	fmt.Fprint(output.Stdout(), "building\n")
+	fmt.Fprint(output.Stderr(), "warning: slow\n")
+
+	var tail string
+	timeout := time.After(5 * time.Second)
+	for tail == "" {
+		select {
+		case event := <-events:
+			if event.Payload.ToolCallID == "live-output-call" {
+				require.Equal(t, "session", event.Payload.SessionID)
+				tail = event.Payload.Tail
+			}
+		case <-timeout:
+			t.Fatal("no output was published")
+		}
+	}
+	require.Equal(t, "building\nwarning: slow\n", tail)
+
+	fmt.Fprint(output.Stdout(), strings.Repeat("line\n", liveTailSize))
+	stdout, stderr := output.Close()
+	require.Equal(t, "building\n"+strings.Repeat("line\n", liveTailSize), stdout)
+	require.Equal(t, "warning: slow\n", stderr)
+	require.False(t, output.FirstOutput().IsZero())
+
+	output.mu.Lock()
+	defer output.mu.Unlock()
+	require.LessOrEqual(t, len(output.tail), liveTailSize)
+	require.True(t, strings.HasPrefix(string(output.tail), "line\n"))
+}
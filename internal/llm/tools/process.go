@@ -0,0 +1,491 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/permission"
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+const (
+	ProcessStartToolName  = "process_start"
+	ProcessOutputToolName = "process_output"
+	ProcessSendToolName   = "process_send"
+	ProcessListToolName   = "process_list"
+	ProcessKillToolName   = "process_kill"
+
+	// DefaultProcessWaitTimeout is how long to wait for wait_for patterns by
+	// default, in milliseconds.
+	DefaultProcessWaitTimeout = 30 * 1000
+	// processStartupTime is how long process_start waits for early output
+	// and failures when there is no pattern to wait for.
+	processStartupTime = time.Second
+	// processPollInterval is how often new output is checked while waiting.
+	processPollInterval = 100 * time.Millisecond
+)
+
+type ProcessStartParams struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	WaitFor string `json:"wait_for"`
+	Timeout int    `json:"timeout"`
+}
+
+type ProcessOutputParams struct {
+	Name    string `json:"name"`
+	WaitFor string `json:"wait_for"`
+	Timeout int    `json:"timeout"`
+}
+
+type ProcessSendParams struct {
+	Name       string `json:"name"`
+	Input      string `json:"input"`
+	CloseInput bool   `json:"close_input"`
+	Signal     string `json:"signal"`
+}
+
+type ProcessKillParams struct {
+	Name string `json:"name"`
+}
+
+const processStartDescription = `Starts a long-running command, like a development server, a watcher or a REPL, as a named background process of the agent's shell, and returns without waiting for it to exit.
+
+WHEN TO USE THIS TOOL:
+- Use for commands that keep running, which would block the bash tool until its timeout
+- Use the bash tool for commands that finish on their own, even slow ones
+
+HOW TO USE:
+- Give the process a short unique name, like "server" or "tests-watch"
+- Set wait_for to a regular expression to wait until the output matches it, like "listening on .*:3000"; timeout (milliseconds, default 30000, max 600000) bounds the wait
+- Without wait_for, the output of the first second is returned
+- The command runs in the agent's shell, from its working directory and with its environment, and goes through the same checks as bash commands
+- Read more output with process_output, send input or signals with process_send, and stop the process with process_kill once you no longer need it
+- Processes are stopped when lash exits`
+
+const processOutputDescription = `Reads the output a background process started with process_start has written since it was last read, stdout and stderr interleaved.
+
+HOW TO USE:
+- Set wait_for to a regular expression to wait until the new output matches it, the process exits, or timeout (milliseconds, default 30000, max 600000) passes
+- Without wait_for, the output is returned right away
+- The status of the process is returned with its output`
+
+const processSendDescription = `Sends input or a signal to a background process started with process_start.
+
+HOW TO USE:
+- input is written to the standard input of the process as is; end it with a newline to send a line
+- input goes through the same checks as bash commands, since the process may run what it reads: the user is asked to approve it unless the command policy allows it, and input the policy denies is not sent
+- Set close_input to close the standard input after writing, for programs that read until the end of their input
+- signal sends a signal like "TERM", "INT" or "HUP" to the process and the programs it started
+- Read the response with process_output`
+
+const processListDescription = `Lists the background processes started with process_start in this session, with their status and command.`
+
+const processKillDescription = `Stops a background process started with process_start. It is interrupted, then killed if it does not exit within a few seconds. Returns the output it wrote since it was last read.`
+
+// processTool holds what the process tools share.
+type processTool struct {
+	shells *shell.Manager
+}
+
+// job returns the process called name of the session's agent shell.
+func (p processTool) job(sessionID, name string) (*shell.Job, *ToolResponse) {
+	if name == "" {
+		response := NewTextErrorResponse("name is required")
+		return nil, &response
+	}
+	j, ok := p.shells.Agent(sessionID).NamedJob(name)
+	if !ok {
+		response := NewTextErrorResponse(fmt.Sprintf("no process called %q, list them with %s", name, ProcessListToolName))
+		return nil, &response
+	}
+	return j, nil
+}
+
+// waitTimeout bounds the timeout of a tool call to MaxTimeout.
+func waitTimeout(ms int) time.Duration {
+	if ms <= 0 {
+		ms = DefaultProcessWaitTimeout
+	}
+	return time.Duration(min(ms, MaxTimeout)) * time.Millisecond
+}
+
+// collectOutput reads the new output of j until it matches waitFor, j
+// finishes or timeout passes. A nil waitFor waits for j to finish or the
+// timeout. matched reports whether waitFor matched.
+func collectOutput(ctx context.Context, j *shell.Job, waitFor *regexp.Regexp, timeout time.Duration) (output string, matched, dropped bool) {
+	var sb strings.Builder
+	read := func() {
+		out, lost := j.ReadOutput()
+		sb.WriteString(out)
+		dropped = dropped || lost
+	}
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(processPollInterval)
+	defer ticker.Stop()
+	for {
+		read()
+		if waitFor != nil && waitFor.MatchString(sb.String()) {
+			return sb.String(), true, dropped
+		}
+		select {
+		case <-j.Done():
+			read()
+			return sb.String(), waitFor != nil && waitFor.MatchString(sb.String()), dropped
+		case <-deadline:
+			return sb.String(), false, dropped
+		case <-ctx.Done():
+			return sb.String(), false, dropped
+		case <-ticker.C:
+		}
+	}
+}
+
+// describeProcess reports the status and output of a process to the model.
+func describeProcess(ctx context.Context, info shell.JobInfo, output string, dropped bool, note string) string {
+	var sb strings.Builder
+	if info.Finished() {
+		fmt.Fprintf(&sb, "Process %q has exited: %s.\n", info.Name, strings.ToLower(info.Status()))
+	} else {
+		fmt.Fprintf(&sb, "Process %q is running, started %s ago.\n", info.Name, time.Since(info.StartedAt).Round(time.Second))
+	}
+	if note != "" {
+		sb.WriteString(note + "\n")
+	}
+	if dropped {
+		sb.WriteString("Some output was lost because the process wrote too much since it was last read.\n")
+	}
+	if output == "" {
+		sb.WriteString("No new output.")
+	} else {
+		fmt.Fprintf(&sb, "<output>\n%s\n</output>", SpillOutput(ctx, strings.TrimRight(output, "\n")))
+	}
+	return sb.String()
+}
+
+type processStartTool struct {
+	processTool
+	guard *commandGuard
+}
+
+func NewProcessStartTool(permissions permission.Service, shells *shell.Manager, workingDir string) BaseTool {
+	return &processStartTool{
+		processTool: processTool{shells: shells},
+		guard:       newCommandGuard(permissions, workingDir),
+	}
+}
+
+func (p *processStartTool) Name() string {
+	return ProcessStartToolName
+}
+
+func (p *processStartTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ProcessStartToolName,
+		Description: processStartDescription,
+		Parameters: map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "A short unique name for the process",
+			},
+			"command": map[string]any{
+				"type":        "string",
+				"description": "The command to run",
+			},
+			"wait_for": map[string]any{
+				"type":        "string",
+				"description": "Optional regular expression to wait for in the output",
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": "Optional timeout in milliseconds for wait_for (default 30000, max 600000)",
+			},
+		},
+		Required: []string{"name", "command"},
+	}
+}
+
+func (p *processStartTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ProcessStartParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+	if params.Name == "" || params.Command == "" {
+		return NewTextErrorResponse("name and command are required"), nil
+	}
+	var waitFor *regexp.Regexp
+	if params.WaitFor != "" {
+		var err error
+		if waitFor, err = regexp.Compile(params.WaitFor); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("invalid wait_for pattern: %v", err)), nil
+		}
+	}
+
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for starting a process")
+	}
+	sh := p.shells.Agent(sessionID)
+	if response, err := p.guard.check(sessionID, call.ID, params.Command, sh); response != nil || err != nil {
+		return *response, err
+	}
+
+	j, err := sh.StartJob(shell.WithPolicy(ctx, p.guard.checkCommand(sessionID, call.ID)), params.Name, params.Command)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("could not start process: %v", err)), nil
+	}
+
+	timeout := processStartupTime
+	if waitFor != nil {
+		timeout = waitTimeout(params.Timeout)
+	}
+	output, matched, dropped := collectOutput(ctx, j, waitFor, timeout)
+	info := j.Info()
+	note := ""
+	switch {
+	case waitFor != nil && matched:
+		note = fmt.Sprintf("The output matched %q.", params.WaitFor)
+	case waitFor != nil && !info.Finished():
+		note = fmt.Sprintf("The output did not match %q within %s, the process is still running.", params.WaitFor, timeout)
+	}
+	response := NewTextResponse(describeProcess(ctx, info, output, dropped, note))
+	response.IsError = info.State == shell.JobFailed
+	return response, nil
+}
+
+type processOutputTool struct {
+	processTool
+}
+
+func NewProcessOutputTool(shells *shell.Manager) BaseTool {
+	return &processOutputTool{processTool{shells: shells}}
+}
+
+func (p *processOutputTool) Name() string {
+	return ProcessOutputToolName
+}
+
+func (p *processOutputTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ProcessOutputToolName,
+		Description: processOutputDescription,
+		Parameters: map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "The name of the process",
+			},
+			"wait_for": map[string]any{
+				"type":        "string",
+				"description": "Optional regular expression to wait for in the new output",
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": "Optional timeout in milliseconds for wait_for (default 30000, max 600000)",
+			},
+		},
+		Required: []string{"name"},
+	}
+}
+
+func (p *processOutputTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ProcessOutputParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+	var waitFor *regexp.Regexp
+	if params.WaitFor != "" {
+		var err error
+		if waitFor, err = regexp.Compile(params.WaitFor); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("invalid wait_for pattern: %v", err)), nil
+		}
+	}
+	sessionID, _ := GetContextValues(ctx)
+	j, response := p.job(sessionID, params.Name)
+	if response != nil {
+		return *response, nil
+	}
+
+	var timeout time.Duration
+	if waitFor != nil {
+		timeout = waitTimeout(params.Timeout)
+	}
+	output, matched, dropped := collectOutput(ctx, j, waitFor, timeout)
+	info := j.Info()
+	note := ""
+	switch {
+	case waitFor != nil && matched:
+		note = fmt.Sprintf("The output matched %q.", params.WaitFor)
+	case waitFor != nil:
+		note = fmt.Sprintf("The output did not match %q within %s.", params.WaitFor, timeout)
+	}
+	return NewTextResponse(describeProcess(ctx, info, output, dropped, note)), nil
+}
+
+type processSendTool struct {
+	processTool
+	guard *commandGuard
+}
+
+func NewProcessSendTool(permissions permission.Service, shells *shell.Manager, workingDir string) BaseTool {
+	return &processSendTool{
+		processTool: processTool{shells: shells},
+		guard:       newCommandGuard(permissions, workingDir),
+	}
+}
+
+func (p *processSendTool) Name() string {
+	return ProcessSendToolName
+}
+
+func (p *processSendTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ProcessSendToolName,
+		Description: processSendDescription,
+		Parameters: map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "The name of the process",
+			},
+			"input": map[string]any{
+				"type":        "string",
+				"description": "Text to write to the standard input of the process",
+			},
+			"close_input": map[string]any{
+				"type":        "boolean",
+				"description": "Close the standard input of the process after writing input",
+			},
+			"signal": map[string]any{
+				"type":        "string",
+				"description": "Signal to send to the process, like TERM, INT or HUP",
+			},
+		},
+		Required: []string{"name"},
+	}
+}
+
+func (p *processSendTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ProcessSendParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+	if params.Input == "" && !params.CloseInput && params.Signal == "" {
+		return NewTextErrorResponse("one of input, close_input or signal is required"), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+	j, response := p.job(sessionID, params.Name)
+	if response != nil {
+		return *response, nil
+	}
+	if j.Info().Finished() {
+		return NewTextErrorResponse(fmt.Sprintf("process %q has already exited", params.Name)), nil
+	}
+
+	var done []string
+	if params.Input != "" {
+		if response, err := p.guard.checkInput(sessionID, call.ID, params.Name, params.Input, p.shells.Agent(sessionID)); response != nil || err != nil {
+			return *response, err
+		}
+		if err := j.WriteInput(params.Input); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("could not write input: %v", err)), nil
+		}
+		done = append(done, fmt.Sprintf("wrote %d bytes of input", len(params.Input)))
+	}
+	if params.CloseInput {
+		if err := j.CloseInput(); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("could not close input: %v", err)), nil
+		}
+		done = append(done, "closed its input")
+	}
+	if params.Signal != "" {
+		sig, err := shell.ParseSignal(params.Signal)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		if err := j.Signal(sig); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("could not send %s: %v", params.Signal, err)), nil
+		}
+		done = append(done, "sent "+strings.ToUpper(params.Signal))
+	}
+	return NewTextResponse(fmt.Sprintf("Process %q: %s.", params.Name, strings.Join(done, ", "))), nil
+}
+
+type processListTool struct {
+	processTool
+}
+
+func NewProcessListTool(shells *shell.Manager) BaseTool {
+	return &processListTool{processTool{shells: shells}}
+}
+
+func (p *processListTool) Name() string {
+	return ProcessListToolName
+}
+
+func (p *processListTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ProcessListToolName,
+		Description: processListDescription,
+		Parameters:  map[string]any{},
+		Required:    []string{},
+	}
+}
+
+func (p *processListTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	sessionID, _ := GetContextValues(ctx)
+	var lines []string
+	for _, info := range p.shells.Agent(sessionID).Jobs() {
+		if info.Name == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s", info.Name, strings.ToLower(info.Status()), info.Command))
+	}
+	if len(lines) == 0 {
+		return NewTextResponse("No processes."), nil
+	}
+	return NewTextResponse(strings.Join(lines, "\n")), nil
+}
+
+type processKillTool struct {
+	processTool
+}
+
+func NewProcessKillTool(shells *shell.Manager) BaseTool {
+	return &processKillTool{processTool{shells: shells}}
+}
+
+func (p *processKillTool) Name() string {
+	return ProcessKillToolName
+}
+
+func (p *processKillTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ProcessKillToolName,
+		Description: processKillDescription,
+		Parameters: map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "The name of the process",
+			},
+		},
+		Required: []string{"name"},
+	}
+}
+
+func (p *processKillTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ProcessKillParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+	j, response := p.job(sessionID, params.Name)
+	if response != nil {
+		return *response, nil
+	}
+	j.Kill()
+	output, _, dropped := collectOutput(ctx, j, nil, 5*time.Second)
+	return NewTextResponse(describeProcess(ctx, j.Info(), output, dropped, "")), nil
+}
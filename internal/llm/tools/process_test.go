@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/lacymorrow/lash/internal/permission"
+	"github.com/lacymorrow/lash/internal/shell"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPermissions records permission requests and answers them with
+// grant. Its other methods are not used by the tools.
+type recordingPermissions struct {
+	permission.Service
+	grant    bool
+	requests []permission.CreatePermissionRequest
+}
+
+func (p *recordingPermissions) Request(opts permission.CreatePermissionRequest) bool {
+	p.requests = append(p.requests, opts)
+	return p.grant
+}
+
+// runProcessTool runs tool with params marshalled as its input.
+func runProcessTool(t *testing.T, ctx context.Context, tool BaseTool, params any) (ToolResponse, error) {
+	t.Helper()
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	return tool.Run(ctx, ToolCall{ID: "call", Name: tool.Name(), Input: string(input)})
+}
+
+func TestProcessTools(t *testing.T) {
+	dir := t.TempDir()
+	shells := shell.NewManager(dir, nil)
+	permissions := &recordingPermissions{grant: true}
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "process-session")
+	ctx = context.WithValue(ctx, MessageIDContextKey, "process-message")
+
+	start := NewProcessStartTool(permissions, shells, dir)
+	output := NewProcessOutputTool(shells)
+	send := NewProcessSendTool(permissions, shells, dir)
+	kill := NewProcessKillTool(shells)
+	t.Cleanup(func() {
+		if j, ok := shells.Agent("process-session").NamedJob("echo"); ok {
+			j.Kill()
+			<-j.Done()
+		}
+	})
+
+	resp, err := runProcessTool(t, ctx, start, ProcessStartParams{Name: "echo", Command: "echo ready; cat", WaitFor: "rea.y"})
+	require.NoError(t, err)
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, `Process "echo" is running`)
+	require.Contains(t, resp.Content, `The output matched "rea.y".`)
+	require.Contains(t, resp.Content, "<output>\nready\n</output>")
+	require.Len(t, permissions.requests, 1)
+	require.Equal(t, "execute", permissions.requests[0].Action)
+
+	resp, err = runProcessTool(t, ctx, start, ProcessStartParams{Name: "echo", Command: "true"})
+	require.NoError(t, err)
+	require.True(t, resp.IsError, "names must be unique")
+
+	resp, err = runProcessTool(t, ctx, output, ProcessOutputParams{Name: "echo"})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, "No new output.")
+
+	t.Run("send denied", func(t *testing.T) {
+		permissions.grant = false
+		t.Cleanup(func() { permissions.grant = true })
+		_, err := runProcessTool(t, ctx, send, ProcessSendParams{Name: "echo", Input: "rm -rf /\n"})
+		require.ErrorIs(t, err, permission.ErrorPermissionDenied)
+		last := permissions.requests[len(permissions.requests)-1]
+		require.Equal(t, "send", last.Action)
+		require.Equal(t, "rm -rf /\n", last.Params.(BashPermissionsParams).Command)
+
+		resp, err := runProcessTool(t, ctx, output, ProcessOutputParams{Name: "echo", WaitFor: "rm", Timeout: 300})
+		require.NoError(t, err)
+		require.Contains(t, resp.Content, "No new output.", "denied input must not be written")
+	})
+
+	resp, err = runProcessTool(t, ctx, send, ProcessSendParams{Name: "echo", Input: "hello\n"})
+	require.NoError(t, err)
+	require.False(t, resp.IsError, resp.Content)
+
+	resp, err = runProcessTool(t, ctx, output, ProcessOutputParams{Name: "echo", WaitFor: "hello"})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, `The output matched "hello".`)
+	require.Contains(t, resp.Content, "<output>\nhello\n</output>")
+
+	resp, err = runProcessTool(t, ctx, kill, ProcessKillParams{Name: "echo"})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, `Process "echo" has exited`)
+
+	resp, err = runProcessTool(t, ctx, send, ProcessSendParams{Name: "echo", Input: "late\n"})
+	require.NoError(t, err)
+	require.True(t, resp.IsError, "input cannot be sent to an exited process")
+
+	resp, err = runProcessTool(t, ctx, output, ProcessOutputParams{Name: "missing"})
+	require.NoError(t, err)
+	require.True(t, resp.IsError)
+}
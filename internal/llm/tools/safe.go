@@ -1,6 +1,12 @@
 package tools
 
-import "runtime"
+import (
+	"runtime"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
 
 var safeCommands = []string{
 	// Bash builtins and core utils
@@ -14,21 +20,17 @@ var safeCommands = []string{
 	"groups",
 	"hostname",
 	"id",
-	"kill",
-	"killall",
 	"ls",
 	"nice",
 	"nohup",
 	"printenv",
 	"ps",
 	"pwd",
-	"set",
 	"time",
 	"timeout",
 	"top",
 	"type",
 	"uname",
-	"unset",
 	"uptime",
 	"whatis",
 	"whereis",
@@ -54,17 +56,10 @@ var safeCommands = []string{
 	"git tag",
 
 	// Go
-	"go build",
-	"go clean",
 	"go doc",
 	"go env",
-	"go fmt",
 	"go help",
-	"go install",
 	"go list",
-	"go mod",
-	"go run",
-	"go test",
 	"go version",
 	"go vet",
 }
@@ -83,3 +78,268 @@ func init() {
 		)
 	}
 }
+
+// wrapperCommands run the command that follows their options, so they are
+// only as safe as that command.
+var wrapperCommands = map[string]func(args []string) ([]string, bool){
+	"env":     envWrapped,
+	"nice":    niceWrapped,
+	"nohup":   func(args []string) ([]string, bool) { return args, true },
+	"time":    timeWrapped,
+	"timeout": timeoutWrapped,
+}
+
+// unsafeFlags are flags of otherwise safe commands that write to files,
+// read files outside of the repository or run other programs.
+var unsafeFlags = map[string][]string{
+	"date": {"-s", "--set"},
+	"git":  {"--output", "--no-index", "--ext-diff", "-O", "--open-files-in-pager"},
+	"go":   {"-w", "-u", "-exec", "-toolexec", "-vettool", "-export"},
+}
+
+// subcommandArgs check the arguments of safe commands that list things with
+// some arguments and change them with others.
+var subcommandArgs = map[string]func(args []string) bool{
+	"git branch": func(args []string) bool {
+		return isListing(args, []string{"-a", "--all", "-r", "--remotes", "-v", "-vv", "--verbose", "--show-current",
+			"-i", "--ignore-case", "--color", "--no-color", "--column", "--no-column", "--abbrev", "--no-abbrev", "-q", "--quiet"})
+	},
+	"git tag": func(args []string) bool {
+		return isListing(args, []string{"-n", "-i", "--ignore-case", "--color", "--no-color", "--column", "--no-column"})
+	},
+	"git remote": func(args []string) bool {
+		for len(args) > 0 && (args[0] == "-v" || args[0] == "--verbose") {
+			args = args[1:]
+		}
+		return len(args) == 0 || args[0] == "show" || args[0] == "get-url"
+	},
+}
+
+// listingValueFlags are the options of git branch and git tag that filter or
+// format the list and take a value.
+var listingValueFlags = []string{"--contains", "--no-contains", "--merged", "--no-merged", "--points-at", "--sort", "--format"}
+
+// isListing reports whether args make git branch or git tag list refs: they
+// may only have the given flags and those of listingValueFlags, and names
+// only with -l or --list, where they are patterns.
+func isListing(args []string, flags []string) bool {
+	listing, named := false, false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(arg, "=")
+		if strings.HasPrefix(name, "-n") && isNumber(name[2:]) {
+			// git tag -n<num> prints that many lines of each annotation.
+			name = "-n"
+		}
+		switch {
+		case arg == "-l" || arg == "--list":
+			listing = true
+		case !strings.HasPrefix(arg, "-"):
+			named = true
+		case slices.Contains(listingValueFlags, name):
+			if !hasValue {
+				i++
+			}
+		case slices.Contains(flags, name):
+		default:
+			return false
+		}
+	}
+	return listing || !named
+}
+
+// hasFlag reports whether args have flag, alone, with a value after "=" or,
+// for short flags, with a value or other flags right after it. Long flags
+// of Go programs may start with one dash or two.
+func hasFlag(args []string, flag string) bool {
+	short := len(flag) == 2 && flag[0] == '-' && flag[1] != '-'
+	for _, arg := range args {
+		if short {
+			if strings.HasPrefix(arg, flag) {
+				return true
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if name == flag || !strings.HasPrefix(flag, "--") && name == "-"+flag {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadOnlyCommand reports whether command can run without asking for
+// permission: it must parse, and every command in every pipeline, list,
+// subshell and substitution must be in safeCommands, without redirections
+// to files, assignments or definitions. Commands named in shadowed, like
+// aliases and functions of the shell, are never safe.
+func isReadOnlyCommand(command string, shadowed []string) bool {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return false
+	}
+
+	safe := true
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if !safe {
+			return false
+		}
+		switch node := node.(type) {
+		case *syntax.CallExpr:
+			safe = len(node.Assigns) == 0 && isSafeCall(node.Args, shadowed)
+		case *syntax.Redirect:
+			safe = isSafeRedirect(node)
+		case *syntax.FuncDecl, *syntax.DeclClause, *syntax.LetClause,
+			*syntax.CoprocClause, *syntax.TestDecl:
+			// These change the state of the shell, so later commands that
+			// look safe might not be.
+			safe = false
+		}
+		return safe
+	})
+	return safe
+}
+
+// isSafeCall reports whether the command made of args is in safeCommands.
+// The words that name it must be literals, as anything else could expand to
+// a different command.
+func isSafeCall(args []*syntax.Word, shadowed []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = arg.Lit()
+	}
+	return isSafeArgs(words, shadowed)
+}
+
+// isSafeArgs is isSafeCall on words, where non-literal words are empty.
+func isSafeArgs(words []string, shadowed []string) bool {
+	if len(words) == 0 || words[0] == "" || slices.Contains(shadowed, words[0]) {
+		return false
+	}
+	name := strings.ToLower(words[0])
+
+	if wrapped, ok := wrapperCommands[name]; ok {
+		rest, ok := wrapped(words[1:])
+		if !ok {
+			return false
+		}
+		return len(rest) == 0 || isSafeArgs(rest, shadowed)
+	}
+
+	for _, flag := range unsafeFlags[name] {
+		if hasFlag(words[1:], flag) {
+			return false
+		}
+	}
+
+	for _, safe := range safeCommands {
+		fields := strings.Fields(safe)
+		if len(fields) > len(words) {
+			continue
+		}
+		matched := true
+		for i, field := range fields {
+			if strings.ToLower(words[i]) != field {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			check, ok := subcommandArgs[safe]
+			return !ok || check(words[len(fields):])
+		}
+	}
+	return false
+}
+
+// isSafeRedirect reports whether r leaves files alone: it reads, is a here
+// document, duplicates a file descriptor or writes to /dev/null.
+func isSafeRedirect(r *syntax.Redirect) bool {
+	switch r.Op {
+	case syntax.RdrIn, syntax.Hdoc, syntax.DashHdoc, syntax.WordHdoc:
+		return true
+	case syntax.DplIn, syntax.DplOut:
+		// ">&word" redirects to a file unless word is a descriptor.
+		target := r.Word.Lit()
+		return target == "-" || isNumber(target)
+	default:
+		return r.Word.Lit() == "/dev/null"
+	}
+}
+
+// envWrapped skips the assignments env makes. Options are not skipped, as
+// some of them split strings into commands.
+func envWrapped(args []string) ([]string, bool) {
+	for len(args) > 0 && strings.Contains(args[0], "=") {
+		args = args[1:]
+	}
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		return nil, false
+	}
+	return args, true
+}
+
+// niceWrapped skips the niceness nice is given.
+func niceWrapped(args []string) ([]string, bool) {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return args, true
+	}
+	switch {
+	case args[0] == "-n" && len(args) >= 2:
+		return args[2:], true
+	case isNumber(strings.TrimPrefix(args[0], "-n")), isNumber(args[0][1:]),
+		strings.HasPrefix(args[0], "--adjustment="):
+		return args[1:], true
+	}
+	return nil, false
+}
+
+// timeWrapped skips -p, the only option of time that does not write to a
+// file.
+func timeWrapped(args []string) ([]string, bool) {
+	if len(args) > 0 && args[0] == "-p" {
+		args = args[1:]
+	}
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		return nil, false
+	}
+	return args, true
+}
+
+// timeoutWrapped skips the options and the duration timeout is given.
+func timeoutWrapped(args []string) ([]string, bool) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch {
+		case args[0] == "-s" || args[0] == "-k" || args[0] == "--signal" || args[0] == "--kill-after":
+			if len(args) < 2 {
+				return nil, false
+			}
+			args = args[2:]
+		case args[0] == "--foreground" || args[0] == "--preserve-status" || args[0] == "-v" || args[0] == "--verbose" ||
+			strings.HasPrefix(args[0], "--signal=") || strings.HasPrefix(args[0], "--kill-after="):
+			args = args[1:]
+		default:
+			return nil, false
+		}
+	}
+	if len(args) == 0 {
+		return nil, false
+	}
+	return args[1:], true
+}
+
+// isNumber reports whether s is made of digits only.
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lacymorrow/lash/internal/config"
+)
+
+const (
+	ReadOutputToolName = "read_output"
+	// DefaultOutputMatchLimit is how many matching lines read_output returns
+	// by default.
+	DefaultOutputMatchLimit = 200
+	readOutputDescription   = `Reads the full output of a tool call that was too long to return whole. Such outputs are saved with an ID, and the tool result shows their start and end with a note naming the ID and the omitted lines.
+
+HOW TO USE:
+- Provide the ID of the saved output
+- Optionally specify an offset (0-based line number) and a limit (defaults to 2000 lines) to page through it, like the view tool
+- Optionally specify a pattern, a regular expression, to get only the matching lines with their line numbers instead; limit then caps the number of matches (defaults to 200)
+
+TIPS:
+- Look for errors in long build or test logs with a pattern like "FAIL|error", then read the lines around a match with offset and limit`
+)
+
+type ReadOutputParams struct {
+	ID      string `json:"id"`
+	Offset  int    `json:"offset"`
+	Limit   int    `json:"limit"`
+	Pattern string `json:"pattern"`
+}
+
+// outputsRoot returns the directory holding the saved outputs of all
+// sessions, or an empty string if the config is not loaded. Tests replace it.
+var outputsRoot = func() string {
+	if cfg := config.Get(); cfg != nil {
+		return filepath.Join(cfg.Options.DataDirectory, "outputs")
+	}
+	return ""
+}
+
+// outputsDir returns where the oversized outputs of a session are saved.
+func outputsDir(sessionID string) string {
+	return filepath.Join(outputsRoot(), sessionID)
+}
+
+// RemoveSessionOutputs deletes the saved outputs of a session.
+func RemoveSessionOutputs(sessionID string) error {
+	if sessionID == "" || outputsRoot() == "" {
+		return nil
+	}
+	if err := os.RemoveAll(outputsDir(sessionID)); err != nil {
+		return fmt.Errorf("failed to remove saved outputs: %w", err)
+	}
+	return nil
+}
+
+func outputPath(sessionID, id string) string {
+	return filepath.Join(outputsDir(sessionID), id+".txt")
+}
+
+// saveOutput saves content for read_output and returns its ID.
+func saveOutput(sessionID, content string) (string, error) {
+	if sessionID == "" {
+		return "", fmt.Errorf("session ID is required to save output")
+	}
+	if outputsRoot() == "" {
+		return "", fmt.Errorf("config not loaded")
+	}
+	// Outputs can hold secrets, like the environment printed by a command.
+	if err := os.MkdirAll(outputsDir(sessionID), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create outputs directory: %w", err)
+	}
+	id := uuid.NewString()
+	if err := os.WriteFile(outputPath(sessionID, id), []byte(content), 0o600); err != nil {
+		return "", fmt.Errorf("failed to save output: %w", err)
+	}
+	return id, nil
+}
+
+// SpillOutput returns content as is if it is at most MaxOutputLength long.
+// Longer content is saved for read_output, and its start and end are
+// returned with a note on how to read the rest.
+func SpillOutput(ctx context.Context, content string) string {
+	return spillOutput(ctx, content, MaxOutputLength/2, MaxOutputLength/2)
+}
+
+// spillOutput keeps about head characters of the start of content and tail
+// of its end, cut at line boundaries, and saves all of it if anything is
+// left out. It falls back to truncating content if saving fails.
+func spillOutput(ctx context.Context, content string, head, tail int) string {
+	if len(content) <= head+tail {
+		return content
+	}
+	sessionID, _ := GetContextValues(ctx)
+	id, err := saveOutput(sessionID, content)
+	if err != nil {
+		return truncateOutput(content)
+	}
+
+	start := content[:head]
+	if i := strings.LastIndexByte(start, '\n'); i >= head/2 {
+		start = start[:i+1]
+	}
+	end := content[len(content)-tail:]
+	if i := strings.IndexByte(end, '\n'); i >= 0 && i < tail/2 {
+		end = end[i+1:]
+	}
+
+	first := strings.Count(start, "\n") + 1
+	omitted := content[:len(content)-len(end)]
+	last := strings.Count(omitted, "\n")
+	if !strings.HasSuffix(omitted, "\n") {
+		last++
+	}
+	note := fmt.Sprintf("... [lines %d-%d of %d omitted; the full output is saved as %s, read it with the %s tool] ...",
+		first, last, countLines(strings.TrimSuffix(content, "\n")), id, ReadOutputToolName)
+	if end == "" {
+		return fmt.Sprintf("%s\n\n%s", start, note)
+	}
+	return fmt.Sprintf("%s\n\n%s\n\n%s", start, note, end)
+}
+
+type readOutputTool struct{}
+
+func NewReadOutputTool() BaseTool {
+	return &readOutputTool{}
+}
+
+func (r *readOutputTool) Name() string {
+	return ReadOutputToolName
+}
+
+func (r *readOutputTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ReadOutputToolName,
+		Description: readOutputDescription,
+		Parameters: map[string]any{
+			"id": map[string]any{
+				"type":        "string",
+				"description": "The ID of the saved output",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "The line number to start reading from (0-based)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "The number of lines to read (defaults to 2000), or of matches to return with pattern (defaults to 200)",
+			},
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Optional regular expression to return only the matching lines",
+			},
+		},
+		Required: []string{"id"},
+	}
+}
+
+func (r *readOutputTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ReadOutputParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if _, err := uuid.Parse(params.ID); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("invalid output ID %q", params.ID)), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+	path := outputPath(sessionID, params.ID)
+	if _, err := os.Stat(path); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("no saved output %s in this session", params.ID)), nil
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+
+	if params.Pattern != "" {
+		re, err := regexp.Compile(params.Pattern)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("invalid pattern: %v", err)), nil
+		}
+		if params.Limit <= 0 {
+			params.Limit = DefaultOutputMatchLimit
+		}
+		return grepOutput(path, re, params.Offset, params.Limit)
+	}
+
+	if params.Limit <= 0 {
+		params.Limit = DefaultReadLimit
+	}
+	content, lineCount, err := readTextFile(path, params.Offset, params.Limit)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading output: %w", err)
+	}
+	output := "<output>\n" + addLineNumbers(content, params.Offset+1)
+	if read := params.Offset + len(strings.Split(content, "\n")); lineCount > read {
+		output += fmt.Sprintf("\n\n(Output has %d lines. Use 'offset' parameter to read beyond line %d)", lineCount, read)
+	}
+	output += "\n</output>"
+	return NewTextResponse(output), nil
+}
+
+// grepOutput returns the lines of the saved output at path from offset on
+// that match re, with their line numbers.
+func grepOutput(path string, re *regexp.Regexp, offset, limit int) (ToolResponse, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading output: %w", err)
+	}
+	defer f.Close()
+
+	var matches []string
+	more := false
+	scanner := NewLineScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if lineNum <= offset {
+			continue
+		}
+		line := scanner.Text()
+		if !re.MatchString(line) {
+			continue
+		}
+		if len(matches) == limit {
+			more = true
+			break
+		}
+		if len(line) > MaxLineLength {
+			line = line[:MaxLineLength] + "..."
+		}
+		matches = append(matches, fmt.Sprintf("%6d|%s", lineNum, line))
+	}
+	if err := scanner.Err(); err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading output: %w", err)
+	}
+
+	if len(matches) == 0 {
+		return NewTextResponse("No matching lines"), nil
+	}
+	output := strings.Join(matches, "\n")
+	if more {
+		output += "\n\n(More lines match. Use 'offset' parameter to search beyond the last match)"
+	}
+	return NewTextResponse(output), nil
+}
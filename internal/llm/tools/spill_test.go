@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// useOutputsRoot makes outputs be saved under dir for the rest of the test.
+func useOutputsRoot(t *testing.T, dir string) {
+	t.Helper()
+	previous := outputsRoot
+	outputsRoot = func() string { return dir }
+	t.Cleanup(func() { outputsRoot = previous })
+}
+
+// numberedLines returns n lines "line01", "line02", ... each ending with a
+// newline.
+func numberedLines(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "line%02d\n", i)
+	}
+	return sb.String()
+}
+
+var savedAsPattern = regexp.MustCompile(`saved as (\S+), read it`)
+
+func TestSpillOutput(t *testing.T) {
+	useOutputsRoot(t, t.TempDir())
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "spill-session")
+	note := func(first, last, total int) string {
+		return fmt.Sprintf("... [lines %d-%d of %d omitted; the full output is saved as ", first, last, total)
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		head, tail int
+		wantStart  string
+		wantNote   string
+		wantEnd    string
+	}{
+		{
+			name:      "cut at line boundaries",
+			content:   numberedLines(10),
+			head:      10,
+			tail:      10,
+			wantStart: "line01\n",
+			wantNote:  note(2, 9, 10),
+			wantEnd:   "line10\n",
+		},
+		{
+			name:      "no trailing newline",
+			content:   strings.TrimSuffix(numberedLines(10), "\n"),
+			head:      10,
+			tail:      10,
+			wantStart: "line01\n",
+			wantNote:  note(2, 9, 10),
+			wantEnd:   "line10",
+		},
+		{
+			name:      "head only",
+			content:   numberedLines(10),
+			head:      10,
+			wantStart: "line01\n",
+			wantNote:  note(2, 10, 10),
+		},
+		{
+			name:      "head ending exactly at a newline",
+			content:   numberedLines(10),
+			head:      14,
+			tail:      7,
+			wantStart: "line01\nline02\n",
+			wantNote:  note(3, 9, 10),
+			wantEnd:   "line10\n",
+		},
+		{
+			name:      "newlines too far from the cut",
+			content:   "a\n" + strings.Repeat("x", 30) + "\nb\n",
+			head:      10,
+			tail:      10,
+			wantStart: "a\nxxxxxxxx",
+			wantNote:  note(2, 2, 3),
+			wantEnd:   "xxxxxxx\nb\n",
+		},
+		{
+			name:      "single line",
+			content:   strings.Repeat("x", 30),
+			head:      10,
+			tail:      10,
+			wantStart: strings.Repeat("x", 10),
+			wantNote:  note(1, 1, 1),
+			wantEnd:   strings.Repeat("x", 10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := spillOutput(ctx, tt.content, tt.head, tt.tail)
+
+			start, rest, ok := strings.Cut(got, "\n\n... [")
+			require.True(t, ok, "no note in %q", got)
+			require.Equal(t, tt.wantStart, start)
+			require.True(t, strings.HasPrefix("... ["+rest, tt.wantNote), "unexpected note in %q", got)
+			if tt.wantEnd == "" {
+				require.True(t, strings.HasSuffix(got, "] ..."), "unexpected end in %q", got)
+			} else {
+				require.True(t, strings.HasSuffix(got, "] ...\n\n"+tt.wantEnd), "unexpected end in %q", got)
+			}
+
+			m := savedAsPattern.FindStringSubmatch(got)
+			require.NotNil(t, m)
+			saved, err := os.ReadFile(outputPath("spill-session", m[1]))
+			require.NoError(t, err)
+			require.Equal(t, tt.content, string(saved))
+		})
+	}
+}
+
+func TestSpillOutputFits(t *testing.T) {
+	root := t.TempDir()
+	useOutputsRoot(t, root)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "spill-session")
+
+	content := numberedLines(2)
+	require.Equal(t, content, spillOutput(ctx, content, len(content)/2, len(content)-len(content)/2))
+	_, err := os.Stat(outputsDir("spill-session"))
+	require.True(t, os.IsNotExist(err), "nothing should be saved")
+}
+
+func TestSpillOutputSaveFails(t *testing.T) {
+	content := strings.Repeat("line\n", MaxOutputLength/4)
+
+	t.Run("no session", func(t *testing.T) {
+		useOutputsRoot(t, t.TempDir())
+		require.Equal(t, truncateOutput(content), SpillOutput(context.Background(), content))
+	})
+	t.Run("no config", func(t *testing.T) {
+		useOutputsRoot(t, "")
+		ctx := context.WithValue(context.Background(), SessionIDContextKey, "spill-session")
+		require.Equal(t, truncateOutput(content), SpillOutput(ctx, content))
+	})
+	t.Run("directory cannot be created", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o600))
+		useOutputsRoot(t, file)
+		ctx := context.WithValue(context.Background(), SessionIDContextKey, "spill-session")
+		require.Equal(t, truncateOutput(content), SpillOutput(ctx, content))
+	})
+}
+
+func TestSavedOutputIsPrivate(t *testing.T) {
+	useOutputsRoot(t, t.TempDir())
+
+	id, err := saveOutput("spill-session", "SECRET=1\n")
+	require.NoError(t, err)
+	info, err := os.Stat(outputPath("spill-session", id))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	require.NoError(t, RemoveSessionOutputs("spill-session"))
+	_, err = os.Stat(outputsDir("spill-session"))
+	require.True(t, os.IsNotExist(err), "outputs should be removed with the session")
+}
+
+func TestReadOutputTool(t *testing.T) {
+	useOutputsRoot(t, t.TempDir())
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "read-session")
+
+	lines := strings.Split(strings.TrimSuffix(numberedLines(10), "\n"), "\n")
+	for _, i := range []int{1, 4, 7, 8} {
+		lines[i] += " ERROR"
+	}
+	id, err := saveOutput("read-session", strings.Join(lines, "\n")+"\n")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		params  ReadOutputParams
+		want    string
+		isError bool
+	}{
+		{
+			name:   "page",
+			params: ReadOutputParams{ID: id, Offset: 2, Limit: 3},
+			want:   "<output>\n     3|line03\n     4|line04\n     5|line05 ERROR\n\n(Output has 10 lines. Use 'offset' parameter to read beyond line 5)\n</output>",
+		},
+		{
+			name:   "last page",
+			params: ReadOutputParams{ID: id, Offset: 8, Limit: 5},
+			want:   "<output>\n     9|line09 ERROR\n    10|line10\n</output>",
+		},
+		{
+			name:   "page ending at the last line",
+			params: ReadOutputParams{ID: id, Offset: 7, Limit: 3},
+			want:   "<output>\n     8|line08 ERROR\n     9|line09 ERROR\n    10|line10\n</output>",
+		},
+		{
+			name:   "pattern",
+			params: ReadOutputParams{ID: id, Pattern: "ERR"},
+			want:   "     2|line02 ERROR\n     5|line05 ERROR\n     8|line08 ERROR\n     9|line09 ERROR",
+		},
+		{
+			name:   "pattern with limit",
+			params: ReadOutputParams{ID: id, Pattern: "ERR", Limit: 2},
+			want:   "     2|line02 ERROR\n     5|line05 ERROR\n\n(More lines match. Use 'offset' parameter to search beyond the last match)",
+		},
+		{
+			name:   "pattern with offset after a match",
+			params: ReadOutputParams{ID: id, Pattern: "ERR", Offset: 5, Limit: 2},
+			want:   "     8|line08 ERROR\n     9|line09 ERROR",
+		},
+		{
+			name:   "pattern with offset on a match",
+			params: ReadOutputParams{ID: id, Pattern: "ERR", Offset: 4, Limit: 1},
+			want:   "     5|line05 ERROR\n\n(More lines match. Use 'offset' parameter to search beyond the last match)",
+		},
+		{
+			name:   "pattern past the last match",
+			params: ReadOutputParams{ID: id, Pattern: "ERR", Offset: 9},
+			want:   "No matching lines",
+		},
+		{
+			name:    "invalid pattern",
+			params:  ReadOutputParams{ID: id, Pattern: "("},
+			isError: true,
+		},
+		{
+			name:    "invalid ID",
+			params:  ReadOutputParams{ID: "../secrets"},
+			isError: true,
+		},
+		{
+			name:    "unknown ID",
+			params:  ReadOutputParams{ID: "00000000-0000-0000-0000-000000000000"},
+			isError: true,
+		},
+	}
+	tool := NewReadOutputTool()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := json.Marshal(tt.params)
+			require.NoError(t, err)
+			resp, err := tool.Run(ctx, ToolCall{Name: ReadOutputToolName, Input: string(input)})
+			require.NoError(t, err)
+			require.Equal(t, tt.isError, resp.IsError, resp.Content)
+			if !tt.isError {
+				require.Equal(t, tt.want, resp.Content)
+			}
+		})
+	}
+
+	t.Run("other session", func(t *testing.T) {
+		input, err := json.Marshal(ReadOutputParams{ID: id})
+		require.NoError(t, err)
+		other := context.WithValue(context.Background(), SessionIDContextKey, "other-session")
+		resp, err := tool.Run(other, ToolCall{Name: ReadOutputToolName, Input: string(input)})
+		require.NoError(t, err)
+		require.True(t, resp.IsError)
+	})
+}
@@ -177,6 +177,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        fsext.PathOrPrefix(filePath, w.workingDir),
+			FilePath:    filePath,
 			ToolCallID:  call.ID,
 			ToolName:    WriteToolName,
 			Action:      "write",
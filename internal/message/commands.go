@@ -0,0 +1,54 @@
+package message
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// codeFencePattern matches fenced code blocks in markdown, capturing the
+// language and the body.
+var codeFencePattern = regexp.MustCompile("(?ms)^[ \t]*```[ \t]*([A-Za-z0-9_+-]*)[^\n]*\n(.*?)\n?[ \t]*```[ \t]*$")
+
+// shellLanguages are the code block languages treated as shell commands.
+var shellLanguages = []string{"sh", "bash", "shell", "zsh", "console", "shell-session"}
+
+// ShellCommands returns the commands in the shell code blocks of a markdown
+// text, one per block, in order. Comment lines are dropped, and for console
+// transcripts only the lines after a "$ " prompt are kept.
+func ShellCommands(markdown string) []string {
+	return shellCommands(markdown, false)
+}
+
+// ShellCommandsWithUntagged is ShellCommands that also takes code blocks
+// without a language as shell commands. It suits replies that were asked
+// for a command, where an untagged block can only be one.
+func ShellCommandsWithUntagged(markdown string) []string {
+	return shellCommands(markdown, true)
+}
+
+func shellCommands(markdown string, untagged bool) []string {
+	var commands []string
+	for _, m := range codeFencePattern.FindAllStringSubmatch(markdown, -1) {
+		lang, body := strings.ToLower(m[1]), m[2]
+		if !slices.Contains(shellLanguages, lang) && (lang != "" || !untagged) {
+			continue
+		}
+		transcript := lang == "console" || lang == "shell-session"
+		var lines []string
+		for line := range strings.SplitSeq(body, "\n") {
+			line = strings.TrimSpace(line)
+			cmd, prompted := strings.CutPrefix(line, "$ ")
+			if transcript && !prompted {
+				continue
+			}
+			if cmd != "" && !strings.HasPrefix(cmd, "#") {
+				lines = append(lines, cmd)
+			}
+		}
+		if len(lines) > 0 {
+			commands = append(commands, strings.Join(lines, "\n"))
+		}
+	}
+	return commands
+}
@@ -2,7 +2,9 @@ package message
 
 import (
 	"encoding/base64"
+	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
@@ -104,6 +106,104 @@ type ToolResult struct {
 
 func (ToolResult) isPart() {}
 
+// ShellExecution is a command the user ran in Shell mode together with its
+// result. Times are Unix milliseconds; a zero FinishedAt means the command is
+// still running. Interactive commands run attached to the terminal, so their
+// output is not captured.
+type ShellExecution struct {
+	Command     string `json:"command"`
+	Cwd         string `json:"cwd"`
+	Stdout      string `json:"stdout"`
+	Stderr      string `json:"stderr"`
+	ExitCode    int    `json:"exit_code"`
+	Canceled    bool   `json:"canceled,omitempty"`
+	Interactive bool   `json:"interactive,omitempty"`
+	StartedAt   int64  `json:"started_at"`
+	FinishedAt  int64  `json:"finished_at,omitempty"`
+}
+
+func (se ShellExecution) IsFinished() bool {
+	return se.FinishedAt != 0
+}
+
+func (se ShellExecution) Duration() time.Duration {
+	end := se.FinishedAt
+	if end == 0 {
+		end = time.Now().UnixMilli()
+	}
+	return time.Duration(end-se.StartedAt) * time.Millisecond
+}
+
+const (
+	// shellContextLines is how many trailing lines of each output stream of
+	// a shell execution are given to a model.
+	shellContextLines = 200
+	// shellContextChars caps each stream regardless of line count.
+	shellContextChars = 16000
+)
+
+// String renders the execution as context for a model, labelled so that it
+// is not mistaken for something the model itself produced. Providers send
+// it as text of the user message carrying the execution, ahead of any text
+// the user typed, so commands run in Shell mode reach the model as context,
+// not as prompts.
+// Only the end of long output is kept.
+func (se ShellExecution) String() string {
+	var sb strings.Builder
+	sb.WriteString("<shell_execution>\n")
+	sb.WriteString("The user ran this command directly in their shell. It was not run by you, and the output below is not your reply.\n")
+	fmt.Fprintf(&sb, "<cwd>%s</cwd>\n", se.Cwd)
+	fmt.Fprintf(&sb, "<command>%s</command>\n", se.Command)
+	switch {
+	case se.Canceled:
+		sb.WriteString("<status>canceled by the user</status>\n")
+	case !se.IsFinished():
+		sb.WriteString("<status>still running</status>\n")
+	default:
+		fmt.Fprintf(&sb, "<exit_code>%d</exit_code>\n", se.ExitCode)
+	}
+	fmt.Fprintf(&sb, "<duration>%s</duration>\n", se.Duration().Round(time.Millisecond))
+	if se.Interactive {
+		sb.WriteString("<note>The command ran interactively in the user's terminal, so its output was not captured.</note>\n")
+	}
+	if se.Stdout != "" {
+		fmt.Fprintf(&sb, "<stdout>\n%s\n</stdout>\n", TailOutput(se.Stdout, shellContextLines, shellContextChars))
+	}
+	if se.Stderr != "" {
+		fmt.Fprintf(&sb, "<stderr>\n%s\n</stderr>\n", TailOutput(se.Stderr, shellContextLines, shellContextChars))
+	}
+	sb.WriteString("</shell_execution>")
+	return sb.String()
+}
+
+func (ShellExecution) isPart() {}
+
+// TailOutput keeps the last maxLines lines of a command's output, where
+// errors usually are, and at most maxChars of them. It notes how many
+// earlier lines were left out.
+func TailOutput(s string, maxLines, maxChars int) string {
+	s = strings.TrimRight(s, "\n")
+	lines := strings.Split(s, "\n")
+	var omitted int
+	if len(lines) > maxLines {
+		omitted = len(lines) - maxLines
+		lines = lines[omitted:]
+	}
+	s = strings.Join(lines, "\n")
+	if len(s) > maxChars {
+		cut := len(s) - maxChars
+		if i := strings.IndexByte(s[cut:], '\n'); i >= 0 {
+			cut += i + 1
+		}
+		omitted += strings.Count(s[:cut], "\n")
+		s = s[cut:]
+	}
+	if omitted > 0 {
+		s = fmt.Sprintf("... [%d earlier lines omitted] ...\n%s", omitted, s)
+	}
+	return s
+}
+
 type Finish struct {
 	Reason  FinishReason `json:"reason"`
 	Time    int64        `json:"time"`
@@ -182,6 +282,28 @@ func (m *Message) ToolResults() []ToolResult {
 	return toolResults
 }
 
+func (m *Message) ShellExecutions() []ShellExecution {
+	executions := make([]ShellExecution, 0)
+	for _, part := range m.Parts {
+		if c, ok := part.(ShellExecution); ok {
+			executions = append(executions, c)
+		}
+	}
+	return executions
+}
+
+// SetShellExecution replaces the message's shell execution, adding it if the
+// message has none yet.
+func (m *Message) SetShellExecution(se ShellExecution) {
+	for i, part := range m.Parts {
+		if _, ok := part.(ShellExecution); ok {
+			m.Parts[i] = se
+			return
+		}
+	}
+	m.Parts = append(m.Parts, se)
+}
+
 func (m *Message) IsFinished() bool {
 	for _, part := range m.Parts {
 		if _, ok := part.(Finish); ok {
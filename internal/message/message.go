@@ -22,6 +22,9 @@ type CreateMessageParams struct {
 type Service interface {
 	pubsub.Suscriber[Message]
 	Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error)
+	// Copy adds a copy of message to the session sessionID, keeping its
+	// parts and times.
+	Copy(ctx context.Context, sessionID string, message Message) (Message, error)
 	Update(ctx context.Context, message Message) error
 	Get(ctx context.Context, id string) (Message, error)
 	List(ctx context.Context, sessionID string) ([]Message, error)
@@ -83,6 +86,38 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 	return message, nil
 }
 
+func (s *service) Copy(ctx context.Context, sessionID string, message Message) (Message, error) {
+	partsJSON, err := marshallParts(message.Parts)
+	if err != nil {
+		return Message{}, err
+	}
+	finishedAt := sql.NullInt64{}
+	if f := message.FinishPart(); f != nil {
+		finishedAt.Int64 = f.Time
+		finishedAt.Valid = true
+	}
+	dbMessage, err := s.q.CopyMessage(ctx, db.CopyMessageParams{
+		ID:         uuid.New().String(),
+		SessionID:  sessionID,
+		Role:       string(message.Role),
+		Parts:      string(partsJSON),
+		Model:      sql.NullString{String: message.Model, Valid: true},
+		Provider:   sql.NullString{String: message.Provider, Valid: message.Provider != ""},
+		CreatedAt:  message.CreatedAt,
+		UpdatedAt:  message.UpdatedAt,
+		FinishedAt: finishedAt,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	message, err = s.fromDBItem(dbMessage)
+	if err != nil {
+		return Message{}, err
+	}
+	s.Publish(pubsub.CreatedEvent, message)
+	return message, nil
+}
+
 func (s *service) DeleteSessionMessages(ctx context.Context, sessionID string) error {
 	messages, err := s.List(ctx, sessionID)
 	if err != nil {
@@ -171,6 +206,7 @@ const (
 	binaryType     partType = "binary"
 	toolCallType   partType = "tool_call"
 	toolResultType partType = "tool_result"
+	shellExecType  partType = "shell_execution"
 	finishType     partType = "finish"
 )
 
@@ -198,6 +234,8 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 			typ = toolCallType
 		case ToolResult:
 			typ = toolResultType
+		case ShellExecution:
+			typ = shellExecType
 		case Finish:
 			typ = finishType
 		default:
@@ -267,6 +305,12 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case shellExecType:
+			part := ShellExecution{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		case finishType:
 			part := Finish{}
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
@@ -0,0 +1,105 @@
+package message
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellExecutionRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	parts := []ContentPart{
+		ShellExecution{
+			Command:    "go test ./...",
+			Cwd:        "/tmp/project",
+			Stdout:     "ok\n",
+			Stderr:     "warning\n",
+			ExitCode:   2,
+			StartedAt:  1000,
+			FinishedAt: 2500,
+		},
+		Finish{Reason: "stop"},
+	}
+
+	data, err := marshallParts(parts)
+	require.NoError(t, err)
+
+	got, err := unmarshallParts(data)
+	require.NoError(t, err)
+	require.Equal(t, parts, got)
+}
+
+func TestShellExecutionString(t *testing.T) {
+	t.Parallel()
+
+	se := ShellExecution{
+		Command:    "make",
+		Cwd:        "/src",
+		Stderr:     "make: *** No targets specified.\n",
+		ExitCode:   2,
+		StartedAt:  1000,
+		FinishedAt: 1250,
+	}
+
+	s := se.String()
+	require.Contains(t, s, "not run by you")
+	require.Contains(t, s, "<command>make</command>")
+	require.Contains(t, s, "<exit_code>2</exit_code>")
+	require.Contains(t, s, "<duration>250ms</duration>")
+	require.Contains(t, s, "<stderr>\nmake: *** No targets specified.\n</stderr>")
+	require.NotContains(t, s, "<stdout>")
+}
+
+func TestShellExecutionStringKeepsEndOfOutput(t *testing.T) {
+	t.Parallel()
+
+	se := ShellExecution{
+		Command:    "yes",
+		Stdout:     strings.Repeat("y\n", shellContextLines+50) + "last\n",
+		StartedAt:  1000,
+		FinishedAt: 2000,
+	}
+	s := se.String()
+	require.Contains(t, s, "<stdout>\n... [51 earlier lines omitted] ...\ny\n")
+	require.Contains(t, s, "y\nlast\n</stdout>")
+	require.Equal(t, shellContextLines, strings.Count(s[strings.Index(s, "<stdout>"):], "y\n")+1)
+
+	se.Stdout = strings.Repeat("x", 3*shellContextChars)
+	require.Less(t, len(se.String()), 2*shellContextChars)
+}
+
+func TestTailOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"short", "a\nb\n", "a\nb"},
+		{"too many lines", "a\nb\nc\nd\n", "... [1 earlier lines omitted] ...\nb\nc\nd"},
+		{"too many chars", "aaaaaa\nbbbbbb\nc\nd", "... [2 earlier lines omitted] ...\nc\nd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, TailOutput(tt.output, 3, 8))
+		})
+	}
+}
+
+func TestShellCommands(t *testing.T) {
+	t.Parallel()
+
+	reply := "Install the module first:\n\n" +
+		"```sh\n# fetch dependencies\ngo mod download\n```\n\n" +
+		"Not a command:\n\n```go\nfmt.Println(1)\n```\n\n" +
+		"```console\n$ make build\nbuilding...\n$ ./bin/app\n```\n" +
+		"```\nls\n```\n"
+	require.Equal(t, []string{"go mod download", "make build\n./bin/app"}, ShellCommands(reply))
+	require.Equal(t, []string{"go mod download", "make build\n./bin/app", "ls"}, ShellCommandsWithUntagged(reply))
+	require.Empty(t, ShellCommands("no code here"))
+	require.Empty(t, ShellCommandsWithUntagged("```go\nfmt.Println(1)\n```"))
+}
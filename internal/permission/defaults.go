@@ -0,0 +1,69 @@
+package permission
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Defaults are permissions granted or denied without asking, on top of the
+// ones of a Service.
+type Defaults struct {
+	// AllowedTools are tools, or tool:action pairs, that may be used without
+	// asking.
+	AllowedTools []string
+	// WritablePaths are glob patterns of the files that may be written or
+	// downloaded to, relative to the working directory. Requests to write
+	// other files are denied. If empty, any file may be written.
+	WritablePaths []string
+}
+
+type defaultsService struct {
+	Service
+	defaults   Defaults
+	workingDir string
+}
+
+// WithDefaults returns a Service that grants and denies the requests
+// defaults cover itself, and passes the others on to s.
+func WithDefaults(s Service, defaults Defaults, workingDir string) Service {
+	return &defaultsService{
+		Service:    s,
+		defaults:   defaults,
+		workingDir: workingDir,
+	}
+}
+
+func (s *defaultsService) Request(opts CreatePermissionRequest) bool {
+	if (opts.Action == "write" || opts.Action == "download") && !s.writable(cmp.Or(opts.FilePath, opts.Path)) {
+		return false
+	}
+	if !opts.IgnoreAllowlist && (slices.Contains(s.defaults.AllowedTools, opts.ToolName+":"+opts.Action) || slices.Contains(s.defaults.AllowedTools, opts.ToolName)) {
+		return true
+	}
+	return s.Service.Request(opts)
+}
+
+func (s *defaultsService) writable(path string) bool {
+	if len(s.defaults.WritablePaths) == 0 {
+		return true
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workingDir, path)
+	}
+	rel, err := filepath.Rel(s.workingDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// Files outside of the working directory match no pattern.
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range s.defaults.WritablePaths {
+		if matched, _ := doublestar.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDefaults(t *testing.T) {
+	// The wrapped service grants everything, so only the defaults deny.
+	service := WithDefaults(NewPermissionService("/work", true, nil), Defaults{
+		AllowedTools:  []string{"edit"},
+		WritablePaths: []string{"**/*.md"},
+	}, "/work")
+
+	assert.True(t, service.Request(CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/work", FilePath: "/work/docs/guide.md"}))
+	assert.True(t, service.Request(CreatePermissionRequest{ToolName: "write", Action: "write", Path: "/work", FilePath: "README.md"}))
+	assert.False(t, service.Request(CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/work", FilePath: "/work/main.go"}))
+	assert.False(t, service.Request(CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/elsewhere/notes.md", FilePath: "/elsewhere/notes.md"}))
+	assert.False(t, service.Request(CreatePermissionRequest{ToolName: "download", Action: "download", Path: "/work/bin/tool", FilePath: "/work/bin/tool"}))
+	assert.True(t, service.Request(CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: "/work"}))
+}
+
+func TestWithDefaultsAllowedTools(t *testing.T) {
+	service := WithDefaults(NewPermissionService("/work", false, nil), Defaults{
+		AllowedTools: []string{"edit", "bash:execute"},
+	}, "/work")
+
+	// These would block waiting for an answer if they were passed on.
+	assert.True(t, service.Request(CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/work/main.go"}))
+	assert.True(t, service.Request(CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: "/work"}))
+}
@@ -23,6 +23,12 @@ type CreatePermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	// FilePath is the file a request to change a file is about. Path is its
+	// directory, or the working directory for files within it.
+	FilePath string `json:"file_path,omitempty"`
+	// IgnoreAllowlist asks even when the tool is in the allowed tools, for
+	// requests the user's configuration explicitly wants to be asked about.
+	IgnoreAllowlist bool `json:"ignore_allowlist,omitempty"`
 }
 
 type PermissionNotification struct {
@@ -138,7 +144,7 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 
 	// Check if the tool/action combination is in the allowlist
 	commandKey := opts.ToolName + ":" + opts.Action
-	if slices.Contains(s.allowedTools, commandKey) || slices.Contains(s.allowedTools, opts.ToolName) {
+	if !opts.IgnoreAllowlist && (slices.Contains(s.allowedTools, commandKey) || slices.Contains(s.allowedTools, opts.ToolName)) {
 		return true
 	}
 
@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+// networkCommands are argv prefixes of commands that can reach the network.
+var networkCommands = [][]string{
+	// Network and download tools
+	{"aria2c"},
+	{"axel"},
+	{"curl"},
+	{"curlie"},
+	{"dig"},
+	{"ftp"},
+	{"http"},
+	{"http-prompt"},
+	{"httpie"},
+	{"https"},
+	{"links"},
+	{"lynx"},
+	{"nc"},
+	{"ncat"},
+	{"netcat"},
+	{"nslookup"},
+	{"ping"},
+	{"rsync"},
+	{"scp"},
+	{"sftp"},
+	{"socat"},
+	{"ssh"},
+	{"telnet"},
+	{"w3m"},
+	{"wget"},
+	{"whois"},
+	{"xh"},
+
+	// Version control
+	{"git", "clone"},
+	{"git", "fetch"},
+	{"git", "ls-remote"},
+	{"git", "pull"},
+	{"git", "push"},
+	{"git", "submodule"},
+	{"gh"},
+
+	// Package managers
+	{"apk", "add"},
+	{"apt", "install"},
+	{"apt", "update"},
+	{"apt-get", "install"},
+	{"apt-get", "update"},
+	{"brew", "install"},
+	{"brew", "update"},
+	{"brew", "upgrade"},
+	{"cargo", "fetch"},
+	{"cargo", "install"},
+	{"cargo", "publish"},
+	{"dnf", "install"},
+	{"docker", "login"},
+	{"docker", "pull"},
+	{"docker", "push"},
+	{"gem", "install"},
+	{"go", "get"},
+	{"go", "install"},
+	{"go", "mod", "download"},
+	{"npm", "ci"},
+	{"npm", "i"},
+	{"npm", "install"},
+	{"npm", "publish"},
+	{"npx"},
+	{"pacman", "-S"},
+	{"pip", "download"},
+	{"pip", "install"},
+	{"pip3", "install"},
+	{"pnpm", "add"},
+	{"pnpm", "install"},
+	{"uv", "pip", "install"},
+	{"yarn", "add"},
+	{"yarn", "install"},
+	{"yum", "install"},
+}
+
+// isNetworkCommand reports whether args start with one of networkCommands.
+func isNetworkCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	args = append([]string{filepath.Base(args[0])}, args[1:]...)
+	for _, prefix := range networkCommands {
+		if len(args) >= len(prefix) && slices.Equal(args[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// shells run the script given to them with -c.
+var shells = []string{"sh", "bash", "dash", "zsh", "ksh"}
+
+// inlineScript returns the script of "sh -c script" commands.
+func inlineScript(args []string) (string, bool) {
+	if len(args) < 3 || !slices.Contains(shells, filepath.Base(args[0])) || args[1] != "-c" {
+		return "", false
+	}
+	return args[2], true
+}
+
+// unwrap returns req followed by the commands it runs through wrappers, as
+// in "env FOO=1 nice -n 5 rm x".
+func unwrap(req shell.ExecRequest) []shell.ExecRequest {
+	layers := []shell.ExecRequest{req}
+	for {
+		args := req.Args
+		if len(args) == 0 {
+			return layers
+		}
+		env := req.Env
+		var rest []string
+		switch filepath.Base(args[0]) {
+		case "command", "exec", "nohup", "builtin":
+			rest = skipFlags(args[1:], nil)
+		case "env":
+			rest = skipFlags(args[1:], []string{"-u", "--unset", "-C", "--chdir", "-S", "--split-string"})
+			env = maps.Clone(env)
+			for len(rest) > 0 && strings.Contains(rest[0], "=") {
+				name, value, _ := strings.Cut(rest[0], "=")
+				env[name] = value
+				rest = rest[1:]
+			}
+		case "nice":
+			rest = skipFlags(args[1:], []string{"-n", "--adjustment"})
+		case "time":
+			rest = skipFlags(args[1:], []string{"-o", "--output", "-f", "--format"})
+		case "timeout":
+			rest = skipFlags(args[1:], []string{"-s", "--signal", "-k", "--kill-after"})
+			if len(rest) > 0 {
+				rest = rest[1:]
+			}
+		case "sudo", "doas":
+			rest = skipFlags(args[1:], []string{"-u", "--user", "-g", "--group", "-C", "-D", "--chdir"})
+		case "xargs":
+			rest = skipFlags(args[1:], []string{"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s"})
+		default:
+			return layers
+		}
+		if len(rest) == 0 {
+			return layers
+		}
+		req = shell.ExecRequest{Args: rest, Dir: req.Dir, Env: env}
+		layers = append(layers, req)
+	}
+}
+
+// skipFlags skips the leading flags of args, and the values of those in
+// withValue.
+func skipFlags(args []string, withValue []string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "--" {
+			return args[1:]
+		}
+		flag := args[0]
+		args = args[1:]
+		if slices.Contains(withValue, flag) && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
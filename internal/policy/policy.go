@@ -0,0 +1,366 @@
+// Package policy decides whether the agent may run a shell command, based on
+// the command policy in lash.json.
+//
+// Rules match simple commands on their argv, the paths they are given, the
+// environment variables changed for them and whether they can reach the
+// network. When several rules apply to a command, deny wins over ask and ask
+// over allow.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"mvdan.cc/sh/v3/syntax"
+
+	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+// Action is what to do with a command.
+type Action string
+
+const (
+	Allow Action = "allow"
+	Ask   Action = "ask"
+	Deny  Action = "deny"
+)
+
+// strictness orders actions so that the strictest applicable rule wins.
+func (a Action) strictness() int {
+	switch a {
+	case Allow:
+		return 1
+	case Ask:
+		return 2
+	case Deny:
+		return 3
+	}
+	return 0
+}
+
+// Rule is a compiled rule of the policy.
+type Rule struct {
+	config.LashPolicyRule
+	index   int
+	command []*regexp.Regexp // nil elements stand for "**"
+	paths   []string
+	env     []*regexp.Regexp
+}
+
+// String names the rule for denials and prompts.
+func (r *Rule) String() string {
+	if r.Name != "" {
+		return fmt.Sprintf("policy rule %q", r.Name)
+	}
+	return fmt.Sprintf("policy rule #%d", r.index+1)
+}
+
+// Decision is the outcome of evaluating a command.
+type Decision struct {
+	// Action is empty when no rule applies.
+	Action Action
+	// Rule is the rule that decided, if any.
+	Rule *Rule
+}
+
+// String describes the rule that decided, with its reason.
+func (d Decision) String() string {
+	if d.Rule == nil {
+		return "no policy rule"
+	}
+	if d.Rule.Reason != "" {
+		return d.Rule.String() + ": " + d.Rule.Reason
+	}
+	return d.Rule.String()
+}
+
+// stricter returns the stricter of d and other, keeping d on ties so the
+// first rule of an action is the one reported.
+func (d Decision) stricter(other Decision) Decision {
+	if other.Action.strictness() > d.Action.strictness() {
+		return other
+	}
+	return d
+}
+
+// Engine evaluates commands against the rules of a policy.
+type Engine struct {
+	rules []*Rule
+}
+
+// New compiles rules. Relative path globs that contain a slash are resolved
+// from workingDir.
+func New(rules []config.LashPolicyRule, workingDir string) (*Engine, error) {
+	e := &Engine{}
+	for i, cfg := range rules {
+		rule, err := compileRule(i, cfg, workingDir)
+		if err != nil {
+			return nil, err
+		}
+		e.rules = append(e.rules, rule)
+	}
+	return e, nil
+}
+
+func compileRule(index int, cfg config.LashPolicyRule, workingDir string) (*Rule, error) {
+	rule := &Rule{LashPolicyRule: cfg, index: index}
+	switch Action(cfg.Action) {
+	case Allow, Ask, Deny:
+	default:
+		return nil, fmt.Errorf("%s: invalid action %q, expected allow, ask or deny", rule, cfg.Action)
+	}
+
+	for _, pattern := range cfg.Command {
+		if pattern == "**" {
+			rule.command = append(rule.command, nil)
+			continue
+		}
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid command pattern %q: %w", rule, pattern, err)
+		}
+		rule.command = append(rule.command, re)
+	}
+
+	for _, pattern := range cfg.Paths {
+		pattern = expandHome(pattern)
+		if strings.Contains(pattern, "/") && !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(workingDir, pattern)
+		}
+		pattern = filepath.ToSlash(pattern)
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("%s: invalid path glob %q", rule, pattern)
+		}
+		rule.paths = append(rule.paths, pattern)
+	}
+
+	for _, pattern := range cfg.Env {
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid env pattern %q: %w", rule, pattern, err)
+		}
+		rule.env = append(rule.env, re)
+	}
+	return rule, nil
+}
+
+// Evaluate decides what to do with a simple command. Commands run through
+// wrappers like env, nice or timeout, and scripts given to sh -c, are also
+// evaluated.
+func (e *Engine) Evaluate(req shell.ExecRequest) Decision {
+	var decision Decision
+	for _, layer := range unwrap(req) {
+		decision = decision.stricter(e.evaluate(layer))
+		if script, ok := inlineScript(layer.Args); ok {
+			scriptDecision, _ := e.EvaluateScript(script, layer.Dir)
+			decision = decision.stricter(scriptDecision)
+		}
+	}
+	return decision
+}
+
+func (e *Engine) evaluate(req shell.ExecRequest) Decision {
+	var decision Decision
+	for _, rule := range e.rules {
+		if rule.matches(req) {
+			decision = decision.stricter(Decision{Action: Action(rule.Action), Rule: rule})
+		}
+	}
+	return decision
+}
+
+// EvaluateScript evaluates the simple commands of script that can be known
+// without running it, from dir. It returns the strictest decision, and
+// whether every command of the script is known and allowed by a rule.
+func (e *Engine) EvaluateScript(script, dir string) (Decision, bool) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		return Decision{}, false
+	}
+
+	var decision Decision
+	allAllowed := true
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		req, known := staticRequest(call, dir)
+		if !known {
+			allAllowed = false
+			return true
+		}
+		d := e.Evaluate(req)
+		decision = decision.stricter(d)
+		if d.Action != Allow {
+			allAllowed = false
+		}
+		return true
+	})
+	return decision, allAllowed
+}
+
+// staticRequest builds the request for call if its words are literals.
+func staticRequest(call *syntax.CallExpr, dir string) (shell.ExecRequest, bool) {
+	req := shell.ExecRequest{Dir: dir, Env: make(map[string]string)}
+	known := true
+	for _, arg := range call.Args {
+		lit := arg.Lit()
+		if lit == "" {
+			known = false
+			break
+		}
+		req.Args = append(req.Args, lit)
+	}
+	for _, assign := range call.Assigns {
+		value := ""
+		if assign.Value != nil {
+			value = assign.Value.Lit()
+		}
+		req.Env[assign.Name.Value] = value
+	}
+	return req, known && len(req.Args) > 0
+}
+
+func (r *Rule) matches(req shell.ExecRequest) bool {
+	if len(req.Args) == 0 {
+		return false
+	}
+	if len(r.command) > 0 && !matchArgv(r.command, req.Args, true) {
+		return false
+	}
+	if len(r.paths) > 0 && !r.matchesPaths(req) {
+		return false
+	}
+	if len(r.env) > 0 && !r.matchesEnv(req.Env) {
+		return false
+	}
+	if r.Network && !isNetworkCommand(req.Args) {
+		return false
+	}
+	return true
+}
+
+// matchArgv reports whether pattern matches the start of args. The name of
+// the command also matches by its base name, so "curl" matches
+// /usr/bin/curl.
+func matchArgv(pattern []*regexp.Regexp, args []string, first bool) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0] == nil {
+		for i := 0; i <= len(args); i++ {
+			if matchArgv(pattern[1:], args[i:], false) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(args) == 0 {
+		return false
+	}
+	arg := args[0]
+	if !pattern[0].MatchString(arg) && !(first && pattern[0].MatchString(filepath.Base(arg))) {
+		return false
+	}
+	return matchArgv(pattern[1:], args[1:], false)
+}
+
+func (r *Rule) matchesPaths(req shell.ExecRequest) bool {
+	for _, path := range pathArgs(req) {
+		slashed := filepath.ToSlash(path)
+		base := filepath.Base(path)
+		for _, pattern := range r.paths {
+			if strings.Contains(pattern, "/") {
+				if ok, _ := doublestar.Match(pattern, slashed); ok {
+					return true
+				}
+			} else if ok, _ := doublestar.Match(pattern, base); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathArgs returns the arguments of req that could be paths, made
+// absolute. The values of --flag=value arguments are included, as is the
+// command itself when it is given as a path.
+func pathArgs(req shell.ExecRequest) []string {
+	var paths []string
+	for i, arg := range req.Args {
+		if i == 0 && !strings.ContainsRune(arg, '/') {
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			_, value, ok := strings.Cut(arg, "=")
+			if !ok || value == "" {
+				continue
+			}
+			arg = value
+		}
+		arg = expandHome(arg)
+		if !filepath.IsAbs(arg) {
+			arg = filepath.Join(req.Dir, arg)
+		}
+		paths = append(paths, filepath.Clean(arg))
+	}
+	return paths
+}
+
+func (r *Rule) matchesEnv(env map[string]string) bool {
+	for name := range env {
+		for _, pattern := range r.env {
+			if pattern.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compileGlob turns a glob where * matches any text, ? any character and
+// [...] a class into an anchored regular expression.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
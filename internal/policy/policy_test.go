@@ -0,0 +1,136 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/shell"
+)
+
+func request(dir string, args ...string) shell.ExecRequest {
+	return shell.ExecRequest{Args: args, Dir: dir, Env: map[string]string{}}
+}
+
+func TestEvaluateCommand(t *testing.T) {
+	t.Parallel()
+
+	e, err := New([]config.LashPolicyRule{
+		{Name: "tests", Action: "allow", Command: []string{"go", "test"}},
+		{Name: "push", Action: "ask", Command: []string{"git", "push"}},
+		{Name: "force-push", Action: "deny", Command: []string{"git", "push", "**", "--force*"}, Reason: "rewrites shared history"},
+	}, "/project")
+	require.NoError(t, err)
+
+	require.Equal(t, Allow, e.Evaluate(request("/project", "go", "test", "./...")).Action)
+	require.Equal(t, Ask, e.Evaluate(request("/project", "git", "push", "origin", "main")).Action)
+	require.Equal(t, Action(""), e.Evaluate(request("/project", "git", "status")).Action)
+
+	d := e.Evaluate(request("/project", "/usr/bin/git", "push", "origin", "main", "--force-with-lease"))
+	require.Equal(t, Deny, d.Action)
+	require.Equal(t, `policy rule "force-push": rewrites shared history`, d.String())
+}
+
+func TestEvaluatePaths(t *testing.T) {
+	t.Parallel()
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	e, err := New([]config.LashPolicyRule{
+		{Name: "secrets", Action: "deny", Paths: []string{"~/.ssh/**", "*.pem", ".env"}},
+		{Name: "generated", Action: "ask", Command: []string{"rm"}, Paths: []string{"gen/**"}},
+	}, "/project")
+	require.NoError(t, err)
+
+	require.Equal(t, Deny, e.Evaluate(request("/tmp", "cat", filepath.Join(home, ".ssh", "id_ed25519"))).Action)
+	require.Equal(t, Deny, e.Evaluate(request("/project", "cat", "~/.ssh/config")).Action)
+	require.Equal(t, Deny, e.Evaluate(request("/project/sub", "openssl", "x509", "-in", "certs/server.pem")).Action)
+	require.Equal(t, Deny, e.Evaluate(request("/project", "source", "--file=.env")).Action)
+	require.Equal(t, Ask, e.Evaluate(request("/project/gen", "rm", "-rf", "out")).Action)
+	require.Equal(t, Action(""), e.Evaluate(request("/project", "rm", "-rf", "src/out")).Action)
+}
+
+func TestEvaluateEnvAndNetwork(t *testing.T) {
+	t.Parallel()
+
+	e, err := New([]config.LashPolicyRule{
+		{Name: "preload", Action: "deny", Env: []string{"LD_*", "DYLD_*"}},
+		{Name: "network", Action: "ask", Network: true},
+	}, "/project")
+	require.NoError(t, err)
+
+	req := request("/project", "make")
+	req.Env["LD_PRELOAD"] = "./hook.so"
+	require.Equal(t, Deny, e.Evaluate(req).Action)
+
+	require.Equal(t, Ask, e.Evaluate(request("/project", "curl", "https://example.com")).Action)
+	require.Equal(t, Ask, e.Evaluate(request("/project", "git", "fetch")).Action)
+	require.Equal(t, Action(""), e.Evaluate(request("/project", "git", "log")).Action)
+}
+
+func TestEvaluateWrappers(t *testing.T) {
+	t.Parallel()
+
+	e, err := New([]config.LashPolicyRule{
+		{Name: "no-rm", Action: "deny", Command: []string{"rm"}},
+		{Name: "preload", Action: "deny", Env: []string{"LD_PRELOAD"}},
+	}, "/project")
+	require.NoError(t, err)
+
+	for _, args := range [][]string{
+		{"env", "rm", "x"},
+		{"env", "-i", "FOO=1", "rm", "x"},
+		{"nice", "-n", "5", "rm", "x"},
+		{"timeout", "-s", "KILL", "5", "rm", "x"},
+		{"sudo", "-u", "root", "rm", "x"},
+		{"xargs", "-n", "1", "rm"},
+		{"nohup", "time", "rm", "x"},
+		{"sh", "-c", "echo hi && rm x"},
+		{"env", "LD_PRELOAD=./hook.so", "make"},
+	} {
+		require.Equal(t, Deny, e.Evaluate(request("/project", args...)).Action, "expected %v to be denied", args)
+	}
+}
+
+func TestEvaluateScript(t *testing.T) {
+	t.Parallel()
+
+	e, err := New([]config.LashPolicyRule{
+		{Action: "allow", Command: []string{"go", "**"}},
+		{Action: "allow", Command: []string{"git", "status"}},
+		{Action: "deny", Command: []string{"rm"}},
+	}, "/project")
+	require.NoError(t, err)
+
+	d, allowed := e.EvaluateScript("go vet ./... && go test ./... | git status", "/project")
+	require.Equal(t, Allow, d.Action)
+	require.True(t, allowed)
+
+	_, allowed = e.EvaluateScript("go test $PKG", "/project")
+	require.False(t, allowed)
+
+	_, allowed = e.EvaluateScript("go test ./... && make", "/project")
+	require.False(t, allowed)
+
+	d, allowed = e.EvaluateScript("go test ./... && echo $(rm -rf /)", "/project")
+	require.Equal(t, Deny, d.Action)
+	require.Equal(t, "policy rule #3", d.String())
+	require.False(t, allowed)
+}
+
+func TestNewInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	_, err := New([]config.LashPolicyRule{{Name: "bad", Action: "block"}}, "/project")
+	require.ErrorContains(t, err, `policy rule "bad": invalid action "block"`)
+
+	_, err = New([]config.LashPolicyRule{{Action: "deny", Command: []string{"[rm"}}}, "/project")
+	require.ErrorContains(t, err, "policy rule #1: invalid command pattern")
+
+	_, err = New([]config.LashPolicyRule{{Action: "deny", Paths: []string{"/etc/[ab"}}}, "/project")
+	require.ErrorContains(t, err, "invalid path glob")
+}
@@ -0,0 +1,231 @@
+package router
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+const (
+	// agentThreshold is the score at or above which input goes to the agent.
+	agentThreshold = 2.0
+	// shellThreshold is the score at or below which input goes to the shell.
+	shellThreshold = 1.0
+
+	// Score contributions of individual arguments.
+	stopwordScore    = 1.5
+	wordScore        = 0.5
+	shellArgScore    = -1.0
+	questionScore    = 2.0
+	punctuationScore = 1.0
+)
+
+// builtins are shell builtins and keywords that are commands even though
+// they aren't on PATH.
+var builtins = map[string]bool{
+	".": true, ":": true, "[": true, "alias": true, "bg": true, "break": true,
+	"builtin": true, "cd": true, "command": true, "continue": true,
+	"declare": true, "dirs": true, "echo": true, "eval": true, "exec": true,
+	"exit": true, "export": true, "false": true, "fg": true, "getopts": true,
+	"hash": true, "jobs": true, "kill": true, "let": true, "local": true,
+	"popd": true, "printf": true, "pushd": true, "pwd": true, "read": true,
+	"readonly": true, "return": true, "set": true, "shift": true,
+	"shopt": true, "source": true, "test": true, "trap": true, "true": true,
+	"type": true, "typeset": true, "umask": true, "unalias": true,
+	"unset": true, "wait": true,
+}
+
+// textCommands take free text as arguments, so prose after them is still a
+// shell command.
+var textCommands = map[string]bool{
+	"echo":   true,
+	"printf": true,
+}
+
+// stopwords are common English function words that rarely appear as
+// arguments to commands.
+var stopwords = map[string]bool{
+	"a": true, "about": true, "all": true, "an": true, "and": true,
+	"are": true, "be": true, "but": true, "can": true, "could": true,
+	"does": true, "for": true, "from": true, "how": true, "i": true,
+	"in": true, "into": true, "is": true, "it": true, "its": true,
+	"me": true, "my": true, "not": true, "of": true, "on": true, "or": true,
+	"our": true, "please": true, "should": true, "some": true, "that": true,
+	"the": true, "there": true, "these": true, "this": true, "those": true,
+	"to": true, "us": true, "was": true, "we": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true,
+	"why": true, "with": true, "would": true, "you": true, "your": true,
+}
+
+func isBuiltin(name string) bool {
+	return builtins[name]
+}
+
+// analysis is the parser's verdict on a line of input. A non-empty target
+// is a definite decision; otherwise score decides.
+type analysis struct {
+	target Target
+	score  float64
+	reason string
+}
+
+// analyze parses input as a shell command and scores how much it reads like
+// natural language.
+func analyze(input, dir string, lookPath func(string) bool) analysis {
+	file, err := syntax.NewParser().Parse(strings.NewReader(input), "")
+	if err != nil {
+		return analysis{target: TargetAgent, reason: "does not parse as a shell command"}
+	}
+	if len(file.Stmts) == 0 {
+		return analysis{target: TargetAgent, reason: "no command found"}
+	}
+	if feature := shellSyntax(file); feature != "" {
+		return analysis{target: TargetShell, reason: "uses shell syntax: " + feature}
+	}
+
+	call, ok := file.Stmts[0].Cmd.(*syntax.CallExpr)
+	if !ok {
+		// Compound commands (if, for, while, function declarations, ...)
+		// only make sense to a shell.
+		return analysis{target: TargetShell, reason: "is a compound shell command"}
+	}
+	if len(call.Assigns) > 0 {
+		return analysis{target: TargetShell, reason: "sets shell variables"}
+	}
+
+	name := call.Args[0].Lit()
+	if name == "" {
+		return analysis{target: TargetShell, reason: "command name is expanded by the shell"}
+	}
+	if !resolves(name, dir, lookPath) {
+		return analysis{target: TargetAgent, reason: fmt.Sprintf("%s is not a command", name)}
+	}
+	if textCommands[name] {
+		return analysis{target: TargetShell, reason: fmt.Sprintf("%s takes free text", name)}
+	}
+
+	var score float64
+	var cues []string
+	args := call.Args[1:]
+	for i, arg := range args {
+		lit := arg.Lit()
+		if lit == "" {
+			// Quoting and expansions are shell syntax.
+			score += shellArgScore
+			continue
+		}
+		if i == len(args)-1 {
+			if trimmed, ok := strings.CutSuffix(lit, "?"); ok && trimmed != "" {
+				score += questionScore
+				cues = append(cues, "ends with a question mark")
+				lit = trimmed
+			} else if trimmed, ok := strings.CutSuffix(lit, "."); ok && isWord(trimmed) {
+				score += punctuationScore
+				cues = append(cues, "ends with a period")
+				lit = trimmed
+			}
+		}
+		if trimmed, ok := strings.CutSuffix(lit, ","); ok && isWord(trimmed) {
+			score += punctuationScore
+			cues = append(cues, "uses commas")
+			lit = trimmed
+		}
+		switch {
+		case strings.HasPrefix(lit, "-"), !isWord(lit), exists(dir, lit):
+			score += shellArgScore
+		case stopwords[strings.ToLower(lit)]:
+			score += stopwordScore
+			cues = append(cues, fmt.Sprintf("%q reads like English", lit))
+		default:
+			score += wordScore
+		}
+	}
+
+	reason := fmt.Sprintf("%s is a command", name)
+	if len(cues) > 0 {
+		reason += ", but " + strings.Join(cues, ", ")
+	} else if score <= shellThreshold {
+		reason += " and the arguments look like shell arguments"
+	}
+	return analysis{score: score, reason: reason}
+}
+
+// shellSyntax returns a description of the first shell-only construct in
+// file, if any.
+func shellSyntax(file *syntax.File) string {
+	if len(file.Stmts) > 1 {
+		return "multiple commands"
+	}
+	var feature string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if feature != "" {
+			return false
+		}
+		switch node := node.(type) {
+		case *syntax.Stmt:
+			switch {
+			case len(node.Redirs) > 0:
+				feature = "redirection"
+			case node.Background:
+				feature = "background job"
+			case node.Negated:
+				feature = "negation"
+			}
+		case *syntax.BinaryCmd:
+			feature = fmt.Sprintf("the %s operator", node.Op)
+		case *syntax.CmdSubst:
+			feature = "command substitution"
+		case *syntax.ParamExp:
+			feature = "variable expansion"
+		case *syntax.ArithmExp:
+			feature = "arithmetic expansion"
+		case *syntax.ProcSubst:
+			feature = "process substitution"
+		}
+		return feature == ""
+	})
+	return feature
+}
+
+// resolves reports whether name is a runnable command. Names containing a
+// slash must point to an executable file, relative to dir.
+func resolves(name, dir string, lookPath func(string) bool) bool {
+	if !strings.Contains(name, "/") {
+		return lookPath(name)
+	}
+	if !filepath.IsAbs(name) && dir != "" {
+		name = filepath.Join(dir, name)
+	}
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// isWord reports whether s is made only of letters, optionally joined by
+// hyphens or apostrophes, like an English word.
+func isWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+		case (r == '-' || r == '\'') && i > 0 && i < len(s)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// exists reports whether name is a file or directory relative to dir.
+func exists(dir, name string) bool {
+	if dir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
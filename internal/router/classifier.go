@@ -0,0 +1,41 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lacymorrow/lash/internal/llm/provider"
+	"github.com/lacymorrow/lash/internal/message"
+)
+
+// modelClassifier asks a model whether input is a command or a prompt.
+type modelClassifier struct {
+	provider provider.Provider
+}
+
+// NewModelClassifier creates a Classifier backed by p, which should be set
+// up with the router system prompt.
+func NewModelClassifier(p provider.Provider) Classifier {
+	return &modelClassifier{provider: p}
+}
+
+func (c *modelClassifier) Classify(ctx context.Context, input string) (Target, error) {
+	resp, err := c.provider.SendMessages(ctx, []message.Message{
+		{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: input}},
+		},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to classify input: %w", err)
+	}
+	answer := strings.ToLower(strings.TrimSpace(resp.Content))
+	switch {
+	case strings.HasPrefix(answer, string(TargetShell)):
+		return TargetShell, nil
+	case strings.HasPrefix(answer, string(TargetAgent)):
+		return TargetAgent, nil
+	}
+	return "", fmt.Errorf("unexpected classifier answer: %q", resp.Content)
+}
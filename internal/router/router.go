@@ -0,0 +1,234 @@
+// Package router decides whether a line typed in Auto mode is a shell command
+// or a prompt for the agent.
+//
+// Input is parsed with the same POSIX parser the shell package runs commands
+// with. Input that doesn't parse, or whose first word doesn't resolve to a
+// command, goes to the agent. Input that does is scored for natural-language
+// features, and when the score is ambiguous an optional Classifier, usually
+// the small model, breaks the tie.
+package router
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target is where a line of input is sent.
+type Target string
+
+const (
+	TargetShell Target = "shell"
+	TargetAgent Target = "agent"
+)
+
+// Source is what made a routing decision.
+type Source string
+
+const (
+	SourceOverride Source = "override"
+	SourceParser   Source = "parser"
+	SourceModel    Source = "model"
+)
+
+const (
+	// DefaultShellPrefix forces a line to the shell.
+	DefaultShellPrefix = "!"
+	// DefaultAgentPrefix forces a line to the agent.
+	DefaultAgentPrefix = "?"
+
+	// maxDecisions is the number of recent decisions kept for inspection.
+	maxDecisions = 100
+	// classifyTimeout bounds how long the classifier may take.
+	classifyTimeout = 5 * time.Second
+)
+
+// Decision records where a line went and why.
+type Decision struct {
+	// Input is the line as typed.
+	Input string
+	// Text is the line to send to the target, without any override prefix.
+	Text   string
+	Target Target
+	Source Source
+	// Score is the natural-language score of the input. Positive values
+	// lean towards the agent.
+	Score  float64
+	Reason string
+	Time   time.Time
+}
+
+func (d Decision) String() string {
+	return fmt.Sprintf("%s (%s): %s", d.Target, d.Source, d.Reason)
+}
+
+// Classifier breaks ties for input the parser can't confidently route.
+type Classifier interface {
+	Classify(ctx context.Context, input string) (Target, error)
+}
+
+// Options configures a Router.
+type Options struct {
+	// ShellPrefix and AgentPrefix force a line to the shell or the agent.
+	// Empty values use DefaultShellPrefix and DefaultAgentPrefix.
+	ShellPrefix string
+	AgentPrefix string
+	// ShellCommands are first words that always route to the shell.
+	ShellCommands []string
+	// AgentCommands are first words that always route to the agent.
+	AgentCommands []string
+	// Dir returns the directory relative paths are resolved against.
+	// Defaults to the process working directory.
+	Dir func() string
+	// LookPath reports whether name resolves to a command. Defaults to
+	// shell builtins and exec.LookPath.
+	LookPath func(name string) bool
+}
+
+// Router routes Auto-mode input.
+type Router struct {
+	opts Options
+
+	mu         sync.Mutex
+	classifier Classifier
+	decisions  []Decision
+}
+
+// New creates a new router with the given options.
+func New(opts Options) *Router {
+	if opts.ShellPrefix == "" {
+		opts.ShellPrefix = DefaultShellPrefix
+	}
+	if opts.AgentPrefix == "" {
+		opts.AgentPrefix = DefaultAgentPrefix
+	}
+	if opts.Dir == nil {
+		opts.Dir = func() string {
+			dir, _ := os.Getwd()
+			return dir
+		}
+	}
+	if opts.LookPath == nil {
+		opts.LookPath = defaultLookPath
+	}
+	return &Router{opts: opts}
+}
+
+// SetClassifier sets the classifier used for ambiguous input. A nil
+// classifier disables it.
+func (r *Router) SetClassifier(c Classifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.classifier = c
+}
+
+// Decisions returns the most recent routing decisions, oldest first.
+func (r *Router) Decisions() []Decision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.decisions)
+}
+
+// Route decides where input goes and records the decision.
+func (r *Router) Route(ctx context.Context, input string) Decision {
+	return r.RouteIn(ctx, r.opts.Dir(), input)
+}
+
+// RouteIn is like Route but resolves relative paths against dir.
+func (r *Router) RouteIn(ctx context.Context, dir, input string) Decision {
+	d := r.route(ctx, dir, input)
+	d.Input = input
+	d.Time = time.Now()
+
+	r.mu.Lock()
+	r.decisions = append(r.decisions, d)
+	if len(r.decisions) > maxDecisions {
+		r.decisions = slices.Delete(r.decisions, 0, len(r.decisions)-maxDecisions)
+	}
+	r.mu.Unlock()
+
+	slog.Info("Auto mode routed input", "target", d.Target, "source", d.Source, "score", d.Score, "reason", d.Reason)
+	return d
+}
+
+func (r *Router) route(ctx context.Context, dir, input string) Decision {
+	text := strings.TrimSpace(input)
+
+	if rest, ok := strings.CutPrefix(text, r.opts.ShellPrefix); ok {
+		return Decision{
+			Text:   strings.TrimSpace(rest),
+			Target: TargetShell,
+			Source: SourceOverride,
+			Reason: fmt.Sprintf("forced with %q", r.opts.ShellPrefix),
+		}
+	}
+	if rest, ok := strings.CutPrefix(text, r.opts.AgentPrefix); ok {
+		return Decision{
+			Text:   strings.TrimSpace(rest),
+			Target: TargetAgent,
+			Source: SourceOverride,
+			Reason: fmt.Sprintf("forced with %q", r.opts.AgentPrefix),
+		}
+	}
+	if first, _, _ := strings.Cut(text, " "); first != "" {
+		if slices.Contains(r.opts.ShellCommands, first) {
+			return Decision{Text: text, Target: TargetShell, Source: SourceOverride, Reason: fmt.Sprintf("%s is configured to always run in the shell", first)}
+		}
+		if slices.Contains(r.opts.AgentCommands, first) {
+			return Decision{Text: text, Target: TargetAgent, Source: SourceOverride, Reason: fmt.Sprintf("%s is configured to always go to the agent", first)}
+		}
+	}
+
+	a := analyze(text, dir, r.opts.LookPath)
+	d := Decision{Text: text, Source: SourceParser, Score: a.score, Reason: a.reason}
+	switch {
+	case a.target != "":
+		d.Target = a.target
+		return d
+	case a.score >= agentThreshold:
+		d.Target = TargetAgent
+		return d
+	case a.score <= shellThreshold:
+		d.Target = TargetShell
+		return d
+	}
+
+	// Ambiguous: ask the classifier, if any, and fall back to the shell
+	// since the first word is a command.
+	d.Target = TargetShell
+	r.mu.Lock()
+	classifier := r.classifier
+	r.mu.Unlock()
+	if classifier == nil {
+		d.Reason += "; ambiguous, defaulting to the shell"
+		return d
+	}
+	ctx, cancel := context.WithTimeout(ctx, classifyTimeout)
+	defer cancel()
+	target, err := classifier.Classify(ctx, text)
+	if err != nil {
+		slog.Warn("Auto mode classifier failed", "error", err)
+		d.Reason += "; ambiguous and the model could not decide, defaulting to the shell"
+		return d
+	}
+	d.Target = target
+	d.Source = SourceModel
+	d.Reason += fmt.Sprintf("; ambiguous, the model chose the %s", target)
+	return d
+}
+
+// defaultLookPath reports whether name is a shell builtin or a command on
+// PATH.
+func defaultLookPath(name string) bool {
+	if isBuiltin(name) {
+		return true
+	}
+	_, err := exec.LookPath(name)
+	return err == nil
+}
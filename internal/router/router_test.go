@@ -0,0 +1,146 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// commands stands in for PATH so tests don't depend on the host.
+var commands = map[string]bool{
+	"cat": true, "find": true, "git": true, "go": true, "ls": true,
+	"make": true, "wc": true, "grep": true,
+}
+
+func newTestRouter(t *testing.T) *Router {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), nil, 0o644))
+	return New(Options{
+		Dir: func() string { return dir },
+		LookPath: func(name string) bool {
+			return commands[name] || isBuiltin(name)
+		},
+	})
+}
+
+type fakeClassifier struct {
+	target Target
+	err    error
+	calls  int
+}
+
+func (c *fakeClassifier) Classify(context.Context, string) (Target, error) {
+	c.calls++
+	return c.target, c.err
+}
+
+func TestRoute(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input  string
+		target Target
+		source Source
+		text   string
+	}{
+		{input: "make the login page responsive", target: TargetAgent, source: SourceParser},
+		{input: "find where we parse config", target: TargetAgent, source: SourceParser},
+		{input: "explain this", target: TargetAgent, source: SourceParser},
+		{input: "why does the build fail?", target: TargetAgent, source: SourceParser},
+		{input: "make test", target: TargetShell, source: SourceParser},
+		{input: "go test ./...", target: TargetShell, source: SourceParser},
+		{input: "git status", target: TargetShell, source: SourceParser},
+		{input: "git commit -m 'fix the thing'", target: TargetShell, source: SourceParser},
+		{input: "cat main.go", target: TargetShell, source: SourceParser},
+		{input: "ls | wc -l", target: TargetShell, source: SourceParser},
+		{input: "ls > out.txt", target: TargetShell, source: SourceParser},
+		{input: "FOO=bar make", target: TargetShell, source: SourceParser},
+		{input: "cd ..", target: TargetShell, source: SourceParser},
+		{input: "echo hello there my friend", target: TargetShell, source: SourceParser},
+		{input: "for f in *; do echo $f; done", target: TargetShell, source: SourceParser},
+		{input: "don't touch the tests", target: TargetAgent, source: SourceParser},
+		{input: "!make the thing", target: TargetShell, source: SourceOverride, text: "make the thing"},
+		{input: "?ls", target: TargetAgent, source: SourceOverride, text: "ls"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			d := newTestRouter(t).Route(context.Background(), tt.input)
+			require.Equal(t, tt.target, d.Target, d.Reason)
+			require.Equal(t, tt.source, d.Source)
+			require.Equal(t, tt.input, d.Input)
+			require.NotEmpty(t, d.Reason)
+			if tt.text != "" {
+				require.Equal(t, tt.text, d.Text)
+			}
+		})
+	}
+}
+
+func TestRouteConfiguredCommands(t *testing.T) {
+	t.Parallel()
+
+	r := New(Options{
+		ShellPrefix:   ";",
+		ShellCommands: []string{"deploy"},
+		AgentCommands: []string{"git"},
+		LookPath:      func(string) bool { return false },
+	})
+
+	d := r.Route(context.Background(), "deploy staging")
+	require.Equal(t, TargetShell, d.Target)
+	require.Equal(t, SourceOverride, d.Source)
+
+	d = r.Route(context.Background(), "git status")
+	require.Equal(t, TargetAgent, d.Target)
+	require.Equal(t, SourceOverride, d.Source)
+
+	d = r.Route(context.Background(), ";whoami")
+	require.Equal(t, TargetShell, d.Target)
+	require.Equal(t, "whoami", d.Text)
+}
+
+func TestRouteClassifier(t *testing.T) {
+	t.Parallel()
+
+	// "make coffee run fast" is ambiguous: a command followed by plain words.
+	r := newTestRouter(t)
+	d := r.Route(context.Background(), "make coffee run fast")
+	require.Equal(t, TargetShell, d.Target)
+	require.Equal(t, SourceParser, d.Source)
+
+	c := &fakeClassifier{target: TargetAgent}
+	r.SetClassifier(c)
+	d = r.Route(context.Background(), "make coffee run fast")
+	require.Equal(t, TargetAgent, d.Target)
+	require.Equal(t, SourceModel, d.Source)
+	require.Equal(t, 1, c.calls)
+
+	// Clear-cut input never reaches the classifier.
+	r.Route(context.Background(), "git status")
+	require.Equal(t, 1, c.calls)
+
+	c.err = errors.New("boom")
+	d = r.Route(context.Background(), "make coffee run fast")
+	require.Equal(t, TargetShell, d.Target)
+	require.Equal(t, SourceParser, d.Source)
+}
+
+func TestDecisions(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRouter(t)
+	for range maxDecisions + 5 {
+		r.Route(context.Background(), "git status")
+	}
+	r.Route(context.Background(), "explain this")
+
+	decisions := r.Decisions()
+	require.Len(t, decisions, maxDecisions)
+	require.Equal(t, "explain this", decisions[len(decisions)-1].Input)
+}
@@ -0,0 +1,77 @@
+// Package sandbox runs the agent's commands with restricted access to the
+// machine.
+//
+// Sandboxed commands can read the whole file system but only write to the
+// directories of their Profile, have no network unless the profile allows
+// it, and run in their own process tree, which is killed as a whole when the
+// command is. On Linux this uses user, network and PID namespaces and
+// Landlock; other systems are not supported.
+//
+// Commands are started through an init process that applies the
+// restrictions before running them. It is the lash binary itself, which must
+// call Main first thing.
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Profile describes what sandboxed commands may do.
+type Profile struct {
+	// Writable are the directories commands may write to, and the files
+	// they may write. Everything else is read-only.
+	Writable []string `json:"writable"`
+	// Network allows commands to reach the network.
+	Network bool `json:"network"`
+}
+
+// CanWrite reports whether path is one of the writable paths or is inside
+// one of them.
+func (p *Profile) CanWrite(path string) bool {
+	path = filepath.Clean(path)
+	for _, writable := range p.Writable {
+		writable = filepath.Clean(writable)
+		if path == writable || strings.HasPrefix(path, writable+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return path == "/dev" || strings.HasPrefix(path, "/dev/")
+}
+
+// deniedOutput matches the errors commands print when the sandbox stops
+// them.
+var deniedOutput = regexp.MustCompile(`(?i)permission denied|read-only file system|operation not permitted|network is unreachable|could not resolve|temporary failure in name resolution|name or service not known`)
+
+// Explain returns a note on what the sandbox blocks if output looks like
+// the sandbox stopped a command, and "" otherwise.
+func (p *Profile) Explain(output string) string {
+	if !deniedOutput.MatchString(output) {
+		return ""
+	}
+	note := fmt.Sprintf("lash sandbox: commands may only write to %s", strings.Join(p.Writable, ", "))
+	if !p.Network {
+		note += " and have no network access"
+	}
+	return note + "; ask the user to run the command themselves if it needs more"
+}
+
+// ErrWriteDenied is the error of writes the sandbox denies in the shell
+// itself, such as redirections.
+var ErrWriteDenied = errors.New("writing here is not allowed by the lash sandbox")
+
+// DenyWrite returns the error for a write outside of the writable paths.
+func (p *Profile) DenyWrite() error {
+	return fmt.Errorf("%w, only %s are writable", ErrWriteDenied, strings.Join(p.Writable, ", "))
+}
+
+// spec is what the init process runs.
+type spec struct {
+	Profile Profile  `json:"profile"`
+	Path    string   `json:"path"`
+	Args    []string `json:"args"`
+	Env     []string `json:"env"`
+}
@@ -0,0 +1,216 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// initArg is the name the init process is started with.
+const initArg = "lash-sandbox-init"
+
+// specFD is the file descriptor the init process reads its spec from.
+const specFD = 3
+
+// Check reports why commands cannot be sandboxed on this machine, if they
+// cannot.
+func (p *Profile) Check() error {
+	if _, err := landlockABI(); err != nil {
+		return err
+	}
+	data, err := os.ReadFile("/proc/sys/user/max_user_namespaces")
+	if err == nil {
+		if n, _ := strconv.Atoi(strings.TrimSpace(string(data))); n == 0 {
+			return errors.New("user namespaces are disabled (user.max_user_namespaces is 0)")
+		}
+	}
+	if _, err := os.Executable(); err != nil {
+		return fmt.Errorf("could not find the lash executable: %w", err)
+	}
+	return nil
+}
+
+// Cmd is a command run in the sandbox. Set its standard streams and
+// directory on the embedded exec.Cmd, then start it with Start.
+type Cmd struct {
+	*exec.Cmd
+	spec spec
+}
+
+// Command returns a command that runs the program at path with args and env
+// in the sandbox.
+func (p *Profile) Command(path string, args, env []string) *Cmd {
+	self, _ := os.Executable()
+	cloneFlags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID)
+	if !p.Network {
+		cloneFlags |= syscall.CLONE_NEWNET
+	}
+	cmd := &exec.Cmd{
+		Path: self,
+		Args: []string{initArg},
+		Env:  []string{},
+		SysProcAttr: &syscall.SysProcAttr{
+			Cloneflags:  cloneFlags,
+			UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+			GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+			// Killing the init process kills everything in its PID
+			// namespace; make sure that happens if lash dies too.
+			Pdeathsig: syscall.SIGKILL,
+		},
+	}
+	return &Cmd{
+		Cmd:  cmd,
+		spec: spec{Profile: *p, Path: path, Args: args, Env: env},
+	}
+}
+
+// Start starts the init process and hands it the command to run.
+func (c *Cmd) Start() error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("could not start sandbox: %w", err)
+	}
+	defer r.Close()
+	c.ExtraFiles = []*os.File{r}
+	if err := c.Cmd.Start(); err != nil {
+		w.Close()
+		return err
+	}
+	err = json.NewEncoder(w).Encode(c.spec)
+	w.Close()
+	if err != nil {
+		_ = c.Process.Kill()
+		_ = c.Wait()
+		return fmt.Errorf("could not start sandbox: %w", err)
+	}
+	return nil
+}
+
+// Main runs the sandbox init process if the program was started as one, and
+// returns otherwise.
+func Main() {
+	if len(os.Args) == 0 || os.Args[0] != initArg {
+		return
+	}
+	if err := runInit(); err != nil {
+		fmt.Fprintf(os.Stderr, "lash sandbox: %v\n", err)
+		os.Exit(126)
+	}
+}
+
+// runInit restricts the process and replaces it with the command of its
+// spec. It only returns on failure.
+func runInit() error {
+	f := os.NewFile(specFD, "spec")
+	var s spec
+	err := json.NewDecoder(f).Decode(&s)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("could not read command: %w", err)
+	}
+
+	// Landlock and no_new_privs apply to the calling thread, which must be
+	// the one that execs.
+	runtime.LockOSThread()
+	if err := restrict(s.Profile); err != nil {
+		return err
+	}
+	if err := syscall.Exec(s.Path, s.Args, s.Env); err != nil {
+		return fmt.Errorf("could not run %s: %w", s.Path, err)
+	}
+	return nil
+}
+
+const (
+	readAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	// fileAccess are the rights that apply to files rather than directories.
+	fileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0, fmt.Errorf("landlock is not available: %w", errno)
+	}
+	return int(abi), nil
+}
+
+// handledAccess returns the file system rights Landlock can restrict at abi.
+func handledAccess(abi int) uint64 {
+	access := uint64(unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		access |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return access
+}
+
+// restrict makes the whole file system read-only for the calling thread,
+// except the writable paths of profile and /dev.
+func restrict(profile Profile) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return err
+	}
+	handled := handledAccess(abi)
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("could not create landlock ruleset: %w", errno)
+	}
+	ruleset := int(fd)
+	defer unix.Close(ruleset)
+
+	if err := addRule(ruleset, "/", readAccess); err != nil {
+		return err
+	}
+	for _, path := range append(profile.Writable, "/dev") {
+		if err := addRule(ruleset, path, handled); err != nil && !errors.Is(err, unix.ENOENT) {
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("could not set no_new_privs: %w", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(ruleset), 0, 0); errno != 0 {
+		return fmt.Errorf("could not apply landlock ruleset: %w", errno)
+	}
+	return nil
+}
+
+// addRule grants access beneath path.
+func addRule(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err == nil && st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= fileAccess
+	}
+	attr := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("could not allow %s: %w", path, errno)
+	}
+	return nil
+}
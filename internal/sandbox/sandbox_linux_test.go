@@ -0,0 +1,90 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	Main()
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, ctx context.Context, profile *Profile, dir, script string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := profile.Command("/bin/sh", []string{"sh", "-c", script}, []string{"PATH=" + os.Getenv("PATH")})
+	cmd.Dir = dir
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	require.NoError(t, cmd.Start())
+	stop := context.AfterFunc(ctx, func() { _ = cmd.Process.Kill() })
+	defer stop()
+	err := cmd.Wait()
+	return out.String(), err
+}
+
+func requireSandbox(t *testing.T) *Profile {
+	t.Helper()
+	profile := &Profile{Writable: []string{t.TempDir()}}
+	if err := profile.Check(); err != nil {
+		t.Skipf("sandbox not available: %v", err)
+	}
+	return profile
+}
+
+func TestSandboxWrites(t *testing.T) {
+	t.Parallel()
+	profile := requireSandbox(t)
+	workspace := profile.Writable[0]
+	outside := t.TempDir()
+
+	out, err := run(t, context.Background(), profile, workspace, "echo hi > inside.txt && mkdir sub && mv inside.txt sub/")
+	require.NoError(t, err, out)
+	require.FileExists(t, filepath.Join(workspace, "sub", "inside.txt"))
+
+	out, err = run(t, context.Background(), profile, workspace, "echo hi > "+filepath.Join(outside, "outside.txt"))
+	require.Error(t, err)
+	require.Contains(t, strings.ToLower(out), "permission denied")
+	require.NoFileExists(t, filepath.Join(outside, "outside.txt"))
+	require.NotEmpty(t, profile.Explain(out))
+
+	out, err = run(t, context.Background(), profile, workspace, "cat /etc/hostname >/dev/null && echo ok 2>/dev/null")
+	require.NoError(t, err, out)
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	t.Parallel()
+	profile := requireSandbox(t)
+
+	// Only the loopback interface exists in the network namespace.
+	out, err := run(t, context.Background(), profile, profile.Writable[0], "tail -n +3 /proc/net/dev | cut -d: -f1")
+	require.NoError(t, err, out)
+	require.Equal(t, "lo", strings.TrimSpace(out))
+
+	profile.Network = true
+	out, err = run(t, context.Background(), profile, profile.Writable[0], "tail -n +3 /proc/net/dev | cut -d: -f1")
+	require.NoError(t, err, out)
+	require.NotEqual(t, "lo", strings.TrimSpace(out))
+}
+
+func TestSandboxKillsProcessTree(t *testing.T) {
+	t.Parallel()
+	profile := requireSandbox(t)
+	workspace := profile.Writable[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err := run(t, ctx, profile, workspace, "(sleep 1; touch marker) & sleep 10")
+	require.Error(t, err)
+
+	time.Sleep(1500 * time.Millisecond)
+	require.NoFileExists(t, filepath.Join(workspace, "marker"))
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Check reports why commands cannot be sandboxed on this machine, if they
+// cannot.
+func (p *Profile) Check() error {
+	return errors.New("the sandbox is only supported on Linux")
+}
+
+// Cmd is a command run in the sandbox. Set its standard streams and
+// directory on the embedded exec.Cmd, then start it with Start.
+type Cmd struct {
+	*exec.Cmd
+}
+
+// Command returns a command that runs the program at path with args and env
+// in the sandbox.
+func (p *Profile) Command(path string, args, env []string) *Cmd {
+	return &Cmd{Cmd: &exec.Cmd{Path: path, Args: args, Env: env}}
+}
+
+// Start fails, as there is no sandbox on this system.
+func (c *Cmd) Start() error {
+	return errors.New("the sandbox is only supported on Linux")
+}
+
+// Main runs the sandbox init process if the program was started as one, and
+// returns otherwise.
+func Main() {}
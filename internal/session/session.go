@@ -18,8 +18,14 @@ type Session struct {
 	CompletionTokens int64
 	SummaryMessageID string
 	Cost             float64
+	AgentID          string
 	CreatedAt        int64
 	UpdatedAt        int64
+
+	// ForkedFromSessionID and ForkedFromMessageID are the session and the
+	// message this session was forked from, if any.
+	ForkedFromSessionID string
+	ForkedFromMessageID string
 }
 
 type Service interface {
@@ -27,6 +33,9 @@ type Service interface {
 	Create(ctx context.Context, title string) (Session, error)
 	CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error)
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
+	// CreateForkSession creates a session branching off parent at the
+	// message messageID, with the title and agent of parent.
+	CreateForkSession(ctx context.Context, parent Session, messageID string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
@@ -65,6 +74,31 @@ func (s *service) CreateTaskSession(ctx context.Context, toolCallID, parentSessi
 	return session, nil
 }
 
+func (s *service) CreateForkSession(ctx context.Context, parent Session, messageID string) (Session, error) {
+	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:                  uuid.New().String(),
+		Title:               parent.Title,
+		ForkedFromSessionID: parent.ID,
+		ForkedFromMessageID: messageID,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	if parent.AgentID != "" {
+		dbSession, err = s.q.UpdateSession(ctx, db.UpdateSessionParams{
+			ID:      dbSession.ID,
+			Title:   dbSession.Title,
+			AgentID: parent.AgentID,
+		})
+		if err != nil {
+			return Session{}, err
+		}
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
 func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error) {
 	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
 		ID:              "title-" + parentSessionID,
@@ -110,7 +144,8 @@ func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 			String: session.SummaryMessageID,
 			Valid:  session.SummaryMessageID != "",
 		},
-		Cost: session.Cost,
+		Cost:    session.Cost,
+		AgentID: session.AgentID,
 	})
 	if err != nil {
 		return Session{}, err
@@ -142,8 +177,12 @@ func (s service) fromDBItem(item db.Session) Session {
 		CompletionTokens: item.CompletionTokens,
 		SummaryMessageID: item.SummaryMessageID.String,
 		Cost:             item.Cost,
+		AgentID:          item.AgentID,
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
+
+		ForkedFromSessionID: item.ForkedFromSessionID,
+		ForkedFromMessageID: item.ForkedFromMessageID,
 	}
 }
 
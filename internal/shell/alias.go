@@ -0,0 +1,98 @@
+package shell
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// aliasTable tracks the aliases defined through the alias and unalias
+// builtins. The interpreter keeps its aliases private, so they are recorded
+// as commands run and replayed into the next interpreter.
+type aliasTable struct {
+	mu      sync.Mutex
+	aliases map[string]string
+}
+
+func newAliasTable(aliases map[string]string) *aliasTable {
+	t := &aliasTable{aliases: maps.Clone(aliases)}
+	if t.aliases == nil {
+		t.aliases = make(map[string]string)
+	}
+	return t
+}
+
+// callHandler is an interp.CallHandlerFunc that records alias changes. It
+// never alters the call.
+func (t *aliasTable) callHandler(_ context.Context, args []string) ([]string, error) {
+	if len(args) < 2 {
+		return args, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch args[0] {
+	case "alias":
+		for _, arg := range args[1:] {
+			name, value, ok := strings.Cut(arg, "=")
+			if ok && name != "" && !strings.HasPrefix(name, "-") && parsesAsWords(value) {
+				t.aliases[name] = value
+			}
+		}
+	case "unalias":
+		for _, arg := range args[1:] {
+			if arg == "-a" {
+				clear(t.aliases)
+				continue
+			}
+			delete(t.aliases, arg)
+		}
+	}
+	return args, nil
+}
+
+// snapshot returns a copy of the recorded aliases.
+func (t *aliasTable) snapshot() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.aliases)
+}
+
+// definitions returns a program that defines the recorded aliases, or nil if
+// there are none.
+func (t *aliasTable) definitions() *syntax.File {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.aliases) == 0 {
+		return nil
+	}
+
+	var src strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(t.aliases)) {
+		quoted, err := syntax.Quote(name+"="+t.aliases[name], syntax.LangBash)
+		if err != nil {
+			continue
+		}
+		src.WriteString("alias " + quoted + "\n")
+	}
+	file, err := syntax.NewParser().Parse(strings.NewReader(src.String()), "")
+	if err != nil {
+		return nil
+	}
+	return file
+}
+
+// parsesAsWords reports whether src is a valid alias value, matching what the
+// alias builtin accepts.
+func parsesAsWords(src string) bool {
+	for _, err := range syntax.NewParser().WordsSeq(strings.NewReader(src)) {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,625 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// CompletionKind tells what a completion candidate is.
+type CompletionKind int
+
+const (
+	CompletionFile CompletionKind = iota
+	CompletionDir
+	CompletionCommand
+	CompletionFlag
+	CompletionVariable
+	CompletionGitRef
+)
+
+// Completion is a candidate for the word being completed.
+type Completion struct {
+	// Text replaces the word being completed. It is quoted for the shell.
+	Text string
+	Kind CompletionKind
+}
+
+// Partial reports whether the completion is usually followed by more text,
+// like a directory, so no space should be added after it.
+func (c Completion) Partial() bool {
+	return c.Kind == CompletionDir
+}
+
+// completionTimeout bounds the helper programs run to find completions.
+const completionTimeout = 2 * time.Second
+
+// shellBuiltins are the builtins of the shell interpreter, offered in
+// command position.
+var shellBuiltins = []string{
+	".", ":", "[", "alias", "break", "builtin", "cd", "command", "continue",
+	"declare", "dirs", "echo", "eval", "exec", "exit", "export", "false",
+	"getopts", "let", "local", "mapfile", "popd", "printf", "pushd", "pwd",
+	"read", "readarray", "readonly", "return", "set", "shift", "source",
+	"test", "times", "trap", "true", "type", "umask", "unalias", "unset",
+	"wait",
+}
+
+// completionWrappers run the command that follows them, so the word after
+// them is completed as a command.
+var completionWrappers = append(slices.Clone(wrapperPrograms), "sudo", "xargs", "watch")
+
+// gitSubcommands are offered after git.
+var gitSubcommands = []string{
+	"add", "bisect", "blame", "branch", "checkout", "cherry-pick", "clean",
+	"clone", "commit", "config", "diff", "fetch", "grep", "init", "log",
+	"merge", "mv", "pull", "push", "rebase", "reflog", "remote", "reset",
+	"restore", "revert", "rm", "show", "stash", "status", "switch", "tag",
+	"worktree",
+}
+
+// gitRefCommands take a branch, tag or other ref as argument. Those marked
+// true also take paths.
+var gitRefCommands = map[string]bool{
+	"branch": false, "cherry-pick": false, "merge": false, "rebase": false,
+	"revert": false, "switch": false, "tag": false,
+	"checkout": true, "diff": true, "log": true, "reset": true,
+	"restore": true, "show": true,
+}
+
+// gitRemoteCommands take a remote and then a branch as arguments.
+var gitRemoteCommands = []string{"fetch", "pull", "push"}
+
+// flagPattern matches the options at the start of a line of help output,
+// like "-a, --all" or "--color[=WHEN]".
+var flagPattern = regexp.MustCompile(`(?:^|[\s,/])(--?[A-Za-z0-9][A-Za-z0-9_-]*)`)
+
+// overstrikePattern matches the bold and underline sequences of man output.
+var overstrikePattern = regexp.MustCompile(`.\x08`)
+
+// flagCache holds the flags parsed from the documentation of each command,
+// by man page and the path and modification time of the program, so that
+// a program updated or found elsewhere is read again.
+var flagCache sync.Map // map[flagCacheKey][]string
+
+type flagCacheKey struct {
+	page    string
+	path    string
+	modTime int64
+}
+
+// helpCommands are the programs whose --help output (-h for git) is read
+// for flags when they have no man page. Other programs are never run, as
+// one that doesn't know --help would do whatever it does.
+var helpCommands = []string{
+	"awk", "cargo", "cat", "chmod", "chown", "cp", "curl", "cut", "date", "df", "diff", "docker",
+	"du", "find", "gh", "git", "go", "grep", "gzip", "head", "kubectl", "less", "ln", "ls",
+	"make", "mkdir", "mv", "node", "npm", "pip", "pnpm", "ps", "python", "python3", "rg",
+	"rm", "rsync", "scp", "sed", "sort", "ssh", "tail", "tar", "touch", "tr", "uniq", "unzip",
+	"wc", "wget", "xargs", "yarn", "zip",
+}
+
+// completionLine describes the word under completion and the command it is
+// part of.
+type completionLine struct {
+	// args are the words of the current command before the completed word,
+	// with quotes removed.
+	args []string
+	// word is the completed word as typed, and value the same word with
+	// quotes removed.
+	word, value string
+	// start is the byte offset of word in the line.
+	start int
+	// redirect is set when the word is the target of a redirection.
+	redirect bool
+}
+
+// parseCompletionLine splits line into the words of the last command,
+// honoring quotes and escapes. The completed word is the one at the end of
+// line.
+func parseCompletionLine(line string) completionLine {
+	var (
+		cl       completionLine
+		value    strings.Builder
+		inWord   bool
+		quote    byte
+		escaped  bool
+		redirect bool
+	)
+	endWord := func() {
+		if inWord {
+			cl.args = append(cl.args, value.String())
+			if redirect {
+				// The target of a redirection isn't an argument.
+				cl.args = cl.args[:len(cl.args)-1]
+				redirect = false
+			}
+		}
+		value.Reset()
+		inWord = false
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if !inWord {
+			cl.start = i
+		}
+		switch {
+		case escaped:
+			escaped = false
+			value.WriteByte(c)
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(line) && strings.IndexByte(`"\$`+"`", line[i+1]) >= 0 {
+				escaped = true
+			} else {
+				value.WriteByte(c)
+			}
+		case c == '\\':
+			inWord = true
+			escaped = true
+		case c == '\'' || c == '"':
+			inWord = true
+			quote = c
+		case c == ' ' || c == '\t':
+			endWord()
+		case strings.IndexByte("|&;()\n", c) >= 0:
+			endWord()
+			cl.args = nil
+			redirect = false
+		case c == '<' || c == '>':
+			if inWord && strings.Trim(value.String(), "0123456789") == "" {
+				// A file descriptor, as in 2>.
+				value.Reset()
+				inWord = false
+			}
+			endWord()
+			redirect = true
+		default:
+			inWord = true
+			value.WriteByte(c)
+		}
+	}
+	if !inWord {
+		cl.start = len(line)
+	}
+	cl.word = line[cl.start:]
+	cl.value = value.String()
+	cl.redirect = redirect
+	return cl
+}
+
+// command returns the words of the command with leading assignments and
+// wrapper programs like sudo removed.
+func (cl completionLine) command() []string {
+	args := cl.args
+	for len(args) > 0 {
+		switch {
+		case isAssignment(args[0]):
+			args = args[1:]
+		case slices.Contains(completionWrappers, args[0]):
+			args = args[1:]
+			// Skip the wrapper's own options, like "sudo -u root".
+			for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+				args = args[1:]
+			}
+		default:
+			return args
+		}
+	}
+	return args
+}
+
+// isAssignment reports whether word is a variable assignment like FOO=bar.
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	return ok && isName(name)
+}
+
+// isName reports whether s is a valid shell variable name.
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && (i == 0 || !(c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// Complete returns the completions for the word at the end of line, like a
+// shell does on Tab: commands in command position, variables after $, flags
+// from the command's documentation, git refs and remotes, and paths
+// relative to the shell's working directory. It also returns the byte offset
+// in line of the word the completions replace.
+func (s *Shell) Complete(ctx context.Context, line string) (int, []Completion) {
+	cl := parseCompletionLine(line)
+	if i := strings.LastIndexByte(cl.word, '$'); i >= 0 && !strings.ContainsAny(cl.word[i:], `/"'}`+"\\") {
+		return cl.start, s.completeVariables(cl.word[:i], cl.word[i+1:])
+	}
+
+	args := cl.command()
+	cwd := s.GetWorkingDir()
+	switch {
+	case cl.redirect:
+		return cl.start, completePaths(cwd, cl.value, false)
+	case len(args) == 0 && !isAssignment(cl.value):
+		if strings.Contains(cl.value, "/") {
+			return cl.start, completePaths(cwd, cl.value, true)
+		}
+		return cl.start, s.completeCommands(cl.value)
+	case strings.HasPrefix(cl.value, "-"):
+		return cl.start, s.completeFlags(ctx, args, cl.value)
+	case len(args) > 0 && args[0] == "git":
+		if completions, ok := s.completeGit(ctx, args[1:], cl.value); ok {
+			return cl.start, completions
+		}
+	}
+	return cl.start, completePaths(cwd, cl.value, false)
+}
+
+// completeVariables completes the variable name after the $ in a word.
+// prefix is the part of the word before the $.
+func (s *Shell) completeVariables(prefix, name string) []Completion {
+	brace := strings.HasPrefix(name, "{")
+	name = strings.TrimPrefix(name, "{")
+	var completions []Completion
+	for _, kv := range s.GetEnv() {
+		v, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(v, name) || !isName(v) {
+			continue
+		}
+		text := prefix + "$" + v
+		if brace {
+			text = prefix + "${" + v + "}"
+		}
+		completions = append(completions, Completion{Text: text, Kind: CompletionVariable})
+	}
+	return sortCompletions(completions)
+}
+
+// completeCommands completes a command name from the aliases, functions,
+// builtins and the executables on PATH.
+func (s *Shell) completeCommands(prefix string) []Completion {
+	var names []string
+	names = append(names, slices.Collect(maps.Keys(s.GetAliases()))...)
+	names = append(names, s.GetFunctions()...)
+	names = append(names, shellBuiltins...)
+	names = append(names, s.executables()...)
+
+	var completions []Completion
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			completions = append(completions, Completion{Text: quoteWord(name), Kind: CompletionCommand})
+		}
+	}
+	return sortCompletions(completions)
+}
+
+// executables returns the names of the executable files in the directories
+// of the shell's PATH.
+func (s *Shell) executables() []string {
+	var names []string
+	for _, dir := range filepath.SplitList(s.getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if isExecutable(filepath.Join(dir, e.Name())) {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	return names
+}
+
+// getenv returns the value of the variable name in the shell's environment.
+func (s *Shell) getenv(name string) string {
+	for _, kv := range s.GetEnv() {
+		if v, value, ok := strings.Cut(kv, "="); ok && v == name {
+			return value
+		}
+	}
+	return ""
+}
+
+// isExecutable reports whether path is a file that can be executed.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// completeFlags completes an option of the command in args from the flags
+// listed in its documentation.
+func (s *Shell) completeFlags(ctx context.Context, args []string, prefix string) []Completion {
+	if len(args) == 0 {
+		return nil
+	}
+	var completions []Completion
+	for _, flag := range s.commandFlags(ctx, args) {
+		if strings.HasPrefix(flag, prefix) {
+			completions = append(completions, Completion{Text: flag, Kind: CompletionFlag})
+		}
+	}
+	return sortCompletions(completions)
+}
+
+// commandFlags returns the flags documented for the command in args. The man
+// page is preferred since reading it doesn't run the command; otherwise the
+// --help output of the helpCommands found on PATH is used. Results are
+// cached.
+func (s *Shell) commandFlags(ctx context.Context, args []string) []string {
+	page, helpArgs := args[0], []string{"--help"}
+	if args[0] == "git" {
+		// "git <cmd> --help" opens the manual, -h prints the usage.
+		helpArgs = []string{"-h"}
+		if sub := gitSubcommand(args[1:]); sub != "" {
+			page, helpArgs = "git-"+sub, []string{sub, "-h"}
+		}
+	}
+	key := flagCacheKey{page: page}
+	path, err := s.lookPath(args[0])
+	if err == nil {
+		if info, err := os.Stat(path); err == nil {
+			key.path, key.modTime = path, info.ModTime().UnixNano()
+		}
+	}
+	if flags, ok := flagCache.Load(key); ok {
+		return flags.([]string)
+	}
+
+	flags := parseFlags(s.runHelper(ctx, "man", page))
+	if len(flags) == 0 && key.path != "" && slices.Contains(helpCommands, args[0]) {
+		flags = parseFlags(s.runHelper(ctx, path, helpArgs...))
+	}
+	flagCache.Store(key, flags)
+	return flags
+}
+
+// lookPath resolves name against the shell's PATH, which may differ from
+// lash's own once rc files have been loaded.
+func (s *Shell) lookPath(name string) (string, error) {
+	return interp.LookPathDir(s.GetWorkingDir(), expand.ListEnviron(s.GetEnv()...), name)
+}
+
+// runHelper runs a program found on the shell's PATH in the shell's working
+// directory and environment to gather completions, returning its combined
+// output.
+func (s *Shell) runHelper(ctx context.Context, name string, args ...string) string {
+	path, err := s.lookPath(name)
+	if err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = s.GetWorkingDir()
+	cmd.Env = append(s.GetEnv(), "MANPAGER=cat", "PAGER=cat", "MANWIDTH=200", "GIT_PAGER=cat")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Help output often comes with a non-zero exit status.
+	_ = cmd.Run()
+	return out.String()
+}
+
+// parseFlags returns the flags listed in help or man output, in order of
+// appearance. Only the option column of a line is considered, so options
+// mentioned in descriptions are ignored.
+func parseFlags(help string) []string {
+	help = overstrikePattern.ReplaceAllString(help, "")
+	var flags []string
+	for line := range strings.SplitSeq(help, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-") {
+			continue
+		}
+		// The description starts after a run of spaces or a tab.
+		if i := strings.Index(line, "  "); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.IndexByte(line, '\t'); i >= 0 {
+			line = line[:i]
+		}
+		for _, m := range flagPattern.FindAllStringSubmatch(line, -1) {
+			if !slices.Contains(flags, m[1]) {
+				flags = append(flags, m[1])
+			}
+		}
+	}
+	return flags
+}
+
+// completeGit completes the arguments of git subcommands. It reports false
+// when the argument should be completed as a path.
+func (s *Shell) completeGit(ctx context.Context, args []string, prefix string) ([]Completion, bool) {
+	sub := gitSubcommand(args)
+	if sub == "" {
+		var completions []Completion
+		for _, name := range gitSubcommands {
+			if strings.HasPrefix(name, prefix) {
+				completions = append(completions, Completion{Text: name, Kind: CompletionCommand})
+			}
+		}
+		return completions, true
+	}
+
+	var names []string
+	withPaths, takesRefs := gitRefCommands[sub]
+	switch {
+	case takesRefs:
+		names = s.gitRefs(ctx)
+	case slices.Contains(gitRemoteCommands, sub):
+		if positional(args) == 1 {
+			names = s.gitLines(ctx, "remote")
+		} else {
+			names = s.gitRefs(ctx)
+		}
+	default:
+		return nil, false
+	}
+
+	var completions []Completion
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			completions = append(completions, Completion{Text: quoteWord(name), Kind: CompletionGitRef})
+		}
+	}
+	completions = sortCompletions(completions)
+	if withPaths {
+		completions = append(completions, completePaths(s.GetWorkingDir(), prefix, false)...)
+	}
+	return completions, true
+}
+
+// gitSubcommand returns the git subcommand in args, skipping git's global
+// options.
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-C" || arg == "-c":
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+	return ""
+}
+
+// positional returns how many arguments in args, which start with a
+// subcommand, are not options.
+func positional(args []string) int {
+	var n int
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			n++
+		}
+	}
+	return n
+}
+
+// gitRefs returns the local branches, tags and remote branches of the
+// repository in the shell's working directory.
+func (s *Shell) gitRefs(ctx context.Context) []string {
+	var refs []string
+	for _, ref := range s.gitLines(ctx, "for-each-ref", "--format=%(refname)", "refs/heads", "refs/tags", "refs/remotes") {
+		if strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		for _, prefix := range []string{"refs/heads/", "refs/tags/", "refs/remotes/"} {
+			if name, ok := strings.CutPrefix(ref, prefix); ok {
+				refs = append(refs, name)
+				break
+			}
+		}
+	}
+	return refs
+}
+
+// gitLines runs git with args and returns the non-empty lines it prints.
+func (s *Shell) gitLines(ctx context.Context, args ...string) []string {
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	path, err := s.lookPath("git")
+	if err != nil {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = s.GetWorkingDir()
+	cmd.Env = s.GetEnv()
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(out))
+}
+
+// completePaths completes a path relative to cwd. A leading ~ stands for the
+// home directory and is kept. If executables is set only directories and
+// executable files are returned. Hidden files are only offered when the
+// typed name starts with a dot.
+func completePaths(cwd, word string, executables bool) []Completion {
+	dir, base := filepath.Split(word)
+	listDir := dir
+	if home, err := os.UserHomeDir(); err == nil {
+		if listDir == "~/" || strings.HasPrefix(listDir, "~/") {
+			listDir = filepath.Join(home, listDir[1:])
+		}
+	}
+	if listDir == "" {
+		listDir = "."
+	}
+	if !filepath.IsAbs(listDir) {
+		listDir = filepath.Join(cwd, listDir)
+	}
+
+	entries, err := os.ReadDir(listDir)
+	if err != nil {
+		return nil
+	}
+	var completions []Completion
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		full := filepath.Join(listDir, name)
+		info, err := os.Stat(full)
+		if err != nil {
+			continue
+		}
+		c := Completion{Text: quoteWord(dir + name), Kind: CompletionFile}
+		switch {
+		case info.IsDir():
+			c.Text += "/"
+			c.Kind = CompletionDir
+		case executables && !isExecutable(full):
+			continue
+		}
+		completions = append(completions, c)
+	}
+	return sortCompletions(completions)
+}
+
+// quoteWord escapes the characters of word that are special to the shell. A
+// leading ~ is left alone so that it still expands.
+func quoteWord(word string) string {
+	var sb strings.Builder
+	for i, c := range word {
+		if i == 0 && c == '~' {
+			sb.WriteRune(c)
+			continue
+		}
+		if strings.ContainsRune(" \t\n\\'\"`$&|;<>()*?[]#!{}", c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// sortCompletions sorts completions by text and removes duplicates.
+func sortCompletions(completions []Completion) []Completion {
+	slices.SortFunc(completions, func(a, b Completion) int {
+		return strings.Compare(a.Text, b.Text)
+	})
+	return slices.CompactFunc(completions, func(a, b Completion) bool {
+		return a.Text == b.Text
+	})
+}
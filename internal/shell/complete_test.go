@@ -0,0 +1,231 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func completionTexts(completions []Completion) []string {
+	texts := make([]string, len(completions))
+	for i, c := range completions {
+		texts[i] = c.Text
+	}
+	return texts
+}
+
+func TestParseCompletionLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		args     []string
+		word     string
+		value    string
+		redirect bool
+	}{
+		{line: "", word: ""},
+		{line: "gi", word: "gi", value: "gi"},
+		{line: "git ch", args: []string{"git"}, word: "ch", value: "ch"},
+		{line: "ls -la ", args: []string{"ls", "-la"}, word: ""},
+		{line: `cat "my fi`, args: []string{"cat"}, word: `"my fi`, value: "my fi"},
+		{line: `cat my\ fi`, args: []string{"cat"}, word: `my\ fi`, value: "my fi"},
+		{line: "make build && go te", args: []string{"go"}, word: "te", value: "te"},
+		{line: "echo hi > ou", args: []string{"echo", "hi"}, word: "ou", value: "ou", redirect: true},
+		{line: "cmd 2>/dev/null ar", args: []string{"cmd"}, word: "ar", value: "ar"},
+	}
+	for _, tt := range tests {
+		cl := parseCompletionLine(tt.line)
+		if !slices.Equal(cl.args, tt.args) || cl.word != tt.word || cl.value != tt.value || cl.redirect != tt.redirect {
+			t.Errorf("parseCompletionLine(%q) = args %q, word %q, value %q, redirect %v", tt.line, cl.args, cl.word, cl.value, cl.redirect)
+		}
+		if cl.start+len(cl.word) != len(tt.line) {
+			t.Errorf("parseCompletionLine(%q): word does not end the line", tt.line)
+		}
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	cl := parseCompletionLine("FOO=1 sudo -E git pu")
+	if args := cl.command(); !slices.Equal(args, []string{"git"}) {
+		t.Fatalf("Expected command [git], got %q", args)
+	}
+}
+
+func TestCompletePaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "make file", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "mod"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mod", "run.sh"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	shell := NewShell(&Options{WorkingDir: dir})
+	ctx := context.Background()
+
+	start, completions := shell.Complete(ctx, "cat m")
+	if start != 4 {
+		t.Fatalf("Expected completions to start at 4, got %d", start)
+	}
+	if got := completionTexts(completions); !slices.Equal(got, []string{"main.go", `make\ file`, "mod/"}) {
+		t.Fatalf("Unexpected completions %q", got)
+	}
+	if !completions[2].Partial() {
+		t.Fatalf("Expected directory completion to be partial")
+	}
+
+	_, completions = shell.Complete(ctx, "cat .")
+	if got := completionTexts(completions); !slices.Equal(got, []string{".hidden"}) {
+		t.Fatalf("Expected hidden file to be completed, got %q", got)
+	}
+
+	_, completions = shell.Complete(ctx, "./mod/r")
+	if got := completionTexts(completions); !slices.Equal(got, []string{"./mod/run.sh"}) {
+		t.Fatalf("Expected executable to be completed, got %q", got)
+	}
+	_, completions = shell.Complete(ctx, "./m")
+	if got := completionTexts(completions); !slices.Equal(got, []string{"./mod/"}) {
+		t.Fatalf("Expected only directories and executables, got %q", got)
+	}
+}
+
+func TestCompleteCommandsAndVariables(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "lashtool"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	shell := NewShell(&Options{
+		WorkingDir: t.TempDir(),
+		Env:        []string{"PATH=" + bin, "LASH_ONE=1", "LASH_TWO=2"},
+	})
+	ctx := context.Background()
+	if _, stderr, err := shell.Exec(ctx, "lashfn() { :; }; alias lashalias='echo'"); err != nil {
+		t.Fatalf("Defining failed: %v, stderr: %s", err, stderr)
+	}
+
+	_, completions := shell.Complete(ctx, "echo hi | lash")
+	if got := completionTexts(completions); !slices.Equal(got, []string{"lashalias", "lashfn", "lashtool"}) {
+		t.Fatalf("Unexpected command completions %q", got)
+	}
+
+	start, completions := shell.Complete(ctx, `echo "$LASH_`)
+	if start != 5 {
+		t.Fatalf("Expected completions to start at 5, got %d", start)
+	}
+	if got := completionTexts(completions); !slices.Equal(got, []string{`"$LASH_ONE`, `"$LASH_TWO`}) {
+		t.Fatalf("Unexpected variable completions %q", got)
+	}
+
+	_, completions = shell.Complete(ctx, "echo ${LASH_O")
+	if got := completionTexts(completions); !slices.Equal(got, []string{"${LASH_ONE}"}) {
+		t.Fatalf("Unexpected braced variable completions %q", got)
+	}
+}
+
+// writeScript writes an executable shell script with the given body to dir.
+func writeScript(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompleteFlagsReadsManPage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses shell scripts")
+	}
+	// The programs are only on the shell's PATH, not on the one of the
+	// process.
+	bin := t.TempDir()
+	writeScript(t, bin, "man", "[ \"$1\" = lashflags ] && echo '  --lash-flag    from the manual'\n")
+	writeScript(t, bin, "lashflags", "touch \"$LASH_MARKER\"\n")
+	marker := filepath.Join(t.TempDir(), "ran")
+	shell := NewShell(&Options{
+		WorkingDir: t.TempDir(),
+		Env:        []string{"PATH=" + bin, "LASH_MARKER=" + marker},
+	})
+
+	_, completions := shell.Complete(context.Background(), "lashflags --lash")
+	if got := completionTexts(completions); !slices.Equal(got, []string{"--lash-flag"}) {
+		t.Fatalf("Unexpected flag completions %q", got)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatal("Expected the program not to run")
+	}
+
+	// Flags are cached by program, not by name.
+	other := t.TempDir()
+	writeScript(t, other, "man", "[ \"$1\" = lashflags ] && echo '  --lash-other   from another manual'\n")
+	writeScript(t, other, "lashflags", "")
+	shell = NewShell(&Options{
+		WorkingDir: t.TempDir(),
+		Env:        []string{"PATH=" + other},
+	})
+	_, completions = shell.Complete(context.Background(), "lashflags --lash")
+	if got := completionTexts(completions); !slices.Equal(got, []string{"--lash-other"}) {
+		t.Fatalf("Unexpected flag completions of another program %q", got)
+	}
+}
+
+func TestCompleteFlagsDoesNotRunUnknownPrograms(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses shell scripts")
+	}
+	bin := t.TempDir()
+	writeScript(t, bin, "lashrun", "touch \"$LASH_MARKER\"\necho '  --lash-flag    a flag'\n")
+	marker := filepath.Join(t.TempDir(), "ran")
+	shell := NewShell(&Options{
+		WorkingDir: t.TempDir(),
+		Env:        []string{"PATH=" + bin, "LASH_MARKER=" + marker},
+	})
+
+	_, completions := shell.Complete(context.Background(), "lashrun --lash")
+	if len(completions) != 0 {
+		t.Fatalf("Expected no flag completions, got %q", completionTexts(completions))
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatal("Expected the program not to run for its help")
+	}
+}
+
+func TestCompleteGitRefsUsesShellPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses shell scripts")
+	}
+	bin := t.TempDir()
+	writeScript(t, bin, "git", "echo refs/heads/lash-branch\n")
+	shell := NewShell(&Options{
+		WorkingDir: t.TempDir(),
+		Env:        []string{"PATH=" + bin},
+	})
+
+	_, completions := shell.Complete(context.Background(), "git checkout lash-")
+	if got := completionTexts(completions); !slices.Equal(got, []string{"lash-branch"}) {
+		t.Fatalf("Unexpected ref completions %q", got)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	// man shows bold text by overstriking each character.
+	var bold strings.Builder
+	for _, c := range "--version" {
+		bold.WriteString(string(c) + "\b" + string(c))
+	}
+	help := "Usage: ls [OPTION]... [FILE]...\n" +
+		"  -a, --all                  do not ignore entries starting with .\n" +
+		"      --color[=WHEN]         color the output; see --hyperlink\n" +
+		"  -l                         use a long listing format\n" +
+		"      " + bold.String() + "  output version information and exit\n"
+	flags := parseFlags(help)
+	if !slices.Equal(flags, []string{"-a", "--all", "--color", "-l", "--version"}) {
+		t.Fatalf("Unexpected flags %q", flags)
+	}
+}
@@ -16,10 +16,10 @@ package shell
 //	shell.Exec(ctx, "export FOO=bar")
 //	shell.Exec(ctx, "echo $FOO")  // Will print "bar"
 //
-// 3. For the singleton persistent shell (used by tools):
+// 3. For the shells of a session, which keep their state across restarts:
 //
-//	shell := shell.GetPersistentShell("/path/to/cwd")
-//	stdout, stderr, err := shell.Exec(ctx, "ls -la")
+//	shells := shell.NewManager("/path/to/cwd", store)
+//	stdout, stderr, err := shells.Agent(sessionID).Exec(ctx, "ls -la")
 //
 // 4. Managing environment and working directory:
 //
@@ -28,3 +28,10 @@ package shell
 //	shell.SetWorkingDir("/tmp")
 //	cwd := shell.GetWorkingDir()
 //	env := shell.GetEnv()
+//
+// 5. Loading rc files, whose functions and aliases persist across commands:
+//
+//	for _, w := range shells.LoadRC(ctx, shell.DefaultRCFiles()...) {
+//	    log.Println(w)
+//	}
+//	shells.User(sessionID).Exec(ctx, "ll")  // Runs the ll alias from ~/.bashrc
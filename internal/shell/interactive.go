@@ -0,0 +1,142 @@
+package shell
+
+import (
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxAliasDepth bounds alias expansion when looking for the program a
+// command runs, in case aliases refer to each other.
+const maxAliasDepth = 8
+
+// terminalPrograms are full-screen or prompting programs that need a real
+// terminal whatever their arguments.
+var terminalPrograms = []string{
+	"btop", "emacs", "fzf", "htop", "hx", "k9s", "lazygit", "less", "man",
+	"mc", "micro", "more", "mosh", "most", "nano", "nnn", "nvim", "ranger",
+	"screen", "ssh", "sudo", "tig", "tmux", "top", "vi", "vim", "watch",
+}
+
+// replPrograms start an interactive prompt when run without a script or an
+// inline program.
+var replPrograms = []string{
+	"bash", "deno", "fish", "ghci", "ipython", "irb", "lua", "mysql", "node",
+	"psql", "python", "python3", "redis-cli", "sh", "sqlite3", "zsh",
+}
+
+// inlineProgramFlags run a program given on the command line instead of
+// starting a REPL.
+var inlineProgramFlags = []string{"-c", "-e", "--command", "--eval", "--execute"}
+
+// wrapperPrograms run the command that follows them.
+var wrapperPrograms = []string{"command", "env", "exec", "nice", "nohup", "time"}
+
+// NeedsTerminal reports whether command runs an interactive program that
+// must be attached to the real terminal, such as an editor, a pager or a
+// REPL. Programs in extra always need the terminal. Aliases defined in the
+// shell are expanded before checking.
+func (s *Shell) NeedsTerminal(command string, extra []string) bool {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return false
+	}
+	aliases := s.GetAliases()
+
+	var found bool
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if found {
+			return false
+		}
+		if call, ok := node.(*syntax.CallExpr); ok {
+			found = needsTerminal(literalArgs(call.Args), aliases, extra)
+		}
+		return !found
+	})
+	return found
+}
+
+// literalArgs returns the literal words of args, stopping at the first word
+// that needs expansion.
+func literalArgs(args []*syntax.Word) []string {
+	var lits []string
+	for _, arg := range args {
+		lit := arg.Lit()
+		if lit == "" {
+			break
+		}
+		lits = append(lits, lit)
+	}
+	return lits
+}
+
+func needsTerminal(args []string, aliases map[string]string, extra []string) bool {
+	for depth := 0; len(args) > 0; depth++ {
+		name := args[0]
+		value, ok := aliases[name]
+		if !ok || depth >= maxAliasDepth {
+			break
+		}
+		args = append(strings.Fields(value), args[1:]...)
+	}
+	for len(args) > 0 && (slices.Contains(wrapperPrograms, args[0]) || strings.Contains(args[0], "=")) {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return false
+	}
+
+	name, args := args[0], args[1:]
+	switch {
+	case slices.Contains(extra, name), slices.Contains(terminalPrograms, name):
+		return true
+	case slices.Contains(replPrograms, name):
+		return isREPL(args)
+	case name == "git":
+		return isInteractiveGit(args)
+	}
+	return false
+}
+
+// isREPL reports whether args start a REPL rather than run a program.
+func isREPL(args []string) bool {
+	for _, arg := range args {
+		if slices.Contains(inlineProgramFlags, arg) || !strings.HasPrefix(arg, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// isInteractiveGit reports whether a git invocation opens an editor or an
+// interactive prompt.
+func isInteractiveGit(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	sub, flags := args[0], args[1:]
+	has := func(names ...string) bool {
+		return slices.ContainsFunc(flags, func(flag string) bool {
+			return slices.ContainsFunc(names, func(name string) bool {
+				if short, ok := strings.CutPrefix(name, "-"); ok && len(short) == 1 &&
+					strings.HasPrefix(flag, "-") && !strings.HasPrefix(flag, "--") {
+					// Short flags may be combined, as in "-am".
+					return strings.Contains(flag[1:], short)
+				}
+				return flag == name || strings.HasPrefix(flag, name+"=")
+			})
+		})
+	}
+	switch sub {
+	case "rebase":
+		return has("-i", "--interactive")
+	case "add", "checkout", "restore", "reset", "stash":
+		return has("-p", "--patch", "-i", "--interactive")
+	case "commit":
+		return !has("-m", "--message", "-F", "--file", "-C", "--reuse-message", "--no-edit", "--fixup")
+	case "mergetool", "difftool":
+		return true
+	}
+	return false
+}
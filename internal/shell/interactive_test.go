@@ -0,0 +1,47 @@
+package shell
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNeedsTerminal(t *testing.T) {
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+	if _, _, err := shell.Exec(context.Background(), "alias e='nvim -p'; alias l='ls -la'"); err != nil {
+		t.Fatalf("Defining aliases failed: %v", err)
+	}
+
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"vim main.go", true},
+		{"cd src && less README.md", true},
+		{"git log | less", true},
+		{"e main.go", true},
+		{"python3", true},
+		{"python3 -q", true},
+		{"python3 script.py", false},
+		{"python3 -c 'print(1)'", false},
+		{"node -e 'console.log(1)'", false},
+		{"git rebase -i HEAD~3", true},
+		{"git rebase main", false},
+		{"git add -p", true},
+		{"git commit", true},
+		{"git commit -am 'fix it'", false},
+		{"git commit --no-edit", false},
+		{"env TERM=xterm htop", true},
+		{"FOO=bar vim", true},
+		{"mycli --interactive", true},
+		{"ls -la", false},
+		{"l", false},
+		{"go test ./...", false},
+		{"echo vim", false},
+		{"if true; then vim; fi", true},
+	}
+	for _, tt := range tests {
+		if got := shell.NeedsTerminal(tt.command, []string{"mycli"}); got != tt.want {
+			t.Errorf("NeedsTerminal(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
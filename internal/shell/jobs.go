@@ -0,0 +1,728 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/pubsub"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// jobOutputSize is how many bytes of output are kept for each job.
+const jobOutputSize = 256 * 1024
+
+// maxFinishedJobs is how many finished jobs are kept until they are reported
+// by the jobs builtin.
+const maxFinishedJobs = 10
+
+// JobState is the state of a background job.
+type JobState string
+
+const (
+	JobRunning JobState = "running"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+	JobKilled  JobState = "killed"
+)
+
+// JobInfo describes a background job at a point in time.
+type JobInfo struct {
+	ID        int
+	SessionID string
+	// Shell is the kind of shell that started the job.
+	Shell Kind
+	// Name is the name the job was started with by StartJob, if any.
+	Name       string
+	Command    string
+	Cwd        string
+	State      JobState
+	ExitCode   int
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Finished reports whether the job is no longer running.
+func (j JobInfo) Finished() bool {
+	return j.State != JobRunning
+}
+
+// Status describes the state of the job like the jobs builtin does.
+func (j JobInfo) Status() string {
+	switch j.State {
+	case JobRunning:
+		return "Running"
+	case JobFailed:
+		return fmt.Sprintf("Exit %d", j.ExitCode)
+	case JobKilled:
+		return "Killed"
+	default:
+		return "Done"
+	}
+}
+
+// Job is a command started in the background with "&", or by StartJob. Its
+// output is kept in a ring buffer.
+type Job struct {
+	mu     sync.Mutex
+	info   JobInfo
+	output *ringBuffer
+	cancel context.CancelFunc
+	done   chan struct{}
+	// stdin is written to by WriteInput, for jobs started by StartJob.
+	stdin *os.File
+	procs processSet
+	// read is how much output ReadOutput has returned.
+	read int64
+}
+
+// Info returns the current state of the job.
+func (j *Job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info
+}
+
+// Output returns the most recent output of the job, stdout and stderr
+// interleaved.
+func (j *Job) Output() string {
+	return j.output.String()
+}
+
+// ReadOutput returns the output of the job since the previous call, stdout
+// and stderr interleaved. dropped reports whether some output was lost in
+// between because the job wrote more than is kept.
+func (j *Job) ReadOutput() (output string, dropped bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	output, j.read, dropped = j.output.since(j.read)
+	return output, dropped
+}
+
+// WriteInput writes input to the standard input of the job.
+func (j *Job) WriteInput(input string) error {
+	if j.stdin == nil {
+		return errors.New("job does not take input")
+	}
+	_, err := io.WriteString(j.stdin, input)
+	return err
+}
+
+// CloseInput closes the standard input of the job, so its programs read the
+// end of the input.
+func (j *Job) CloseInput() error {
+	if j.stdin == nil {
+		return errors.New("job does not take input")
+	}
+	return j.stdin.Close()
+}
+
+// Signal sends sig to the programs the job is running.
+func (j *Job) Signal(sig os.Signal) error {
+	return j.procs.signal(sig)
+}
+
+// Kill stops the job. Programs are interrupted, then killed if they don't
+// exit. Its input is closed too, since builtins like cat cannot be
+// interrupted while they wait for it.
+func (j *Job) Kill() {
+	j.cancel()
+	if j.stdin != nil {
+		j.stdin.Close()
+	}
+}
+
+// Done returns a channel that is closed when the job finishes.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// ringBuffer keeps the last size bytes written to it. Writes are also copied
+// to the attached writer, if any. It is safe for concurrent use.
+type ringBuffer struct {
+	mu      sync.Mutex
+	buf     []byte
+	size    int
+	dropped bool
+	tee     io.Writer
+	// written is how many bytes were ever written.
+	written int64
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{size: size}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	b.written += int64(len(p))
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = b.buf[over:]
+		b.dropped = true
+	}
+	if b.tee != nil {
+		b.tee.Write(p) //nolint:errcheck
+	}
+	return len(p), nil
+}
+
+// String returns the buffered output. Once output was dropped, the partial
+// first line is left out.
+func (b *ringBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.string()
+}
+
+func (b *ringBuffer) string() string {
+	buf := b.buf
+	if b.dropped {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		}
+	}
+	return string(buf)
+}
+
+// since returns the output written after the first offset bytes, and the
+// offset to read from next time. dropped reports whether some of that output
+// is no longer buffered.
+func (b *ringBuffer) since(offset int64) (output string, next int64, dropped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start := b.written - int64(len(b.buf))
+	if offset < start {
+		offset = start
+		dropped = true
+	}
+	return string(b.buf[offset-start:]), b.written, dropped
+}
+
+// attach writes the buffered output to w, then copies new output to it until
+// the returned function is called.
+func (b *ringBuffer) attach(w io.Writer) (detach func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	io.WriteString(w, b.string()) //nolint:errcheck
+	b.tee = w
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.tee = nil
+	}
+}
+
+// jobTable holds the background jobs of a shell.
+type jobTable struct {
+	mu     sync.Mutex
+	jobs   []*Job
+	events *pubsub.Broker[JobInfo]
+	// kind is the kind of shell the jobs belong to.
+	kind Kind
+}
+
+func newJobTable() *jobTable {
+	return &jobTable{events: pubsub.NewBroker[JobInfo]()}
+}
+
+// add registers a job under the lowest free ID above the existing ones.
+func (t *jobTable) add(j *Job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := 1
+	if n := len(t.jobs); n > 0 {
+		id = t.jobs[n-1].info.ID + 1
+	}
+	j.info.ID = id
+	t.jobs = append(t.jobs, j)
+	t.events.Publish(pubsub.CreatedEvent, j.info)
+}
+
+// remove drops j from the table.
+func (t *jobTable) remove(j *Job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i := slices.Index(t.jobs, j); i >= 0 {
+		t.jobs = slices.Delete(t.jobs, i, i+1)
+		t.events.Publish(pubsub.DeletedEvent, j.Info())
+	}
+}
+
+// prune drops the oldest finished jobs beyond maxFinishedJobs.
+func (t *jobTable) prune() {
+	var finished []*Job
+	t.mu.Lock()
+	for _, j := range t.jobs {
+		if j.Info().Finished() {
+			finished = append(finished, j)
+		}
+	}
+	t.mu.Unlock()
+	for len(finished) > maxFinishedJobs {
+		t.remove(finished[0])
+		finished = finished[1:]
+	}
+}
+
+func (t *jobTable) list() []*Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.jobs)
+}
+
+// find returns the job named by spec: %N or N for job N, %% or %+ for the
+// current job, %- for the previous one, %prefix for the job whose command
+// starts with prefix and %?text for the one whose command contains text.
+// An empty spec is the current job.
+func (t *jobTable) find(spec string) (*Job, error) {
+	jobs := t.list()
+	if len(jobs) == 0 {
+		return nil, errors.New("no current job")
+	}
+	name := strings.TrimPrefix(spec, "%")
+	switch {
+	case name == "" || name == "%" || name == "+":
+		return jobs[len(jobs)-1], nil
+	case name == "-":
+		if len(jobs) < 2 {
+			return nil, fmt.Errorf("%s: no such job", spec)
+		}
+		return jobs[len(jobs)-2], nil
+	}
+	if id, err := strconv.Atoi(name); err == nil {
+		for _, j := range jobs {
+			if j.Info().ID == id {
+				return j, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: no such job", spec)
+	}
+	match := func(cmd string) bool { return strings.HasPrefix(cmd, name) }
+	if text, ok := strings.CutPrefix(name, "?"); ok {
+		match = func(cmd string) bool { return strings.Contains(cmd, text) }
+	}
+	for _, j := range slices.Backward(jobs) {
+		if match(j.Info().Command) {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+// SubscribeJobs returns a channel of events for the shell's background jobs:
+// created when a job starts, updated when it finishes and deleted once it
+// is no longer listed.
+func (s *Shell) SubscribeJobs(ctx context.Context) <-chan pubsub.Event[JobInfo] {
+	return s.jobs.events.Subscribe(ctx)
+}
+
+// Jobs returns the background jobs of the shell, oldest first.
+func (s *Shell) Jobs() []JobInfo {
+	jobs := s.jobs.list()
+	infos := make([]JobInfo, len(jobs))
+	for i, j := range jobs {
+		infos[i] = j.Info()
+	}
+	return infos
+}
+
+// Job returns the background job with the given ID.
+func (s *Shell) Job(id int) (*Job, bool) {
+	j, err := s.jobs.find(strconv.Itoa(id))
+	return j, err == nil
+}
+
+// KillJobs stops all the background jobs of the shell.
+func (s *Shell) KillJobs() {
+	for _, j := range s.jobs.list() {
+		j.Kill()
+	}
+}
+
+// startJob runs stmt in the background in a copy of runner, so it doesn't
+// share state with the commands that follow.
+func (s *Shell) startJob(ctx context.Context, runner *interp.Runner, stmt *syntax.Stmt) (*Job, error) {
+	fg := *stmt
+	fg.Background = false
+	var sb strings.Builder
+	if err := syntax.NewPrinter(syntax.SingleLine(true)).Print(&sb, &fg); err != nil {
+		return nil, err
+	}
+	return s.runJob(ctx, runner.Subshell(), &fg, JobInfo{Command: sb.String()}, nil, nil)
+}
+
+// StartJob starts command in the background as a job called name. Unlike
+// jobs started with "&", its programs can be given input with WriteInput and
+// sent signals with Signal. The values of ctx, such as the command policy,
+// apply to the job, but its cancellation does not. Only one running job can
+// have a given name.
+func (s *Shell) StartJob(ctx context.Context, name, command string) (*Job, error) {
+	if j, ok := s.NamedJob(name); ok && !j.Info().Finished() {
+		return nil, fmt.Errorf("a job called %q is already running", name)
+	}
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, fmt.Errorf("could not parse command: %w", err)
+	}
+	runner, _, err := s.newRunner(nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create runner: %w", err)
+	}
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("could not create job input: %w", err)
+	}
+	return s.runJob(ctx, runner, file, JobInfo{Name: name, Command: command}, stdin, input)
+}
+
+// NamedJob returns the most recent job called name.
+func (s *Shell) NamedJob(name string) (*Job, bool) {
+	for _, j := range slices.Backward(s.jobs.list()) {
+		if j.Info().Name == name {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// runJob runs node with runner in the background. stdin and input, if not
+// nil, are the reading and writing ends of a pipe for the standard input of
+// the job. They are closed when the job finishes.
+func (s *Shell) runJob(ctx context.Context, runner *interp.Runner, node syntax.Node, info JobInfo, stdin, input *os.File) (*Job, error) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	info.SessionID = s.sessionID
+	info.Shell = s.jobs.kind
+	info.Cwd = runner.Dir
+	info.State = JobRunning
+	info.StartedAt = time.Now()
+	j := &Job{
+		info:   info,
+		output: newRingBuffer(jobOutputSize),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		stdin:  input,
+	}
+	closeInput := func() {
+		if stdin != nil {
+			stdin.Close()
+			input.Close()
+		}
+	}
+	var in io.Reader
+	if stdin != nil {
+		in = stdin
+	}
+	if err := interp.StdIO(in, j.output, j.output)(runner); err != nil {
+		cancel()
+		closeInput()
+		return nil, err
+	}
+	s.jobs.add(j)
+
+	go func() {
+		defer cancel()
+		err := runner.Run(withProcessSet(ctx, &j.procs), node)
+		closeInput()
+
+		j.mu.Lock()
+		j.info.FinishedAt = time.Now()
+		j.info.ExitCode = ExitCode(err)
+		switch {
+		case ctx.Err() != nil:
+			j.info.State = JobKilled
+		case j.info.ExitCode != 0:
+			j.info.State = JobFailed
+		default:
+			j.info.State = JobDone
+		}
+		info := j.info
+		j.mu.Unlock()
+
+		close(j.done)
+		s.logger.InfoPersist("Background job finished", "job", info.ID, "command", info.Command, "err", err)
+		s.jobs.events.Publish(pubsub.UpdatedEvent, info)
+		s.jobs.prune()
+	}()
+	return j, nil
+}
+
+// hasBackgroundStmt reports whether a top-level statement of file runs in
+// the background.
+func hasBackgroundStmt(file *syntax.File) bool {
+	return slices.ContainsFunc(file.Stmts, func(stmt *syntax.Stmt) bool {
+		return stmt.Background
+	})
+}
+
+// runWithJobs runs the statements of file one by one, starting those that
+// end with "&" as jobs.
+func (s *Shell) runWithJobs(ctx context.Context, runner *interp.Runner, file *syntax.File, stderr io.Writer) error {
+	var err error
+	for _, stmt := range file.Stmts {
+		if stmt.Background {
+			j, jobErr := s.startJob(ctx, runner, stmt)
+			if jobErr != nil {
+				return fmt.Errorf("could not start job: %w", jobErr)
+			}
+			info := j.Info()
+			fmt.Fprintf(stderr, "[%d] %s\n", info.ID, info.Command)
+			err = nil
+			continue
+		}
+		err = runner.Run(ctx, stmt)
+		if runner.Exited() || ctx.Err() != nil {
+			break
+		}
+		if err != nil && !errors.As(err, new(interp.ExitStatus)) {
+			break
+		}
+	}
+	return err
+}
+
+// jobBuiltinPrefix renames the fg, bg and wait builtins of the interpreter
+// so that calls reach jobsHandler instead.
+const jobBuiltinPrefix = "lash-job-"
+
+// jobsCallHandler wraps next so that fg, bg, and wait with job specs, are
+// handled by jobsHandler.
+func jobsCallHandler(next interp.CallHandlerFunc) interp.CallHandlerFunc {
+	return func(ctx context.Context, args []string) ([]string, error) {
+		args, err := next(ctx, args)
+		if err != nil || len(args) == 0 {
+			return args, err
+		}
+		switch args[0] {
+		case "fg", "bg":
+		case "wait":
+			if len(args) == 1 || !slices.ContainsFunc(args[1:], func(arg string) bool {
+				return strings.HasPrefix(arg, "%")
+			}) {
+				return args, nil
+			}
+		default:
+			return args, nil
+		}
+		args = slices.Clone(args)
+		args[0] = jobBuiltinPrefix + args[0]
+		return args, nil
+	}
+}
+
+// jobsHandler implements the job control builtins: jobs, fg, bg, wait and
+// kill with job specs.
+func (s *Shell) jobsHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			if len(args) == 0 {
+				return next(ctx, args)
+			}
+			hc := interp.HandlerCtx(ctx)
+			name := strings.TrimPrefix(args[0], jobBuiltinPrefix)
+			var err error
+			switch args[0] {
+			case "jobs":
+				err = s.jobsBuiltin(hc.Stdout, args[1:])
+			case jobBuiltinPrefix + "fg":
+				err = s.fgBuiltin(ctx, hc.Stdout, args[1:])
+			case jobBuiltinPrefix + "bg":
+				err = s.bgBuiltin(hc.Stderr, args[1:])
+			case jobBuiltinPrefix + "wait":
+				err = s.waitBuiltin(ctx, args[1:])
+			case "kill":
+				var rest []string
+				rest, err = s.killBuiltin(args[1:])
+				if err == nil && rest != nil {
+					return next(ctx, append([]string{"kill"}, rest...))
+				}
+			default:
+				return next(ctx, args)
+			}
+			var status interp.ExitStatus
+			if err != nil && !errors.As(err, &status) && ctx.Err() == nil {
+				fmt.Fprintf(hc.Stderr, "%s: %v\n", name, err)
+				return interp.ExitStatus(1)
+			}
+			return err
+		}
+	}
+}
+
+// jobsBuiltin lists the jobs, then forgets those that finished, like bash.
+// With -l the working directory of each job is shown, with -r only running
+// jobs are listed.
+func (s *Shell) jobsBuiltin(w io.Writer, args []string) error {
+	var long, running bool
+	var specs []string
+	for _, arg := range args {
+		switch arg {
+		case "-l":
+			long = true
+		case "-r":
+			running = true
+		default:
+			specs = append(specs, arg)
+		}
+	}
+
+	jobs := s.jobs.list()
+	if len(specs) > 0 {
+		jobs = jobs[:0:0]
+		for _, spec := range specs {
+			j, err := s.jobs.find(spec)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, j)
+		}
+	}
+	all := s.jobs.list()
+	for _, j := range jobs {
+		info := j.Info()
+		if running && info.Finished() {
+			continue
+		}
+		mark := " "
+		switch {
+		case len(all) > 0 && all[len(all)-1] == j:
+			mark = "+"
+		case len(all) > 1 && all[len(all)-2] == j:
+			mark = "-"
+		}
+		command := info.Command
+		if !info.Finished() {
+			command += " &"
+		}
+		fmt.Fprintf(w, "[%d]%s  %-22s  %s", info.ID, mark, info.Status(), command)
+		if long {
+			fmt.Fprintf(w, "  (wd: %s)", info.Cwd)
+		}
+		fmt.Fprintln(w)
+		if info.Finished() {
+			s.jobs.remove(j)
+		}
+	}
+	return nil
+}
+
+// fgBuiltin shows the output of a job and waits for it to finish, returning
+// its exit status. Interrupting fg kills the job.
+func (s *Shell) fgBuiltin(ctx context.Context, w io.Writer, args []string) error {
+	var spec string
+	if len(args) > 0 {
+		spec = args[0]
+	}
+	j, err := s.jobs.find(spec)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, j.Info().Command)
+	detach := j.output.attach(w)
+	defer detach()
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		j.Kill()
+		<-j.done
+		s.jobs.remove(j)
+		return ctx.Err()
+	}
+	s.jobs.remove(j)
+	if code := j.Info().ExitCode; code != 0 {
+		return interp.ExitStatus(code)
+	}
+	return nil
+}
+
+// waitBuiltin waits for the given jobs to finish and returns the exit status
+// of the last one.
+func (s *Shell) waitBuiltin(ctx context.Context, args []string) error {
+	var code int
+	for _, spec := range args {
+		j, err := s.jobs.find(spec)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		code = j.Info().ExitCode
+	}
+	if code != 0 {
+		return interp.ExitStatus(code)
+	}
+	return nil
+}
+
+// bgBuiltin reports the given jobs. Jobs can't be stopped, so they always
+// run in the background already.
+func (s *Shell) bgBuiltin(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	for _, spec := range args {
+		j, err := s.jobs.find(spec)
+		if err != nil {
+			return err
+		}
+		info := j.Info()
+		if info.Finished() {
+			fmt.Fprintf(w, "bg: job %d has finished\n", info.ID)
+			continue
+		}
+		fmt.Fprintf(w, "bg: job %d already in background\n", info.ID)
+	}
+	return nil
+}
+
+// killBuiltin kills the jobs given by spec. Signal options are accepted but
+// jobs are always interrupted, then killed. The arguments that are not job
+// specs are returned, with the options, for the kill program; rest is nil
+// if there are none.
+func (s *Shell) killBuiltin(args []string) (rest []string, err error) {
+	var opts, pids []string
+	var jobs []*Job
+	for i, arg := range args {
+		switch {
+		case arg == "-s" || arg == "-n":
+			opts = append(opts, args[i:min(i+2, len(args))]...)
+		case strings.HasPrefix(arg, "-") && len(pids) == 0 && len(jobs) == 0:
+			opts = append(opts, arg)
+		case strings.HasPrefix(arg, "%"):
+			j, err := s.jobs.find(arg)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, j)
+		default:
+			if i > 0 && (args[i-1] == "-s" || args[i-1] == "-n") {
+				continue
+			}
+			pids = append(pids, arg)
+		}
+	}
+	if len(jobs) == 0 {
+		return append([]string{}, args...), nil
+	}
+	for _, j := range jobs {
+		j.Kill()
+	}
+	if len(pids) == 0 {
+		return nil, nil
+	}
+	return append(opts, pids...), nil
+}
@@ -0,0 +1,206 @@
+package shell
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newJobShell(t *testing.T) *Shell {
+	t.Helper()
+	s := NewShell(&Options{WorkingDir: t.TempDir(), JobControl: true})
+	t.Cleanup(s.KillJobs)
+	return s
+}
+
+func waitJob(t *testing.T, s *Shell, id int) JobInfo {
+	t.Helper()
+	j, ok := s.Job(id)
+	if !ok {
+		t.Fatalf("job %d not found", id)
+	}
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %d did not finish", id)
+	}
+	return j.Info()
+}
+
+func TestBackgroundJob(t *testing.T) {
+	s := newJobShell(t)
+	stdout, stderr, err := s.Exec(context.Background(), "echo hello & echo after")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if stdout != "after\n" {
+		t.Fatalf("Expected the foreground output only, got %q", stdout)
+	}
+	if stderr != "[1] echo hello\n" {
+		t.Fatalf("Expected the job to be announced, got %q", stderr)
+	}
+
+	info := waitJob(t, s, 1)
+	if info.State != JobDone || info.ExitCode != 0 {
+		t.Fatalf("Expected job to be done, got %+v", info)
+	}
+	j, _ := s.Job(1)
+	if out := j.Output(); out != "hello\n" {
+		t.Fatalf("Expected job output to be kept, got %q", out)
+	}
+}
+
+func TestJobsBuiltin(t *testing.T) {
+	s := newJobShell(t)
+	if _, _, err := s.Exec(context.Background(), "sleep 10 &\nfalse &"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	waitJob(t, s, 2)
+
+	stdout, _, err := s.Exec(context.Background(), "jobs")
+	if err != nil {
+		t.Fatalf("jobs failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], "[1]-  Running") || !strings.HasSuffix(lines[0], "sleep 10 &") ||
+		!strings.HasPrefix(lines[1], "[2]+  Exit 1") {
+		t.Fatalf("Unexpected jobs output:\n%s", stdout)
+	}
+
+	// Finished jobs are forgotten once reported.
+	if jobs := s.Jobs(); len(jobs) != 1 || jobs[0].ID != 1 {
+		t.Fatalf("Expected only the running job to be left, got %+v", jobs)
+	}
+}
+
+func TestKillJob(t *testing.T) {
+	s := newJobShell(t)
+	if _, _, err := s.Exec(context.Background(), "sleep 10 &"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, stderr, err := s.Exec(context.Background(), "kill %sleep"); err != nil {
+		t.Fatalf("kill failed: %v: %s", err, stderr)
+	}
+	if info := waitJob(t, s, 1); info.State != JobKilled {
+		t.Fatalf("Expected job to be killed, got %+v", info)
+	}
+
+	_, stderr, err := s.Exec(context.Background(), "kill %5")
+	if ExitCode(err) != 1 || !strings.Contains(stderr, "%5: no such job") {
+		t.Fatalf("Expected an unknown job to fail, got %v: %q", err, stderr)
+	}
+}
+
+func TestFgJob(t *testing.T) {
+	s := newJobShell(t)
+	if _, _, err := s.Exec(context.Background(), "(echo out; sleep 0.2; exit 3) &"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	stdout, _, err := s.Exec(context.Background(), "fg %1")
+	if ExitCode(err) != 3 {
+		t.Fatalf("Expected the exit status of the job, got %v", err)
+	}
+	if !strings.Contains(stdout, "out\n") {
+		t.Fatalf("Expected the job output, got %q", stdout)
+	}
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Fatalf("Expected the job to be gone, got %+v", jobs)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	b := newRingBuffer(8)
+	b.Write([]byte("one\ntwo\nthree\n")) //nolint:errcheck
+	if got := b.String(); got != "three\n" {
+		t.Fatalf("Expected the last complete lines, got %q", got)
+	}
+}
+
+func TestNamedJob(t *testing.T) {
+	s := newJobShell(t)
+	j, err := s.StartJob(context.Background(), "cat", "cat")
+	if err != nil {
+		t.Fatalf("StartJob failed: %v", err)
+	}
+	if _, err := s.StartJob(context.Background(), "cat", "cat"); err == nil {
+		t.Fatalf("Expected a duplicate name to be rejected")
+	}
+	if found, ok := s.NamedJob("cat"); !ok || found != j {
+		t.Fatalf("Expected the job to be found by name")
+	}
+
+	if err := j.WriteInput("hello\n"); err != nil {
+		t.Fatalf("WriteInput failed: %v", err)
+	}
+	var out string
+	for deadline := time.Now().Add(5 * time.Second); out != "hello\n"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the input to be echoed, got %q", out)
+		}
+		time.Sleep(10 * time.Millisecond)
+		chunk, _ := j.ReadOutput()
+		out += chunk
+	}
+	if chunk, _ := j.ReadOutput(); chunk != "" {
+		t.Fatalf("Expected output to be read once, got %q", chunk)
+	}
+
+	if err := j.CloseInput(); err != nil {
+		t.Fatalf("CloseInput failed: %v", err)
+	}
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the job to exit at the end of its input")
+	}
+	if info := j.Info(); info.State != JobDone || info.Name != "cat" {
+		t.Fatalf("Expected the named job to be done, got %+v", info)
+	}
+}
+
+func TestSignalJob(t *testing.T) {
+	s := newJobShell(t)
+	j, err := s.StartJob(context.Background(), "sleeper", "sleep 10")
+	if err != nil {
+		t.Fatalf("StartJob failed: %v", err)
+	}
+	sig, err := ParseSignal("TERM")
+	if err != nil {
+		t.Fatalf("ParseSignal failed: %v", err)
+	}
+	// The program may not have started yet.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if err := j.Signal(sig); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Signal failed: %v", err)
+		}
+	}
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the job to exit on SIGTERM")
+	}
+	if info := j.Info(); info.State != JobFailed || info.ExitCode != 143 {
+		t.Fatalf("Expected the job to fail with 143, got %+v", info)
+	}
+}
+
+func TestKillJobReadingInput(t *testing.T) {
+	s := newJobShell(t)
+	j, err := s.StartJob(context.Background(), "cat", "cat")
+	if err != nil {
+		t.Fatalf("StartJob failed: %v", err)
+	}
+	j.Kill()
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a builtin waiting for input to be killed")
+	}
+	if info := j.Info(); info.State != JobKilled {
+		t.Fatalf("Expected the job to be killed, got %+v", info)
+	}
+}
@@ -0,0 +1,504 @@
+package shell
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lacymorrow/lash/internal/pubsub"
+	"github.com/lacymorrow/lash/internal/sandbox"
+)
+
+// Kind is the role of a shell in a session.
+type Kind string
+
+const (
+	// AgentShell runs the commands of the agent's bash tool.
+	AgentShell Kind = "agent"
+	// UserShell runs the commands typed in Shell mode. It loads the user's
+	// rc files and has job control.
+	UserShell Kind = "user"
+)
+
+// SyncPolicy is what the agent shell and the user shell of a session share.
+type SyncPolicy string
+
+const (
+	// SyncNone keeps the two shells isolated.
+	SyncNone SyncPolicy = "none"
+	// SyncCwd makes a cd in one shell move the other one too.
+	SyncCwd SyncPolicy = "cwd"
+	// SyncEnv shares the working directory and the environment, so that
+	// activating a virtualenv in one shell activates it in the other.
+	SyncEnv SyncPolicy = "env"
+)
+
+// State is what is kept of a session's shell between runs of lash: its
+// working directory and the environment variables that differ from the
+// environment lash started with.
+type State struct {
+	Cwd string            `json:"cwd"`
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// StateStore persists the state of session shells.
+type StateStore interface {
+	// LoadShellState returns the saved state of a shell, and false if there
+	// is none.
+	LoadShellState(ctx context.Context, sessionID string, kind Kind) (State, bool, error)
+	SaveShellState(ctx context.Context, sessionID string, kind Kind, state State) error
+}
+
+// volatileVars are maintained by the interpreter itself and not worth
+// restoring.
+var volatileVars = []string{"PWD", "OLDPWD", "SHLVL", "_"}
+
+type shellKey struct {
+	sessionID string
+	kind      Kind
+}
+
+// sibling returns the key of the other shell of the session.
+func (k shellKey) sibling() shellKey {
+	if k.kind == AgentShell {
+		return shellKey{k.sessionID, UserShell}
+	}
+	return shellKey{k.sessionID, AgentShell}
+}
+
+// managedShell is a shell being created, or created, by a Manager.
+type managedShell struct {
+	ready chan struct{}
+	shell *Shell
+}
+
+// Manager hands out an agent shell and a user shell per session, so a cd or
+// an export in one session does not change where another one runs commands.
+// Shells are created on first use from the state saved in the store, and
+// their state is saved back after every command. Input given before a
+// session exists goes to the shells of the empty session ID, which are
+// never saved.
+type Manager struct {
+	workingDir string
+	baseEnv    map[string]string
+	store      StateStore
+	jobs       *pubsub.Broker[JobInfo]
+
+	mu      sync.Mutex
+	shells  map[shellKey]*managedShell
+	saved   map[shellKey]*State
+	rcFiles []string
+	policy  SyncPolicy
+	sandbox *sandbox.Profile
+	// lastEnv is the environment of each shell after its last command, to
+	// tell what a command changed.
+	lastEnv map[shellKey]map[string]string
+}
+
+// NewManager creates a manager whose shells start in workingDir unless
+// their session says otherwise. A nil store keeps state in memory only.
+func NewManager(workingDir string, store StateStore) *Manager {
+	return &Manager{
+		workingDir: workingDir,
+		baseEnv:    envMap(os.Environ()),
+		store:      store,
+		jobs:       pubsub.NewBroker[JobInfo](),
+		shells:     make(map[shellKey]*managedShell),
+		saved:      make(map[shellKey]*State),
+		policy:     SyncNone,
+		lastEnv:    make(map[shellKey]map[string]string),
+	}
+}
+
+// SetSandbox runs the programs of agent shells created from now on in a
+// sandbox with profile. A nil profile turns the sandbox off.
+func (m *Manager) SetSandbox(profile *sandbox.Profile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sandbox = profile
+}
+
+// SetSyncPolicy sets what the agent and user shells of a session share from
+// now on. An empty policy is SyncNone.
+func (m *Manager) SetSyncPolicy(policy SyncPolicy) {
+	if policy == "" {
+		policy = SyncNone
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = policy
+}
+
+// Divergence describes how the agent shell and the user shell of a session
+// differ.
+type Divergence struct {
+	AgentCwd string
+	UserCwd  string
+	// Env lists the variables whose values differ. It is only filled in
+	// when the environment is synced, as the shells start from different
+	// environments otherwise.
+	Env []string
+}
+
+// Diverged reports whether the shells differ.
+func (d Divergence) Diverged() bool {
+	return d.AgentCwd != d.UserCwd || len(d.Env) > 0
+}
+
+// Divergence compares the agent shell and the user shell of a session. It
+// reports no divergence until both shells exist.
+func (m *Manager) Divergence(sessionID string) Divergence {
+	m.mu.Lock()
+	agent := m.ready(shellKey{sessionID, AgentShell})
+	user := m.ready(shellKey{sessionID, UserShell})
+	policy := m.policy
+	m.mu.Unlock()
+	if agent == nil || user == nil {
+		return Divergence{}
+	}
+
+	d := Divergence{
+		AgentCwd: agent.GetWorkingDir(),
+		UserCwd:  user.GetWorkingDir(),
+	}
+	if policy == SyncEnv {
+		agentEnv := envMap(agent.GetEnv())
+		userEnv := envMap(user.GetEnv())
+		for name, value := range agentEnv {
+			if v, ok := userEnv[name]; (!ok || v != value) && !slices.Contains(volatileVars, name) {
+				d.Env = append(d.Env, name)
+			}
+		}
+		for name := range userEnv {
+			if _, ok := agentEnv[name]; !ok && !slices.Contains(volatileVars, name) {
+				d.Env = append(d.Env, name)
+			}
+		}
+		slices.Sort(d.Env)
+	}
+	return d
+}
+
+// Agent returns the shell of the agent's bash tool in a session.
+func (m *Manager) Agent(sessionID string) *Shell {
+	return m.get(shellKey{sessionID, AgentShell}, nil)
+}
+
+// User returns the Shell mode shell of a session.
+func (m *Manager) User(sessionID string) *Shell {
+	return m.get(shellKey{sessionID, UserShell}, nil)
+}
+
+// Fork gives a new session an agent shell that starts in the working
+// directory and environment of the agent shell of parentID, as task
+// sub-agents do. It does nothing if the session already has one.
+func (m *Manager) Fork(parentID, sessionID string) {
+	parent := m.Agent(parentID)
+	m.get(shellKey{sessionID, AgentShell}, parent)
+}
+
+// WorkingDir returns the working directory of the user shell of a session
+// without waiting for the shell to be created.
+func (m *Manager) WorkingDir(sessionID string) string {
+	key := shellKey{sessionID, UserShell}
+	m.mu.Lock()
+	s := m.ready(key)
+	m.mu.Unlock()
+	if s != nil {
+		return s.GetWorkingDir()
+	}
+	if state := m.load(key); state != nil && isDir(state.Cwd) {
+		return state.Cwd
+	}
+	return m.workingDir
+}
+
+// LoadRC sets the rc files loaded into every user shell, loads them into the
+// user shell used before a session exists, and returns what could not be
+// loaded. It is meant to be called once at startup.
+func (m *Manager) LoadRC(ctx context.Context, files ...string) []RCWarning {
+	m.mu.Lock()
+	m.rcFiles = files
+	m.mu.Unlock()
+	return m.User("").LoadRC(ctx, files...)
+}
+
+// SubscribeJobs returns a channel of events for the background jobs of all
+// the shells. Jobs carry the ID of their session and the kind of their
+// shell.
+func (m *Manager) SubscribeJobs(ctx context.Context) <-chan pubsub.Event[JobInfo] {
+	return m.jobs.Subscribe(ctx)
+}
+
+// Remove forgets the shells of a deleted session and stops their background
+// jobs. Its saved state is left to the store, which deletes it with the
+// session.
+func (m *Manager) Remove(sessionID string) {
+	var removed []*managedShell
+	m.mu.Lock()
+	for _, kind := range []Kind{AgentShell, UserShell} {
+		key := shellKey{sessionID, kind}
+		if ms, ok := m.shells[key]; ok {
+			removed = append(removed, ms)
+		}
+		delete(m.shells, key)
+		delete(m.saved, key)
+		delete(m.lastEnv, key)
+	}
+	m.mu.Unlock()
+	for _, ms := range removed {
+		<-ms.ready
+		ms.shell.KillJobs()
+	}
+}
+
+// Close stops the background jobs of all the shells, and waits for them to
+// finish for a little longer than programs are given to exit.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	shells := slices.Collect(maps.Values(m.shells))
+	m.mu.Unlock()
+	var jobs []*Job
+	for _, ms := range shells {
+		<-ms.ready
+		ms.shell.KillJobs()
+		jobs = append(jobs, ms.shell.jobs.list()...)
+	}
+	timeout := time.After(killTimeout + time.Second)
+	for _, j := range jobs {
+		select {
+		case <-j.Done():
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// get returns the shell for key, creating it from parent, or else from the
+// saved state, if it doesn't exist yet.
+func (m *Manager) get(key shellKey, parent *Shell) *Shell {
+	m.mu.Lock()
+	ms, ok := m.shells[key]
+	if !ok {
+		ms = &managedShell{ready: make(chan struct{})}
+		m.shells[key] = ms
+	}
+	m.mu.Unlock()
+	if ok {
+		<-ms.ready
+		return ms.shell
+	}
+
+	ms.shell = m.newShell(key, parent)
+	close(ms.ready)
+	return ms.shell
+}
+
+func (m *Manager) newShell(key shellKey, parent *Shell) *Shell {
+	opts := &Options{
+		WorkingDir: m.workingDir,
+		Logger:     &loggingAdapter{},
+		SessionID:  key.sessionID,
+		JobControl: key.kind == UserShell,
+	}
+	if key.kind == AgentShell {
+		m.mu.Lock()
+		opts.Sandbox = m.sandbox
+		m.mu.Unlock()
+	}
+	if parent != nil {
+		opts.WorkingDir = parent.GetWorkingDir()
+		opts.Env = parent.GetEnv()
+	}
+	s := NewShell(opts)
+
+	s.jobs.events = m.jobs
+	s.jobs.kind = key.kind
+	if key.kind == UserShell {
+		m.mu.Lock()
+		files := m.rcFiles
+		m.mu.Unlock()
+		for _, w := range s.LoadRC(context.Background(), files...) {
+			slog.Warn("Shell rc file not fully loaded", "session_id", key.sessionID, "warning", w.String())
+		}
+	}
+	if parent == nil {
+		if state := m.load(key); state != nil {
+			if isDir(state.Cwd) {
+				_ = s.SetWorkingDir(state.Cwd)
+			}
+			for name, value := range state.Env {
+				s.SetEnv(name, value)
+			}
+		}
+	}
+
+	// Start where the other shell of the session is, if they are synced.
+	m.mu.Lock()
+	policy := m.policy
+	sibling := m.ready(key.sibling())
+	m.mu.Unlock()
+	if sibling != nil && policy != SyncNone {
+		_ = s.SetWorkingDir(sibling.GetWorkingDir())
+		if policy == SyncEnv {
+			for name, value := range envMap(sibling.GetEnv()) {
+				if !slices.Contains(volatileVars, name) {
+					s.SetEnv(name, value)
+				}
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.lastEnv[key] = envMap(s.GetEnv())
+	m.mu.Unlock()
+	s.afterCommand = func() {
+		m.commandDone(key, s)
+	}
+	return s
+}
+
+// ready returns the shell for key if it has been created. m.mu must be
+// held.
+func (m *Manager) ready(key shellKey) *Shell {
+	ms, ok := m.shells[key]
+	if !ok {
+		return nil
+	}
+	select {
+	case <-ms.ready:
+		return ms.shell
+	default:
+		return nil
+	}
+}
+
+// commandDone syncs and saves the state of s after it ran a command. It
+// does nothing once s has been removed.
+func (m *Manager) commandDone(key shellKey, s *Shell) {
+	m.mu.Lock()
+	if m.ready(key) != s {
+		m.mu.Unlock()
+		return
+	}
+	policy := m.policy
+	prev := m.lastEnv[key]
+	env := envMap(s.GetEnv())
+	m.lastEnv[key] = env
+	sibling := m.ready(key.sibling())
+	m.mu.Unlock()
+
+	if key.sessionID != "" {
+		m.save(key, s)
+	}
+	if sibling == nil || policy == SyncNone {
+		return
+	}
+
+	if cwd := s.GetWorkingDir(); cwd != sibling.GetWorkingDir() {
+		_ = sibling.SetWorkingDir(cwd)
+	}
+	if policy == SyncEnv {
+		// Only pass on what the command changed, so variables that only
+		// one of the shells has, like those from rc files, are kept.
+		for name, value := range env {
+			if old, ok := prev[name]; (!ok || old != value) && !slices.Contains(volatileVars, name) {
+				sibling.SetEnv(name, value)
+			}
+		}
+		for name := range prev {
+			if _, ok := env[name]; !ok {
+				sibling.UnsetEnv(name)
+			}
+		}
+		m.mu.Lock()
+		m.lastEnv[key.sibling()] = envMap(sibling.GetEnv())
+		m.mu.Unlock()
+	}
+	if key.sessionID != "" {
+		m.save(key.sibling(), sibling)
+	}
+}
+
+// load returns the saved state of the shell for key, or nil.
+func (m *Manager) load(key shellKey) *State {
+	if key.sessionID == "" || m.store == nil {
+		return nil
+	}
+	m.mu.Lock()
+	state, ok := m.saved[key]
+	m.mu.Unlock()
+	if ok {
+		return state
+	}
+
+	loaded, found, err := m.store.LoadShellState(context.Background(), key.sessionID, key.kind)
+	if err != nil {
+		slog.Error("Failed to load shell state", "session_id", key.sessionID, "kind", key.kind, "error", err)
+	}
+	if found {
+		state = &loaded
+	}
+	m.mu.Lock()
+	m.saved[key] = state
+	m.mu.Unlock()
+	return state
+}
+
+// save stores the state of s if it changed since it was last saved.
+func (m *Manager) save(key shellKey, s *Shell) {
+	state := &State{Cwd: s.GetWorkingDir()}
+	for name, value := range envMap(s.GetEnv()) {
+		if base, ok := m.baseEnv[name]; ok && base == value {
+			continue
+		}
+		if slices.Contains(volatileVars, name) {
+			continue
+		}
+		if state.Env == nil {
+			state.Env = make(map[string]string)
+		}
+		state.Env[name] = value
+	}
+
+	m.mu.Lock()
+	prev := m.saved[key]
+	m.saved[key] = state
+	m.mu.Unlock()
+	if prev != nil && prev.Cwd == state.Cwd && maps.Equal(prev.Env, state.Env) {
+		return
+	}
+	if m.store == nil {
+		return
+	}
+	if err := m.store.SaveShellState(context.Background(), key.sessionID, key.kind, *state); err != nil {
+		slog.Error("Failed to save shell state", "session_id", key.sessionID, "kind", key.kind, "error", err)
+	}
+}
+
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			m[name] = value
+		}
+	}
+	return m
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// loggingAdapter adapts slog to the Logger interface.
+type loggingAdapter struct{}
+
+func (l *loggingAdapter) InfoPersist(msg string, keysAndValues ...any) {
+	slog.Info(msg, keysAndValues...)
+}
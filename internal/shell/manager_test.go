@@ -0,0 +1,221 @@
+package shell
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	mu     sync.Mutex
+	states map[shellKey]State
+}
+
+func (s *memoryStore) LoadShellState(ctx context.Context, sessionID string, kind Kind) (State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[shellKey{sessionID, kind}]
+	return state, ok, nil
+}
+
+func (s *memoryStore) SaveShellState(ctx context.Context, sessionID string, kind Kind, state State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = make(map[shellKey]State)
+	}
+	s.states[shellKey{sessionID, kind}] = state
+	return nil
+}
+
+func TestManagerIsolatesSessions(t *testing.T) {
+	dir := t.TempDir()
+	sub := t.TempDir()
+	m := NewManager(dir, nil)
+
+	if _, _, err := m.Agent("a").Exec(context.Background(), "cd "+sub+" && export FOO=a"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got := m.Agent("a").GetWorkingDir(); got != sub {
+		t.Fatalf("Expected session a to be in %s, got %s", sub, got)
+	}
+	if got := m.Agent("b").GetWorkingDir(); got != dir {
+		t.Fatalf("Expected session b to stay in %s, got %s", dir, got)
+	}
+	stdout, _, _ := m.Agent("b").Exec(context.Background(), "echo -n $FOO")
+	if stdout != "" {
+		t.Fatalf("Expected FOO not to leak into session b, got %q", stdout)
+	}
+	if got := m.User("a").GetWorkingDir(); got != dir {
+		t.Fatalf("Expected the user shell to be separate from the agent shell, got %s", got)
+	}
+}
+
+func TestManagerRestoresState(t *testing.T) {
+	dir := t.TempDir()
+	sub := t.TempDir()
+	store := &memoryStore{}
+
+	m := NewManager(dir, store)
+	if _, _, err := m.User("a").Exec(context.Background(), "cd "+sub+" && export FOO=bar"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	state, ok, _ := store.LoadShellState(context.Background(), "a", UserShell)
+	if !ok || state.Cwd != sub || state.Env["FOO"] != "bar" {
+		t.Fatalf("Expected the state to be saved, got %+v", state)
+	}
+	if _, ok := state.Env["PATH"]; ok {
+		t.Fatalf("Expected unchanged variables not to be saved, got %+v", state.Env)
+	}
+
+	m = NewManager(dir, store)
+	if got := m.WorkingDir("a"); got != sub {
+		t.Fatalf("Expected the saved working directory before the shell exists, got %s", got)
+	}
+	stdout, _, err := m.User("a").Exec(context.Background(), "echo -n $FOO $PWD")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if stdout != "bar "+sub {
+		t.Fatalf("Expected the state to be restored, got %q", stdout)
+	}
+}
+
+func TestManagerFork(t *testing.T) {
+	dir := t.TempDir()
+	sub := t.TempDir()
+	m := NewManager(dir, nil)
+	if _, _, err := m.Agent("parent").Exec(context.Background(), "cd "+sub); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	m.Fork("parent", "child")
+	if got := m.Agent("child").GetWorkingDir(); got != sub {
+		t.Fatalf("Expected the child to start in %s, got %s", sub, got)
+	}
+	if _, _, err := m.Agent("child").Exec(context.Background(), "cd "+dir); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got := m.Agent("parent").GetWorkingDir(); got != sub {
+		t.Fatalf("Expected the parent to stay in %s, got %s", sub, got)
+	}
+}
+
+func TestManagerTagsJobs(t *testing.T) {
+	m := NewManager(t.TempDir(), nil)
+	t.Cleanup(m.Close)
+	// The subscription lives as long as the manager, as the job finishes
+	// when it is killed on cleanup.
+	events := m.SubscribeJobs(context.Background())
+
+	if _, _, err := m.User("a").Exec(context.Background(), "sleep 10 &"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if event := <-events; event.Payload.SessionID != "a" || event.Payload.ID != 1 {
+		t.Fatalf("Expected a job of session a, got %+v", event.Payload)
+	}
+	if jobs := m.User("b").Jobs(); len(jobs) != 0 {
+		t.Fatalf("Expected session b to have no jobs, got %+v", jobs)
+	}
+}
+
+func TestManagerSyncPolicy(t *testing.T) {
+	dir := t.TempDir()
+	sub := t.TempDir()
+
+	m := NewManager(dir, nil)
+	if _, _, err := m.User("a").Exec(context.Background(), "cd "+sub); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got := m.Agent("a").GetWorkingDir(); got != dir {
+		t.Fatalf("Expected isolated shells by default, got %s", got)
+	}
+	if d := m.Divergence("a"); !d.Diverged() || d.UserCwd != sub || d.AgentCwd != dir {
+		t.Fatalf("Expected the shells to have diverged, got %+v", d)
+	}
+
+	m = NewManager(dir, nil)
+	m.SetSyncPolicy(SyncCwd)
+	m.Agent("a")
+	if _, _, err := m.User("a").Exec(context.Background(), "cd "+sub+" && export FOO=bar"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got := m.Agent("a").GetWorkingDir(); got != sub {
+		t.Fatalf("Expected the agent shell to follow the cd, got %s", got)
+	}
+	if got := m.Agent("a").GetEnv(); slices.Contains(got, "FOO=bar") {
+		t.Fatalf("Expected the environment not to be shared")
+	}
+	if d := m.Divergence("a"); d.Diverged() {
+		t.Fatalf("Expected no divergence, got %+v", d)
+	}
+
+	m = NewManager(dir, nil)
+	m.SetSyncPolicy(SyncEnv)
+	if _, _, err := m.Agent("a").Exec(context.Background(), "export FOO=bar KEEP=1"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	stdout, _, err := m.User("a").Exec(context.Background(), "echo -n $FOO; unset FOO")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if stdout != "bar" {
+		t.Fatalf("Expected the user shell to start with the agent's environment, got %q", stdout)
+	}
+	stdout, _, _ = m.Agent("a").Exec(context.Background(), "echo -n ${FOO-unset} $KEEP")
+	if stdout != "unset 1" {
+		t.Fatalf("Expected the unset to reach the agent shell, got %q", stdout)
+	}
+}
+
+func TestManagerRemove(t *testing.T) {
+	dir := t.TempDir()
+	sub := t.TempDir()
+	m := NewManager(dir, nil)
+
+	if _, _, err := m.Agent("a").Exec(context.Background(), "cd "+sub); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	user := m.User("a")
+	j, err := user.StartJob(context.Background(), "sleeper", "sleep 10")
+	if err != nil {
+		t.Fatalf("StartJob failed: %v", err)
+	}
+	m.Agent("b")
+
+	m.Remove("a")
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the jobs of the removed shells to be stopped")
+	}
+	m.mu.Lock()
+	for key := range m.shells {
+		if key.sessionID == "a" {
+			t.Errorf("Expected the %s shell of the session to be removed", key.kind)
+		}
+	}
+	if _, ok := m.lastEnv[shellKey{"a", AgentShell}]; ok {
+		t.Errorf("Expected the environment of the removed shell to be forgotten")
+	}
+	if _, ok := m.shells[shellKey{"b", AgentShell}]; !ok {
+		t.Errorf("Expected the shells of other sessions to be kept")
+	}
+	m.mu.Unlock()
+
+	// A command finishing in a removed shell does not bring it back.
+	if _, _, err := user.Exec(context.Background(), "true"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	m.mu.Lock()
+	_, ok := m.lastEnv[shellKey{"a", UserShell}]
+	m.mu.Unlock()
+	if ok {
+		t.Fatalf("Expected a removed shell not to be tracked again")
+	}
+	if got := m.Agent("a").GetWorkingDir(); got != dir {
+		t.Fatalf("Expected a new shell to start in %s, got %s", dir, got)
+	}
+}
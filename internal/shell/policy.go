@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"context"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// ExecRequest is a simple command about to be run, as seen by a Policy.
+type ExecRequest struct {
+	// Args are the expanded words of the command, starting with its name.
+	Args []string
+	// Dir is the directory the command runs in.
+	Dir string
+	// Env holds the exported variables the command runs with that differ
+	// from the shell's environment when the script started, such as those
+	// set with "FOO=bar cmd" or by an earlier export.
+	Env map[string]string
+}
+
+// Policy decides whether a simple command may run. A non-nil error stops
+// the script and is returned by Exec.
+type Policy func(ctx context.Context, req ExecRequest) error
+
+type policyKey struct{}
+
+// WithPolicy returns a context that makes the commands run with it, down to
+// those inside scripts and command substitutions, go through policy.
+func WithPolicy(ctx context.Context, policy Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+func policyFromContext(ctx context.Context) Policy {
+	policy, _ := ctx.Value(policyKey{}).(Policy)
+	return policy
+}
+
+// execRequest describes the command in args run from an exec handler, given
+// the environment the script started with.
+func execRequest(ctx context.Context, args []string, baseEnv map[string]string) ExecRequest {
+	hc := interp.HandlerCtx(ctx)
+	req := ExecRequest{
+		Args: args,
+		Dir:  hc.Dir,
+		Env:  make(map[string]string),
+	}
+	hc.Env.Each(func(name string, vr expand.Variable) bool {
+		if !vr.Exported || !vr.IsSet() {
+			return true
+		}
+		if value := vr.String(); baseEnv[name] != value {
+			req.Env[name] = value
+		}
+		return true
+	})
+	return req
+}
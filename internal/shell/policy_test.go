@@ -0,0 +1,60 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPolicySeesEverySimpleCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	var seen [][]string
+	ctx := WithPolicy(context.Background(), func(ctx context.Context, req ExecRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, req.Args)
+		return nil
+	})
+
+	s := NewShell(&Options{WorkingDir: dir})
+	if _, _, err := s.Exec(ctx, "f() { "+script+" inner; }; echo $(f) && "+script+" outer"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	for _, want := range [][]string{{script, "inner"}, {script, "outer"}} {
+		if !slices.ContainsFunc(seen, func(args []string) bool { return slices.Equal(args, want) }) {
+			t.Fatalf("Expected the policy to see %v, got %v", want, seen)
+		}
+	}
+}
+
+func TestPolicyStopsScript(t *testing.T) {
+	dir := t.TempDir()
+	errDenied := errors.New("denied")
+	var env map[string]string
+	ctx := WithPolicy(context.Background(), func(ctx context.Context, req ExecRequest) error {
+		env = req.Env
+		return errDenied
+	})
+
+	s := NewShell(&Options{WorkingDir: dir, Env: []string{"PATH=" + os.Getenv("PATH")}})
+	stdout, _, err := s.Exec(ctx, "export FOO=1; BAR=2 sh -c true; echo after")
+	if !errors.Is(err, errDenied) {
+		t.Fatalf("Expected the policy error, got %v", err)
+	}
+	if stdout != "" {
+		t.Fatalf("Expected the script to stop, got %q", stdout)
+	}
+	if env["FOO"] != "1" || env["BAR"] != "2" || len(env) != 2 {
+		t.Fatalf("Expected the changed variables, got %v", env)
+	}
+}
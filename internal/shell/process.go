@@ -0,0 +1,151 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// killTimeout is how long programs have to exit once interrupted before they
+// are killed, as with the interpreter's default exec handler.
+const killTimeout = 2 * time.Second
+
+// errNoProcesses is returned when signalling a job that runs no program.
+var errNoProcesses = errors.New("no running program to signal")
+
+// processSet holds the programs a job is running, so they can be sent
+// signals.
+type processSet struct {
+	mu    sync.Mutex
+	procs map[*os.Process]struct{}
+}
+
+func (ps *processSet) add(p *os.Process) (remove func()) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.procs == nil {
+		ps.procs = make(map[*os.Process]struct{})
+	}
+	ps.procs[p] = struct{}{}
+	return func() {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		delete(ps.procs, p)
+	}
+}
+
+// signal sends sig to every program of the set, and the programs they
+// started.
+func (ps *processSet) signal(sig os.Signal) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if len(ps.procs) == 0 {
+		return errNoProcesses
+	}
+	var errs []error
+	for p := range ps.procs {
+		if err := signalProcess(p, sig); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type processSetKey struct{}
+
+func withProcessSet(ctx context.Context, ps *processSet) context.Context {
+	return context.WithValue(ctx, processSetKey{}, ps)
+}
+
+// trackProcess adds p to the process set of ctx, if any, until the returned
+// function is called.
+func trackProcess(ctx context.Context, p *os.Process) (untrack func()) {
+	ps, ok := ctx.Value(processSetKey{}).(*processSet)
+	if !ok {
+		return func() {}
+	}
+	return ps.add(p)
+}
+
+// processExecHandler runs the programs of jobs like the interpreter's
+// default exec handler does, keeping track of them so that they can be sent
+// signals. Programs outside of jobs are left to next.
+func processExecHandler(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(ctx context.Context, args []string) error {
+		if _, ok := ctx.Value(processSetKey{}).(*processSet); !ok {
+			return next(ctx, args)
+		}
+		hc := interp.HandlerCtx(ctx)
+		path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+		if err != nil {
+			fmt.Fprintln(hc.Stderr, err)
+			return interp.ExitStatus(127)
+		}
+		cmd := exec.Cmd{
+			Path:   path,
+			Args:   args,
+			Env:    execEnv(hc.Env),
+			Dir:    hc.Dir,
+			Stdin:  hc.Stdin,
+			Stdout: hc.Stdout,
+			Stderr: hc.Stderr,
+		}
+		prepareCommand(&cmd)
+
+		err = cmd.Start()
+		if err == nil {
+			defer trackProcess(ctx, cmd.Process)()
+			exited := make(chan struct{})
+			stop := context.AfterFunc(ctx, func() {
+				if signalProcess(cmd.Process, os.Interrupt) == nil {
+					select {
+					case <-exited:
+						return
+					case <-time.After(killTimeout):
+					}
+				}
+				_ = signalProcess(cmd.Process, os.Kill)
+			})
+			defer stop()
+			err = cmd.Wait()
+			close(exited)
+		}
+
+		var exitErr *exec.ExitError
+		var execErr *exec.Error
+		switch {
+		case errors.As(err, &exitErr):
+			if signal, ok := exitSignal(exitErr); ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return interp.ExitStatus(128 + signal)
+			}
+			return interp.ExitStatus(exitErr.ExitCode())
+		case errors.As(err, &execErr):
+			fmt.Fprintln(hc.Stderr, err)
+			return interp.ExitStatus(127)
+		default:
+			return err
+		}
+	}
+}
+
+// execEnv returns the exported variables of env for a program.
+func execEnv(env expand.Environ) []string {
+	var list []string
+	env.Each(func(name string, vr expand.Variable) bool {
+		if vr.Exported && vr.IsSet() {
+			list = append(list, name+"="+vr.String())
+		}
+		return true
+	})
+	return list
+}
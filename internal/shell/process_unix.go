@@ -0,0 +1,60 @@
+//go:build !windows
+
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// prepareCommand starts the program in its own process group, so that
+// signals reach the programs it starts too.
+func prepareCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcess sends sig to the process group of p, or to p alone if it
+// does not lead one.
+func signalProcess(p *os.Process, sig os.Signal) error {
+	if s, ok := sig.(syscall.Signal); ok {
+		if err := syscall.Kill(-p.Pid, s); err == nil {
+			return nil
+		}
+	}
+	return p.Signal(sig)
+}
+
+// exitSignal returns the signal that killed the program, if one did.
+func exitSignal(err *exec.ExitError) (uint8, bool) {
+	status, ok := err.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0, false
+	}
+	return uint8(status.Signal()), true
+}
+
+var signalNames = map[string]syscall.Signal{
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"KILL":  syscall.SIGKILL,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"TERM":  syscall.SIGTERM,
+	"CONT":  syscall.SIGCONT,
+	"STOP":  syscall.SIGSTOP,
+	"TSTP":  syscall.SIGTSTP,
+	"WINCH": syscall.SIGWINCH,
+}
+
+// ParseSignal returns the signal named name, as in "TERM" or "SIGTERM".
+func ParseSignal(name string) (os.Signal, error) {
+	sig, ok := signalNames[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return nil, fmt.Errorf("unknown signal %q", name)
+	}
+	return sig, nil
+}
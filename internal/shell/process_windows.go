@@ -0,0 +1,28 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func prepareCommand(cmd *exec.Cmd) {}
+
+// signalProcess sends sig to p. Only os.Kill is supported on Windows.
+func signalProcess(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
+
+func exitSignal(err *exec.ExitError) (uint8, bool) {
+	return 0, false
+}
+
+// ParseSignal returns the signal named name. Only KILL is supported on
+// Windows.
+func ParseSignal(name string) (os.Signal, error) {
+	if strings.TrimPrefix(strings.ToUpper(name), "SIG") == "KILL" {
+		return os.Kill, nil
+	}
+	return nil, fmt.Errorf("unsupported signal %q, only KILL can be sent on Windows", name)
+}
@@ -0,0 +1,233 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+const (
+	// rcStatementTimeout bounds each top-level statement of an rc file, so a
+	// slow plugin manager can't stall startup.
+	rcStatementTimeout = 5 * time.Second
+
+	// interactiveFlagsVar is "$-". It is set to "i" while loading rc files,
+	// since most of them return early when the shell isn't interactive.
+	interactiveFlagsVar = "-"
+)
+
+// unsupportedRCCommands configure interactive line editing and completion,
+// which the interpreter doesn't have. Statements using them are skipped.
+var unsupportedRCCommands = map[string]bool{
+	"autoload": true,
+	"bind":     true,
+	"bindkey":  true,
+	"compdef":  true,
+	"compgen":  true,
+	"compinit": true,
+	"complete": true,
+	"compopt":  true,
+	"setopt":   true,
+	"unsetopt": true,
+	"zle":      true,
+	"zmodload": true,
+	"zstyle":   true,
+}
+
+// RCWarning describes part of an rc file that could not be loaded.
+type RCWarning struct {
+	File string
+	// Line is the line the warning refers to, or 0 for the whole file.
+	Line    int
+	Message string
+}
+
+func (w RCWarning) String() string {
+	file := w.File
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, ok := strings.CutPrefix(file, home+string(filepath.Separator)); ok {
+			file = filepath.Join("~", rel)
+		}
+	}
+	if w.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", file, w.Line, w.Message)
+	}
+	return fmt.Sprintf("%s: %s", file, w.Message)
+}
+
+// DefaultRCFiles returns the rc files loaded when none are configured: the
+// rc file of the user's login shell, followed by ~/.lashrc.
+func DefaultRCFiles() []string {
+	var files []string
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "zsh":
+		files = append(files, "~/.zshrc")
+	case "bash", "sh":
+		files = append(files, "~/.bashrc")
+	}
+	return append(files, "~/.lashrc")
+}
+
+// LoadRC runs the given rc files in the shell, so the variables, functions
+// and aliases they define are available to later commands. Missing files are
+// skipped. Statements that fail, or that use constructs the interpreter
+// doesn't support, are reported as warnings and the rest of the file still
+// loads.
+func (s *Shell) LoadRC(ctx context.Context, paths ...string) []RCWarning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var warnings []RCWarning
+	for _, path := range paths {
+		path = expandHome(path)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			warnings = append(warnings, RCWarning{File: path, Message: err.Error()})
+			continue
+		}
+		fileWarnings := s.loadRC(ctx, path, data)
+		warnings = append(warnings, fileWarnings...)
+		s.logger.InfoPersist("Loaded shell rc file", "path", path, "warnings", len(fileWarnings))
+	}
+	return warnings
+}
+
+func (s *Shell) loadRC(ctx context.Context, path string, data []byte) []RCWarning {
+	var warnings []RCWarning
+	warn := func(line int, format string, args ...any) {
+		warnings = append(warnings, RCWarning{File: path, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	stmts, err := parseRC(path, data)
+	if err != nil {
+		var line int
+		var parseErr syntax.ParseError
+		if errors.As(err, &parseErr) {
+			line = int(parseErr.Pos.Line())
+		}
+		warn(line, "could not parse the file (%v); only single-line alias and export statements were loaded", unwrapParseError(err))
+	}
+
+	var stderr bytes.Buffer
+	runner, aliases, err := s.newRunner(nil, io.Discard, &stderr, interactiveFlagsVar+"=i")
+	if err != nil {
+		warn(0, "could not start the interpreter: %v", err)
+		return warnings
+	}
+	defer s.saveRunner(runner, aliases)
+
+	for _, stmt := range stmts {
+		line := int(stmt.Pos().Line())
+		if name := unsupportedCommand(stmt); name != "" {
+			warn(line, "%s is not supported by the built-in shell, skipped", name)
+			continue
+		}
+
+		stderr.Reset()
+		stmtCtx, cancel := context.WithTimeout(ctx, rcStatementTimeout)
+		err := runner.Run(stmtCtx, stmt)
+		cancel()
+		switch {
+		case IsInterrupt(err):
+			warn(line, "timed out after %s", rcStatementTimeout)
+		case stderr.Len() > 0:
+			msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+			warn(line, "%s", msg)
+		case err != nil && !errors.As(err, new(interp.ExitStatus)):
+			warn(line, "%v", err)
+		}
+		if runner.Exited() {
+			warn(line, "the file exits the shell, the rest of it was skipped")
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return warnings
+}
+
+// parseRC parses an rc file. Files that don't parse as bash, such as most
+// .zshrc files, fall back to their single-line alias and export statements,
+// which is where most of the value is; the parse error is returned with them.
+func parseRC(path string, data []byte) ([]*syntax.Stmt, error) {
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	file, err := parser.Parse(bytes.NewReader(data), path)
+	if err == nil {
+		return file.Stmts, nil
+	}
+
+	var stmts []*syntax.Stmt
+	for i, line := range strings.Split(string(data), "\n") {
+		first, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if first != "alias" && first != "export" {
+			continue
+		}
+		// Pad with newlines so positions match the line in the file.
+		src := strings.Repeat("\n", i) + line
+		lineFile, lineErr := parser.Parse(strings.NewReader(src), path)
+		if lineErr != nil {
+			continue
+		}
+		stmts = append(stmts, lineFile.Stmts...)
+	}
+	return stmts, err
+}
+
+// unwrapParseError strips the file and position prefix from parse errors,
+// since warnings carry them already.
+func unwrapParseError(err error) string {
+	var parseErr syntax.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Text
+	}
+	return err.Error()
+}
+
+// unsupportedCommand returns the first unsupported command called by stmt
+// outside of function bodies, if any.
+func unsupportedCommand(stmt *syntax.Stmt) string {
+	var name string
+	syntax.Walk(stmt, func(node syntax.Node) bool {
+		if name != "" {
+			return false
+		}
+		switch node := node.(type) {
+		case *syntax.FuncDecl:
+			// Defining a function is harmless; it only fails when called.
+			return false
+		case *syntax.CallExpr:
+			if len(node.Args) > 0 && unsupportedRCCommands[node.Args[0].Lit()] {
+				name = node.Args[0].Lit()
+			}
+		}
+		return name == ""
+	})
+	return name
+}
+
+// expandHome expands a leading ~ in path to the user's home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + rest
+}
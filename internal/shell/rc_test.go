@@ -0,0 +1,103 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFunctionsAndAliasesPersist(t *testing.T) {
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, stderr, err := shell.Exec(ctx, `greet() { echo "hi $1"; }; alias ll='echo listing'`); err != nil {
+		t.Fatalf("Defining failed: %v, stderr: %s", err, stderr)
+	}
+
+	stdout, stderr, err := shell.Exec(ctx, "greet bob")
+	if err != nil {
+		t.Fatalf("Calling function failed: %v, stderr: %s", err, stderr)
+	}
+	if strings.TrimSpace(stdout) != "hi bob" {
+		t.Fatalf("Expected function output 'hi bob', got %q", stdout)
+	}
+
+	stdout, stderr, err = shell.Exec(ctx, "ll")
+	if err != nil {
+		t.Fatalf("Calling alias failed: %v, stderr: %s", err, stderr)
+	}
+	if strings.TrimSpace(stdout) != "listing" {
+		t.Fatalf("Expected alias output 'listing', got %q", stdout)
+	}
+
+	if _, _, err := shell.Exec(ctx, "unalias ll"); err != nil {
+		t.Fatalf("unalias failed: %v", err)
+	}
+	if _, ok := shell.GetAliases()["ll"]; ok {
+		t.Fatalf("Expected alias to be removed")
+	}
+}
+
+func TestLoadRC(t *testing.T) {
+	dir := t.TempDir()
+	rc := filepath.Join(dir, ".bashrc")
+	content := `case $- in *i*) ;; *) return;; esac
+export RC_VAR=loaded
+alias hello='echo hello from rc'
+greet() { complete -F _greet greet; echo "hi $1"; }
+complete -F _foo foo
+no-such-command-lash
+`
+	if err := os.WriteFile(rc, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write rc file: %v", err)
+	}
+
+	shell := NewShell(&Options{WorkingDir: dir})
+	warnings := shell.LoadRC(context.Background(), rc, filepath.Join(dir, "missing"))
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %v", warnings)
+	}
+	if warnings[0].Line != 5 || !strings.Contains(warnings[0].Message, "complete is not supported") {
+		t.Fatalf("Unexpected warning for complete: %v", warnings[0])
+	}
+	if warnings[1].Line != 6 {
+		t.Fatalf("Unexpected warning for unknown command: %v", warnings[1])
+	}
+
+	stdout, stderr, err := shell.Exec(context.Background(), `hello; greet bob; echo "$RC_VAR"; echo "[$-]"`)
+	if err != nil {
+		t.Fatalf("Command failed: %v, stderr: %s", err, stderr)
+	}
+	expected := "hello from rc\nhi bob\nloaded\n[]\n"
+	if stdout != expected {
+		t.Fatalf("Expected %q, got %q", expected, stdout)
+	}
+}
+
+func TestLoadRCUnparseable(t *testing.T) {
+	dir := t.TempDir()
+	rc := filepath.Join(dir, ".zshrc")
+	content := `alias gs='echo status'
+echo ${(j:,:)arr}
+export ZSH_VAR=set
+`
+	if err := os.WriteFile(rc, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write rc file: %v", err)
+	}
+
+	shell := NewShell(&Options{WorkingDir: dir})
+	warnings := shell.LoadRC(context.Background(), rc)
+	if len(warnings) != 1 || warnings[0].Line != 2 {
+		t.Fatalf("Expected a parse warning on line 2, got %v", warnings)
+	}
+
+	stdout, _, err := shell.Exec(context.Background(), `gs; echo "$ZSH_VAR"`)
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if stdout != "status\nset\n" {
+		t.Fatalf("Expected aliases and exports to load, got %q", stdout)
+	}
+}
@@ -0,0 +1,119 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"mvdan.cc/sh/v3/interp"
+
+	"github.com/lacymorrow/lash/internal/sandbox"
+)
+
+// sandboxExecHandler runs programs in the sandbox. It takes over from both
+// the core utilities, which would run inside lash, and the default exec
+// handler.
+func sandboxExecHandler(profile *sandbox.Profile) func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			hc := interp.HandlerCtx(ctx)
+			path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+			if err != nil {
+				fmt.Fprintln(hc.Stderr, err)
+				return interp.ExitStatus(127)
+			}
+
+			stderr := &tailBuffer{w: hc.Stderr}
+			cmd := profile.Command(path, args, execEnv(hc.Env))
+			cmd.Dir = hc.Dir
+			cmd.Stdin = hc.Stdin
+			cmd.Stdout = hc.Stdout
+			cmd.Stderr = stderr
+			if err := cmd.Start(); err != nil {
+				fmt.Fprintln(hc.Stderr, err)
+				return interp.ExitStatus(126)
+			}
+			defer trackProcess(ctx, cmd.Process)()
+			// The program runs as the init process of its PID namespace, so
+			// killing it kills everything it started.
+			stop := context.AfterFunc(ctx, func() {
+				_ = cmd.Process.Kill()
+			})
+			defer stop()
+			err = cmd.Wait()
+
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return err
+			}
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return interp.ExitStatus(128 + uint8(status.Signal()))
+			}
+			if note := profile.Explain(stderr.String()); note != "" {
+				fmt.Fprintln(hc.Stderr, note)
+			}
+			return interp.ExitStatus(exitErr.ExitCode())
+		}
+	}
+}
+
+// sandboxOpenHandler denies the redirections that would write outside the
+// writable paths of the sandbox, as they are opened by lash itself.
+func sandboxOpenHandler(profile *sandbox.Profile) interp.OpenHandlerFunc {
+	open := interp.DefaultOpenHandler()
+	return func(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
+		if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) != 0 {
+			target := path
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(interp.HandlerCtx(ctx).Dir, target)
+			}
+			if !profile.CanWrite(resolvePath(target)) {
+				// Path errors are reported like a failed redirection instead
+				// of stopping the script.
+				return nil, &os.PathError{Op: "open", Path: path, Err: profile.DenyWrite()}
+			}
+		}
+		return open(ctx, path, flag, perm)
+	}
+}
+
+// resolvePath resolves the symlinks of path, or of its directory if it does
+// not exist yet, so links cannot point writes out of the sandbox.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+// tailBuffer passes writes through to w and keeps the last bytes written.
+type tailBuffer struct {
+	w    io.Writer
+	tail bytes.Buffer
+}
+
+const tailBufferSize = 4096
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.tail.Write(p)
+	if b.tail.Len() > tailBufferSize {
+		b.tail.Next(b.tail.Len() - tailBufferSize)
+	}
+	return b.w.Write(p)
+}
+
+func (b *tailBuffer) String() string {
+	return b.tail.String()
+}
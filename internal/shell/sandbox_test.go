@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lacymorrow/lash/internal/sandbox"
+)
+
+func TestMain(m *testing.M) {
+	// Sandboxed programs are started through the test binary.
+	sandbox.Main()
+	os.Exit(m.Run())
+}
+
+func TestSandboxedShell(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	profile := &sandbox.Profile{Writable: []string{workspace}}
+	if err := profile.Check(); err != nil {
+		t.Skipf("Sandbox not available: %v", err)
+	}
+	s := NewShell(&Options{WorkingDir: workspace, Sandbox: profile})
+
+	stdout, stderr, err := s.Exec(context.Background(), "echo hi > a.txt && cat a.txt >&2 && rm a.txt && echo done")
+	if err != nil || stdout != "done\n" || stderr != "hi\n" {
+		t.Fatalf("Expected the workspace to be writable, got %q %q %v", stdout, stderr, err)
+	}
+
+	target := filepath.Join(outside, "b.txt")
+	if err := os.WriteFile(target, []byte("keep"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	_, stderr, err = s.Exec(context.Background(), "rm "+target)
+	if err == nil || !strings.Contains(stderr, "lash sandbox:") {
+		t.Fatalf("Expected rm outside the workspace to be denied with a note, got %q %v", stderr, err)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Fatalf("Expected %s to be kept: %v", target, err)
+	}
+
+	if err := os.Symlink(target, filepath.Join(workspace, "link")); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+	_, stderr, err = s.Exec(context.Background(), "echo pwned > link")
+	if err == nil || !strings.Contains(stderr, "open link: writing here is not allowed by the lash sandbox") {
+		t.Fatalf("Expected the redirection through a link to be denied, got %q %v", stderr, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "keep" {
+		t.Fatalf("Expected %s to be unchanged, got %q", target, data)
+	}
+}
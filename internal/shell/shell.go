@@ -2,7 +2,7 @@
 //
 // This package offers two main types:
 // - Shell: A general-purpose shell executor for one-off or managed commands
-// - PersistentShell: A singleton shell that maintains state across the application
+// - Manager: The agent and user shells of each session, whose state persists
 //
 // WINDOWS COMPATIBILITY:
 // This implementation provides both POSIX shell emulation (mvdan.cc/sh/v3),
@@ -15,7 +15,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 
@@ -23,6 +26,8 @@ import (
 	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/interp"
 	"mvdan.cc/sh/v3/syntax"
+
+	"github.com/lacymorrow/lash/internal/sandbox"
 )
 
 // ShellType represents the type of shell to use
@@ -49,11 +54,25 @@ type BlockFunc func(args []string) bool
 
 // Shell provides cross-platform shell execution with optional state persistence
 type Shell struct {
-	env        []string
-	cwd        string
+	// mu serializes command execution.
 	mu         sync.Mutex
 	logger     Logger
 	blockFuncs []BlockFunc
+	jobControl bool
+	jobs       *jobTable
+	sessionID  string
+	sandbox    *sandbox.Profile
+	// afterCommand is called once a command has run and its state has been
+	// saved.
+	afterCommand func()
+
+	// stateMu guards the state carried between commands, so it can be read
+	// while a command is running.
+	stateMu sync.RWMutex
+	env     []string
+	cwd     string
+	funcs   map[string]*syntax.Stmt
+	aliases map[string]string
 }
 
 // Options for creating a new shell
@@ -62,6 +81,15 @@ type Options struct {
 	Env        []string
 	Logger     Logger
 	BlockFuncs []BlockFunc
+	// JobControl starts commands ending with "&" as jobs and provides the
+	// jobs, fg, bg and kill %N builtins.
+	JobControl bool
+	// SessionID is the session the shell belongs to, if any. Its jobs are
+	// tagged with it.
+	SessionID string
+	// Sandbox runs programs in a sandbox with this profile and denies
+	// redirections outside of its writable paths.
+	Sandbox *sandbox.Profile
 }
 
 // NewShell creates a new shell instance with the given options
@@ -90,6 +118,10 @@ func NewShell(opts *Options) *Shell {
 		env:        env,
 		logger:     logger,
 		blockFuncs: opts.BlockFuncs,
+		jobControl: opts.JobControl,
+		jobs:       newJobTable(),
+		sessionID:  opts.SessionID,
+		sandbox:    opts.Sandbox,
 	}
 }
 
@@ -98,20 +130,42 @@ func (s *Shell) Exec(ctx context.Context, command string) (string, string, error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.execPOSIX(ctx, command)
+	var stdout, stderr bytes.Buffer
+	err := s.execPOSIX(ctx, command, nil, &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
 }
 
-// GetWorkingDir returns the current working directory
-func (s *Shell) GetWorkingDir() string {
+// ExecStream executes a command in the shell, writing output to stdout and
+// stderr as it is produced instead of buffering it until the command exits.
+// The writers may be called from multiple goroutines.
+func (s *Shell) ExecStream(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.execPOSIX(ctx, command, nil, stdout, stderr)
+}
+
+// ExecInteractive executes a command attached to the given terminal streams,
+// for programs that read from the user or need a TTY. Pass os.Stdin and
+// friends so child processes inherit the real terminal.
+func (s *Shell) ExecInteractive(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	return s.execPOSIX(ctx, command, stdin, stdout, stderr)
+}
+
+// GetWorkingDir returns the current working directory
+func (s *Shell) GetWorkingDir() string {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
 	return s.cwd
 }
 
 // SetWorkingDir sets the working directory
 func (s *Shell) SetWorkingDir(dir string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
 
 	// Verify the directory exists
 	if _, err := os.Stat(dir); err != nil {
@@ -124,8 +178,8 @@ func (s *Shell) SetWorkingDir(dir string) error {
 
 // GetEnv returns a copy of the environment variables
 func (s *Shell) GetEnv() []string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
 
 	env := make([]string, len(s.env))
 	copy(env, s.env)
@@ -134,8 +188,8 @@ func (s *Shell) GetEnv() []string {
 
 // SetEnv sets an environment variable
 func (s *Shell) SetEnv(key, value string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
 
 	// Update or add the environment variable
 	keyPrefix := key + "="
@@ -148,6 +202,31 @@ func (s *Shell) SetEnv(key, value string) {
 	s.env = append(s.env, keyPrefix+value)
 }
 
+// UnsetEnv removes an environment variable
+func (s *Shell) UnsetEnv(key string) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	keyPrefix := key + "="
+	s.env = slices.DeleteFunc(s.env, func(env string) bool {
+		return strings.HasPrefix(env, keyPrefix)
+	})
+}
+
+// GetAliases returns a copy of the aliases defined in the shell
+func (s *Shell) GetAliases() map[string]string {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return maps.Clone(s.aliases)
+}
+
+// GetFunctions returns the sorted names of the functions defined in the shell
+func (s *Shell) GetFunctions() []string {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return slices.Sorted(maps.Keys(s.funcs))
+}
+
 // SetBlockFuncs sets the command block functions for the shell
 func (s *Shell) SetBlockFuncs(blockFuncs []BlockFunc) {
 	s.mu.Lock()
@@ -191,7 +270,10 @@ func ArgumentsBlocker(blockedSubCommands [][]string) BlockFunc {
 	}
 }
 
-func (s *Shell) blockHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+// blockHandler stops the commands rejected by the block functions or by the
+// policy of the context. env is the environment the script started with.
+func (s *Shell) blockHandler(env []string) func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	baseEnv := envMap(env)
 	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
 		return func(ctx context.Context, args []string) error {
 			if len(args) == 0 {
@@ -204,39 +286,107 @@ func (s *Shell) blockHandler() func(next interp.ExecHandlerFunc) interp.ExecHand
 				}
 			}
 
+			if policy := policyFromContext(ctx); policy != nil {
+				if err := policy(ctx, execRequest(ctx, args, baseEnv)); err != nil {
+					return err
+				}
+			}
+
 			return next(ctx, args)
 		}
 	}
 }
 
 // execPOSIX executes commands using POSIX shell emulation (cross-platform)
-func (s *Shell) execPOSIX(ctx context.Context, command string) (string, string, error) {
+func (s *Shell) execPOSIX(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) error {
 	line, err := syntax.NewParser().Parse(strings.NewReader(command), "")
 	if err != nil {
-		return "", "", fmt.Errorf("could not parse command: %w", err)
+		return fmt.Errorf("could not parse command: %w", err)
 	}
 
-	var stdout, stderr bytes.Buffer
+	runner, aliases, err := s.newRunner(stdin, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("could not run command: %w", err)
+	}
+
+	if s.jobControl && hasBackgroundStmt(line) {
+		err = s.runWithJobs(ctx, runner, line, stderr)
+	} else {
+		err = runner.Run(ctx, line)
+	}
+	s.saveRunner(runner, aliases)
+	if s.afterCommand != nil {
+		s.afterCommand()
+	}
+	s.logger.InfoPersist("POSIX command finished", "command", command, "err", err)
+	return err
+}
+
+// newRunner creates an interpreter carrying the shell's state: working
+// directory, environment, functions and aliases. A nil stdin reads as empty.
+// Extra env entries are added on top of the shell's environment.
+func (s *Shell) newRunner(stdin io.Reader, stdout, stderr io.Writer, env ...string) (*interp.Runner, *aliasTable, error) {
+	s.stateMu.RLock()
+	env = append(slices.Clone(s.env), env...)
+	cwd := s.cwd
+	funcs := maps.Clone(s.funcs)
+	aliases := newAliasTable(s.aliases)
+	s.stateMu.RUnlock()
+
 	// Provide a non-nil stdin to avoid panics in coreutils (e.g., cat reading from stdin)
-	runner, err := interp.New(
-		interp.StdIO(bytes.NewReader(nil), &stdout, &stderr),
-		interp.Interactive(false),
-		interp.Env(expand.ListEnviron(s.env...)),
-		interp.Dir(s.cwd),
-		interp.ExecHandlers(s.blockHandler(), coreutils.ExecHandler),
-	)
+	if stdin == nil {
+		stdin = bytes.NewReader(nil)
+	}
+	callHandler := aliases.callHandler
+	handlers := []func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc{s.blockHandler(env), coreutils.ExecHandler, processExecHandler}
+	if s.jobControl {
+		callHandler = jobsCallHandler(callHandler)
+		handlers = append([]func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc{s.jobsHandler()}, handlers...)
+	}
+	opts := []interp.RunnerOption{
+		interp.StdIO(stdin, stdout, stderr),
+		// Interactive only enables alias expansion.
+		interp.Interactive(true),
+		interp.Env(expand.ListEnviron(env...)),
+		interp.Dir(cwd),
+		interp.CallHandler(callHandler),
+	}
+	if s.sandbox != nil {
+		handlers = []func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc{s.blockHandler(env), sandboxExecHandler(s.sandbox)}
+		opts = append(opts, interp.OpenHandler(sandboxOpenHandler(s.sandbox)))
+	}
+	runner, err := interp.New(append(opts, interp.ExecHandlers(handlers...))...)
 	if err != nil {
-		return "", "", fmt.Errorf("could not run command: %w", err)
+		return nil, nil, err
 	}
 
-	err = runner.Run(ctx, line)
+	// Reset now so that it doesn't clear the functions when the first
+	// program runs.
+	runner.Reset()
+	runner.Funcs = funcs
+	if defs := aliases.definitions(); defs != nil {
+		if err := runner.Run(context.Background(), defs); err != nil {
+			return nil, nil, fmt.Errorf("could not restore aliases: %w", err)
+		}
+	}
+	return runner, aliases, nil
+}
+
+// saveRunner stores the state left behind by runner for the next command.
+func (s *Shell) saveRunner(runner *interp.Runner, aliases *aliasTable) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
 	s.cwd = runner.Dir
 	s.env = []string{}
 	for name, vr := range runner.Vars {
+		if name == interactiveFlagsVar || !vr.IsSet() {
+			continue
+		}
 		s.env = append(s.env, fmt.Sprintf("%s=%s", name, vr.Str))
 	}
-	s.logger.InfoPersist("POSIX command finished", "command", command, "err", err)
-	return stdout.String(), stderr.String(), err
+	s.funcs = runner.Funcs
+	s.aliases = aliases.snapshot()
 }
 
 // IsInterrupt checks if an error is due to interruption
@@ -118,3 +118,27 @@ func TestCrossPlatformExecution(t *testing.T) {
 		t.Errorf("Echo output should contain 'hello', got: %q", stdout)
 	}
 }
+
+func TestExecStream(t *testing.T) {
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+
+	var stdout, stderr strings.Builder
+	err := shell.ExecStream(t.Context(), "echo out; echo err >&2; export STREAMED=yes", &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("ExecStream failed: %v", err)
+	}
+	if stdout.String() != "out\n" {
+		t.Fatalf("expected stdout %q, got %q", "out\n", stdout.String())
+	}
+	if stderr.String() != "err\n" {
+		t.Fatalf("expected stderr %q, got %q", "err\n", stderr.String())
+	}
+
+	out, _, err := shell.Exec(t.Context(), "echo $STREAMED")
+	if err != nil {
+		t.Fatalf("failed to echo: %v", err)
+	}
+	if out != "yes\n" {
+		t.Fatalf("expected state to persist after ExecStream, got %q", out)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
     "github.com/lacymorrow/lash/internal/app"
     "github.com/lacymorrow/lash/internal/llm/agent"
+    "github.com/lacymorrow/lash/internal/llm/tools"
     "github.com/lacymorrow/lash/internal/message"
     "github.com/lacymorrow/lash/internal/permission"
     "github.com/lacymorrow/lash/internal/pubsub"
@@ -25,6 +26,12 @@ type SendMsg struct {
 	Attachments []message.Attachment
 }
 
+// SteerMsg asks the agent to take Text into account in the request it is
+// running, without cancelling it.
+type SteerMsg struct {
+	Text string
+}
+
 type SessionSelectedMsg = session.Session
 
 type SessionClearedMsg struct{}
@@ -170,6 +177,8 @@ func (m *messageListCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case pubsub.Event[permission.PermissionNotification]:
 		return m, m.handlePermissionRequest(msg.Payload)
+	case pubsub.Event[tools.ToolOutput]:
+		return m, m.handleToolOutput(msg.Payload)
 	case SessionSelectedMsg:
 		if msg.ID != m.session.ID {
 			cmd := m.SetSession(msg)
@@ -220,6 +229,23 @@ func (m *messageListCmp) handlePermissionRequest(permission permission.Permissio
 	return nil
 }
 
+// handleToolOutput shows the output of a running tool call.
+func (m *messageListCmp) handleToolOutput(output tools.ToolOutput) tea.Cmd {
+	if output.SessionID != m.session.ID {
+		return nil
+	}
+	items := m.listCmp.Items()
+	if toolCallIndex := m.findToolCallByID(items, output.ToolCallID); toolCallIndex != NotFound {
+		toolCall := items[toolCallIndex].(messages.ToolCallCmp)
+		if toolCall.GetToolResult().ToolCallID != "" {
+			return nil
+		}
+		toolCall.SetLiveOutput(output.Tail)
+		m.listCmp.UpdateItem(toolCall.ID(), toolCall)
+	}
+	return nil
+}
+
 // handleChildSession handles messages from child sessions (agent tools).
 func (m *messageListCmp) handleChildSession(event pubsub.Event[message.Message]) tea.Cmd {
 	var cmds []tea.Cmd
@@ -297,6 +323,8 @@ func (m *messageListCmp) handleMessageEvent(event pubsub.Event[message.Message])
 			return m.handleChildSession(event)
 		}
 		switch event.Payload.Role {
+		case message.User:
+			return m.handleUpdateUserMessage(event.Payload)
 		case message.Assistant:
 			return m.handleUpdateAssistantMessage(event.Payload)
 		case message.Tool:
@@ -337,6 +365,20 @@ func (m *messageListCmp) handleNewUserMessage(msg message.Message) tea.Cmd {
 	return m.listCmp.AppendItem(messages.NewMessageCmp(msg))
 }
 
+// handleUpdateUserMessage refreshes an existing user message, such as a shell
+// execution whose output is still streaming in.
+func (m *messageListCmp) handleUpdateUserMessage(msg message.Message) tea.Cmd {
+	items := m.listCmp.Items()
+	for i := len(items) - 1; i >= 0; i-- {
+		if uiMsg, ok := items[i].(messages.MessageCmp); ok && uiMsg.GetMessage().ID == msg.ID {
+			uiMsg.SetMessage(msg)
+			m.listCmp.UpdateItem(uiMsg.ID(), uiMsg)
+			return nil
+		}
+	}
+	return nil
+}
+
 // handleToolMessage updates existing tool calls with their results.
 func (m *messageListCmp) handleToolMessage(msg message.Message) tea.Cmd {
 	items := m.listCmp.Items()
@@ -424,7 +466,7 @@ func (m *messageListCmp) updateAssistantMessageContent(msg message.Message, assi
 			items[assistantIndex].ID(),
 			uiMsg,
 		)
-		if msg.FinishPart() != nil && msg.FinishPart().Reason == message.FinishReasonEndTurn {
+		if m.shouldShowAssistantSection(msg) {
 			m.listCmp.AppendItem(
 				messages.NewAssistantSection(
 					msg,
@@ -445,6 +487,12 @@ func (m *messageListCmp) shouldShowAssistantMessage(msg message.Message) bool {
 	return len(msg.ToolCalls()) == 0 || msg.Content().Text != "" || msg.ReasoningContent().Thinking != "" || msg.IsThinking()
 }
 
+// shouldShowAssistantSection determines if the model footer should follow an
+// assistant message. Shell output is not produced by a model and gets none.
+func (m *messageListCmp) shouldShowAssistantSection(msg message.Message) bool {
+	return msg.FinishReason() == message.FinishReasonEndTurn && msg.Model != ""
+}
+
 // updateToolCalls handles updates to tool calls, updating existing ones and adding new ones.
 func (m *messageListCmp) updateToolCalls(msg message.Message, existingToolCalls map[int]messages.ToolCallCmp) tea.Cmd {
 	var cmds []tea.Cmd
@@ -549,7 +597,7 @@ func (m *messageListCmp) convertMessagesToUI(sessionMessages []message.Message,
 			uiMessages = append(uiMessages, messages.NewMessageCmp(msg))
 		case message.Assistant:
 			uiMessages = append(uiMessages, m.convertAssistantMessage(msg, toolResultMap)...)
-			if msg.FinishPart() != nil && msg.FinishPart().Reason == message.FinishReasonEndTurn {
+			if m.shouldShowAssistantSection(msg) {
 				uiMessages = append(uiMessages, messages.NewAssistantSection(msg, time.Unix(m.lastUserMessageTime, 0)))
 			}
 		}
@@ -2,6 +2,7 @@ package editor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -12,21 +13,29 @@ import (
 	"slices"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/textarea"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/lacymorrow/lash/internal/app"
 	"github.com/lacymorrow/lash/internal/fsext"
+	"github.com/lacymorrow/lash/internal/inputhistory"
+	"github.com/lacymorrow/lash/internal/llm/agent"
 	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/pubsub"
 	"github.com/lacymorrow/lash/internal/session"
+	"github.com/lacymorrow/lash/internal/shell"
 	"github.com/lacymorrow/lash/internal/tui/components/chat"
 	"github.com/lacymorrow/lash/internal/tui/components/completions"
 	"github.com/lacymorrow/lash/internal/tui/components/core/layout"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/commands"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/filepicker"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs/history"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs/queue"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/quit"
 	"github.com/lacymorrow/lash/internal/tui/styles"
 	"github.com/lacymorrow/lash/internal/tui/util"
@@ -41,6 +50,7 @@ type Editor interface {
 
 	SetSession(session session.Session) tea.Cmd
 	IsCompletionsOpen() bool
+	CompletesShell() bool
 	HasAttachments() bool
 	Cursor() *tea.Cursor
 }
@@ -49,6 +59,18 @@ type FileCompletionItem struct {
 	Path string // The file path
 }
 
+type ShellCompletionItem struct {
+	Text    string // The completed word, quoted for the shell
+	Partial bool   // Whether more text usually follows, like after a directory
+}
+
+// ShellCompletionsMsg carries the shell completions computed for the input.
+type ShellCompletionsMsg struct {
+	line        string
+	start       int
+	completions []shell.Completion
+}
+
 type editorCmp struct {
 	width              int
 	height             int
@@ -63,10 +85,11 @@ type editorCmp struct {
 
 	keyMap EditorKeyMap
 
-	// File path completions
+	// File path and shell completions
 	currentQuery          string
 	completionsStartIndex int
 	isCompletionsOpen     bool
+	isShellCompletion     bool
 
 	// Per-session input history
 	inputHistory map[string][]string // sessionID -> history entries (most recent at end)
@@ -74,10 +97,19 @@ type editorCmp struct {
 	historyTemp  string              // the current unsent input before entering history navigation
 	inHistoryNav bool                // whether we are actively navigating history
 
+	// Autosuggestion from the input history, and the input it was computed for
+	suggestion    string
+	suggestionFor string
+
 	// When the first submission happens before a session exists, we temporarily
 	// store that entry and attach it to the session's history once the session
 	// is created and SetSession is called.
 	pendingFirstHistoryEntry string
+
+	// Prompts queued in the session while the agent is busy, and the one
+	// being edited, if any
+	queue         []agent.QueuedPrompt
+	editingQueued string
 }
 
 var DeleteKeyMaps = DeleteAttachmentKeyMaps{
@@ -152,13 +184,32 @@ func (m *editorCmp) send() tea.Cmd {
 	if m.app.CoderAgent == nil {
 		return util.ReportError(fmt.Errorf("coder agent is not initialized"))
 	}
-	if m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+	// Agent prompts sent while the agent is busy are queued.
+	if m.app.IsShellSessionBusy(m.session.ID) || (m.app.Mode == "Shell" && m.app.IsSessionBusy(m.session.ID)) {
 		return util.ReportWarn("Agent is working, please wait...")
 	}
 
 	value := m.textarea.Value()
 	value = strings.TrimSpace(value)
 
+	if id := m.editingQueued; id != "" {
+		m.editingQueued = ""
+		var err error
+		if value == "" {
+			err = m.app.CoderAgent.RemoveQueued(m.session.ID, id)
+		} else {
+			err = m.app.CoderAgent.EditQueued(m.session.ID, id, value)
+		}
+		if err == nil {
+			m.textarea.Reset()
+			return util.ReportInfo("Queued prompt updated")
+		}
+		if !errors.Is(err, agent.ErrPromptNotQueued) {
+			return util.ReportError(err)
+		}
+		// The prompt already ran, send the edit as a new one.
+	}
+
 	switch value {
 	case "exit", "quit":
 		m.textarea.Reset()
@@ -169,7 +220,7 @@ func (m *editorCmp) send() tea.Cmd {
 	// Also keep existing per-session stashing behavior for backward compatibility.
 	if value != "" {
 		// Global history (app-wide, persisted)
-		_ = m.app.AppendInputHistory(value)
+		_ = m.app.AppendInputHistory(m.session.ID, value)
 
 		// Existing per-session history behavior
 		if m.session.ID == "" {
@@ -209,6 +260,21 @@ func (m *editorCmp) send() tea.Cmd {
 	)
 }
 
+// steer sends the input to the agent running in the session as a note to
+// take into account, or as a regular message if it is not running.
+func (m *editorCmp) steer() tea.Cmd {
+	if m.app.CoderAgent == nil || !m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+		return m.send()
+	}
+	value := strings.TrimSpace(m.textarea.Value())
+	if value == "" {
+		return nil
+	}
+	_ = m.app.AppendInputHistory(m.session.ID, value)
+	m.textarea.Reset()
+	return util.CmdHandler(chat.SteerMsg{Text: value})
+}
+
 func (m *editorCmp) repositionCompletions() tea.Msg {
 	x, y := m.completionsPosition()
 	return completions.RepositionCompletionsMsg{X: x, Y: y}
@@ -230,8 +296,11 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.isCompletionsOpen = true
 	case completions.CompletionsClosedMsg:
 		m.isCompletionsOpen = false
+		m.isShellCompletion = false
 		m.currentQuery = ""
 		m.completionsStartIndex = 0
+	case ShellCompletionsMsg:
+		return m, m.showShellCompletions(msg)
 	case completions.SelectCompletionMsg:
 		if !m.isCompletionsOpen {
 			return m, nil
@@ -252,15 +321,41 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.completionsStartIndex = 0
 			}
 		}
+		if item, ok := msg.Value.(ShellCompletionItem); ok {
+			m.insertShellCompletion(m.completionsStartIndex, item.Text, !msg.Insert && !item.Partial)
+			if !msg.Insert {
+				m.isCompletionsOpen = false
+				m.isShellCompletion = false
+				m.currentQuery = ""
+				m.completionsStartIndex = 0
+			}
+		}
 
 	case commands.OpenExternalEditorMsg:
-		if m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+		if m.app.IsSessionBusy(m.session.ID) {
 			return m, util.ReportWarn("Agent is working, please wait...")
 		}
 		return m, m.openEditor(m.textarea.Value())
 	case OpenEditorMsg:
 		m.textarea.SetValue(msg.Text)
 		m.textarea.MoveToEnd()
+	case history.SelectedMsg:
+		m.textarea.SetValue(msg.Text)
+		m.textarea.MoveToEnd()
+		return m, nil
+	case queue.EditMsg:
+		if msg.SessionID != m.session.ID {
+			return m, nil
+		}
+		m.editingQueued = msg.Prompt.ID
+		m.textarea.SetValue(msg.Prompt.Content)
+		m.textarea.MoveToEnd()
+		return m, nil
+	case pubsub.Event[agent.PromptQueue]:
+		if msg.Payload.SessionID == m.session.ID {
+			m.queue = msg.Payload.Prompts
+		}
+		return m, nil
 	case tea.PasteMsg:
 		path := strings.ReplaceAll(string(msg), "\\ ", " ")
 		// try to get an image
@@ -307,7 +402,7 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		curIdx := m.textarea.Width()*cur.Y + cur.X
 		switch {
 		// Completions
-		case msg.String() == "/" && !m.isCompletionsOpen &&
+		case msg.String() == "/" && m.app.Mode != "Shell" && !m.isCompletionsOpen &&
 			// only show if beginning of prompt, or if previous char is a space or newline:
 			(len(m.textarea.Value()) == 0 || unicode.IsSpace(rune(m.textarea.Value()[len(m.textarea.Value())-1]))):
 			m.isCompletionsOpen = true
@@ -318,10 +413,15 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 		}
 
+		if key.Matches(msg, m.keyMap.Complete) && m.CompletesShell() {
+			return m, m.completeShell()
+		}
+
 		// Clear input: ctrl+c or cmd+backspace
 		if key.Matches(msg, m.keyMap.ClearInput) && m.textarea.Focused() {
 			if strings.TrimSpace(m.textarea.Value()) != "" {
 				m.textarea.Reset()
+				m.editingQueued = ""
 				// consume event so app doesn't open quit dialog
 				return m, nil
 			}
@@ -329,11 +429,36 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, util.CmdHandler(dialogs.OpenDialogMsg{Model: quit.NewQuitDialog()})
 		}
 
+		// Reverse history search, unless ctrl+r starts deleting attachments
+		if key.Matches(msg, m.keyMap.SearchHistory) && m.textarea.Focused() && len(m.attachments) == 0 {
+			sessionID := m.session.ID
+			search := func(query string, limit int) []inputhistory.Entry {
+				return m.app.SearchInputHistory(sessionID, query, limit)
+			}
+			return m, util.CmdHandler(dialogs.OpenDialogMsg{
+				Model: history.NewHistoryDialogCmp(search, m.textarea.Value()),
+			})
+		}
+
+		// Accept the autosuggestion, in full or up to the end of its next word
+		if ghost := m.ghostText(); ghost != "" {
+			switch {
+			case key.Matches(msg, m.keyMap.AcceptSuggestion):
+				m.textarea.SetValue(m.textarea.Value() + ghost)
+				m.textarea.MoveToEnd()
+				return m, nil
+			case key.Matches(msg, m.keyMap.AcceptSuggestionWord):
+				m.textarea.SetValue(m.textarea.Value() + nextWord(ghost))
+				m.textarea.MoveToEnd()
+				return m, nil
+			}
+		}
+
 		// History navigation: Up/Down (global, across sessions)
 		if msg.String() == "up" || msg.String() == "down" {
 			// Only handle when focused and not deleting attachments and not showing completions
 			if m.textarea.Focused() && !m.deleteMode && !m.isCompletionsOpen {
-				history := m.app.InputHistory
+				history := m.app.InputHistory.Entries()
 				if len(history) > 0 {
 					if msg.String() == "up" {
 						// Enter history nav only if at the top line to avoid interfering with multi-line editing
@@ -346,7 +471,7 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							if m.historyIndex > 0 {
 								m.historyIndex--
 							}
-							m.textarea.SetValue(history[m.historyIndex])
+							m.textarea.SetValue(history[m.historyIndex].Text)
 							m.textarea.MoveToEnd()
 							return m, nil
 						}
@@ -354,7 +479,7 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						if m.inHistoryNav {
 							if m.historyIndex < len(history)-1 {
 								m.historyIndex++
-								m.textarea.SetValue(history[m.historyIndex])
+								m.textarea.SetValue(history[m.historyIndex].Text)
 							} else {
 								// Exit history navigation and restore the temp content
 								m.inHistoryNav = false
@@ -393,7 +518,7 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		if key.Matches(msg, m.keyMap.OpenEditor) {
-			if m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+			if m.app.IsSessionBusy(m.session.ID) {
 				return m, util.ReportWarn("Agent is working, please wait...")
 			}
 			return m, m.openEditor(m.textarea.Value())
@@ -406,6 +531,9 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.textarea.InsertRune('\n')
 			cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 		}
+		if m.textarea.Focused() && key.Matches(msg, m.keyMap.Steer) {
+			return m, m.steer()
+		}
 		// Handle Enter key
 		if m.textarea.Focused() && key.Matches(msg, m.keyMap.SendMessage) {
 			trimmed := strings.TrimSpace(m.textarea.Value())
@@ -432,10 +560,13 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if ok {
 			if kp.String() == "space" || m.textarea.Value() == "" {
 				m.isCompletionsOpen = false
+				m.isShellCompletion = false
 				m.currentQuery = ""
 				m.completionsStartIndex = 0
 				cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
-			} else {
+			} else if m.isShellCompletion {
+				cmds = append(cmds, m.filterShellCompletions())
+			} else if m.app.Mode != "Shell" {
 				word := m.textarea.Word()
 				if strings.HasPrefix(word, "/") {
 					// XXX: wont' work if editing in the middle of the field.
@@ -513,10 +644,70 @@ func (m *editorCmp) randomizePlaceholders() {
 	m.readyPlaceholder = readyPlaceholders[rand.Intn(len(readyPlaceholders))]
 }
 
+// ghostText returns the rest of the history entry suggested for the input,
+// shown after the cursor when it is at the end of a single-line input.
+func (m *editorCmp) ghostText() string {
+	if !m.textarea.Focused() || m.deleteMode || m.isCompletionsOpen || m.inHistoryNav {
+		return ""
+	}
+	value := m.textarea.Value()
+	if strings.Contains(value, "\n") {
+		return ""
+	}
+	li := m.textarea.LineInfo()
+	if li.StartColumn+li.ColumnOffset != utf8.RuneCountInString(value) {
+		return ""
+	}
+	if value != m.suggestionFor {
+		m.suggestion = m.app.SuggestInput(m.session.ID, value)
+		m.suggestionFor = value
+	}
+	ghost, ok := strings.CutPrefix(m.suggestion, value)
+	if !ok {
+		return ""
+	}
+	return ghost
+}
+
+// nextWord returns text up to the end of its first word, leading spaces
+// included.
+func nextWord(text string) string {
+	start := len(text) - len(strings.TrimLeft(text, " "))
+	if end := strings.IndexByte(text[start:], ' '); end >= 0 {
+		return text[:start+end]
+	}
+	return text
+}
+
+// textareaView renders the textarea with the autosuggestion, if any, dimmed
+// after the cursor.
+func (m *editorCmp) textareaView() string {
+	view := m.textarea.View()
+	ghost := m.ghostText()
+	cur := m.textarea.Cursor()
+	if ghost == "" || cur == nil {
+		return view
+	}
+	lines := strings.Split(view, "\n")
+	if cur.Y < 0 || cur.Y >= len(lines) {
+		return view
+	}
+	t := styles.CurrentTheme()
+	line := lines[cur.Y]
+	width := lipgloss.Width(line)
+	line = ansi.Truncate(line, cur.X, "") +
+		t.S().Subtle.Render(ansi.Truncate(ghost, max(0, width-cur.X), ""))
+	if pad := width - lipgloss.Width(line); pad > 0 {
+		line += strings.Repeat(" ", pad)
+	}
+	lines[cur.Y] = line
+	return strings.Join(lines, "\n")
+}
+
 func (m *editorCmp) View() string {
 	t := styles.CurrentTheme()
 	// Update placeholder
-	if m.app.CoderAgent != nil && m.app.CoderAgent.IsBusy() {
+	if m.app.IsBusy() {
 		m.textarea.Placeholder = m.workingPlaceholder
 	} else {
 		m.textarea.Placeholder = m.readyPlaceholder
@@ -528,21 +719,37 @@ func (m *editorCmp) View() string {
 	if m.session.ID == "" && strings.TrimSpace(m.textarea.Value()) == "" {
 		m.textarea.Placeholder = " Press Enter for recent sessions"
 	}
-	if len(m.attachments) == 0 {
+	if len(m.attachments) == 0 && len(m.queue) == 0 {
 		content := t.S().Base.Padding(1).Render(
-			m.textarea.View(),
+			m.textareaView(),
 		)
 		return content
 	}
 	content := t.S().Base.Padding(0, 1, 1, 1).Render(
 		lipgloss.JoinVertical(lipgloss.Top,
-			m.attachmentsContent(),
-			m.textarea.View(),
+			lipgloss.JoinHorizontal(lipgloss.Left, m.attachmentsContent(), m.queueContent()),
+			m.textareaView(),
 		),
 	)
 	return content
 }
 
+// queueContent summarizes the prompts queued in the session on a single
+// line.
+func (m *editorCmp) queueContent() string {
+	if len(m.queue) == 0 {
+		return ""
+	}
+	t := styles.CurrentTheme()
+	next := strings.ReplaceAll(m.queue[0].Content, "\n", " ")
+	summary := fmt.Sprintf("%d queued, next: %s", len(m.queue), next)
+	hint := " (ctrl+q to manage)"
+	width := max(0, m.width-lipgloss.Width(m.attachmentsContent())-lipgloss.Width(hint)-3)
+	return t.S().Base.MarginLeft(1).Render(
+		t.S().Muted.Render(ansi.Truncate(summary, width, "…")) + t.S().Subtle.Render(hint),
+	)
+}
+
 func (m *editorCmp) SetSize(width, height int) tea.Cmd {
 	m.width = width
 	m.height = height
@@ -584,6 +791,104 @@ func (m *editorCmp) SetPosition(x, y int) tea.Cmd {
 	return nil
 }
 
+// completeShell computes the shell completions of the input in the
+// background. Programs may be run to find them, so it can take a moment.
+func (m *editorCmp) completeShell() tea.Cmd {
+	line := m.textarea.Value()
+	sessionID := m.session.ID
+	return func() tea.Msg {
+		start, items := m.app.CompleteShell(context.Background(), sessionID, line)
+		return ShellCompletionsMsg{line: line, start: start, completions: items}
+	}
+}
+
+// showShellCompletions completes the input like a shell does: a single
+// candidate is inserted, otherwise the common prefix of the candidates is
+// inserted and the candidates are listed.
+func (m *editorCmp) showShellCompletions(msg ShellCompletionsMsg) tea.Cmd {
+	// Drop results for input that has changed since.
+	if m.textarea.Value() != msg.line || len(msg.completions) == 0 {
+		return nil
+	}
+	if len(msg.completions) == 1 {
+		c := msg.completions[0]
+		m.insertShellCompletion(msg.start, c.Text, !c.Partial())
+		return nil
+	}
+
+	texts := make([]string, len(msg.completions))
+	items := make([]completions.Completion, len(msg.completions))
+	for i, c := range msg.completions {
+		texts[i] = c.Text
+		items[i] = completions.Completion{
+			Title: c.Text,
+			Value: ShellCompletionItem{Text: c.Text, Partial: c.Partial()},
+		}
+	}
+	if prefix := commonPrefix(texts); len(prefix) > len(msg.line)-msg.start {
+		m.insertShellCompletion(msg.start, prefix, false)
+	}
+
+	m.isCompletionsOpen = true
+	m.isShellCompletion = true
+	m.completionsStartIndex = msg.start
+	m.currentQuery = m.textarea.Value()[msg.start:]
+	x, y := m.completionsPosition()
+	return util.CmdHandler(completions.OpenCompletionsMsg{
+		Completions: items,
+		X:           x - len(m.currentQuery),
+		Y:           y,
+	})
+}
+
+// filterShellCompletions narrows the listed shell completions down to the
+// word typed so far.
+func (m *editorCmp) filterShellCompletions() tea.Cmd {
+	value := m.textarea.Value()
+	if len(value) < m.completionsStartIndex {
+		m.isCompletionsOpen = false
+		m.isShellCompletion = false
+		return util.CmdHandler(completions.CloseCompletionsMsg{})
+	}
+	m.currentQuery = value[m.completionsStartIndex:]
+	x, y := m.completionsPosition()
+	return util.CmdHandler(completions.FilterCompletionsMsg{
+		Query: m.currentQuery,
+		X:     x - len(m.currentQuery),
+		Y:     y,
+	})
+}
+
+// insertShellCompletion replaces the input from start on with text,
+// followed by a space if space is set.
+func (m *editorCmp) insertShellCompletion(start int, text string, space bool) {
+	value := m.textarea.Value()
+	if start > len(value) {
+		return
+	}
+	value = value[:start] + text
+	if space {
+		value += " "
+	}
+	// XXX: This will always move the cursor to the end of the textarea.
+	m.textarea.SetValue(value)
+	m.textarea.MoveToEnd()
+}
+
+// commonPrefix returns the longest prefix shared by all of texts.
+func commonPrefix(texts []string) string {
+	if len(texts) == 0 {
+		return ""
+	}
+	prefix := texts[0]
+	for _, t := range texts[1:] {
+		for !strings.HasPrefix(t, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
 func (m *editorCmp) startCompletions() tea.Msg {
 	files, _, _ := fsext.ListDirectory(".", nil, 0)
 	completionItems := make([]completions.Completion, 0, len(files))
@@ -630,6 +935,11 @@ func (c *editorCmp) Bindings() []key.Binding {
 // we need to move some functionality to the page level
 func (c *editorCmp) SetSession(session session.Session) tea.Cmd {
 	c.session = session
+	c.editingQueued = ""
+	c.queue = nil
+	if c.app.CoderAgent != nil && session.ID != "" {
+		c.queue = c.app.CoderAgent.Queue(session.ID)
+	}
 	// Reset transient history navigation state on session switch
 	c.inHistoryNav = false
 	c.historyIndex = -1
@@ -652,6 +962,15 @@ func (c *editorCmp) IsCompletionsOpen() bool {
 	return c.isCompletionsOpen
 }
 
+// CompletesShell reports whether Tab completes the input like a shell, which
+// it does in Shell and Auto mode once something has been typed.
+func (c *editorCmp) CompletesShell() bool {
+	if !c.textarea.Focused() || strings.TrimSpace(c.textarea.Value()) == "" {
+		return false
+	}
+	return c.app.Mode == "Shell" || c.app.Mode == "Auto"
+}
+
 func (c *editorCmp) HasAttachments() bool {
 	return len(c.attachments) > 0
 }
@@ -7,9 +7,15 @@ import (
 type EditorKeyMap struct {
 	AddFile     key.Binding
 	SendMessage key.Binding
+	Steer       key.Binding
 	OpenEditor  key.Binding
 	Newline     key.Binding
 	ClearInput  key.Binding
+	Complete    key.Binding
+
+	AcceptSuggestion     key.Binding
+	AcceptSuggestionWord key.Binding
+	SearchHistory        key.Binding
 }
 
 func DefaultEditorKeyMap() EditorKeyMap {
@@ -22,6 +28,10 @@ func DefaultEditorKeyMap() EditorKeyMap {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "send"),
 		),
+		Steer: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "steer the agent"),
+		),
 		OpenEditor: key.NewBinding(
 			key.WithKeys("ctrl+o"),
 			key.WithHelp("ctrl+o", "open editor"),
@@ -34,6 +44,22 @@ func DefaultEditorKeyMap() EditorKeyMap {
 			key.WithKeys("ctrl+c", "cmd+backspace"),
 			key.WithHelp("ctrl+c", "clear input"),
 		),
+		Complete: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "complete"),
+		),
+		AcceptSuggestion: key.NewBinding(
+			key.WithKeys("right", "end"),
+			key.WithHelp("→", "accept suggestion"),
+		),
+		AcceptSuggestionWord: key.NewBinding(
+			key.WithKeys("alt+right", "alt+f"),
+			key.WithHelp("alt+→", "accept suggested word"),
+		),
+		SearchHistory: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "search history"),
+		),
 	}
 }
 
@@ -42,9 +68,14 @@ func (k EditorKeyMap) KeyBindings() []key.Binding {
 	return []key.Binding{
 		k.AddFile,
 		k.SendMessage,
+		k.Steer,
 		k.OpenEditor,
 		k.Newline,
 		k.ClearInput,
+		k.Complete,
+		k.AcceptSuggestion,
+		k.AcceptSuggestionWord,
+		k.SearchHistory,
 		AttachmentsKeyMaps.AttachmentDeleteMode,
 		AttachmentsKeyMaps.DeleteAllAttachments,
 		AttachmentsKeyMaps.Escape,
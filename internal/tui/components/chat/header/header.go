@@ -29,12 +29,14 @@ type header struct {
 	width       int
 	session     session.Session
 	lspClients  map[string]*lsp.Client
+	shells      *shell.Manager
 	detailsOpen bool
 }
 
-func New(lspClients map[string]*lsp.Client) Header {
+func New(lspClients map[string]*lsp.Client, shells *shell.Manager) Header {
 	return &header{
 		lspClients: lspClients,
+		shells:     shells,
 		width:      0,
 	}
 }
@@ -89,8 +91,8 @@ func (p *header) View() string {
 
 func (h *header) details() string {
 	t := styles.CurrentTheme()
-	// Prefer the live cwd from the persistent user shell; fall back to config cwd
-	liveCwd := shell.GetUserPersistentShell(config.Get().WorkingDir()).GetWorkingDir()
+	// Prefer the live cwd from the session's user shell; fall back to config cwd
+	liveCwd := h.shells.WorkingDir(h.session.ID)
 	if liveCwd == "" {
 		liveCwd = config.Get().WorkingDir()
 	}
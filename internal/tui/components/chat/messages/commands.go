@@ -0,0 +1,131 @@
+package messages
+
+import (
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/tui/styles"
+	"github.com/lacymorrow/lash/internal/tui/util"
+)
+
+// Key bindings for the command cards of a focused assistant message.
+var (
+	RunCommandKey  = key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "run"))
+	EditCommandKey = key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit"))
+	CopyCommandKey = key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "copy command"))
+	NextCommandKey = key.NewBinding(key.WithKeys("right"), key.WithHelp("←/→", "choose"))
+	PrevCommandKey = key.NewBinding(key.WithKeys("left"), key.WithHelp("←/→", "choose"))
+)
+
+type (
+	// RunCommandMsg asks to run a command suggested by the agent in the
+	// user's shell, as if it was entered in Shell mode.
+	RunCommandMsg struct {
+		Command string
+	}
+
+	// EditCommandMsg asks to put a command suggested by the agent in the
+	// editor, so the user can change it before running it.
+	EditCommandMsg struct {
+		Command string
+	}
+)
+
+// suggestedCommands returns the shell commands suggested in a finished
+// assistant reply.
+func suggestedCommands(msg message.Message) []string {
+	if msg.Role != message.Assistant || !msg.IsFinished() {
+		return nil
+	}
+	return message.ShellCommands(msg.Content().Text)
+}
+
+// handleCommandKey runs the action of a command card key. It reports whether
+// the key was handled.
+func (m *messageCmp) handleCommandKey(msg tea.KeyPressMsg) (tea.Cmd, bool) {
+	if len(m.commands) == 0 {
+		return nil, false
+	}
+	command := m.commands[m.selectedCommand]
+	switch {
+	case key.Matches(msg, RunCommandKey):
+		m.ranCommands[m.selectedCommand] = true
+		return util.CmdHandler(RunCommandMsg{Command: command}), true
+	case key.Matches(msg, EditCommandKey):
+		return util.CmdHandler(EditCommandMsg{Command: command}), true
+	case key.Matches(msg, CopyCommandKey):
+		return tea.Sequence(
+			tea.SetClipboard(command),
+			func() tea.Msg {
+				_ = clipboard.WriteAll(command)
+				return nil
+			},
+			util.ReportInfo("Command copied to clipboard"),
+		), true
+	case key.Matches(msg, NextCommandKey):
+		m.selectedCommand = (m.selectedCommand + 1) % len(m.commands)
+		return nil, true
+	case key.Matches(msg, PrevCommandKey):
+		m.selectedCommand = (m.selectedCommand + len(m.commands) - 1) % len(m.commands)
+		return nil, true
+	}
+	return nil, false
+}
+
+// renderCommandCards renders the suggested commands as cards. The selected
+// card of a focused message lists the actions available for it.
+func (m *messageCmp) renderCommandCards() string {
+	t := styles.CurrentTheme()
+	width := m.textWidth()
+
+	cards := make([]string, 0, len(m.commands))
+	for i, command := range m.commands {
+		selected := m.focused && i == m.selectedCommand
+		border := t.Border
+		if selected {
+			border = t.Primary
+		}
+		style := t.S().Base.
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(border).
+			Padding(0, 1).
+			Width(width)
+		inner := width - style.GetHorizontalFrameSize()
+
+		prompt := t.S().Base.Foreground(t.Blue).Render("$") + " "
+		var lines []string
+		for j, line := range strings.Split(command, "\n") {
+			if j > 0 {
+				prompt = "  "
+			}
+			lines = append(lines, prompt+t.S().Base.Foreground(t.FgBase).Render(ansi.Truncate(line, inner-2, "…")))
+		}
+		if m.ranCommands[i] {
+			lines = append(lines, t.S().Subtle.Render("sent to the shell"))
+		}
+		if selected {
+			lines = append(lines, m.commandHelp())
+		}
+		cards = append(cards, style.Render(strings.Join(lines, "\n")))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, cards...)
+}
+
+// commandHelp returns the key hints shown on the selected command card.
+func (m *messageCmp) commandHelp() string {
+	t := styles.CurrentTheme()
+	bindings := []key.Binding{RunCommandKey, EditCommandKey, CopyCommandKey}
+	if len(m.commands) > 1 {
+		bindings = append(bindings, NextCommandKey)
+	}
+	hints := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		hints = append(hints, t.S().Muted.Render(b.Help().Key)+" "+t.S().Subtle.Render(b.Help().Desc))
+	}
+	return strings.Join(hints, t.S().Subtle.Render(" · "))
+}
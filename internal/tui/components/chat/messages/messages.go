@@ -28,6 +28,37 @@ import (
 // CopyKey is the key binding for copying message content to the clipboard.
 var CopyKey = key.NewBinding(key.WithKeys("c", "y", "C", "Y"), key.WithHelp("c/y", "copy"))
 
+// CancelToolKey is the key binding for cancelling the selected running tool
+// call.
+var CancelToolKey = key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "cancel tool"))
+
+// CancelToolCallMsg asks for a running tool call to be cancelled, leaving the
+// rest of the agent's turn running.
+type CancelToolCallMsg struct {
+	ToolCallID string
+}
+
+// ForkKey is the key binding for forking the session at the selected
+// message.
+var ForkKey = key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "fork here"))
+
+// EditPromptKey is the key binding for editing the selected prompt and
+// sending it again in a fork of the session.
+var EditPromptKey = key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit & resend"))
+
+// ForkMsg asks for the session to be forked at a message, keeping the
+// original session as another branch.
+type ForkMsg struct {
+	MessageID string
+}
+
+// EditPromptMsg asks for the session to be forked just before a prompt,
+// with the prompt in the editor to be changed and sent again.
+type EditPromptMsg struct {
+	MessageID string
+	Text      string
+}
+
 // ClearSelectionKey is the key binding for clearing the current selection in the chat interface.
 var ClearSelectionKey = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear selection"))
 
@@ -57,6 +88,11 @@ type messageCmp struct {
 
 	// Thinking viewport for displaying reasoning content
 	thinkingViewport viewport.Model
+
+	// Shell commands suggested in the reply, shown as runnable cards
+	commands        []string
+	selectedCommand int
+	ranCommands     map[int]bool
 }
 
 var focusedMessageBorder = lipgloss.Border{
@@ -80,6 +116,8 @@ func NewMessageCmp(msg message.Message) MessageCmp {
 			CycleColors: true,
 		}),
 		thinkingViewport: thinkingViewport,
+		commands:         suggestedCommands(msg),
+		ranCommands:      make(map[int]bool),
 	}
 	return m
 }
@@ -103,11 +141,27 @@ func (m *messageCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 	case tea.KeyPressMsg:
+		if cmd, ok := m.handleCommandKey(msg); ok {
+			return m, cmd
+		}
+		if key.Matches(msg, ForkKey) && m.message.IsFinished() {
+			return m, util.CmdHandler(ForkMsg{MessageID: m.message.ID})
+		}
+		if _, ok := m.shellExecution(); !ok && key.Matches(msg, EditPromptKey) && m.message.Role == message.User {
+			return m, util.CmdHandler(EditPromptMsg{
+				MessageID: m.message.ID,
+				Text:      m.message.Content().Text,
+			})
+		}
 		if key.Matches(msg, CopyKey) {
+			text := m.message.Content().Text
+			if se, ok := m.shellExecution(); ok {
+				text = "$ " + se.Command + "\n" + se.Stdout + se.Stderr
+			}
 			return m, tea.Sequence(
-				tea.SetClipboard(m.message.Content().Text),
+				tea.SetClipboard(text),
 				func() tea.Msg {
-					_ = clipboard.WriteAll(m.message.Content().Text)
+					_ = clipboard.WriteAll(text)
 					return nil
 				},
 				util.ReportInfo("Message copied to clipboard"),
@@ -120,6 +174,9 @@ func (m *messageCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // View renders the message component based on its current state.
 // Returns different views for spinning, user, and assistant messages.
 func (m *messageCmp) View() string {
+	if se, ok := m.shellExecution(); ok {
+		return m.renderShellExecution(se)
+	}
 	if m.spinning && m.message.ReasoningContent().Thinking == "" {
 		return m.style().PaddingLeft(1).Render(m.anim.View())
 	}
@@ -142,6 +199,10 @@ func (m *messageCmp) GetMessage() message.Message {
 
 func (m *messageCmp) SetMessage(msg message.Message) {
 	m.message = msg
+	m.commands = suggestedCommands(msg)
+	if m.selectedCommand >= len(m.commands) {
+		m.selectedCommand = 0
+	}
 }
 
 // textWidth calculates the available width for text content,
@@ -210,6 +271,10 @@ func (m *messageCmp) renderAssistantMessage() string {
 		parts = append(parts, m.toMarkdown(content))
 	}
 
+	if len(m.commands) > 0 {
+		parts = append(parts, m.renderCommandCards())
+	}
+
 	joined := lipgloss.JoinVertical(lipgloss.Left, parts...)
 	return m.style().Render(joined)
 }
@@ -300,8 +365,12 @@ func (m *messageCmp) renderThinkingContent() string {
 }
 
 // shouldSpin determines whether the message should show a loading animation.
-// Only assistant messages without content that aren't finished should spin.
+// Only assistant messages without content that aren't finished should spin,
+// as well as shell executions that are still running without output.
 func (m *messageCmp) shouldSpin() bool {
+	if se, ok := m.shellExecution(); ok {
+		return !se.IsFinished() && se.Stdout == "" && se.Stderr == ""
+	}
 	if m.message.Role != message.Assistant {
 		return false
 	}
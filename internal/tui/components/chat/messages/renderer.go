@@ -724,6 +724,9 @@ func earlyState(header string, v *toolCallCmp) (string, bool) {
 	case v.cancelled:
 		message = t.S().Base.Foreground(t.FgSubtle).Render("Canceled.")
 	case v.result.ToolCallID == "":
+		if v.liveOutput != "" {
+			return joinHeaderBody(header, renderLiveOutput(v)), true
+		}
 		if v.permissionRequested && !v.permissionGranted {
 			message = t.S().Base.Foreground(t.FgSubtle).Render("Requesting for permission...")
 		} else {
@@ -780,6 +783,44 @@ func renderPlainContent(v *toolCallCmp, content string) string {
 	return strings.Join(out, "\n")
 }
 
+// renderLiveOutput shows the last lines of the output of a running tool.
+func renderLiveOutput(v *toolCallCmp) string {
+	t := styles.CurrentTheme()
+	content := strings.ReplaceAll(v.liveOutput, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\t", "    ")
+	content = strings.TrimRight(content, "\n")
+	lines := strings.Split(content, "\n")
+
+	width := v.textWidth() - 2 // -2 for left padding
+	var out []string
+	if len(lines) > responseContextHeight {
+		lines = lines[len(lines)-responseContextHeight:]
+		out = append(out, t.S().Muted.
+			Background(t.BgBaseLighter).
+			Width(width).
+			Render("…"))
+	}
+	for _, ln := range lines {
+		// Progress bars redraw the line with carriage returns.
+		if i := strings.LastIndex(ln, "\r"); i >= 0 {
+			ln = ln[i+1:]
+		}
+		ln = ansiext.Escape(ln)
+		ln = " " + ln // left padding
+		if len(ln) > width {
+			ln = v.fit(ln, width)
+		}
+		out = append(out, t.S().Muted.
+			Width(width).
+			Background(t.BgBaseLighter).
+			Render(ln))
+	}
+	if v.focused {
+		out = append(out, "", t.S().Base.Foreground(t.FgSubtle).Render(fmt.Sprintf("Running… press %s to cancel", CancelToolKey.Help().Key)))
+	}
+	return strings.Join(out, "\n")
+}
+
 func getDigits(n int) int {
 	if n == 0 {
 		return 1
@@ -880,6 +921,18 @@ func prettifyToolName(name string) string {
 		return "Grep"
 	case tools.LSToolName:
 		return "List"
+	case tools.ProcessStartToolName:
+		return "Process Start"
+	case tools.ProcessOutputToolName:
+		return "Process Output"
+	case tools.ProcessSendToolName:
+		return "Process Send"
+	case tools.ProcessListToolName:
+		return "Processes"
+	case tools.ProcessKillToolName:
+		return "Process Kill"
+	case tools.ReadOutputToolName:
+		return "Read Output"
 	case tools.SourcegraphToolName:
 		return "Sourcegraph"
 	case tools.ViewToolName:
@@ -0,0 +1,116 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/lacymorrow/lash/internal/ansiext"
+	"github.com/lacymorrow/lash/internal/fsext"
+	"github.com/lacymorrow/lash/internal/message"
+	"github.com/lacymorrow/lash/internal/tui/styles"
+)
+
+// shellExecution returns the shell execution carried by the message, if any.
+func (m *messageCmp) shellExecution() (message.ShellExecution, bool) {
+	executions := m.message.ShellExecutions()
+	if len(executions) == 0 {
+		return message.ShellExecution{}, false
+	}
+	return executions[0], true
+}
+
+// renderShellExecution renders a command run in Shell mode like a terminal
+// block: the prompt line with a status badge, followed by the tail of its
+// output with stderr highlighted.
+func (m *messageCmp) renderShellExecution(se message.ShellExecution) string {
+	t := styles.CurrentTheme()
+	width := m.textWidth()
+
+	badge := shellStatusBadge(se)
+	prompt := t.S().Base.Foreground(t.Blue).Render("$") + " "
+	command := strings.ReplaceAll(se.Command, "\n", " ")
+	command = ansi.Truncate(command, width-lipgloss.Width(prompt)-lipgloss.Width(badge)-2, "…")
+	header := prompt + t.S().Base.Foreground(t.FgBase).Render(command) + " " + badge
+
+	details := []string{fsext.PrettyPath(se.Cwd)}
+	if se.Interactive {
+		details = append(details, "ran in the terminal")
+	}
+	if se.IsFinished() {
+		details = append(details, se.Duration().Round(10*time.Millisecond).String())
+	}
+	info := t.S().Subtle.Render(ansi.Truncate(strings.Join(details, " · "), width, "…"))
+
+	parts := []string{header, info}
+	if output := m.renderShellOutput(se, width); output != "" {
+		parts = append(parts, "", output)
+	} else if m.spinning {
+		parts = append(parts, "", m.anim.View())
+	}
+
+	return m.style().Render(lipgloss.JoinVertical(lipgloss.Left, parts...))
+}
+
+// shellStatusBadge returns the badge describing how a shell execution ended.
+func shellStatusBadge(se message.ShellExecution) string {
+	t := styles.CurrentTheme()
+	badge := t.S().Base.Padding(0, 1).Foreground(t.White)
+	switch {
+	case se.Canceled:
+		return badge.Background(t.FgMuted).Render("CANCELED")
+	case !se.IsFinished():
+		return badge.Background(t.Yellow).Foreground(t.BgBase).Render("RUNNING")
+	case se.ExitCode == 0:
+		return badge.Background(t.GreenDark).Render("EXIT 0")
+	default:
+		return badge.Background(t.Red).Render(fmt.Sprintf("EXIT %d", se.ExitCode))
+	}
+}
+
+// renderShellOutput renders the last lines of the execution's stdout followed
+// by its stderr. Output is escaped so it can't break the surrounding layout.
+func (m *messageCmp) renderShellOutput(se message.ShellExecution, width int) string {
+	t := styles.CurrentTheme()
+	stdoutStyle := t.S().Muted.Background(t.BgBaseLighter).Width(width)
+	stderrStyle := t.S().Base.Foreground(t.RedLight).Background(t.BgBaseLighter).Width(width)
+
+	type outputLine struct {
+		text  string
+		style lipgloss.Style
+	}
+	var lines []outputLine
+	for _, stream := range []struct {
+		content string
+		style   lipgloss.Style
+	}{
+		{se.Stdout, stdoutStyle},
+		{se.Stderr, stderrStyle},
+	} {
+		content := strings.ReplaceAll(stream.content, "\r\n", "\n")
+		content = strings.ReplaceAll(content, "\t", "    ")
+		content = strings.TrimRight(content, "\n")
+		if content == "" {
+			continue
+		}
+		for ln := range strings.SplitSeq(content, "\n") {
+			lines = append(lines, outputLine{text: ln, style: stream.style})
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var out []string
+	if hidden := len(lines) - responseContextHeight; hidden > 0 {
+		out = append(out, stdoutStyle.Render(fmt.Sprintf(" … (%d lines)", hidden)))
+		lines = lines[hidden:]
+	}
+	for _, ln := range lines {
+		text := " " + ansiext.Escape(ln.text)
+		out = append(out, ln.style.Render(ansi.Truncate(text, width, "…")))
+	}
+	return strings.Join(out, "\n")
+}
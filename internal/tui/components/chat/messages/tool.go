@@ -43,6 +43,7 @@ type ToolCallCmp interface {
 	ID() string
 	SetPermissionRequested() // Mark permission request
 	SetPermissionGranted()   // Mark permission granted
+	SetLiveOutput(string)    // Update the output of the running tool
 }
 
 // toolCallCmp implements the ToolCallCmp interface for displaying tool calls.
@@ -59,6 +60,7 @@ type toolCallCmp struct {
 	cancelled           bool               // Whether the tool call was cancelled
 	permissionRequested bool
 	permissionGranted   bool
+	liveOutput          string // Tail of the output while the tool runs
 
 	// Animation state for pending tool calls
 	spinning bool       // Whether to show loading animation
@@ -168,6 +170,9 @@ func (m *toolCallCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if key.Matches(msg, CopyKey) {
 			return m, m.copyTool()
 		}
+		if key.Matches(msg, CancelToolKey) && m.running() {
+			return m, util.CmdHandler(CancelToolCallMsg{ToolCallID: m.call.ID})
+		}
 	}
 	return m, nil
 }
@@ -797,6 +802,12 @@ func (m *toolCallCmp) Spinning() bool {
 	return m.spinning
 }
 
+// running reports whether the tool is executing: its call is complete but
+// it has no result yet.
+func (m *toolCallCmp) running() bool {
+	return m.call.Finished && m.result.ToolCallID == "" && !m.cancelled
+}
+
 func (m *toolCallCmp) ID() string {
 	return m.call.ID
 }
@@ -810,3 +821,8 @@ func (m *toolCallCmp) SetPermissionRequested() {
 func (m *toolCallCmp) SetPermissionGranted() {
 	m.permissionGranted = true
 }
+
+// SetLiveOutput updates the output shown while the tool runs
+func (m *toolCallCmp) SetLiveOutput(output string) {
+	m.liveOutput = output
+}
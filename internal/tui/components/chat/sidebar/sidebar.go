@@ -1,6 +1,7 @@
 package sidebar
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"os"
@@ -12,14 +13,18 @@ import (
     "github.com/lacymorrow/lash/internal/config"
     "github.com/lacymorrow/lash/internal/csync"
     "github.com/lacymorrow/lash/internal/diff"
+    "github.com/lacymorrow/lash/internal/fsext"
     "github.com/lacymorrow/lash/internal/history"
+    "github.com/lacymorrow/lash/internal/llm/agent"
     "github.com/lacymorrow/lash/internal/lsp"
     "github.com/lacymorrow/lash/internal/pubsub"
     "github.com/lacymorrow/lash/internal/session"
+    "github.com/lacymorrow/lash/internal/shell"
     "github.com/lacymorrow/lash/internal/tui/components/chat"
     "github.com/lacymorrow/lash/internal/tui/components/core"
     "github.com/lacymorrow/lash/internal/tui/components/core/layout"
     "github.com/lacymorrow/lash/internal/tui/components/files"
+    "github.com/lacymorrow/lash/internal/tui/components/jobs"
     "github.com/lacymorrow/lash/internal/tui/components/logo"
     lspcomponent "github.com/lacymorrow/lash/internal/tui/components/lsp"
     "github.com/lacymorrow/lash/internal/tui/components/mcp"
@@ -27,6 +32,7 @@ import (
     "github.com/lacymorrow/lash/internal/tui/util"
     "github.com/lacymorrow/lash/internal/version"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -43,6 +49,7 @@ const (
 	DefaultMaxFilesShown = 10
 	DefaultMaxLSPsShown  = 8
 	DefaultMaxMCPsShown  = 8
+	DefaultMaxJobsShown  = 5
 	MinItemsPerSection   = 2 // Minimum items to show per section
 )
 
@@ -72,12 +79,16 @@ type sidebarCmp struct {
 	compactMode   bool
 	history       history.Service
 	files         *csync.Map[string, SessionFile]
+	jobs          []shell.JobInfo
+	subAgents     []agent.SubAgent
+	shells        *shell.Manager
 }
 
-func New(history history.Service, lspClients map[string]*lsp.Client, compact bool) Sidebar {
+func New(history history.Service, lspClients map[string]*lsp.Client, shells *shell.Manager, compact bool) Sidebar {
 	return &sidebarCmp{
 		lspClients:  lspClients,
 		history:     history,
+		shells:      shells,
 		compactMode: compact,
 		files:       csync.NewMap[string, SessionFile](),
 	}
@@ -100,6 +111,10 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.session = session.Session{}
 	case pubsub.Event[history.File]:
 		return m, m.handleFileHistoryEvent(msg)
+	case pubsub.Event[shell.JobInfo]:
+		m.handleJobEvent(msg)
+	case pubsub.Event[agent.SubAgent]:
+		m.handleSubAgentEvent(msg)
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
 			if m.session.ID == msg.Payload.ID {
@@ -140,10 +155,11 @@ func (m *sidebarCmp) View() string {
 	}
 
 	if !m.compactMode {
-		parts = append(parts,
-			m.cwd,
-			"",
-		)
+		parts = append(parts, m.cwd)
+		if divergence := m.divergenceBlock(); divergence != "" {
+			parts = append(parts, divergence)
+		}
+		parts = append(parts, "")
 	}
 	parts = append(parts,
 		m.currentModelBlock(),
@@ -161,6 +177,15 @@ func (m *sidebarCmp) View() string {
 		if m.session.ID != "" {
 			parts = append(parts, "", m.filesBlock())
 		}
+		if subAgents := m.sessionSubAgents(); len(subAgents) > 0 {
+			parts = append(parts, "", m.subAgentsBlock(subAgents))
+		}
+		if jobs := m.sessionJobs(shell.UserShell); len(jobs) > 0 {
+			parts = append(parts, "", m.jobsBlock(jobs, "Jobs"))
+		}
+		if processes := m.sessionJobs(shell.AgentShell); len(processes) > 0 {
+			parts = append(parts, "", m.jobsBlock(processes, "Processes"))
+		}
 		parts = append(parts,
 			"",
 			m.lspBlock(),
@@ -219,6 +244,42 @@ func (m *sidebarCmp) handleFileHistoryEvent(event pubsub.Event[history.File]) te
 	}
 }
 
+// handleJobEvent keeps the list of background jobs of the shells in sync
+// with the shells.
+func (m *sidebarCmp) handleJobEvent(event pubsub.Event[shell.JobInfo]) {
+	i := slices.IndexFunc(m.jobs, func(j shell.JobInfo) bool {
+		return j.SessionID == event.Payload.SessionID && j.Shell == event.Payload.Shell && j.ID == event.Payload.ID
+	})
+	switch {
+	case event.Type == pubsub.DeletedEvent:
+		if i >= 0 {
+			m.jobs = slices.Delete(m.jobs, i, i+1)
+		}
+	case i >= 0:
+		m.jobs[i] = event.Payload
+	default:
+		m.jobs = append(m.jobs, event.Payload)
+	}
+}
+
+// handleSubAgentEvent keeps the list of running sub-agents in sync with the
+// agent tool.
+func (m *sidebarCmp) handleSubAgentEvent(event pubsub.Event[agent.SubAgent]) {
+	i := slices.IndexFunc(m.subAgents, func(a agent.SubAgent) bool {
+		return a.SessionID == event.Payload.SessionID
+	})
+	switch {
+	case event.Type == pubsub.DeletedEvent:
+		if i >= 0 {
+			m.subAgents = slices.Delete(m.subAgents, i, i+1)
+		}
+	case i >= 0:
+		m.subAgents[i] = event.Payload
+	default:
+		m.subAgents = append(m.subAgents, event.Payload)
+	}
+}
+
 func (m *sidebarCmp) loadSessionFiles() tea.Msg {
 	files, err := m.history.ListBySession(context.Background(), m.session.ID)
 	if err != nil {
@@ -474,6 +535,94 @@ func (m *sidebarCmp) filesBlock() string {
 	}, true)
 }
 
+// sessionJobs returns the background jobs of the current session's shell of
+// the given kind: jobs of the user's shell, or processes of the agent's.
+func (m *sidebarCmp) sessionJobs(kind shell.Kind) []shell.JobInfo {
+	var sessionJobs []shell.JobInfo
+	for _, job := range m.jobs {
+		if job.SessionID == m.session.ID && job.Shell == kind {
+			sessionJobs = append(sessionJobs, job)
+		}
+	}
+	return sessionJobs
+}
+
+// sessionSubAgents returns the running sub-agents of the session.
+func (m *sidebarCmp) sessionSubAgents() []agent.SubAgent {
+	var subAgents []agent.SubAgent
+	for _, subAgent := range m.subAgents {
+		if subAgent.ParentSessionID == m.session.ID {
+			subAgents = append(subAgents, subAgent)
+		}
+	}
+	return subAgents
+}
+
+// subAgentsBlock shows what each running sub-agent is doing.
+func (m *sidebarCmp) subAgentsBlock(subAgents []agent.SubAgent) string {
+	t := styles.CurrentTheme()
+	maxWidth := m.getMaxWidth()
+	lines := []string{core.Section("Sub-agents", maxWidth), ""}
+	for _, subAgent := range subAgents {
+		prompt, _, _ := strings.Cut(strings.TrimSpace(subAgent.Prompt), "\n")
+		status := "starting"
+		if subAgent.ToolCalls > 0 {
+			status = fmt.Sprintf("%d tools · %s", subAgent.ToolCalls, subAgent.CurrentTool)
+		}
+		if subAgent.Cost > 0 {
+			status += fmt.Sprintf(" · $%.2f", subAgent.Cost)
+		}
+		lines = append(lines,
+			core.Status(
+				core.StatusOpts{
+					Icon:        t.ItemBusyIcon.String(),
+					Title:       ansi.Truncate(prompt, maxWidth/2, "…"),
+					Description: status,
+				},
+				maxWidth,
+			),
+		)
+	}
+	return lipgloss.NewStyle().Width(maxWidth).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+func (m *sidebarCmp) jobsBlock(sessionJobs []shell.JobInfo, section string) string {
+	return jobs.RenderJobBlock(sessionJobs, jobs.RenderOptions{
+		MaxWidth:    m.getMaxWidth(),
+		MaxItems:    DefaultMaxJobsShown,
+		ShowSection: true,
+		SectionName: core.Section(section, m.getMaxWidth()),
+	}, true)
+}
+
+// divergenceBlock shows where the agent's shell and the user's shell of the
+// session differ, if they do.
+func (m *sidebarCmp) divergenceBlock() string {
+	if m.shells == nil || m.session.ID == "" {
+		return ""
+	}
+	d := m.shells.Divergence(m.session.ID)
+	if !d.Diverged() {
+		return ""
+	}
+
+	t := styles.CurrentTheme()
+	maxWidth := m.getMaxWidth()
+	lines := []string{}
+	if d.AgentCwd != d.UserCwd {
+		lines = append(lines,
+			t.S().Warning.Render(ansi.Truncate("shell: "+fsext.DirTrim(fsext.PrettyPath(d.UserCwd), 2), maxWidth, "…")),
+			t.S().Warning.Render(ansi.Truncate("agent: "+fsext.DirTrim(fsext.PrettyPath(d.AgentCwd), 2), maxWidth, "…")),
+		)
+	}
+	if len(d.Env) > 0 {
+		lines = append(lines,
+			t.S().Warning.Render(ansi.Truncate("env differs: "+strings.Join(d.Env, ", "), maxWidth, "…")),
+		)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
 func (m *sidebarCmp) lspBlock() string {
 	// Limit the number of LSPs shown
 	_, maxLSPs, _ := m.getDynamicLimits()
@@ -542,7 +691,10 @@ func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
 
 func (s *sidebarCmp) currentModelBlock() string {
 	cfg := config.Get()
-	agentCfg := cfg.Agents["coder"]
+	agentCfg := cfg.Agents[cmp.Or(s.session.AgentID, cfg.Options.DefaultAgent, "coder")]
+	if cfg.GetModelByType(agentCfg.Model) == nil {
+		agentCfg = cfg.Agents["coder"]
+	}
 
 	selectedModel := cfg.Models[agentCfg.Model]
 
@@ -554,6 +706,9 @@ func (s *sidebarCmp) currentModelBlock() string {
 	modelIcon := t.S().Base.Foreground(t.FgSubtle).Render(styles.ModelIcon)
 	modelName := t.S().Text.Render(model.Name)
 	modelInfo := fmt.Sprintf("%s %s", modelIcon, modelName)
+	if agentCfg.ID != "coder" {
+		modelInfo += t.S().Subtle.Render(" · " + agentCfg.Name)
+	}
 	parts := []string{
 		modelInfo,
 	}
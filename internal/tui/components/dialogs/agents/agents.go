@@ -0,0 +1,175 @@
+package agents
+
+import (
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/tui/components/core"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
+	"github.com/lacymorrow/lash/internal/tui/exp/list"
+	"github.com/lacymorrow/lash/internal/tui/styles"
+	"github.com/lacymorrow/lash/internal/tui/util"
+)
+
+const AgentsDialogID dialogs.DialogID = "agents"
+
+// AgentSelectedMsg is sent when an agent is selected
+type AgentSelectedMsg struct {
+	Agent config.Agent
+}
+
+// AgentDialog interface for the agent switching dialog
+type AgentDialog interface {
+	dialogs.DialogModel
+}
+
+type AgentsList = list.FilterableList[list.CompletionItem[config.Agent]]
+
+type agentDialogCmp struct {
+	wWidth          int
+	wHeight         int
+	width           int
+	selectedAgentID string
+	keyMap          KeyMap
+	agentsList      AgentsList
+	help            help.Model
+}
+
+// NewAgentDialogCmp creates a new agent switching dialog
+func NewAgentDialogCmp(agents []config.Agent, selectedID string) AgentDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[config.Agent], len(agents))
+	for i, agent := range agents {
+		title := agent.Name
+		if agent.Description != "" {
+			title += " - " + agent.Description
+		}
+		items[i] = list.NewCompletionItem(title, agent, list.WithCompletionID(agent.ID), list.WithCompletionShortcut(string(agent.Model)))
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	agentsList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter an agent name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &agentDialogCmp{
+		selectedAgentID: selectedID,
+		keyMap:          keyMap,
+		agentsList:      agentsList,
+		help:            help,
+	}
+}
+
+func (s *agentDialogCmp) Init() tea.Cmd {
+	return tea.Sequence(s.agentsList.Init(), s.agentsList.Focus())
+}
+
+func (s *agentDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		var cmds []tea.Cmd
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(80, s.wWidth-8)
+		s.agentsList.SetInputWidth(s.listWidth() - 2)
+		cmds = append(cmds, s.agentsList.SetSize(s.listWidth(), s.listHeight()))
+		if s.selectedAgentID != "" {
+			cmds = append(cmds, s.agentsList.SetSelected(s.selectedAgentID))
+		}
+		return s, tea.Batch(cmds...)
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.agentsList.SelectedItem()
+			if selectedItem != nil {
+				selected := *selectedItem
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.CmdHandler(AgentSelectedMsg{Agent: selected.Value()}),
+				)
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.agentsList.Update(msg)
+			s.agentsList = u.(AgentsList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+func (s *agentDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Agent", s.width-4)),
+		s.agentsList.View(),
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+	return s.style().Render(content)
+}
+
+func (s *agentDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.agentsList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *agentDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *agentDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *agentDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *agentDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *agentDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements AgentDialog.
+func (s *agentDialogCmp) ID() dialogs.DialogID {
+	return AgentsDialogID
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/charmbracelet/bubbles/v2/help"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/lacymorrow/lash/internal/config"
 	"github.com/lacymorrow/lash/internal/llm/prompt"
+	"github.com/lacymorrow/lash/internal/shell"
 	"github.com/lacymorrow/lash/internal/tui/components/chat"
 	"github.com/lacymorrow/lash/internal/tui/components/core"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
@@ -57,12 +59,14 @@ type commandDialogCmp struct {
 	commandType  int       // SystemCommands or UserCommands
 	userCommands []Command // User-defined commands
 	sessionID    string    // Current session ID
+	jobs         []shell.JobInfo
 }
 
 type (
 	SwitchSessionsMsg     struct{}
 	NewSessionsMsg        struct{}
 	SwitchModelMsg        struct{}
+	SwitchAgentMsg        struct{}
 	QuitMsg               struct{}
 	OpenFilePickerMsg     struct{}
 	ToggleHelpMsg         struct{}
@@ -71,12 +75,23 @@ type (
 	OpenExternalEditorMsg struct{}
 	OpenConfigFileMsg     struct{}
 	ToggleYoloModeMsg     struct{}
+	ImportShellHistoryMsg struct{}
+	ShowRoutesMsg         struct{}
 	CompactMsg            struct {
 		SessionID string
 	}
+	// AttachJobOutputMsg attaches the output of a background job to the
+	// prompt.
+	AttachJobOutputMsg struct {
+		ID int
+	}
+	// SetModeMsg switches the input mode to Shell, Agent or Auto.
+	SetModeMsg struct {
+		Mode string
+	}
 )
 
-func NewCommandDialog(sessionID string) CommandsDialog {
+func NewCommandDialog(sessionID string, jobs []shell.JobInfo) CommandsDialog {
 	keyMap := DefaultCommandsDialogKeyMap()
 	listKeyMap := list.DefaultKeyMap()
 	listKeyMap.Down.SetEnabled(false)
@@ -104,6 +119,7 @@ func NewCommandDialog(sessionID string) CommandsDialog {
 		help:        help,
 		commandType: SystemCommands,
 		sessionID:   sessionID,
+		jobs:        jobs,
 	}
 }
 
@@ -285,6 +301,14 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(SwitchModelMsg{})
 			},
 		},
+		{
+			ID:          "switch_agent",
+			Title:       "Switch Agent",
+			Description: "Switch the agent of the session",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SwitchAgentMsg{})
+			},
+		},
 	}
 
 	// Only show compact command if there's an active session
@@ -373,6 +397,17 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 		},
 	})
 
+	for _, job := range c.jobs {
+		commands = append(commands, Command{
+			ID:          fmt.Sprintf("attach_job_%d", job.ID),
+			Title:       fmt.Sprintf("Attach Output of Job %d", job.ID),
+			Description: job.Command,
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(AttachJobOutputMsg{ID: job.ID})
+			},
+		})
+	}
+
 	return append(commands, []Command{
 		{
 			ID:          "toggle_yolo",
@@ -391,6 +426,22 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(ToggleHelpMsg{})
 			},
 		},
+		{
+			ID:          "import_shell_history",
+			Title:       "Import Shell History",
+			Description: "Add bash, zsh and fish history to suggestions and history search",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ImportShellHistoryMsg{})
+			},
+		},
+		{
+			ID:          "show_routes",
+			Title:       "Show Auto Mode Routes",
+			Description: "See where recent Auto mode lines went and why",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ShowRoutesMsg{})
+			},
+		},
 		{
 			ID:          "init",
 			Title:       "Initialize Project",
@@ -0,0 +1,238 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/lacymorrow/lash/internal/inputhistory"
+	"github.com/lacymorrow/lash/internal/tui/components/core"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
+	"github.com/lacymorrow/lash/internal/tui/exp/list"
+	"github.com/lacymorrow/lash/internal/tui/styles"
+	"github.com/lacymorrow/lash/internal/tui/util"
+)
+
+const HistoryDialogID dialogs.DialogID = "history"
+
+// maxResults is the number of history entries listed at once.
+const maxResults = 100
+
+// SelectedMsg is sent when an entry is picked in the history search.
+type SelectedMsg struct {
+	Text string
+}
+
+// SearchFunc returns the history entries matching query, most relevant
+// first.
+type SearchFunc func(query string, limit int) []inputhistory.Entry
+
+// HistoryDialog is the incremental reverse search over the input history.
+type HistoryDialog interface {
+	dialogs.DialogModel
+}
+
+type EntriesList = list.List[list.CompletionItem[inputhistory.Entry]]
+
+type historyDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+	search  SearchFunc
+	query   string
+	input   textinput.Model
+	list    EntriesList
+	keyMap  KeyMap
+	help    help.Model
+}
+
+// NewHistoryDialogCmp creates the history search dialog, starting with
+// query.
+func NewHistoryDialogCmp(search SearchFunc, query string) HistoryDialog {
+	t := styles.CurrentTheme()
+
+	ti := textinput.New()
+	ti.Placeholder = "Search history"
+	ti.SetVirtualCursor(false)
+	ti.SetStyles(t.S().TextInput)
+	ti.SetValue(query)
+	ti.Focus()
+
+	help := help.New()
+	help.Styles = t.S().Help
+	h := &historyDialogCmp{
+		search: search,
+		input:  ti,
+		list:   list.New[list.CompletionItem[inputhistory.Entry]](nil, list.WithWrapNavigation()),
+		keyMap: DefaultKeyMap(),
+		help:   help,
+	}
+	return h
+}
+
+func (h *historyDialogCmp) Init() tea.Cmd {
+	return tea.Sequence(h.list.Init(), h.list.Focus(), h.refresh())
+}
+
+func (h *historyDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		h.wWidth = msg.Width
+		h.wHeight = msg.Height
+		h.width = min(120, h.wWidth-8)
+		h.input.SetWidth(h.listWidth() - 4)
+		return h, h.list.SetSize(h.listWidth(), h.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, h.keyMap.Select):
+			selectedItem := h.list.SelectedItem()
+			if selectedItem == nil {
+				return h, nil
+			}
+			selected := *selectedItem
+			return h, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(SelectedMsg{Text: selected.Value().Text}),
+			)
+		case key.Matches(msg, h.keyMap.Next):
+			return h, h.list.SelectItemBelow()
+		case key.Matches(msg, h.keyMap.Previous):
+			return h, h.list.SelectItemAbove()
+		case key.Matches(msg, h.keyMap.Close):
+			return h, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			var cmd tea.Cmd
+			h.input, cmd = h.input.Update(msg)
+			if h.input.Value() == h.query {
+				return h, cmd
+			}
+			return h, tea.Batch(cmd, h.refresh())
+		}
+	}
+	return h, nil
+}
+
+// refresh lists the entries matching the query.
+func (h *historyDialogCmp) refresh() tea.Cmd {
+	h.query = h.input.Value()
+	entries := h.search(h.query, maxResults)
+	items := make([]list.CompletionItem[inputhistory.Entry], len(entries))
+	for i, e := range entries {
+		title := strings.ReplaceAll(e.Text, "\n", " ")
+		opts := []list.CompletionItemOption{
+			list.WithCompletionID(fmt.Sprintf("%d", i)),
+			list.WithCompletionShortcut(describe(e)),
+		}
+		if indexes := matchIndexes(title, h.query); len(indexes) > 0 {
+			opts = append(opts, list.WithCompletionMatchIndexes(indexes...))
+		}
+		items[i] = list.NewCompletionItem(title, e, opts...)
+	}
+	return h.list.SetItems(items)
+}
+
+// matchIndexes returns the byte positions of the first case-insensitive
+// occurrence of query in text.
+func matchIndexes(text, query string) []int {
+	lower := strings.ToLower(text)
+	// Lowercasing may change the length of some characters, in which case
+	// positions would no longer line up with text.
+	if query == "" || len(lower) != len(text) {
+		return nil
+	}
+	start := strings.Index(lower, strings.ToLower(query))
+	if start < 0 {
+		return nil
+	}
+	indexes := make([]int, len(query))
+	for i := range indexes {
+		indexes[i] = start + i
+	}
+	return indexes
+}
+
+// describe summarizes when and how an entry was run.
+func describe(e inputhistory.Entry) string {
+	var parts []string
+	if e.Failed() {
+		parts = append(parts, fmt.Sprintf("exit %d", *e.ExitCode))
+	}
+	if e.Source != "" {
+		parts = append(parts, e.Source)
+	}
+	if !e.Time.IsZero() {
+		parts = append(parts, age(time.Since(e.Time)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// age formats d in its largest whole unit, like "5m" or "3d".
+func age(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+func (h *historyDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Search History", h.width-4)),
+		t.S().Base.PaddingLeft(1).PaddingBottom(1).Render(h.input.View()),
+		h.list.View(),
+		"",
+		t.S().Base.Width(h.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(h.help.View(h.keyMap)),
+	)
+	return h.style().Render(content)
+}
+
+func (h *historyDialogCmp) Cursor() *tea.Cursor {
+	cursor := h.input.Cursor()
+	if cursor == nil {
+		return nil
+	}
+	row, col := h.Position()
+	cursor.Y += row + 3 // Border + title
+	cursor.X += col + 2
+	return cursor
+}
+
+func (h *historyDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(h.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (h *historyDialogCmp) listHeight() int {
+	return h.wHeight/2 - 8 // border, title, input and help
+}
+
+func (h *historyDialogCmp) listWidth() int {
+	return h.width - 2 // 2 for the border
+}
+
+func (h *historyDialogCmp) Position() (int, int) {
+	row := h.wHeight/4 - 2 // just a bit above the center
+	col := h.wWidth / 2
+	col -= h.width / 2
+	return row, col
+}
+
+// ID implements HistoryDialog.
+func (h *historyDialogCmp) ID() dialogs.DialogID {
+	return HistoryDialogID
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+type KeyMap struct {
+	Edit,
+	Next,
+	Previous,
+	MoveUp,
+	MoveDown,
+	Delete,
+	Close key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Edit: key.NewBinding(
+			key.WithKeys("enter", "tab", "ctrl+y"),
+			key.WithHelp("enter", "edit"),
+		),
+		Next: key.NewBinding(
+			key.WithKeys("down", "ctrl+n"),
+			key.WithHelp("↓", "next item"),
+		),
+		Previous: key.NewBinding(
+			key.WithKeys("up", "ctrl+p"),
+			key.WithHelp("↑", "previous item"),
+		),
+		MoveUp: key.NewBinding(
+			key.WithKeys("shift+up", "ctrl+up", "K"),
+			key.WithHelp("shift+↑", "move up"),
+		),
+		MoveDown: key.NewBinding(
+			key.WithKeys("shift+down", "ctrl+down", "J"),
+			key.WithHelp("shift+↓", "move down"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("ctrl+d", "delete", "x"),
+			key.WithHelp("x", "delete"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "ctrl+q"),
+			key.WithHelp("esc", "close"),
+		),
+	}
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Edit,
+		k.Next,
+		k.Previous,
+		k.MoveUp,
+		k.MoveDown,
+		k.Delete,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	m := [][]key.Binding{}
+	slice := k.KeyBindings()
+	for i := 0; i < len(slice); i += 4 {
+		end := min(i+4, len(slice))
+		m = append(m, slice[i:end])
+	}
+	return m
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(
+			key.WithKeys("down", "up"),
+			key.WithHelp("↑↓", "choose"),
+		),
+		k.Edit,
+		k.MoveUp,
+		k.MoveDown,
+		k.Delete,
+		k.Close,
+	}
+}
@@ -0,0 +1,200 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/lacymorrow/lash/internal/llm/agent"
+	"github.com/lacymorrow/lash/internal/pubsub"
+	"github.com/lacymorrow/lash/internal/tui/components/core"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
+	"github.com/lacymorrow/lash/internal/tui/exp/list"
+	"github.com/lacymorrow/lash/internal/tui/styles"
+	"github.com/lacymorrow/lash/internal/tui/util"
+)
+
+const QueueDialogID dialogs.DialogID = "queue"
+
+// EditMsg is sent when a queued prompt is picked for editing.
+type EditMsg struct {
+	SessionID string
+	Prompt    agent.QueuedPrompt
+}
+
+// QueueDialog lists the prompts queued in a session, to edit, reorder or
+// delete them.
+type QueueDialog interface {
+	dialogs.DialogModel
+}
+
+type PromptsList = list.List[list.CompletionItem[agent.QueuedPrompt]]
+
+type queueDialogCmp struct {
+	wWidth    int
+	wHeight   int
+	width     int
+	agents    *agent.Agents
+	sessionID string
+	list      PromptsList
+	keyMap    KeyMap
+	help      help.Model
+}
+
+// NewQueueDialogCmp creates the dialog for the prompt queue of a session.
+func NewQueueDialogCmp(agents *agent.Agents, sessionID string) QueueDialog {
+	t := styles.CurrentTheme()
+	help := help.New()
+	help.Styles = t.S().Help
+	return &queueDialogCmp{
+		agents:    agents,
+		sessionID: sessionID,
+		list:      list.New[list.CompletionItem[agent.QueuedPrompt]](nil, list.WithWrapNavigation()),
+		keyMap:    DefaultKeyMap(),
+		help:      help,
+	}
+}
+
+func (q *queueDialogCmp) Init() tea.Cmd {
+	return tea.Sequence(q.list.Init(), q.list.Focus(), q.refresh(""))
+}
+
+func (q *queueDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		q.wWidth = msg.Width
+		q.wHeight = msg.Height
+		q.width = min(100, q.wWidth-8)
+		return q, q.list.SetSize(q.listWidth(), q.listHeight())
+	case pubsub.Event[agent.PromptQueue]:
+		if msg.Payload.SessionID != q.sessionID {
+			return q, nil
+		}
+		return q, q.refresh(q.selectedID())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, q.keyMap.Edit):
+			selectedItem := q.list.SelectedItem()
+			if selectedItem == nil {
+				return q, nil
+			}
+			selected := *selectedItem
+			return q, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(EditMsg{SessionID: q.sessionID, Prompt: selected.Value()}),
+			)
+		case key.Matches(msg, q.keyMap.MoveUp):
+			return q, q.move(-1)
+		case key.Matches(msg, q.keyMap.MoveDown):
+			return q, q.move(1)
+		case key.Matches(msg, q.keyMap.Delete):
+			id := q.selectedID()
+			if id == "" {
+				return q, nil
+			}
+			if err := q.agents.RemoveQueued(q.sessionID, id); err != nil {
+				return q, util.ReportError(err)
+			}
+			return q, q.refresh("")
+		case key.Matches(msg, q.keyMap.Next):
+			return q, q.list.SelectItemBelow()
+		case key.Matches(msg, q.keyMap.Previous):
+			return q, q.list.SelectItemAbove()
+		case key.Matches(msg, q.keyMap.Close):
+			return q, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return q, nil
+}
+
+// move moves the selected prompt offset places in the queue.
+func (q *queueDialogCmp) move(offset int) tea.Cmd {
+	id := q.selectedID()
+	if id == "" {
+		return nil
+	}
+	if err := q.agents.MoveQueued(q.sessionID, id, offset); err != nil {
+		return util.ReportError(err)
+	}
+	return q.refresh(id)
+}
+
+// selectedID returns the ID of the selected prompt, if any.
+func (q *queueDialogCmp) selectedID() string {
+	selectedItem := q.list.SelectedItem()
+	if selectedItem == nil {
+		return ""
+	}
+	return (*selectedItem).Value().ID
+}
+
+// refresh lists the prompts queued in the session, keeping the prompt with
+// the given ID selected if it is still queued.
+func (q *queueDialogCmp) refresh(selectedID string) tea.Cmd {
+	prompts := q.agents.Queue(q.sessionID)
+	items := make([]list.CompletionItem[agent.QueuedPrompt], len(prompts))
+	for i, p := range prompts {
+		title := strings.ReplaceAll(p.Content, "\n", " ")
+		opts := []list.CompletionItemOption{
+			list.WithCompletionID(p.ID),
+			list.WithCompletionShortcut(fmt.Sprintf("#%d", i+1)),
+		}
+		items[i] = list.NewCompletionItem(title, p, opts...)
+	}
+	cmds := []tea.Cmd{q.list.SetItems(items)}
+	if selectedID != "" {
+		cmds = append(cmds, q.list.SetSelected(selectedID))
+	}
+	return tea.Sequence(cmds...)
+}
+
+func (q *queueDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	body := q.list.View()
+	if len(q.list.Items()) == 0 {
+		body = t.S().Base.PaddingLeft(1).Render(t.S().Subtle.Render("No queued prompts"))
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Queued Prompts", q.width-4)),
+		body,
+		"",
+		t.S().Base.Width(q.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(q.help.View(q.keyMap)),
+	)
+	return q.style().Render(content)
+}
+
+func (q *queueDialogCmp) Cursor() *tea.Cursor {
+	return nil
+}
+
+func (q *queueDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(q.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (q *queueDialogCmp) listHeight() int {
+	return q.wHeight/2 - 6 // border, title and help
+}
+
+func (q *queueDialogCmp) listWidth() int {
+	return q.width - 2 // 2 for the border
+}
+
+func (q *queueDialogCmp) Position() (int, int) {
+	row := q.wHeight/4 - 2 // just a bit above the center
+	col := q.wWidth / 2
+	col -= q.width / 2
+	return row, col
+}
+
+// ID implements QueueDialog.
+func (q *queueDialogCmp) ID() dialogs.DialogID {
+	return QueueDialogID
+}
@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/lacymorrow/lash/internal/router"
+	"github.com/lacymorrow/lash/internal/tui/components/core"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
+	"github.com/lacymorrow/lash/internal/tui/exp/list"
+	"github.com/lacymorrow/lash/internal/tui/styles"
+	"github.com/lacymorrow/lash/internal/tui/util"
+)
+
+const RoutesDialogID dialogs.DialogID = "routes"
+
+// RoutesDialog lists the recent Auto-mode routing decisions, newest first,
+// with why each line went to the shell or the agent.
+type RoutesDialog interface {
+	dialogs.DialogModel
+}
+
+type DecisionsList = list.List[list.CompletionItem[router.Decision]]
+
+type routesDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+	list    DecisionsList
+	keyMap  KeyMap
+	help    help.Model
+}
+
+// NewRoutesDialogCmp creates the dialog for decisions, given oldest first
+// as Router.Decisions returns them.
+func NewRoutesDialogCmp(decisions []router.Decision) RoutesDialog {
+	t := styles.CurrentTheme()
+	items := make([]list.CompletionItem[router.Decision], 0, len(decisions))
+	for i, d := range slices.Backward(decisions) {
+		items = append(items, list.NewCompletionItem(
+			strings.ReplaceAll(d.Input, "\n", " "),
+			d,
+			list.WithCompletionID(strconv.Itoa(i)),
+			list.WithCompletionShortcut(fmt.Sprintf("%s %+.1f", d.Target, d.Score)),
+		))
+	}
+	help := help.New()
+	help.Styles = t.S().Help
+	return &routesDialogCmp{
+		list:   list.New(items, list.WithWrapNavigation()),
+		keyMap: DefaultKeyMap(),
+		help:   help,
+	}
+}
+
+func (r *routesDialogCmp) Init() tea.Cmd {
+	return tea.Sequence(r.list.Init(), r.list.Focus())
+}
+
+func (r *routesDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		r.wWidth = msg.Width
+		r.wHeight = msg.Height
+		r.width = min(100, r.wWidth-8)
+		return r, r.list.SetSize(r.listWidth(), r.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, r.keyMap.Next):
+			return r, r.list.SelectItemBelow()
+		case key.Matches(msg, r.keyMap.Previous):
+			return r, r.list.SelectItemAbove()
+		case key.Matches(msg, r.keyMap.Close):
+			return r, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return r, nil
+}
+
+func (r *routesDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	body := r.list.View()
+	if len(r.list.Items()) == 0 {
+		body = t.S().Base.PaddingLeft(1).Render(t.S().Subtle.Render("No lines routed yet, type in Auto mode to route them"))
+	} else if selectedItem := r.list.SelectedItem(); selectedItem != nil {
+		d := (*selectedItem).Value()
+		why := fmt.Sprintf("%s, %s, score %+.1f at %s: %s", d.Target, d.Source, d.Score, d.Time.Format("15:04:05"), d.Reason)
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			body,
+			"",
+			t.S().Base.Width(r.width-2).Padding(0, 1).Render(t.S().Subtle.Render(why)),
+		)
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Auto Mode Routes", r.width-4)),
+		body,
+		"",
+		t.S().Base.Width(r.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(r.help.View(r.keyMap)),
+	)
+	return r.style().Render(content)
+}
+
+func (r *routesDialogCmp) Cursor() *tea.Cursor {
+	return nil
+}
+
+func (r *routesDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(r.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (r *routesDialogCmp) listHeight() int {
+	return r.wHeight/2 - 10 // border, title, reason and help
+}
+
+func (r *routesDialogCmp) listWidth() int {
+	return r.width - 2 // 2 for the border
+}
+
+func (r *routesDialogCmp) Position() (int, int) {
+	row := r.wHeight/4 - 2 // just a bit above the center
+	col := r.wWidth / 2
+	col -= r.width / 2
+	return row, col
+}
+
+// ID implements RoutesDialog.
+func (r *routesDialogCmp) ID() dialogs.DialogID {
+	return RoutesDialogID
+}
@@ -1,6 +1,8 @@
 package sessions
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -32,6 +34,10 @@ type sessionDialogCmp struct {
 	keyMap            KeyMap
 	sessionsList      SessionsList
 	help              help.Model
+
+	// All the sessions, and whether only the branches of one are listed
+	sessions     []session.Session
+	showBranches bool
 }
 
 // NewSessionDialogCmp creates a new session switching dialog
@@ -44,16 +50,9 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 	listKeyMap.DownOneItem = keyMap.Next
 	listKeyMap.UpOneItem = keyMap.Previous
 
-	items := make([]list.CompletionItem[session.Session], len(sessions))
-	if len(sessions) > 0 {
-		for i, session := range sessions {
-			items[i] = list.NewCompletionItem(session.Title, session, list.WithCompletionID(session.ID))
-		}
-	}
-
 	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
 	sessionsList := list.NewFilterableList(
-		items,
+		sessionItems(sessions, false),
 		list.WithFilterPlaceholder("Enter a session name"),
 		list.WithFilterInputStyle(inputStyle),
 		list.WithFilterListOptions(
@@ -68,6 +67,7 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 		keyMap:            DefaultKeyMap(),
 		sessionsList:      sessionsList,
 		help:              help,
+		sessions:          sessions,
 	}
 
 	return s
@@ -106,7 +106,15 @@ func (s *sessionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					),
 				)
 			}
+		case key.Matches(msg, s.keyMap.Branches):
+			if s.showBranches {
+				return s, s.listAll()
+			}
+			return s, s.listBranches()
 		case key.Matches(msg, s.keyMap.Close):
+			if s.showBranches {
+				return s, s.listAll()
+			}
 			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
 		default:
 			u, cmd := s.sessionsList.Update(msg)
@@ -117,12 +125,91 @@ func (s *sessionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return s, nil
 }
 
+// listBranches lists the selected session with the sessions forked from
+// the same conversation.
+func (s *sessionDialogCmp) listBranches() tea.Cmd {
+	selectedItem := s.sessionsList.SelectedItem()
+	if selectedItem == nil {
+		return nil
+	}
+	selected := (*selectedItem).Value()
+	s.showBranches = true
+	return tea.Sequence(
+		s.sessionsList.SetItems(sessionItems(branches(s.sessions, selected), true)),
+		s.sessionsList.SetSelected(selected.ID),
+	)
+}
+
+// listAll goes back to listing all the sessions.
+func (s *sessionDialogCmp) listAll() tea.Cmd {
+	var selectedID string
+	if selectedItem := s.sessionsList.SelectedItem(); selectedItem != nil {
+		selectedID = (*selectedItem).Value().ID
+	}
+	s.showBranches = false
+	return tea.Sequence(
+		s.sessionsList.SetItems(sessionItems(s.sessions, false)),
+		s.sessionsList.SetSelected(selectedID),
+	)
+}
+
+// sessionItems returns the list items of sessions. Forks are marked, and
+// when listing branches the original session is too.
+func sessionItems(sessions []session.Session, showBranches bool) []list.CompletionItem[session.Session] {
+	items := make([]list.CompletionItem[session.Session], len(sessions))
+	for i, session := range sessions {
+		var shortcut string
+		switch {
+		case showBranches && session.ForkedFromSessionID == "":
+			shortcut = fmt.Sprintf("original · %d messages", session.MessageCount)
+		case showBranches:
+			shortcut = fmt.Sprintf("fork · %d messages", session.MessageCount)
+		case session.ForkedFromSessionID != "":
+			shortcut = "fork"
+		}
+		items[i] = list.NewCompletionItem(session.Title, session, list.WithCompletionID(session.ID), list.WithCompletionShortcut(shortcut))
+	}
+	return items
+}
+
+// branches returns the sessions forked, directly or not, from the session
+// s was forked from, that session and s included.
+func branches(sessions []session.Session, s session.Session) []session.Session {
+	byID := make(map[string]session.Session, len(sessions))
+	for _, session := range sessions {
+		byID[session.ID] = session
+	}
+	root := func(session session.Session) string {
+		for session.ForkedFromSessionID != "" {
+			parent, ok := byID[session.ForkedFromSessionID]
+			if !ok {
+				break
+			}
+			session = parent
+		}
+		return session.ID
+	}
+
+	want := root(s)
+	var family []session.Session
+	for _, session := range sessions {
+		if root(session) == want {
+			family = append(family, session)
+		}
+	}
+	return family
+}
+
 func (s *sessionDialogCmp) View() string {
 	t := styles.CurrentTheme()
 	listView := s.sessionsList.View()
+	title := "Switch Session"
+	if s.showBranches {
+		title = "Switch Branch"
+	}
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
-		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Session", s.width-4)),
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title(title, s.width-4)),
 		listView,
 		"",
 		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/lacymorrow/lash/internal/shell"
+	"github.com/lacymorrow/lash/internal/tui/components/core"
+	"github.com/lacymorrow/lash/internal/tui/styles"
+)
+
+// RenderOptions contains options for rendering job lists.
+type RenderOptions struct {
+	MaxWidth    int
+	MaxItems    int
+	ShowSection bool
+	SectionName string
+}
+
+// RenderJobList renders background jobs, keeping the most recent ones when
+// there are more than MaxItems. Named jobs are shown by their name.
+func RenderJobList(jobs []shell.JobInfo, opts RenderOptions) []string {
+	t := styles.CurrentTheme()
+	jobList := []string{}
+
+	if opts.ShowSection {
+		sectionName := opts.SectionName
+		if sectionName == "" {
+			sectionName = "Jobs"
+		}
+		section := t.S().Subtle.Render(sectionName)
+		jobList = append(jobList, section, "")
+	}
+
+	if opts.MaxItems > 0 && len(jobs) > opts.MaxItems {
+		jobs = jobs[len(jobs)-opts.MaxItems:]
+	}
+
+	for _, job := range jobs {
+		icon := t.ItemOnlineIcon
+		switch job.State {
+		case shell.JobRunning:
+			icon = t.ItemBusyIcon
+		case shell.JobFailed, shell.JobKilled:
+			icon = t.ItemErrorIcon
+		}
+
+		title := fmt.Sprintf("[%d] %s", job.ID, job.Command)
+		if job.Name != "" {
+			title = job.Name
+		}
+		jobList = append(jobList,
+			core.Status(
+				core.StatusOpts{
+					Icon:        icon.String(),
+					Title:       title,
+					Description: t.S().Subtle.Render(job.Status()),
+				},
+				opts.MaxWidth,
+			),
+		)
+	}
+
+	return jobList
+}
+
+// RenderJobBlock renders a complete jobs block with optional truncation indicator.
+func RenderJobBlock(jobs []shell.JobInfo, opts RenderOptions, showTruncationIndicator bool) string {
+	t := styles.CurrentTheme()
+	jobList := RenderJobList(jobs, opts)
+
+	if showTruncationIndicator && opts.MaxItems > 0 && len(jobs) > opts.MaxItems {
+		remaining := len(jobs) - opts.MaxItems
+		jobList = append(jobList,
+			t.S().Base.Foreground(t.FgSubtle).Render(fmt.Sprintf("…and %d more", remaining)),
+		)
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, jobList...)
+	if opts.MaxWidth > 0 {
+		return lipgloss.NewStyle().Width(opts.MaxWidth).Render(content)
+	}
+	return content
+}
@@ -0,0 +1,139 @@
+package terminal
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// cursorKeys are the final bytes of the cursor and editing keys that are
+// sent as SS3 sequences in application cursor mode.
+var cursorKeys = map[rune]byte{
+	tea.KeyUp:    'A',
+	tea.KeyDown:  'B',
+	tea.KeyRight: 'C',
+	tea.KeyLeft:  'D',
+	tea.KeyHome:  'H',
+	tea.KeyEnd:   'F',
+}
+
+// tildeKeys are the keys sent as "CSI n ~".
+var tildeKeys = map[rune]int{
+	tea.KeyInsert: 2,
+	tea.KeyDelete: 3,
+	tea.KeyPgUp:   5,
+	tea.KeyPgDown: 6,
+	tea.KeyF5:     15,
+	tea.KeyF6:     17,
+	tea.KeyF7:     18,
+	tea.KeyF8:     19,
+	tea.KeyF9:     20,
+	tea.KeyF10:    21,
+	tea.KeyF11:    23,
+	tea.KeyF12:    24,
+}
+
+// functionKeys are F1 to F4, sent as SS3 sequences.
+var functionKeys = map[rune]byte{
+	tea.KeyF1: 'P',
+	tea.KeyF2: 'Q',
+	tea.KeyF3: 'R',
+	tea.KeyF4: 'S',
+}
+
+// keyToBytes encodes a key press the way xterm does. appCursor selects
+// application cursor keys, which programs like vim and less enable.
+func keyToBytes(k tea.Key, appCursor bool) []byte {
+	shift := k.Mod&tea.ModShift != 0
+	alt := k.Mod&tea.ModAlt != 0
+	ctrl := k.Mod&tea.ModCtrl != 0
+	// xterm's modifier parameter, 1 when no modifier is held.
+	mod := 1
+	if shift {
+		mod++
+	}
+	if alt {
+		mod += 2
+	}
+	if ctrl {
+		mod += 4
+	}
+
+	if final, ok := cursorKeys[k.Code]; ok {
+		switch {
+		case mod > 1:
+			return fmt.Appendf(nil, "\x1b[1;%d%c", mod, final)
+		case appCursor:
+			return []byte{0x1b, 'O', final}
+		default:
+			return []byte{0x1b, '[', final}
+		}
+	}
+	if n, ok := tildeKeys[k.Code]; ok {
+		if mod > 1 {
+			return fmt.Appendf(nil, "\x1b[%d;%d~", n, mod)
+		}
+		return fmt.Appendf(nil, "\x1b[%d~", n)
+	}
+	if final, ok := functionKeys[k.Code]; ok {
+		if mod > 1 {
+			return fmt.Appendf(nil, "\x1b[1;%d%c", mod, final)
+		}
+		return []byte{0x1b, 'O', final}
+	}
+
+	var seq []byte
+	switch {
+	case k.Code == tea.KeyEnter:
+		seq = []byte{'\r'}
+	case k.Code == tea.KeyTab && shift:
+		return []byte("\x1b[Z")
+	case k.Code == tea.KeyTab:
+		seq = []byte{'\t'}
+	case k.Code == tea.KeyBackspace && ctrl:
+		seq = []byte{0x08}
+	case k.Code == tea.KeyBackspace:
+		seq = []byte{0x7f}
+	case k.Code == tea.KeyEscape:
+		seq = []byte{0x1b}
+	case ctrl:
+		b, ok := ctrlByte(k.Code)
+		if !ok {
+			return nil
+		}
+		seq = []byte{b}
+	case k.Text != "":
+		seq = []byte(k.Text)
+	case k.Code == tea.KeySpace:
+		seq = []byte{' '}
+	case k.Code > 0 && k.Code < tea.KeyExtended:
+		seq = []byte(string(k.Code))
+	default:
+		return nil
+	}
+	if alt {
+		// Alt sends ESC before the key, like xterm's metaSendsEscape.
+		seq = append([]byte{0x1b}, seq...)
+	}
+	return seq
+}
+
+// ctrlByte returns the control character for Ctrl held with r.
+func ctrlByte(r rune) (byte, bool) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return byte(r-'a') + 1, true
+	case r >= '@' && r <= '_':
+		return byte(r - '@'), true
+	case r == ' ', r == '2':
+		return 0, true
+	case r >= '3' && r <= '7':
+		// Ctrl+3 to Ctrl+7 are ESC, FS, GS, RS and US.
+		return byte(r-'3') + 0x1b, true
+	case r == '8', r == '?':
+		return 0x7f, true
+	case r == '/':
+		return 0x1f, true
+	}
+	return 0, false
+}
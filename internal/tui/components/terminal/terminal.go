@@ -1,12 +1,21 @@
 package terminal
 
 import (
-	"strings"
-	"sync"
-
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/lacymorrow/lash/internal/app"
 	"github.com/lacymorrow/lash/internal/shell"
+	"github.com/lacymorrow/lash/internal/tui/components/core/layout"
+	"github.com/lacymorrow/lash/internal/vt"
+)
+
+const (
+	// Default size of the emulator until the container sets one.
+	defaultWidth  = 80
+	defaultHeight = 24
+
+	// wheelScrollLines is how far one wheel step scrolls the scrollback.
+	wheelScrollLines = 3
 )
 
 // TerminalOutputMsg carries raw bytes from the PTY to the UI loop.
@@ -15,23 +24,35 @@ type TerminalOutputMsg struct{ Data []byte }
 // TerminalClosedMsg indicates the PTY process exited.
 type TerminalClosedMsg struct{ Err error }
 
-// Terminal is a minimal Bubble Tea component that displays a PTY-backed user shell
-// and forwards keystrokes to it.
+// Terminal is a Bubble Tea component that displays a PTY-backed user shell
+// and forwards keystrokes, pastes and mouse events to it. Output goes
+// through a terminal emulator, so full-screen programs render correctly.
 type Terminal struct {
 	app    *app.App
 	width  int
 	height int
+	// x and y are the position of the component on screen, used to
+	// translate mouse coordinates.
+	x, y int
 
-	mu     sync.Mutex
 	pty    *shell.PTYShell
+	screen *vt.Terminal
 	closed bool
-
-	// naive text buffer – we accumulate and trim to a reasonable size
-	// Rendering assumes ANSI bytes can be passed through as-is.
-	buffer strings.Builder
 }
 
-func New(a *app.App) *Terminal { return &Terminal{app: a} }
+var (
+	_ layout.Sizeable   = (*Terminal)(nil)
+	_ layout.Positional = (*Terminal)(nil)
+)
+
+func New(a *app.App) *Terminal {
+	return &Terminal{
+		app:    a,
+		width:  defaultWidth,
+		height: defaultHeight,
+		screen: vt.New(defaultWidth, defaultHeight),
+	}
+}
 
 func (t *Terminal) Init() tea.Cmd {
 	if t.pty != nil {
@@ -44,6 +65,9 @@ func (t *Terminal) Init() tea.Cmd {
 		return func() tea.Msg { return TerminalClosedMsg{Err: err} }
 	}
 	t.pty = s
+	// Answer queries such as cursor position reports.
+	t.screen.SetReplyHandler(func(b []byte) { _, _ = s.Write(b) })
+	_ = t.pty.Resize(t.width, t.height)
 	return t.listen()
 }
 
@@ -67,14 +91,14 @@ func (t *Terminal) listen() tea.Cmd {
 func (t *Terminal) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch m := msg.(type) {
 	case tea.WindowSizeMsg:
-		_ = t.Resize(m.Width, m.Height)
-		return t, nil
+		return t, t.SetSize(m.Width, m.Height)
 	case tea.KeyPressMsg:
 		if t.closed || t.pty == nil {
 			return t, nil
 		}
-		seq := keyToBytes(m)
+		seq := keyToBytes(m.Key(), t.screen.Modes().AppCursorKeys)
 		if len(seq) > 0 {
+			t.screen.ScrollToBottom()
 			_, _ = t.pty.Write(seq)
 		}
 		return t, nil
@@ -83,23 +107,15 @@ func (t *Terminal) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return t, nil
 		}
 		if len(m) > 0 {
-			_, _ = t.pty.Write([]byte(m))
+			t.screen.ScrollToBottom()
+			_, _ = t.pty.WriteString(t.screen.EncodePaste(string(m)))
 		}
 		return t, nil
+	case tea.MouseMsg:
+		t.handleMouse(m)
+		return t, nil
 	case TerminalOutputMsg:
-		t.mu.Lock()
-		// Bound the buffer to avoid unbounded growth
-		if t.buffer.Len() > 1_000_000 { // ~1MB
-			// drop first half by recreating builder with suffix
-			current := t.buffer.String()
-			half := len(current) / 2
-			var b strings.Builder
-			b.Grow(len(current) - half + len(m.Data))
-			b.WriteString(current[half:])
-			t.buffer = b
-		}
-		t.buffer.Write(m.Data)
-		t.mu.Unlock()
+		_, _ = t.screen.Write(m.Data)
 		// Continue listening
 		return t, t.listen()
 	case TerminalClosedMsg:
@@ -109,11 +125,46 @@ func (t *Terminal) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return t, nil
 }
 
+// handleMouse forwards mouse events to programs that enabled mouse
+// tracking. Otherwise the wheel scrolls through the scrollback.
+func (t *Terminal) handleMouse(msg tea.MouseMsg) {
+	m := msg.Mouse()
+	ev := vt.MouseEvent{
+		Button: ansi.MouseButton(m.Button),
+		X:      m.X - t.x,
+		Y:      m.Y - t.y,
+		Shift:  m.Mod&tea.ModShift != 0,
+		Alt:    m.Mod&tea.ModAlt != 0,
+		Ctrl:   m.Mod&tea.ModCtrl != 0,
+	}
+	switch msg.(type) {
+	case tea.MouseReleaseMsg:
+		ev.Release = true
+	case tea.MouseMotionMsg:
+		ev.Motion = true
+	}
+
+	if t.screen.Modes().Mouse == vt.MouseOff {
+		if _, ok := msg.(tea.MouseWheelMsg); ok {
+			switch ev.Button {
+			case ansi.MouseWheelUp:
+				t.screen.ScrollUp(wheelScrollLines)
+			case ansi.MouseWheelDown:
+				t.screen.ScrollDown(wheelScrollLines)
+			}
+		}
+		return
+	}
+	if t.closed || t.pty == nil {
+		return
+	}
+	if seq, ok := t.screen.EncodeMouse(ev); ok {
+		_, _ = t.pty.WriteString(seq)
+	}
+}
+
 func (t *Terminal) View() string {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	// For now, return the raw buffer; lipgloss canvas above will compose it.
-	return t.buffer.String()
+	return t.screen.Render(!t.closed)
 }
 
 // SetSize allows the container to control width/height.
@@ -123,43 +174,24 @@ func (t *Terminal) SetSize(width, height int) tea.Cmd {
 	return nil
 }
 
-// Resize notifies the PTY of a window size change.
+// GetSize returns the size of the terminal in cells.
+func (t *Terminal) GetSize() (int, int) {
+	return t.width, t.height
+}
+
+// SetPosition sets where the component is drawn, so mouse events can be
+// translated to terminal cells.
+func (t *Terminal) SetPosition(x, y int) tea.Cmd {
+	t.x, t.y = x, y
+	return nil
+}
+
+// Resize resizes the emulator and notifies the PTY of the window size
+// change.
 func (t *Terminal) Resize(width, height int) error {
+	t.screen.Resize(width, height)
 	if t.pty == nil {
 		return nil
 	}
 	return t.pty.Resize(width, height)
 }
-
-// keyToBytes maps Bubble Tea key strings to terminal byte sequences.
-func keyToBytes(k tea.KeyPressMsg) []byte {
-	s := k.String()
-	switch s {
-	case "enter":
-		return []byte{'\r'}
-	case "tab":
-		return []byte{'\t'}
-	case "backspace":
-		return []byte{0x7f}
-	case "ctrl+c":
-		return []byte{0x03}
-	case "ctrl+d":
-		return []byte{0x04}
-	case "ctrl+z":
-		return []byte{0x1a}
-	case "up":
-		return []byte("\x1b[A")
-	case "down":
-		return []byte("\x1b[B")
-	case "right":
-		return []byte("\x1b[C")
-	case "left":
-		return []byte("\x1b[D")
-	default:
-		// If it's a single printable character, forward directly
-		if len(s) == 1 {
-			return []byte(s)
-		}
-		return nil
-	}
-}
@@ -2,8 +2,9 @@ package chat
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -15,9 +16,12 @@ import (
 	"github.com/lacymorrow/lash/internal/app"
 	"github.com/lacymorrow/lash/internal/config"
 	"github.com/lacymorrow/lash/internal/history"
+	"github.com/lacymorrow/lash/internal/llm/agent"
+	"github.com/lacymorrow/lash/internal/llm/tools"
 	"github.com/lacymorrow/lash/internal/message"
 	"github.com/lacymorrow/lash/internal/permission"
 	"github.com/lacymorrow/lash/internal/pubsub"
+	"github.com/lacymorrow/lash/internal/router"
 	"github.com/lacymorrow/lash/internal/session"
 	"github.com/lacymorrow/lash/internal/shell"
 	"github.com/lacymorrow/lash/internal/tui/components/anim"
@@ -30,9 +34,12 @@ import (
 	"github.com/lacymorrow/lash/internal/tui/components/completions"
 	"github.com/lacymorrow/lash/internal/tui/components/core"
 	"github.com/lacymorrow/lash/internal/tui/components/core/layout"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/commands"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/filepicker"
+	historydialog "github.com/lacymorrow/lash/internal/tui/components/dialogs/history"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/models"
+	queuedialog "github.com/lacymorrow/lash/internal/tui/components/dialogs/queue"
 	"github.com/lacymorrow/lash/internal/tui/page"
 	"github.com/lacymorrow/lash/internal/tui/styles"
 	"github.com/lacymorrow/lash/internal/tui/util"
@@ -46,6 +53,21 @@ type (
 		Focused bool
 	}
 	CancelTimerExpiredMsg struct{}
+
+	// autoRoutedMsg carries the router's decision for input sent in Auto mode.
+	autoRoutedMsg struct {
+		sessionID   string
+		decision    router.Decision
+		attachments []message.Attachment
+	}
+
+	// fixSuggestedMsg carries the command the agent proposed to fix a failed
+	// shell command.
+	fixSuggestedMsg struct {
+		sessionID string
+		command   string
+		err       error
+	}
 )
 
 type PanelType string
@@ -109,6 +131,10 @@ type chatPage struct {
 	editor  editor.Editor
 	splash  splash.Splash
 
+	// failedShell is the last shell command of the session if it failed,
+	// which the agent can be asked to fix.
+	failedShell *message.ShellExecution
+
 	// Simple state flags
 	showingDetails   bool
 	isCanceling      bool
@@ -117,43 +143,12 @@ type chatPage struct {
 	isProjectInit    bool
 }
 
-// shouldRouteToShell returns true if the first token resolves to an executable command.
-// Rules:
-// - If the first token contains a slash, require it to point to an executable file
-// - Else, resolve via PATH using exec.LookPath
-func shouldRouteToShell(input string) bool {
-	fields := strings.Fields(input)
-	if len(fields) == 0 {
-		return false
-	}
-	first := fields[0]
-
-	// Treat common shell builtins as shell-routable even if not found on PATH
-	switch first {
-	case "cd", "export", "set", "unset", "alias", "unalias", "pwd":
-		return true
-	}
-	if strings.Contains(first, "/") {
-		if info, err := os.Stat(first); err == nil {
-			mode := info.Mode()
-			if !mode.IsDir() && (mode&0o111) != 0 {
-				return true
-			}
-		}
-		return false
-	}
-	if _, err := exec.LookPath(first); err == nil {
-		return true
-	}
-	return false
-}
-
 func New(app *app.App) ChatPage {
 	return &chatPage{
 		app:         app,
 		keyMap:      DefaultKeyMap(),
-		header:      header.New(app.LSPClients),
-		sidebar:     sidebar.New(app.History, app.LSPClients, false),
+		header:      header.New(app.LSPClients, app.Shells),
+		sidebar:     sidebar.New(app.History, app.LSPClients, app.Shells, false),
 		chat:        chat.New(app),
 		editor:      editor.New(app),
 		splash:      splash.New(),
@@ -260,6 +255,23 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return p, cmd
 	case chat.SendMsg:
 		return p, p.sendMessage(msg.Text, msg.Attachments)
+	case chat.SteerMsg:
+		return p, p.steer(msg.Text)
+	case autoRoutedMsg:
+		return p, p.dispatchRouted(msg)
+	case fixSuggestedMsg:
+		return p, p.suggestFix(msg)
+	case messages.RunCommandMsg:
+		if p.session.ID == "" {
+			return p, nil
+		}
+		return p, p.runShell(p.session.ID, msg.Command)
+	case messages.EditCommandMsg:
+		return p, p.editCommand(msg.Command, "Command is in the editor, press enter to run it")
+	case messages.ForkMsg:
+		return p, p.fork(msg.MessageID, "")
+	case messages.EditPromptMsg:
+		return p, p.fork(msg.MessageID, msg.Text)
 	case chat.SessionSelectedMsg:
 		return p, p.setSession(msg)
 	case splash.SubmitAPIKeyMsg:
@@ -293,6 +305,7 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
 	case chat.SessionClearedMsg:
+		p.failedShell = nil
 		u, cmd := p.header.Update(msg)
 		p.header = u.(header.Header)
 		cmds = append(cmds, cmd)
@@ -304,6 +317,10 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
 	case filepicker.FilePickedMsg,
+		editor.ShellCompletionsMsg,
+		historydialog.SelectedMsg,
+		queuedialog.EditMsg,
+		pubsub.Event[agent.PromptQueue],
 		completions.CompletionsClosedMsg,
 		completions.SelectCompletionMsg:
 		u, cmd := p.editor.Update(msg)
@@ -321,6 +338,9 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case pubsub.Event[message.Message],
 		anim.StepMsg,
 		spinner.TickMsg:
+		if event, ok := msg.(pubsub.Event[message.Message]); ok {
+			cmds = append(cmds, p.trackShellResult(event))
+		}
 		if p.focusedPane == PanelTypeSplash {
 			u, cmd := p.splash.Update(msg)
 			p.splash = u.(splash.Splash)
@@ -337,19 +357,24 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
-	case pubsub.Event[history.File], sidebar.SessionFilesMsg:
+	case pubsub.Event[history.File], pubsub.Event[shell.JobInfo], pubsub.Event[agent.SubAgent], sidebar.SessionFilesMsg:
 		u, cmd := p.sidebar.Update(msg)
 		p.sidebar = u.(sidebar.Sidebar)
 		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
-	case pubsub.Event[permission.PermissionNotification]:
+	case pubsub.Event[permission.PermissionNotification], pubsub.Event[tools.ToolOutput]:
 		u, cmd := p.chat.Update(msg)
 		p.chat = u.(chat.MessageListCmp)
 		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
+	case messages.CancelToolCallMsg:
+		if p.app.CoderAgent != nil {
+			p.app.CoderAgent.CancelToolCall(msg.ToolCallID)
+		}
+		return p, util.ReportInfo("Cancelling tool call...")
 
 	case commands.CommandRunCustomMsg:
-		if p.app.CoderAgent.IsBusy() {
+		if p.app.IsBusy() {
 			return p, util.ReportWarn("Agent is busy, please wait before executing a command...")
 		}
 
@@ -368,7 +393,7 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		p.focusedPane = PanelTypeEditor
 		return p, p.SetSize(p.width, p.height)
 	case commands.NewSessionsMsg:
-		if p.app.CoderAgent.IsBusy() {
+		if p.app.IsBusy() {
 			return p, util.ReportWarn("Agent is busy, please wait before starting a new session...")
 		}
 		return p, p.newSession()
@@ -379,7 +404,7 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if p.app.CoderAgent == nil {
 				return p, nil
 			}
-			if p.app.CoderAgent.IsBusy() {
+			if p.app.IsBusy() {
 				return p, util.ReportWarn("Agent is busy, please wait before starting a new session...")
 			}
 			return p, p.newSession()
@@ -392,6 +417,10 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return p, util.ReportWarn("File attachments are not supported by the current model: " + model.Name)
 			}
 		case key.Matches(msg, p.keyMap.Tab):
+			if p.focusedPane == PanelTypeEditor && p.editor.CompletesShell() {
+				// Let the editor complete the command line.
+				break
+			}
 			if p.session.ID == "" {
 				u, cmd := p.splash.Update(msg)
 				p.splash = u.(splash.Splash)
@@ -400,12 +429,21 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			p.changeFocus()
 			return p, nil
 		case key.Matches(msg, p.keyMap.Cancel):
-			if p.session.ID != "" && p.app.CoderAgent.IsBusy() {
+			if p.session.ID != "" && p.app.IsSessionBusy(p.session.ID) {
 				return p, p.cancel()
 			}
 		case key.Matches(msg, p.keyMap.Details):
 			p.toggleDetails()
 			return p, nil
+		case key.Matches(msg, p.keyMap.FixIt):
+			return p, p.fixShell()
+		case key.Matches(msg, p.keyMap.Queue):
+			if p.session.ID == "" || p.app.CoderAgent == nil {
+				return p, nil
+			}
+			return p, util.CmdHandler(dialogs.OpenDialogMsg{
+				Model: queuedialog.NewQueueDialogCmp(p.app.CoderAgent, p.session.ID),
+			})
 		}
 
 		switch p.focusedPane {
@@ -651,6 +689,10 @@ func (p *chatPage) setSession(session session.Session) tea.Cmd {
 
 	var cmds []tea.Cmd
 	p.session = session
+	p.failedShell = nil
+	if exec, failed, err := p.app.LastFailedShellExecution(context.Background(), session.ID); err == nil && failed {
+		p.failedShell = &exec
+	}
 
 	cmds = append(cmds, p.SetSize(p.width, p.height))
 	cmds = append(cmds, p.chat.SetSession(session))
@@ -680,7 +722,7 @@ func (p *chatPage) changeFocus() {
 func (p *chatPage) cancel() tea.Cmd {
 	if p.isCanceling {
 		p.isCanceling = false
-		p.app.CoderAgent.Cancel(p.session.ID)
+		p.app.Cancel(p.session.ID)
 		return nil
 	}
 
@@ -722,86 +764,228 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 
 	switch mode {
 	case "Shell":
-		sh := shell.GetUserPersistentShell(p.app.Config().WorkingDir())
-		stdout, stderr, err := sh.Exec(context.Background(), text)
-		if err != nil {
-			if stderr == "" {
-				stderr = err.Error()
-			} else {
-				stderr += "\n" + err.Error()
-			}
-		}
-		_, _ = p.app.Messages.Create(context.Background(), session.ID, message.CreateMessageParams{
-			Role:  message.User,
-			Parts: []message.ContentPart{message.TextContent{Text: text}},
-		})
-		combined := stdout
-		if combined != "" && stderr != "" {
-			combined += "\n"
-		}
-		combined += stderr
-		_, _ = p.app.Messages.Create(context.Background(), session.ID, message.CreateMessageParams{
-			Role:  message.Assistant,
-			Parts: []message.ContentPart{message.TextContent{Text: combined}},
-		})
-		cmds = append(cmds, p.chat.GoToBottom())
+		cmds = append(cmds, p.runShell(session.ID, text))
 		return tea.Batch(cmds...)
 
 	case "Auto":
-		// Decide based on heuristics only (no explicit prefixes)
-		if shouldRouteToShell(strings.TrimSpace(text)) {
-			// Prefer persistent shell; synthesize output if the command succeeds but prints nothing
-			sh := shell.GetUserPersistentShell(p.app.Config().WorkingDir())
-			stdout, stderr, err := sh.Exec(context.Background(), text)
-			if err != nil {
-				if stderr == "" {
-					stderr = err.Error()
-				} else {
-					stderr += "\n" + err.Error()
-				}
+		// Routing may consult the small model, so decide off the UI loop.
+		sessionID := session.ID
+		cmds = append(cmds, func() tea.Msg {
+			return autoRoutedMsg{
+				sessionID:   sessionID,
+				decision:    p.app.Router.RouteIn(context.Background(), p.app.Shells.WorkingDir(sessionID), text),
+				attachments: attachments,
 			}
-			combined := stdout
-			if combined != "" && stderr != "" {
-				combined += "\n"
-			}
-			combined += stderr
-			if combined == "" && err == nil {
-				// No visible output; provide a helpful acknowledgement
-				trimmed := strings.TrimSpace(text)
-				if strings.HasPrefix(trimmed, "cd") {
-					combined = "cwd: " + sh.GetWorkingDir()
-				} else {
-					combined = "(ok)"
-				}
-			}
-			_, _ = p.app.Messages.Create(context.Background(), session.ID, message.CreateMessageParams{
-				Role:  message.User,
-				Parts: []message.ContentPart{message.TextContent{Text: text}},
-			})
-			_, _ = p.app.Messages.Create(context.Background(), session.ID, message.CreateMessageParams{
-				Role:  message.Assistant,
-				Parts: []message.ContentPart{message.TextContent{Text: combined}},
-			})
-			cmds = append(cmds, p.chat.GoToBottom())
-			return tea.Batch(cmds...)
-		}
-
-		// Default: Agent
-		_, err := p.app.CoderAgent.Run(context.Background(), session.ID, text, attachments...)
-		if err != nil {
-			return util.ReportError(err)
-		}
-		cmds = append(cmds, p.chat.GoToBottom())
+		})
 		return tea.Batch(cmds...)
 
 	default: // Agent
-		_, err := p.app.CoderAgent.Run(context.Background(), session.ID, text, attachments...)
-		if err != nil {
+		cmds = append(cmds, p.runAgent(session.ID, text, attachments))
+		return tea.Batch(cmds...)
+	}
+}
+
+// runAgent sends text to the agent, or queues it if the agent is busy with
+// the session.
+func (p *chatPage) runAgent(sessionID, text string, attachments []message.Attachment) tea.Cmd {
+	if p.app.CoderAgent.IsSessionBusy(sessionID) {
+		queued := len(p.app.CoderAgent.Queue(sessionID)) + 1
+		p.app.CoderAgent.Enqueue(sessionID, text, attachments...)
+		return util.ReportInfo(fmt.Sprintf("Queued (%d waiting), press %s to steer the running request instead",
+			queued, editor.DefaultEditorKeyMap().Steer.Help().Key))
+	}
+	if _, err := p.app.CoderAgent.Run(context.Background(), sessionID, text, attachments...); err != nil {
+		return util.ReportError(err)
+	}
+	return p.chat.GoToBottom()
+}
+
+// steer passes text to the request the agent is running in the session.
+func (p *chatPage) steer(text string) tea.Cmd {
+	if p.session.ID == "" || p.app.CoderAgent == nil {
+		return nil
+	}
+	err := p.app.CoderAgent.Steer(p.session.ID, text)
+	if errors.Is(err, agent.ErrSessionNotBusy) {
+		// The request finished meanwhile.
+		return p.sendMessage(text, nil)
+	}
+	if err != nil {
+		return util.ReportError(err)
+	}
+	return util.ReportInfo("The agent will see your note after its current step")
+}
+
+// dispatchRouted sends Auto mode input where the router decided.
+func (p *chatPage) dispatchRouted(msg autoRoutedMsg) tea.Cmd {
+	d := msg.decision
+	if d.Text == "" {
+		return nil
+	}
+	info := util.ReportInfo(fmt.Sprintf("Auto: sent to the %s, %s", d.Target, d.Reason))
+	if d.Target == router.TargetShell {
+		return tea.Batch(p.runShell(msg.sessionID, d.Text), info)
+	}
+	return tea.Batch(p.runAgent(msg.sessionID, d.Text, msg.attachments), info)
+}
+
+// runShell runs command in the user's shell. Interactive programs get the
+// terminal while the TUI is suspended; everything else runs in the
+// background with its output streamed into the chat.
+func (p *chatPage) runShell(sessionID, command string) tea.Cmd {
+	if !p.app.NeedsTerminal(sessionID, command) {
+		if err := p.app.RunShell(context.Background(), sessionID, command); err != nil {
 			return util.ReportError(err)
 		}
-		cmds = append(cmds, p.chat.GoToBottom())
-		return tea.Batch(cmds...)
+		return p.chat.GoToBottom()
+	}
+	if p.app.IsSessionBusy(sessionID) {
+		return util.ReportError(app.ErrShellBusy)
 	}
+	c := p.app.NewShellTerminalCommand(sessionID, command)
+	return tea.Exec(c, func(err error) tea.Msg {
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return nil
+	})
+}
+
+// trackShellResult remembers whether the last shell command of the session
+// failed, and offers to hand a failure to the agent.
+func (p *chatPage) trackShellResult(event pubsub.Event[message.Message]) tea.Cmd {
+	msg := event.Payload
+	executions := msg.ShellExecutions()
+	if msg.SessionID != p.session.ID || len(executions) == 0 {
+		return nil
+	}
+	exec := executions[len(executions)-1]
+	if !exec.IsFinished() {
+		return nil
+	}
+	if !app.IsFailedShellExecution(exec) {
+		p.failedShell = nil
+		return nil
+	}
+	if p.failedShell != nil && p.failedShell.StartedAt == exec.StartedAt {
+		return nil
+	}
+	p.failedShell = &exec
+	return util.ReportWarn(fmt.Sprintf("%s exited with status %d, press %s to ask the agent to fix it",
+		firstLine(exec.Command), exec.ExitCode, p.keyMap.FixIt.Help().Key))
+}
+
+// fixShell starts an agent run to fix the last shell command of the session
+// if it failed. The command the agent proposes comes back as a
+// fixSuggestedMsg.
+func (p *chatPage) fixShell() tea.Cmd {
+	if p.session.ID == "" || p.app.CoderAgent == nil {
+		return nil
+	}
+	if p.app.IsSessionBusy(p.session.ID) {
+		return util.ReportWarn("Agent is busy, please wait...")
+	}
+	exec, failed, err := p.app.LastFailedShellExecution(context.Background(), p.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	if !failed {
+		p.failedShell = nil
+		return util.ReportWarn("The last shell command didn't fail, there is nothing to fix")
+	}
+	events, err := p.app.FixShell(context.Background(), p.session.ID, exec)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	p.failedShell = nil
+
+	sessionID := p.session.ID
+	return tea.Batch(
+		p.chat.GoToBottom(),
+		func() tea.Msg {
+			msg := fixSuggestedMsg{sessionID: sessionID}
+			for event := range events {
+				switch event.Type {
+				case agent.AgentEventTypeError:
+					if !errors.Is(event.Error, agent.ErrRequestCancelled) && !errors.Is(event.Error, context.Canceled) {
+						msg.err = event.Error
+					}
+				case agent.AgentEventTypeResponse:
+					msg.command = app.FixCommand(event.Message.Content().Text)
+				}
+			}
+			return msg
+		},
+	)
+}
+
+// suggestFix puts the command the agent proposed in the editor in Shell
+// mode, so the user can review it and run it with enter.
+func (p *chatPage) suggestFix(msg fixSuggestedMsg) tea.Cmd {
+	switch {
+	case msg.err != nil:
+		return util.ReportError(msg.err)
+	case msg.sessionID != p.session.ID:
+		return nil
+	case msg.command == "":
+		return util.ReportInfo("The agent did not suggest a command to run")
+	}
+	return p.editCommand(msg.command, "Suggested fix is in the editor, press enter to run it")
+}
+
+// editCommand focuses the editor in Shell mode with command in it, so the
+// user can review and change it before running it.
+func (p *chatPage) editCommand(command, info string) tea.Cmd {
+	p.focusedPane = PanelTypeEditor
+	p.editor.Focus()
+	p.chat.Blur()
+	return tea.Batch(
+		util.CmdHandler(commands.SetModeMsg{Mode: "Shell"}),
+		util.CmdHandler(editor.OpenEditorMsg{Text: command}),
+		util.ReportInfo(info),
+	)
+}
+
+// fork switches to a new session forked from the current one at a message.
+// With a prompt, the fork stops before the message and the prompt is put in
+// the editor, to be changed and sent again.
+func (p *chatPage) fork(messageID, prompt string) tea.Cmd {
+	if p.session.ID == "" {
+		return nil
+	}
+	var fork session.Session
+	var err error
+	if prompt == "" {
+		fork, err = p.app.ForkSession(context.Background(), p.session.ID, messageID)
+	} else {
+		fork, err = p.app.ForkSessionBefore(context.Background(), p.session.ID, messageID)
+	}
+	if err != nil {
+		return util.ReportError(err)
+	}
+
+	cmds := []tea.Cmd{util.CmdHandler(chat.SessionSelectedMsg(fork))}
+	if prompt == "" {
+		cmds = append(cmds, util.ReportInfo("Forked the session, switch back to the original from the branches of the sessions dialog"))
+		return tea.Sequence(cmds...)
+	}
+	p.focusedPane = PanelTypeEditor
+	p.editor.Focus()
+	p.chat.Blur()
+	cmds = append(cmds,
+		util.CmdHandler(commands.SetModeMsg{Mode: "Agent"}),
+		util.CmdHandler(editor.OpenEditorMsg{Text: prompt}),
+		util.ReportInfo("Edit the prompt and press enter to send it in the new branch"),
+	)
+	return tea.Sequence(cmds...)
+}
+
+// firstLine returns the first line of s, marking that there is more.
+func firstLine(s string) string {
+	if first, _, ok := strings.Cut(s, "\n"); ok {
+		return first + " …"
+	}
+	return s
 }
 
 func (p *chatPage) Bindings() []key.Binding {
@@ -809,7 +993,7 @@ func (p *chatPage) Bindings() []key.Binding {
 		p.keyMap.NewSession,
 		p.keyMap.AddAttachment,
 	}
-	if p.app.CoderAgent != nil && p.app.CoderAgent.IsBusy() {
+	if p.app.IsBusy() {
 		cancelBinding := p.keyMap.Cancel
 		if p.isCanceling {
 			cancelBinding = key.NewBinding(
@@ -818,6 +1002,8 @@ func (p *chatPage) Bindings() []key.Binding {
 			)
 		}
 		bindings = append([]key.Binding{cancelBinding}, bindings...)
+	} else if p.failedShell != nil {
+		bindings = append(bindings, p.keyMap.FixIt)
 	}
 
 	switch p.focusedPane {
@@ -909,7 +1095,7 @@ func (p *chatPage) Help() help.KeyMap {
 			}
 			return core.NewSimpleHelp(shortList, fullList)
 		}
-		if p.app.CoderAgent != nil && p.app.CoderAgent.IsBusy() {
+		if p.app.IsBusy() {
 			cancelBinding := key.NewBinding(
 				key.WithKeys("esc"),
 				key.WithHelp("esc", "cancel"),
@@ -926,6 +1112,9 @@ func (p *chatPage) Help() help.KeyMap {
 					cancelBinding,
 				},
 			)
+		} else if p.failedShell != nil {
+			shortList = append(shortList, p.keyMap.FixIt)
+			fullList = append(fullList, []key.Binding{p.keyMap.FixIt})
 		}
 		globalBindings := []key.Binding{}
 		// we are in a session
@@ -1039,8 +1228,13 @@ func (p *chatPage) Help() help.KeyMap {
 				},
 				[]key.Binding{
 					messages.CopyKey,
+					messages.CancelToolKey,
 					messages.ClearSelectionKey,
 				},
+				[]key.Binding{
+					messages.ForkKey,
+					messages.EditPromptKey,
+				},
 			)
 		case PanelTypeEditor:
 			newLineBinding := key.NewBinding(
@@ -1062,6 +1256,16 @@ func (p *chatPage) Help() help.KeyMap {
 						key.WithKeys("ctrl+o"),
 						key.WithHelp("ctrl+o", "open editor"),
 					),
+				},
+				[]key.Binding{
+					key.NewBinding(
+						key.WithKeys("ctrl+r"),
+						key.WithHelp("ctrl+r", "search history"),
+					),
+					key.NewBinding(
+						key.WithKeys("right"),
+						key.WithHelp("→", "accept suggestion"),
+					),
 				})
 
 			if p.editor.HasAttachments() {
@@ -11,6 +11,8 @@ type KeyMap struct {
 	Cancel        key.Binding
 	Tab           key.Binding
 	Details       key.Binding
+	FixIt         key.Binding
+	Queue         key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -35,5 +37,13 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+e"),
 			key.WithHelp("ctrl+e", "toggle details"),
 		),
+		FixIt: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "fix failed command"),
+		),
+		Queue: key.NewBinding(
+			key.WithKeys("ctrl+q"),
+			key.WithHelp("ctrl+q", "queued prompts"),
+		),
 	}
 }
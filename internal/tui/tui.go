@@ -12,11 +12,14 @@ import (
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/lacymorrow/lash/internal/app"
 	"github.com/lacymorrow/lash/internal/config"
+	"github.com/lacymorrow/lash/internal/inputhistory"
 	"github.com/lacymorrow/lash/internal/llm/agent"
 	"github.com/lacymorrow/lash/internal/permission"
 	"github.com/lacymorrow/lash/internal/pubsub"
+	"github.com/lacymorrow/lash/internal/shell"
 	cmpChat "github.com/lacymorrow/lash/internal/tui/components/chat"
 	"github.com/lacymorrow/lash/internal/tui/components/chat/splash"
 	"github.com/lacymorrow/lash/internal/tui/components/completions"
@@ -24,12 +27,14 @@ import (
 	"github.com/lacymorrow/lash/internal/tui/components/core/layout"
 	"github.com/lacymorrow/lash/internal/tui/components/core/status"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs/agents"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/commands"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/compact"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/filepicker"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/models"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/permissions"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/quit"
+	"github.com/lacymorrow/lash/internal/tui/components/dialogs/routes"
 	"github.com/lacymorrow/lash/internal/tui/components/dialogs/sessions"
 	"github.com/lacymorrow/lash/internal/tui/page"
 	"github.com/lacymorrow/lash/internal/tui/page/chat"
@@ -128,6 +133,8 @@ func (a *appModel) Init() tea.Cmd {
 	cmds = append(cmds, cmd)
 
 	cmds = append(cmds, tea.EnableMouseAllMotion)
+	cmds = append(cmds, a.loadShellRC())
+	cmds = append(cmds, a.offerHistoryImport())
 
 	// Initialize mode from config helper
 	a.activeMode = config.Get().ActiveMode()
@@ -138,6 +145,84 @@ func (a *appModel) Init() tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// loadShellRC loads the user's rc files into the Shell mode interpreter and
+// reports anything that could not be loaded.
+func (a *appModel) loadShellRC() tea.Cmd {
+	return func() tea.Msg {
+		warnings := a.app.LoadShellRC(context.Background())
+		switch len(warnings) {
+		case 0:
+			return nil
+		case 1:
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: "Shell rc: " + warnings[0].String()}
+		default:
+			return util.InfoMsg{
+				Type: util.InfoTypeWarn,
+				Msg:  fmt.Sprintf("Shell rc: %s (and %d more, see logs)", warnings[0], len(warnings)-1),
+			}
+		}
+	}
+}
+
+// offerHistoryImport points the user to the import of their shell history
+// the first time lash finds one.
+func (a *appModel) offerHistoryImport() tea.Cmd {
+	cfg := config.Get()
+	if cfg.Lash != nil && cfg.Lash.Shell.HistoryImportOffered {
+		return nil
+	}
+	found := inputhistory.ShellHistories()
+	if len(found) == 0 {
+		return nil
+	}
+	if cfg.Lash == nil {
+		cfg.Lash = &config.LashConfig{}
+	}
+	cfg.Lash.Shell.HistoryImportOffered = true
+	_ = cfg.SetConfigField("lash.shell.history_import_offered", true)
+	shells := make([]string, len(found))
+	for i, h := range found {
+		shells[i] = h.Shell
+	}
+	return util.ReportInfo(fmt.Sprintf("Found %s history: run Import Shell History from ctrl+p to use it for suggestions", strings.Join(shells, ", ")))
+}
+
+// importShellHistory adds the user's shell history to the input history.
+func (a *appModel) importShellHistory() tea.Cmd {
+	return func() tea.Msg {
+		n, err := a.app.ImportShellHistory()
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return util.InfoMsg{
+			Type: util.InfoTypeInfo,
+			Msg:  fmt.Sprintf("Imported %d commands from shell history", n),
+		}
+	}
+}
+
+// notifyJobFinished reports that a background job finished in the status
+// bar, along with a desktop notification or the bell as configured.
+func (a *appModel) notifyJobFinished(job shell.JobInfo) tea.Cmd {
+	text := fmt.Sprintf("[%d] %s  %s", job.ID, job.Status(), job.Command)
+	report := util.ReportInfo(text)
+	if job.State == shell.JobFailed {
+		report = util.ReportWarn(text)
+	}
+	notification := "desktop"
+	if cfg := config.Get(); cfg.Lash != nil && cfg.Lash.Shell.JobNotification != "" {
+		notification = cfg.Lash.Shell.JobNotification
+	}
+	switch notification {
+	case "desktop":
+		return tea.Batch(report, tea.Raw(ansi.Notify("lash: "+text)))
+	case "bell":
+		return tea.Batch(report, tea.Raw("\a"))
+	default:
+		return report
+	}
+}
+
 // Update handles incoming messages and updates the application state.
 func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -214,11 +299,36 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				Model: models.NewModelDialogCmp(),
 			},
 		)
+	case commands.SwitchAgentMsg:
+		if a.app.CoderAgent == nil {
+			return a, util.ReportWarn("Agent is not initialized")
+		}
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: agents.NewAgentDialogCmp(
+					config.Get().SessionAgents(),
+					a.app.CoderAgent.SessionAgent(context.Background(), a.selectedSessionID),
+				),
+			},
+		)
+	case agents.AgentSelectedMsg:
+		if err := a.app.SelectAgent(context.Background(), a.selectedSessionID, msg.Agent.ID); err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to switch to agent %s: %w", msg.Agent.Name, err))
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Switched to agent %s", msg.Agent.Name))
 	// Compact
 	case commands.CompactMsg:
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: compact.NewCompactDialogCmp(a.app.CoderAgent, msg.SessionID, true),
 		})
+	case commands.SetModeMsg:
+		return a, a.setMode(msg.Mode)
+	case commands.ImportShellHistoryMsg:
+		return a, a.importShellHistory()
+	case commands.ShowRoutesMsg:
+		return a, util.CmdHandler(dialogs.OpenDialogMsg{
+			Model: routes.NewRoutesDialogCmp(a.app.Router.Decisions()),
+		})
 	case commands.QuitMsg:
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: quit.NewQuitDialog(),
@@ -343,7 +453,7 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Get current session to check token usage
 			session, err := a.app.Sessions.Get(context.Background(), a.selectedSessionID)
 			if err == nil {
-				model := a.app.CoderAgent.Model()
+				model := a.app.CoderAgent.SessionModel(context.Background(), a.selectedSessionID)
 				contextWindow := model.ContextWindow
 				tokens := session.CompletionTokens + session.PromptTokens
 				if (tokens >= int64(float64(contextWindow)*0.95)) && !config.Get().Options.DisableAutoSummarize { // Show compact confirmation dialog
@@ -355,6 +465,18 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		return a, tea.Batch(cmds...)
+	// Background jobs
+	case pubsub.Event[shell.JobInfo]:
+		// The agent checks on its own processes.
+		if msg.Type == pubsub.UpdatedEvent && msg.Payload.Finished() && msg.Payload.Shell == shell.UserShell {
+			cmds = append(cmds, a.notifyJobFinished(msg.Payload))
+		}
+	case commands.AttachJobOutputMsg:
+		attachment, err := a.app.JobOutputAttachment(a.selectedSessionID, msg.ID)
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.CmdHandler(filepicker.FilePickedMsg{Attachment: attachment})
 	case splash.OnboardingCompleteMsg:
 		a.isConfigured = config.HasInitialDataConfig()
 		updated, pageCmd := a.pages[a.currentPage].Update(msg)
@@ -401,6 +523,16 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return a, tea.Batch(cmds...)
 }
 
+// setMode switches the input mode and persists it as the last selected mode.
+func (a *appModel) setMode(mode string) tea.Cmd {
+	a.activeMode = mode
+	a.status.SetLeft(a.renderLeftPrefix())
+	a.app.Mode = a.activeMode
+	// Persist last selected mode using config helper
+	_ = config.Get().SetActiveMode(a.activeMode)
+	return a.handleWindowResize(a.wWidth, a.wHeight)
+}
+
 // handleWindowResize processes window resize events and updates all components.
 func (a *appModel) handleWindowResize(width, height int) tea.Cmd {
 	var cmds []tea.Cmd
@@ -482,7 +614,7 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 			return nil
 		}
 		return util.CmdHandler(dialogs.OpenDialogMsg{
-			Model: commands.NewCommandDialog(a.selectedSessionID),
+			Model: commands.NewCommandDialog(a.selectedSessionID, a.app.Jobs(a.selectedSessionID)),
 		})
 	case key.Matches(msg, a.keyMap.Sessions):
 		// if the app is not configured show no sessions
@@ -513,17 +645,12 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 		// Cycle through Shell -> Agent -> Auto -> Shell
 		switch a.activeMode {
 		case "Shell":
-			a.activeMode = "Agent"
+			return a.setMode("Agent")
 		case "Agent":
-			a.activeMode = "Auto"
+			return a.setMode("Auto")
 		default:
-			a.activeMode = "Shell"
+			return a.setMode("Shell")
 		}
-		a.status.SetLeft(a.renderLeftPrefix())
-		a.app.Mode = a.activeMode
-		// Persist last selected mode using config helper
-		_ = config.Get().SetActiveMode(a.activeMode)
-		return a.handleWindowResize(a.wWidth, a.wHeight)
 	case key.Matches(msg, a.keyMap.ToggleYolo):
 		// Route through the common handler so pages update their prompts/styles
 		return util.CmdHandler(commands.ToggleYoloModeMsg{})
@@ -551,7 +678,7 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 		a.app.Permissions.SetAllowedTools([]string{})
 		return util.ReportInfo("Auto-confirm disabled")
 	case key.Matches(msg, a.keyMap.Suspend):
-		if a.app.CoderAgent != nil && a.app.CoderAgent.IsBusy() {
+		if a.app.IsBusy() {
 			return util.ReportWarn("Agent is busy, please wait...")
 		}
 		return tea.Suspend
@@ -570,7 +697,7 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 
 // moveToPage handles navigation between different pages in the application.
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
-	if a.app.CoderAgent.IsBusy() {
+	if a.app.IsBusy() {
 		// TODO: maybe remove this :  For now we don't move to any page if the agent is busy
 		return util.ReportWarn("Agent is busy, please wait...")
 	}
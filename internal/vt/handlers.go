@@ -0,0 +1,501 @@
+package vt
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+// charset is a character set designated to G0 or G1.
+type charset int
+
+const (
+	charsetASCII charset = iota
+	// charsetGraphics is the DEC special graphics set, used for line
+	// drawing.
+	charsetGraphics
+)
+
+// decGraphics maps the DEC special graphics set onto Unicode.
+var decGraphics = map[rune]rune{
+	'`': '◆', 'a': '▒', 'b': '␉', 'c': '␌', 'd': '␍', 'e': '␊', 'f': '°',
+	'g': '±', 'h': '␤', 'i': '␋', 'j': '┘', 'k': '┐', 'l': '┌', 'm': '└',
+	'n': '┼', 'o': '⎺', 'p': '⎻', 'q': '─', 'r': '⎼', 's': '⎽', 't': '├',
+	'u': '┤', 'v': '┴', 'w': '┬', 'x': '│', 'y': '≤', 'z': '≥', '{': 'π',
+	'|': '≠', '}': '£', '~': '·',
+}
+
+func (t *Terminal) print(r rune) {
+	c := &t.scr.cur
+	if c.charsets[c.charset] == charsetGraphics {
+		if g, ok := decGraphics[r]; ok {
+			r = g
+		}
+	}
+
+	w := runewidth.RuneWidth(r)
+	if w == 0 {
+		t.combine(r)
+		return
+	}
+	if w > t.width {
+		return
+	}
+
+	if c.pendingWrap && t.autowrap {
+		t.wrap()
+	}
+	c.pendingWrap = false
+	if c.x+w > t.width {
+		if t.autowrap {
+			t.wrap()
+		} else {
+			c.x = t.width - w
+		}
+	}
+
+	if t.insert {
+		t.insertCells(w)
+	}
+	cells := t.scr.lines[c.y].cells
+	// Overwriting half of a wide character blanks the other half.
+	if cells[c.x].Width == 0 && c.x > 0 {
+		cells[c.x-1] = blank
+	}
+	if end := c.x + w; end < t.width && cells[end].Width == 0 {
+		cells[end] = blank
+	}
+	cells[c.x] = Cell{Content: string(r), Width: w, Style: c.style}
+	if w == 2 {
+		cells[c.x+1] = Cell{Style: c.style}
+	}
+	t.lastRune = r
+
+	c.x += w
+	if c.x >= t.width {
+		c.x = t.width - 1
+		c.pendingWrap = true
+	}
+}
+
+// wrap continues printing on the next line.
+func (t *Terminal) wrap() {
+	t.scr.lines[t.scr.cur.y].wrapped = true
+	t.scr.cur.x = 0
+	t.index()
+}
+
+// combine appends a zero-width character to the previous cell.
+func (t *Terminal) combine(r rune) {
+	c := t.scr.cur
+	x := c.x - 1
+	if c.pendingWrap {
+		x = c.x
+	}
+	cells := t.scr.lines[c.y].cells
+	if x >= 0 && cells[x].Width == 0 && x > 0 {
+		x--
+	}
+	if x < 0 {
+		return
+	}
+	cells[x].Content += string(r)
+}
+
+func (t *Terminal) execute(b byte) {
+	c := &t.scr.cur
+	switch b {
+	case ansi.BS:
+		if c.pendingWrap {
+			c.pendingWrap = false
+		} else if c.x > 0 {
+			c.x--
+		}
+	case ansi.HT:
+		t.tab(1)
+	case ansi.LF, ansi.VT, ansi.FF:
+		t.index()
+		if t.newline {
+			c.x = 0
+		}
+		c.pendingWrap = false
+	case ansi.CR:
+		c.x = 0
+		c.pendingWrap = false
+	case ansi.SO:
+		c.charset = 1
+	case ansi.SI:
+		c.charset = 0
+	}
+}
+
+// tab moves the cursor n tab stops forward, or backward when n is negative.
+func (t *Terminal) tab(n int) {
+	c := &t.scr.cur
+	c.pendingWrap = false
+	for ; n > 0 && c.x < t.width-1; n-- {
+		c.x++
+		for c.x < t.width-1 && !t.tabs[c.x] {
+			c.x++
+		}
+	}
+	for ; n < 0 && c.x > 0; n++ {
+		c.x--
+		for c.x > 0 && !t.tabs[c.x] {
+			c.x--
+		}
+	}
+}
+
+func (t *Terminal) handleESC(cmd ansi.Cmd) {
+	c := &t.scr.cur
+	switch inter, final := cmd.Intermediate(), cmd.Final(); inter {
+	case 0:
+		switch final {
+		case '7':
+			t.scr.saved = *c
+		case '8':
+			t.restoreCursor()
+		case 'D':
+			t.index()
+		case 'E':
+			c.x = 0
+			c.pendingWrap = false
+			t.index()
+		case 'H':
+			t.tabs[c.x] = true
+		case 'M':
+			t.reverseIndex()
+		case 'c':
+			t.reset()
+		}
+	case '(', ')':
+		g := 0
+		if inter == ')' {
+			g = 1
+		}
+		if final == '0' {
+			c.charsets[g] = charsetGraphics
+		} else {
+			c.charsets[g] = charsetASCII
+		}
+	}
+}
+
+func (t *Terminal) restoreCursor() {
+	saved := t.scr.saved
+	saved.x = min(saved.x, t.width-1)
+	saved.y = min(saved.y, t.height-1)
+	t.scr.cur = saved
+}
+
+func (t *Terminal) handleCSI(cmd ansi.Cmd, params ansi.Params) {
+	c := &t.scr.cur
+	param := func(i, def int) int {
+		n, _, _ := params.Param(i, def)
+		if n == 0 && def > 0 {
+			return def
+		}
+		return n
+	}
+
+	if cmd.Prefix() == '?' {
+		switch cmd.Final() {
+		case 'h':
+			t.setPrivateModes(params, true)
+		case 'l':
+			t.setPrivateModes(params, false)
+		}
+		return
+	}
+	if cmd.Prefix() == '>' {
+		if cmd.Final() == 'c' {
+			// Secondary device attributes: a VT220.
+			t.sendReply("\x1b[>1;10;0c")
+		}
+		return
+	}
+	if cmd.Prefix() != 0 {
+		return
+	}
+	if cmd.Intermediate() != 0 {
+		// Cursor style (DECSCUSR) and the like don't affect the grid.
+		return
+	}
+
+	switch cmd.Final() {
+	case '@':
+		t.insertCells(param(0, 1))
+	case 'A':
+		t.moveVertically(-param(0, 1))
+	case 'B', 'e':
+		t.moveVertically(param(0, 1))
+	case 'C', 'a':
+		t.moveHorizontally(param(0, 1))
+	case 'D':
+		t.moveHorizontally(-param(0, 1))
+	case 'E':
+		t.moveVertically(param(0, 1))
+		c.x = 0
+	case 'F':
+		t.moveVertically(-param(0, 1))
+		c.x = 0
+	case 'G', '`':
+		c.x = min(param(0, 1), t.width) - 1
+		c.pendingWrap = false
+	case 'H', 'f':
+		t.moveTo(param(1, 1)-1, param(0, 1)-1)
+	case 'I':
+		t.tab(param(0, 1))
+	case 'J':
+		t.eraseDisplay(param(0, 0))
+	case 'K':
+		switch param(0, 0) {
+		case 0:
+			t.erase(c.y, c.x, t.width)
+		case 1:
+			t.erase(c.y, 0, c.x+1)
+		case 2:
+			t.erase(c.y, 0, t.width)
+		}
+	case 'L':
+		t.insertLines(param(0, 1))
+	case 'M':
+		t.deleteLines(param(0, 1))
+	case 'P':
+		t.deleteCells(param(0, 1))
+	case 'S':
+		t.scrollUp(param(0, 1))
+	case 'T':
+		t.scrollDown(param(0, 1))
+	case 'X':
+		t.erase(c.y, c.x, c.x+param(0, 1))
+	case 'Z':
+		t.tab(-param(0, 1))
+	case 'b':
+		if t.lastRune != 0 {
+			for range min(param(0, 1), t.width*t.height) {
+				t.print(t.lastRune)
+			}
+		}
+	case 'c':
+		// Primary device attributes: a VT220 with ANSI color.
+		t.sendReply("\x1b[?62;22c")
+	case 'd':
+		t.moveTo(c.x, param(0, 1)-1)
+	case 'g':
+		switch param(0, 0) {
+		case 0:
+			t.tabs[c.x] = false
+		case 3:
+			clear(t.tabs)
+		}
+	case 'h', 'l':
+		set := cmd.Final() == 'h'
+		params.ForEach(0, func(_, p int, _ bool) {
+			switch p {
+			case 4:
+				t.insert = set
+			case 20:
+				t.newline = set
+			}
+		})
+	case 'm':
+		t.selectGraphicRendition(params)
+	case 'n':
+		switch param(0, 0) {
+		case 5:
+			t.sendReply("\x1b[0n")
+		case 6:
+			y := c.y
+			if c.origin {
+				y -= t.top
+			}
+			t.sendReply(fmt.Sprintf("\x1b[%d;%dR", y+1, c.x+1))
+		}
+	case 'r':
+		top, bottom := param(0, 1)-1, param(1, t.height)-1
+		bottom = min(bottom, t.height-1)
+		if top < bottom {
+			t.top, t.bottom = top, bottom
+			t.moveTo(0, 0)
+		}
+	case 's':
+		t.scr.saved = *c
+	case 'u':
+		t.restoreCursor()
+	}
+}
+
+func (t *Terminal) eraseDisplay(mode int) {
+	c := t.scr.cur
+	switch mode {
+	case 0:
+		t.erase(c.y, c.x, t.width)
+		for y := c.y + 1; y < t.height; y++ {
+			t.erase(y, 0, t.width)
+		}
+	case 1:
+		for y := range c.y {
+			t.erase(y, 0, t.width)
+		}
+		t.erase(c.y, 0, c.x+1)
+	case 2:
+		for y := range t.height {
+			t.erase(y, 0, t.width)
+		}
+	case 3:
+		t.scrollback = nil
+		t.scrollOffset = 0
+	}
+}
+
+func (t *Terminal) setPrivateModes(params ansi.Params, set bool) {
+	params.ForEach(0, func(_, p int, _ bool) {
+		switch p {
+		case 1:
+			t.modes.AppCursorKeys = set
+		case 6:
+			t.scr.cur.origin = set
+			t.moveTo(0, 0)
+		case 7:
+			t.autowrap = set
+		case 25:
+			t.modes.CursorVisible = set
+		case 9:
+			t.setMouse(MouseX10, set)
+		case 1000:
+			t.setMouse(MouseNormal, set)
+		case 1002:
+			t.setMouse(MouseButtonEvent, set)
+		case 1003:
+			t.setMouse(MouseAnyEvent, set)
+		case 1006:
+			t.modes.MouseSGR = set
+		case 2004:
+			t.modes.BracketedPaste = set
+		case 47, 1047:
+			t.switchScreen(set, p == 1047 && !set)
+		case 1048:
+			if set {
+				t.scr.saved = t.scr.cur
+			} else {
+				t.restoreCursor()
+			}
+		case 1049:
+			if set {
+				t.main.saved = t.main.cur
+				t.switchScreen(true, true)
+			} else {
+				t.switchScreen(false, true)
+				t.restoreCursor()
+			}
+		}
+	})
+}
+
+func (t *Terminal) setMouse(mode MouseMode, set bool) {
+	switch {
+	case set:
+		t.modes.Mouse = mode
+	case t.modes.Mouse == mode:
+		t.modes.Mouse = MouseOff
+	}
+}
+
+func (t *Terminal) selectGraphicRendition(params ansi.Params) {
+	st := &t.scr.cur.style
+	if len(params) == 0 {
+		*st = Style{}
+		return
+	}
+	for i := 0; i < len(params); i++ {
+		p, more, _ := params.Param(i, 0)
+		switch {
+		case p == 0:
+			*st = Style{}
+		case p == 1:
+			st.Attrs |= AttrBold
+		case p == 2:
+			st.Attrs |= AttrFaint
+		case p == 3:
+			st.Attrs |= AttrItalic
+		case p == 4:
+			st.Attrs |= AttrUnderline
+			if more {
+				// 4:0 turns underlining off; other styles are shown as a
+				// plain underline.
+				if u, _, _ := params.Param(i+1, 1); u == 0 {
+					st.Attrs &^= AttrUnderline
+				}
+			}
+		case p == 5 || p == 6:
+			st.Attrs |= AttrBlink
+		case p == 7:
+			st.Attrs |= AttrReverse
+		case p == 8:
+			st.Attrs |= AttrConceal
+		case p == 9:
+			st.Attrs |= AttrStrikethrough
+		case p == 21:
+			st.Attrs |= AttrUnderline
+		case p == 22:
+			st.Attrs &^= AttrBold | AttrFaint
+		case p == 23:
+			st.Attrs &^= AttrItalic
+		case p == 24:
+			st.Attrs &^= AttrUnderline
+		case p == 25:
+			st.Attrs &^= AttrBlink
+		case p == 27:
+			st.Attrs &^= AttrReverse
+		case p == 28:
+			st.Attrs &^= AttrConceal
+		case p == 29:
+			st.Attrs &^= AttrStrikethrough
+		case p >= 30 && p <= 37:
+			st.Fg = ansi.BasicColor(p - 30)
+		case p == 39:
+			st.Fg = nil
+		case p >= 40 && p <= 47:
+			st.Bg = ansi.BasicColor(p - 40)
+		case p == 49:
+			st.Bg = nil
+		case p >= 90 && p <= 97:
+			st.Fg = ansi.BasicColor(p - 90 + 8)
+		case p >= 100 && p <= 107:
+			st.Bg = ansi.BasicColor(p - 100 + 8)
+		case p == 38 || p == 48 || p == 58:
+			var c color.Color
+			n := ansi.ReadStyleColor(params[i:], &c)
+			if n == 0 {
+				return
+			}
+			switch p {
+			case 38:
+				st.Fg = c
+			case 48:
+				st.Bg = c
+			}
+			i += n - 1
+			continue
+		}
+		// Skip sub-parameters we don't use.
+		for more && i+1 < len(params) {
+			i++
+			_, more, _ = params.Param(i, 0)
+		}
+	}
+}
+
+func (t *Terminal) handleOSC(cmd int, data []byte) {
+	switch cmd {
+	case 0, 2:
+		if _, title, ok := bytes.Cut(data, []byte(";")); ok {
+			t.title = string(title)
+		}
+	}
+}
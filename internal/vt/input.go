@@ -0,0 +1,89 @@
+package vt
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// x10MaxCoord is the largest coordinate the X10 mouse encoding can carry.
+const x10MaxCoord = 255 - 32 - 1
+
+// MouseEvent is a mouse event at a cell of the terminal.
+type MouseEvent struct {
+	Button ansi.MouseButton
+	X, Y   int
+	// Release is set when Button is released, Motion when the mouse moves
+	// with Button held, or with no button held if Button is MouseNone.
+	Release bool
+	Motion  bool
+	Shift   bool
+	Alt     bool
+	Ctrl    bool
+}
+
+// EncodePaste returns the input to send to the program for pasted text.
+// Line breaks are sent as carriage returns, like a key press, and the text
+// is bracketed when the program enabled bracketed paste.
+func (t *Terminal) EncodePaste(text string) string {
+	t.mu.Lock()
+	bracketed := t.modes.BracketedPaste
+	t.mu.Unlock()
+
+	text = strings.NewReplacer("\r\n", "\r", "\n", "\r").Replace(text)
+	if !bracketed {
+		return text
+	}
+	// The pasted text must not be able to end the paste early.
+	text = strings.ReplaceAll(text, ansi.BracketedPasteEnd, "")
+	return ansi.BracketedPasteStart + text + ansi.BracketedPasteEnd
+}
+
+// EncodeMouse returns the input to send to the program for a mouse event,
+// or false if the program didn't ask for events of this kind.
+func (t *Terminal) EncodeMouse(ev MouseEvent) (string, bool) {
+	t.mu.Lock()
+	modes := t.modes
+	width, height := t.width, t.height
+	t.mu.Unlock()
+
+	if ev.X < 0 || ev.Y < 0 || ev.X >= width || ev.Y >= height {
+		return "", false
+	}
+	wheel := ev.Button >= ansi.MouseWheelUp && ev.Button <= ansi.MouseWheelRight
+	switch modes.Mouse {
+	case MouseOff:
+		return "", false
+	case MouseX10:
+		if ev.Release || ev.Motion || wheel {
+			return "", false
+		}
+		ev.Shift, ev.Alt, ev.Ctrl = false, false, false
+	case MouseNormal:
+		if ev.Motion {
+			return "", false
+		}
+	case MouseButtonEvent:
+		if ev.Motion && ev.Button == ansi.MouseNone {
+			return "", false
+		}
+	}
+	if ev.Release && wheel {
+		return "", false
+	}
+
+	if modes.MouseSGR {
+		b := ansi.EncodeMouseButton(ev.Button, ev.Motion, ev.Shift, ev.Alt, ev.Ctrl)
+		return ansi.MouseSgr(b, ev.X, ev.Y, ev.Release), true
+	}
+	if ev.X > x10MaxCoord || ev.Y > x10MaxCoord {
+		return "", false
+	}
+	button := ev.Button
+	if ev.Release {
+		// The legacy encoding doesn't say which button was released.
+		button = ansi.MouseNone
+	}
+	b := ansi.EncodeMouseButton(button, ev.Motion, ev.Shift, ev.Alt, ev.Ctrl)
+	return ansi.MouseX10(b, ev.X, ev.Y), true
+}
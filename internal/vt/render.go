@@ -0,0 +1,164 @@
+package vt
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Render returns the visible part of the terminal as styled text: one line
+// per row, each exactly as wide as the terminal. When showCursor is set and
+// the program hasn't hidden the cursor, the cell under it is drawn in
+// reverse video.
+func (t *Terminal) Render(showCursor bool) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cx, cy := -1, -1
+	if showCursor && t.modes.CursorVisible && t.scrollOffset == 0 {
+		cx, cy = t.scr.cur.x, t.scr.cur.y
+	}
+
+	var b strings.Builder
+	for y, l := range t.view() {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		cursorX := -1
+		if y == cy {
+			cursorX = cx
+		}
+		renderLine(&b, l.cells, cursorX)
+	}
+	return b.String()
+}
+
+func renderLine(b *strings.Builder, cells []Cell, cursorX int) {
+	var cur Style
+	for x, c := range cells {
+		if c.Width == 0 {
+			continue
+		}
+		st := c.Style
+		if x == cursorX || (c.Width == 2 && x+1 == cursorX) {
+			st.Attrs ^= AttrReverse
+		}
+		if st != cur {
+			b.WriteString(sgr(st))
+			cur = st
+		}
+		if st.Attrs&AttrConceal != 0 {
+			b.WriteString(strings.Repeat(" ", c.Width))
+		} else {
+			b.WriteString(c.Content)
+		}
+	}
+	if cur != (Style{}) {
+		b.WriteString(ansi.ResetStyle)
+	}
+}
+
+// sgr returns the sequence that switches to st from any other style.
+func sgr(st Style) string {
+	s := ansi.NewStyle(ansi.ResetAttr)
+	attrs := []struct {
+		attr Attr
+		sgr  ansi.Attr
+	}{
+		{AttrBold, ansi.BoldAttr},
+		{AttrFaint, ansi.FaintAttr},
+		{AttrItalic, ansi.ItalicAttr},
+		{AttrUnderline, ansi.UnderlineAttr},
+		{AttrBlink, ansi.SlowBlinkAttr},
+		{AttrReverse, ansi.ReverseAttr},
+		{AttrStrikethrough, ansi.StrikethroughAttr},
+	}
+	for _, a := range attrs {
+		if st.Attrs&a.attr != 0 {
+			s = append(s, ansi.NewStyle(a.sgr)...)
+		}
+	}
+	if st.Fg != nil {
+		s = s.ForegroundColor(st.Fg)
+	}
+	if st.Bg != nil {
+		s = s.BackgroundColor(st.Bg)
+	}
+	return s.String()
+}
+
+// view returns the lines currently on display, taking the scrollback
+// position into account.
+func (t *Terminal) view() []line {
+	if t.scrollOffset == 0 || t.scr != t.main {
+		return t.scr.lines
+	}
+	n := len(t.scrollback)
+	start := n - t.scrollOffset
+	lines := make([]line, 0, t.height)
+	for i := start; i < start+t.height; i++ {
+		if i < n {
+			lines = append(lines, t.scrollback[i])
+		} else {
+			lines = append(lines, t.scr.lines[i-n])
+		}
+	}
+	return lines
+}
+
+// Text returns the plain text of the scrollback and the screen, without
+// trailing blank lines or trailing spaces. Wrapped lines are joined.
+func (t *Terminal) Text() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := t.scr.lines
+	if t.scr == t.main {
+		lines = append(t.scrollback[:len(t.scrollback):len(t.scrollback)], lines...)
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		for _, c := range l.cells {
+			b.WriteString(c.Content)
+		}
+		if !l.wrapped {
+			b.WriteByte('\n')
+		}
+	}
+	text := strings.Split(b.String(), "\n")
+	for i, l := range text {
+		text[i] = strings.TrimRight(l, " ")
+	}
+	return strings.TrimRight(strings.Join(text, "\n"), "\n")
+}
+
+// ScrollUp scrolls the view n lines back into the scrollback.
+func (t *Terminal) ScrollUp(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.scr != t.main {
+		return
+	}
+	t.scrollOffset = min(t.scrollOffset+n, len(t.scrollback))
+}
+
+// ScrollDown scrolls the view n lines towards the bottom.
+func (t *Terminal) ScrollDown(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scrollOffset = max(t.scrollOffset-n, 0)
+}
+
+// ScrollToBottom scrolls the view back to the screen.
+func (t *Terminal) ScrollToBottom() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scrollOffset = 0
+}
+
+// ScrollOffset returns how many lines the view is scrolled back.
+func (t *Terminal) ScrollOffset() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.scrollOffset
+}
@@ -0,0 +1,151 @@
+package vt
+
+import "slices"
+
+// Resize changes the size of the terminal. The main screen and its
+// scrollback are reflowed: lines that were wrapped are joined and wrapped
+// again at the new width. The alternate screen is cut or padded, since the
+// full-screen program using it redraws after a resize.
+func (t *Terminal) Resize(width, height int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	width, height = max(width, 1), max(height, 1)
+	if width == t.width && height == t.height {
+		return
+	}
+
+	t.reflowMain(width, height)
+	t.alt = resizeScreen(t.alt, width, height)
+	t.width, t.height = width, height
+	t.top, t.bottom = 0, height-1
+	t.scrollOffset = min(t.scrollOffset, len(t.scrollback))
+
+	tabs := t.tabs
+	t.resetTabs()
+	copy(t.tabs, tabs)
+}
+
+// reflowMain rewraps the scrollback and the main screen at the new width
+// and puts the bottom height lines on the screen.
+func (t *Terminal) reflowMain(width, height int) {
+	scr := t.main
+	cur := scr.cur
+
+	// Drop blank lines below the cursor so they aren't pushed into the
+	// scrollback when the screen gets shorter.
+	last := len(scr.lines) - 1
+	for last > cur.y && scr.lines[last].isBlank() {
+		last--
+	}
+	lines := append(t.scrollback[:len(t.scrollback):len(t.scrollback)], scr.lines[:last+1]...)
+	cursorLine := len(t.scrollback) + cur.y
+
+	var out []line
+	var newCur cursor
+	for i := 0; i < len(lines); {
+		// Join the logical line.
+		var cells []Cell
+		cursorAt := -1
+		for {
+			l := lines[i]
+			if i == cursorLine {
+				cursorAt = len(cells) + cur.x
+			}
+			cells = append(cells, l.cells...)
+			i++
+			if !l.wrapped || i == len(lines) {
+				break
+			}
+		}
+		cells = trimBlank(cells)
+		if cursorAt >= len(cells) {
+			cells = append(cells, blanks(cursorAt+1-len(cells))...)
+		}
+
+		// Wrap it at the new width.
+		for off, first := 0, true; first || len(cells) > 0; first = false {
+			n := min(width, len(cells))
+			// Don't split a wide character.
+			if n < len(cells) && n > 1 && cells[n].Width == 0 {
+				n--
+			}
+			l := line{cells: append(make([]Cell, 0, width), cells[:n]...)}
+			l.cells = append(l.cells, blanks(width-n)...)
+			l.wrapped = n < len(cells)
+			if cursorAt >= off && cursorAt < off+n {
+				newCur.x, newCur.y = cursorAt-off, len(out)
+			}
+			out = append(out, l)
+			cells = cells[n:]
+			off += n
+		}
+	}
+
+	for len(out) < height {
+		out = append(out, newLine(width, Style{}))
+	}
+	// Keep the cursor on screen, preferring to show as much as possible
+	// above it.
+	split := max(len(out)-height, 0)
+	if newCur.y < split {
+		split = newCur.y
+	}
+	t.scrollback = slices.Clip(out[:split])
+	scr.lines = out[split : split+height]
+	t.trimScrollback()
+
+	cur.x, cur.y = newCur.x, newCur.y-split
+	cur.pendingWrap = false
+	scr.cur = cur
+	scr.saved.x = min(scr.saved.x, width-1)
+	scr.saved.y = min(scr.saved.y, height-1)
+}
+
+// resizeScreen cuts or pads s to the new size without reflowing it.
+func resizeScreen(s *screen, width, height int) *screen {
+	// Drop lines from the top when the cursor would fall off the bottom.
+	if drop := s.cur.y - (height - 1); drop > 0 {
+		s.lines = s.lines[drop:]
+		s.cur.y -= drop
+	}
+	lines := make([]line, height)
+	for y := range lines {
+		if y < len(s.lines) {
+			cells := s.lines[y].cells
+			if len(cells) > width {
+				cells = cells[:width]
+				if cells[width-1].Width == 2 {
+					cells[width-1] = blank
+				}
+			} else {
+				cells = append(cells, blanks(width-len(cells))...)
+			}
+			lines[y] = line{cells: cells}
+		} else {
+			lines[y] = newLine(width, Style{})
+		}
+	}
+	s.lines = lines
+	s.cur.x = min(s.cur.x, width-1)
+	s.cur.y = min(s.cur.y, height-1)
+	s.cur.pendingWrap = false
+	s.saved.x = min(s.saved.x, width-1)
+	s.saved.y = min(s.saved.y, height-1)
+	return s
+}
+
+// trimBlank removes trailing blank cells.
+func trimBlank(cells []Cell) []Cell {
+	n := len(cells)
+	for n > 0 && cells[n-1] == blank {
+		n--
+	}
+	return cells[:n]
+}
+
+func blanks(n int) []Cell {
+	cells := make([]Cell, max(n, 0))
+	fill(cells, Style{})
+	return cells
+}
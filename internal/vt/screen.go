@@ -0,0 +1,275 @@
+package vt
+
+import "slices"
+
+// blank is an empty cell with the default style.
+var blank = Cell{Content: " ", Width: 1}
+
+// line is a row of the grid.
+type line struct {
+	cells []Cell
+	// wrapped is set when the text continues on the next line because it
+	// ran past the right margin. Reflow joins wrapped lines.
+	wrapped bool
+}
+
+func newLine(width int, style Style) line {
+	cells := make([]Cell, width)
+	fill(cells, style)
+	return line{cells: cells}
+}
+
+// fill blanks cells, keeping only the background of style as erasing does.
+func fill(cells []Cell, style Style) {
+	c := blank
+	c.Style.Bg = style.Bg
+	for i := range cells {
+		cells[i] = c
+	}
+}
+
+func (l line) isBlank() bool {
+	return !l.wrapped && slices.IndexFunc(l.cells, func(c Cell) bool { return c != blank }) < 0
+}
+
+// screen is a grid of lines with its own cursor, so the main screen keeps
+// its cursor while the alternate screen is in use.
+type screen struct {
+	lines []line
+	cur   cursor
+	saved cursor
+}
+
+func newScreen(width, height int) *screen {
+	s := &screen{lines: make([]line, height)}
+	for y := range s.lines {
+		s.lines[y] = newLine(width, Style{})
+	}
+	s.cur = cursor{}
+	s.saved = s.cur
+	return s
+}
+
+func (s *screen) clear(width int, style Style) {
+	for y := range s.lines {
+		s.lines[y] = newLine(width, style)
+	}
+}
+
+// erase blanks the cells of line y from x0 up to, but excluding, x1.
+func (t *Terminal) erase(y, x0, x1 int) {
+	l := &t.scr.lines[y]
+	x0, x1 = max(x0, 0), min(x1, t.width)
+	if x0 >= x1 {
+		return
+	}
+	// Erasing half of a wide character erases all of it.
+	if x0 > 0 && l.cells[x0].Width == 0 {
+		x0--
+	}
+	if x1 < t.width && l.cells[x1].Width == 0 {
+		x1++
+	}
+	fill(l.cells[x0:x1], t.scr.cur.style)
+	if x1 == t.width {
+		l.wrapped = false
+	}
+}
+
+// scrollUp moves the lines of the scrolling region up by n, adding blank
+// lines at the bottom. Lines leaving the top of the main screen go to the
+// scrollback.
+func (t *Terminal) scrollUp(n int) {
+	n = min(n, t.bottom-t.top+1)
+	lines := t.scr.lines
+	for range n {
+		if t.scr == t.main && t.top == 0 {
+			t.pushScrollback(lines[0])
+		}
+		copy(lines[t.top:t.bottom], lines[t.top+1:t.bottom+1])
+		lines[t.bottom] = newLine(t.width, t.scr.cur.style)
+	}
+}
+
+// scrollDown moves the lines of the scrolling region down by n, adding blank
+// lines at the top.
+func (t *Terminal) scrollDown(n int) {
+	n = min(n, t.bottom-t.top+1)
+	lines := t.scr.lines
+	for range n {
+		copy(lines[t.top+1:t.bottom+1], lines[t.top:t.bottom])
+		lines[t.top] = newLine(t.width, t.scr.cur.style)
+	}
+}
+
+func (t *Terminal) pushScrollback(l line) {
+	if t.maxScrollback == 0 {
+		return
+	}
+	t.scrollback = append(t.scrollback, l)
+	if t.scrollOffset > 0 {
+		// Keep the view still while the user is reading history.
+		t.scrollOffset++
+	}
+	// Trim in batches so that a full scrollback isn't copied for every
+	// line.
+	if len(t.scrollback) > t.maxScrollback+t.maxScrollback/4 {
+		t.trimScrollback()
+	}
+}
+
+func (t *Terminal) trimScrollback() {
+	if over := len(t.scrollback) - t.maxScrollback; over > 0 {
+		t.scrollback = slices.Delete(t.scrollback, 0, over)
+	}
+	t.scrollOffset = min(t.scrollOffset, len(t.scrollback))
+}
+
+// index moves the cursor down, scrolling at the bottom of the scrolling
+// region.
+func (t *Terminal) index() {
+	c := &t.scr.cur
+	switch {
+	case c.y == t.bottom:
+		t.scrollUp(1)
+	case c.y < t.height-1:
+		c.y++
+	}
+}
+
+// reverseIndex moves the cursor up, scrolling at the top of the scrolling
+// region.
+func (t *Terminal) reverseIndex() {
+	c := &t.scr.cur
+	switch {
+	case c.y == t.top:
+		t.scrollDown(1)
+	case c.y > 0:
+		c.y--
+	}
+}
+
+// moveTo moves the cursor to an absolute position, relative to the
+// scrolling region in origin mode.
+func (t *Terminal) moveTo(x, y int) {
+	c := &t.scr.cur
+	minY, maxY := 0, t.height-1
+	if c.origin {
+		y += t.top
+		minY, maxY = t.top, t.bottom
+	}
+	c.x = min(max(x, 0), t.width-1)
+	c.y = min(max(y, minY), maxY)
+	c.pendingWrap = false
+}
+
+// moveVertically moves the cursor up or down by n lines, stopping at the
+// scrolling region's margins when it starts inside it.
+func (t *Terminal) moveVertically(n int) {
+	c := &t.scr.cur
+	minY, maxY := 0, t.height-1
+	if c.y >= t.top && c.y <= t.bottom {
+		minY, maxY = t.top, t.bottom
+	}
+	c.y = min(max(c.y+n, minY), maxY)
+	c.pendingWrap = false
+}
+
+func (t *Terminal) moveHorizontally(n int) {
+	c := &t.scr.cur
+	c.x = min(max(c.x+n, 0), t.width-1)
+	c.pendingWrap = false
+}
+
+// insertCells inserts n blank cells at the cursor, shifting the rest of the
+// line right.
+func (t *Terminal) insertCells(n int) {
+	c := t.scr.cur
+	cells := t.scr.lines[c.y].cells
+	n = min(n, t.width-c.x)
+	copy(cells[c.x+n:], cells[c.x:t.width-n])
+	fill(cells[c.x:c.x+n], c.style)
+	t.fixWideEdge(c.y)
+}
+
+// deleteCells deletes n cells at the cursor, shifting the rest of the line
+// left.
+func (t *Terminal) deleteCells(n int) {
+	c := t.scr.cur
+	cells := t.scr.lines[c.y].cells
+	n = min(n, t.width-c.x)
+	copy(cells[c.x:], cells[c.x+n:])
+	fill(cells[t.width-n:], c.style)
+	if c.x > 0 && cells[c.x].Width == 0 {
+		cells[c.x-1] = blank
+		cells[c.x] = blank
+	}
+	t.fixWideEdge(c.y)
+}
+
+// fixWideEdge blanks a wide character cut in half at the right margin.
+func (t *Terminal) fixWideEdge(y int) {
+	cells := t.scr.lines[y].cells
+	if last := cells[t.width-1]; last.Width == 2 {
+		cells[t.width-1] = blank
+	}
+	if first := cells[0]; first.Width == 0 {
+		cells[0] = blank
+	}
+}
+
+// insertLines inserts n blank lines at the cursor when it is inside the
+// scrolling region.
+func (t *Terminal) insertLines(n int) {
+	y := t.scr.cur.y
+	if y < t.top || y > t.bottom {
+		return
+	}
+	top := t.top
+	t.top = y
+	t.scrollDown(n)
+	t.top = top
+	t.scr.cur.x = 0
+	t.scr.cur.pendingWrap = false
+}
+
+// deleteLines deletes n lines at the cursor when it is inside the scrolling
+// region.
+func (t *Terminal) deleteLines(n int) {
+	y := t.scr.cur.y
+	if y < t.top || y > t.bottom {
+		return
+	}
+	top := t.top
+	t.top = y
+	// Lines deleted in the middle of the screen don't go to scrollback.
+	scr := t.scr
+	t.scr = &screen{lines: scr.lines, cur: scr.cur}
+	t.scrollUp(n)
+	t.scr = scr
+	t.top = top
+	t.scr.cur.x = 0
+	t.scr.cur.pendingWrap = false
+}
+
+// switchScreen switches between the main and the alternate screen. The
+// alternate screen starts with the main screen's cursor.
+func (t *Terminal) switchScreen(alt, clear bool) {
+	if alt == (t.scr == t.alt) {
+		return
+	}
+	if alt {
+		t.alt.cur = t.main.cur
+		if clear {
+			t.alt.clear(t.width, Style{})
+		}
+		t.scr = t.alt
+		t.scrollOffset = 0
+	} else {
+		if clear {
+			t.alt.clear(t.width, Style{})
+		}
+		t.scr = t.main
+	}
+	t.top, t.bottom = 0, t.height-1
+}
@@ -0,0 +1,243 @@
+// Package vt implements a VT100/xterm compatible terminal emulator.
+//
+// A Terminal consumes the output of a program running in a PTY and keeps a
+// grid of styled cells, with scrollback for the main screen, an alternate
+// screen for full-screen programs, scrolling regions and the common xterm
+// modes. The grid is rendered as lines of exactly the terminal's width so it
+// can be composed in a lipgloss layout.
+//
+// Input going the other way, such as pastes and mouse events, is encoded
+// according to the modes the program enabled.
+package vt
+
+import (
+	"image/color"
+	"sync"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// DefaultScrollback is the number of lines kept once they scroll off the
+	// top of the main screen.
+	DefaultScrollback = 10_000
+
+	// tabWidth is the distance between the default tab stops.
+	tabWidth = 8
+)
+
+// Attr is a set of text attributes.
+type Attr uint8
+
+const (
+	AttrBold Attr = 1 << iota
+	AttrFaint
+	AttrItalic
+	AttrUnderline
+	AttrBlink
+	AttrReverse
+	AttrConceal
+	AttrStrikethrough
+)
+
+// Style is the look of a cell. Nil colors are the terminal's defaults.
+type Style struct {
+	Fg    color.Color
+	Bg    color.Color
+	Attrs Attr
+}
+
+// Cell is a single cell of the grid. A wide character occupies a cell of
+// Width 2 followed by a continuation cell of Width 0.
+type Cell struct {
+	Content string
+	Width   int
+	Style   Style
+}
+
+// MouseMode is the kind of mouse tracking a program requested.
+type MouseMode int
+
+const (
+	MouseOff MouseMode = iota
+	// MouseX10 reports button presses only.
+	MouseX10
+	// MouseNormal reports presses, releases and the wheel.
+	MouseNormal
+	// MouseButtonEvent also reports motion while a button is held.
+	MouseButtonEvent
+	// MouseAnyEvent reports all motion.
+	MouseAnyEvent
+)
+
+// Modes are the terminal modes that affect how input is encoded and how the
+// terminal is displayed.
+type Modes struct {
+	AppCursorKeys  bool
+	BracketedPaste bool
+	Mouse          MouseMode
+	MouseSGR       bool
+	CursorVisible  bool
+	AltScreen      bool
+}
+
+// cursor is a cursor position together with the state DECSC saves.
+type cursor struct {
+	x, y  int
+	style Style
+	// pendingWrap is set after printing in the last column; the next
+	// printed character wraps to the next line first.
+	pendingWrap bool
+	origin      bool
+	charsets    [2]charset
+	charset     int
+}
+
+// Terminal is a terminal emulator. It is safe for concurrent use.
+type Terminal struct {
+	mu sync.Mutex
+
+	width, height int
+
+	main, alt *screen
+	// scr is the active screen, either main or alt.
+	scr *screen
+
+	scrollback    []line
+	maxScrollback int
+	// scrollOffset is how many lines the view is scrolled back from the
+	// bottom of the main screen.
+	scrollOffset int
+
+	// top and bottom are the scrolling region, inclusive.
+	top, bottom int
+
+	modes    Modes
+	autowrap bool
+	insert   bool
+	newline  bool
+	tabs     []bool
+
+	// lastRune is the last printed character, for REP.
+	lastRune rune
+	title    string
+
+	parser *ansi.Parser
+	reply  func([]byte)
+}
+
+// New creates a terminal of the given size.
+func New(width, height int) *Terminal {
+	width, height = max(width, 1), max(height, 1)
+	t := &Terminal{
+		width:         width,
+		height:        height,
+		maxScrollback: DefaultScrollback,
+	}
+	t.reset()
+	t.parser = ansi.NewParser()
+	t.parser.SetHandler(ansi.Handler{
+		Print:     t.print,
+		Execute:   t.execute,
+		HandleCsi: t.handleCSI,
+		HandleEsc: t.handleESC,
+		HandleOsc: t.handleOSC,
+	})
+	return t
+}
+
+// reset puts the terminal in its initial state, keeping its size.
+func (t *Terminal) reset() {
+	t.main = newScreen(t.width, t.height)
+	t.alt = newScreen(t.width, t.height)
+	t.scr = t.main
+	t.scrollback = nil
+	t.scrollOffset = 0
+	t.top, t.bottom = 0, t.height-1
+	t.modes = Modes{CursorVisible: true}
+	t.autowrap = true
+	t.insert = false
+	t.newline = false
+	t.resetTabs()
+	t.title = ""
+}
+
+func (t *Terminal) resetTabs() {
+	t.tabs = make([]bool, t.width)
+	for x := tabWidth; x < t.width; x += tabWidth {
+		t.tabs[x] = true
+	}
+}
+
+// SetReplyHandler sets the function that receives the terminal's replies to
+// queries, such as cursor position reports. They should be written back to
+// the program. The handler is called with the terminal locked, so it must
+// not call back into the terminal.
+func (t *Terminal) SetReplyHandler(fn func([]byte)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reply = fn
+}
+
+// SetMaxScrollback sets how many lines of scrollback are kept.
+func (t *Terminal) SetMaxScrollback(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxScrollback = max(n, 0)
+	t.trimScrollback()
+}
+
+// Write feeds program output to the terminal.
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.parser.Parse(p)
+	return len(p), nil
+}
+
+// Size returns the size of the terminal in cells.
+func (t *Terminal) Size() (width, height int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.width, t.height
+}
+
+// Modes returns the current terminal modes.
+func (t *Terminal) Modes() Modes {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := t.modes
+	m.AltScreen = t.scr == t.alt
+	return m
+}
+
+// Title returns the window title set by the program.
+func (t *Terminal) Title() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.title
+}
+
+// Cursor returns the cursor position on the screen and whether it is
+// visible.
+func (t *Terminal) Cursor() (x, y int, visible bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.scr.cur.x, t.scr.cur.y, t.modes.CursorVisible
+}
+
+// Cell returns the cell at the given screen position.
+func (t *Terminal) Cell(x, y int) Cell {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if y < 0 || y >= t.height || x < 0 || x >= t.width {
+		return Cell{}
+	}
+	return t.scr.lines[y].cells[x]
+}
+
+func (t *Terminal) sendReply(s string) {
+	if t.reply != nil {
+		t.reply([]byte(s))
+	}
+}
@@ -0,0 +1,200 @@
+package vt
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *Terminal, s string) {
+	_, _ = t.Write([]byte(s))
+}
+
+// screenText returns the visible lines with trailing spaces removed.
+func screenText(t *Terminal) []string {
+	lines := strings.Split(ansi.Strip(t.Render(false)), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return lines
+}
+
+func TestPrintAndWrap(t *testing.T) {
+	t.Parallel()
+
+	term := New(5, 3)
+	write(term, "hello world")
+	require.Equal(t, []string{"hello", " worl", "d"}, screenText(term))
+	x, y, _ := term.Cursor()
+	require.Equal(t, 1, x)
+	require.Equal(t, 2, y)
+
+	// Printing in the last column defers the wrap until the next character.
+	term = New(5, 3)
+	write(term, "hello\r\nab")
+	require.Equal(t, []string{"hello", "ab", ""}, screenText(term))
+}
+
+func TestRenderWidth(t *testing.T) {
+	t.Parallel()
+
+	term := New(6, 2)
+	write(term, "\x1b[1;31mred\x1b[m 世")
+	for _, l := range strings.Split(term.Render(true), "\n") {
+		require.Equal(t, 6, ansi.StringWidth(l))
+	}
+	require.Equal(t, "red 世", screenText(term)[0])
+
+	cell := term.Cell(0, 0)
+	require.Equal(t, AttrBold, cell.Style.Attrs)
+	require.Equal(t, color.Color(ansi.Red), cell.Style.Fg)
+	require.Equal(t, 2, term.Cell(4, 0).Width)
+	require.Equal(t, 0, term.Cell(5, 0).Width)
+}
+
+func TestSGRColors(t *testing.T) {
+	t.Parallel()
+
+	term := New(10, 1)
+	write(term, "\x1b[38;5;200;48;2;1;2;3;4mx\x1b[22;24;39my")
+	x := term.Cell(0, 0).Style
+	require.Equal(t, color.Color(ansi.IndexedColor(200)), x.Fg)
+	require.Equal(t, color.Color(color.RGBA{R: 1, G: 2, B: 3, A: 0xff}), x.Bg)
+	require.Equal(t, AttrUnderline, x.Attrs)
+
+	y := term.Cell(1, 0).Style
+	require.Nil(t, y.Fg)
+	require.Equal(t, x.Bg, y.Bg)
+	require.Zero(t, y.Attrs)
+}
+
+func TestCursorMovementAndErase(t *testing.T) {
+	t.Parallel()
+
+	term := New(10, 4)
+	write(term, "aaaaaaaaaa\r\nbbbbbbbbbb\r\ncccccccccc")
+	write(term, "\x1b[2;3H\x1b[K")
+	write(term, "\x1b[3;5H\x1b[1K")
+	write(term, "\x1b[1;4H\x1b[2P")
+	write(term, "\x1b[4;1Hxyz\x1b[2D\x1b[@!")
+	require.Equal(t, []string{"aaaaaaaa", "bb", "     ccccc", "x!yz"}, screenText(term))
+
+	write(term, "\x1b[2J")
+	require.Equal(t, []string{"", "", "", ""}, screenText(term))
+}
+
+func TestScrollbackAndRegion(t *testing.T) {
+	t.Parallel()
+
+	term := New(10, 3)
+	write(term, "1\r\n2\r\n3\r\n4\r\n5")
+	require.Equal(t, []string{"3", "4", "5"}, screenText(term))
+	require.Equal(t, "1\n2\n3\n4\n5", term.Text())
+
+	term.ScrollUp(10)
+	require.Equal(t, 2, term.ScrollOffset())
+	require.Equal(t, []string{"1", "2", "3"}, screenText(term))
+	term.ScrollToBottom()
+
+	// Scrolling inside a region leaves the rest of the screen alone and
+	// doesn't add to the scrollback.
+	write(term, "\x1b[2;3r\x1b[3;1H\nnew")
+	require.Equal(t, []string{"3", "5", "new"}, screenText(term))
+	require.Equal(t, "1\n2\n3\n5\nnew", term.Text())
+
+	write(term, "\x1b[2;1H\x1b[L")
+	require.Equal(t, []string{"3", "", "5"}, screenText(term))
+}
+
+func TestAltScreen(t *testing.T) {
+	t.Parallel()
+
+	term := New(10, 3)
+	write(term, "shell$ ")
+	write(term, "\x1b[?1049h\x1b[Hfull screen")
+	require.True(t, term.Modes().AltScreen)
+	require.Equal(t, []string{"full scree", "n", ""}, screenText(term))
+
+	write(term, "\x1b[?1049l")
+	require.False(t, term.Modes().AltScreen)
+	require.Equal(t, []string{"shell$", "", ""}, screenText(term))
+	x, y, _ := term.Cursor()
+	require.Equal(t, 7, x)
+	require.Equal(t, 0, y)
+}
+
+func TestResizeReflow(t *testing.T) {
+	t.Parallel()
+
+	term := New(10, 3)
+	write(term, "0123456789abcde\r\nnext")
+	require.Equal(t, []string{"0123456789", "abcde", "next"}, screenText(term))
+
+	term.Resize(20, 3)
+	require.Equal(t, []string{"0123456789abcde", "next", ""}, screenText(term))
+	x, y, _ := term.Cursor()
+	require.Equal(t, 4, x)
+	require.Equal(t, 1, y)
+
+	term.Resize(5, 3)
+	require.Equal(t, []string{"56789", "abcde", "next"}, screenText(term))
+	require.Equal(t, "0123456789abcde\nnext", term.Text())
+
+	term.Resize(5, 2)
+	require.Equal(t, []string{"abcde", "next"}, screenText(term))
+}
+
+func TestModesAndReplies(t *testing.T) {
+	t.Parallel()
+
+	term := New(10, 5)
+	var replies []string
+	term.SetReplyHandler(func(b []byte) { replies = append(replies, string(b)) })
+
+	write(term, "\x1b[3;4H\x1b[6n\x1b[?1h\x1b[?2004h\x1b[?25l\x1b]2;my title\a")
+	require.Equal(t, []string{"\x1b[3;4R"}, replies)
+	modes := term.Modes()
+	require.True(t, modes.AppCursorKeys)
+	require.True(t, modes.BracketedPaste)
+	require.False(t, modes.CursorVisible)
+	require.Equal(t, "my title", term.Title())
+
+	require.Equal(t, "\x1b[200~a\rb\x1b[201~", term.EncodePaste("a\nb"))
+	write(term, "\x1b[?2004l")
+	require.Equal(t, "a\rb", term.EncodePaste("a\r\nb"))
+}
+
+func TestEncodeMouse(t *testing.T) {
+	t.Parallel()
+
+	term := New(80, 24)
+	press := MouseEvent{Button: ansi.MouseLeft, X: 2, Y: 3}
+	_, ok := term.EncodeMouse(press)
+	require.False(t, ok)
+
+	write(term, "\x1b[?1000h")
+	seq, ok := term.EncodeMouse(press)
+	require.True(t, ok)
+	require.Equal(t, "\x1b[M #$", seq)
+	_, ok = term.EncodeMouse(MouseEvent{Button: ansi.MouseLeft, X: 2, Y: 3, Motion: true})
+	require.False(t, ok)
+
+	write(term, "\x1b[?1002h\x1b[?1006h")
+	seq, ok = term.EncodeMouse(MouseEvent{Button: ansi.MouseLeft, X: 2, Y: 3, Release: true})
+	require.True(t, ok)
+	require.Equal(t, "\x1b[<0;3;4m", seq)
+	seq, ok = term.EncodeMouse(MouseEvent{Button: ansi.MouseWheelUp, X: 0, Y: 0})
+	require.True(t, ok)
+	require.Equal(t, "\x1b[<64;1;1M", seq)
+}
+
+func TestDECGraphics(t *testing.T) {
+	t.Parallel()
+
+	term := New(10, 1)
+	write(term, "\x1b(0lqk\x1b(Bx")
+	require.Equal(t, "┌─┐x", screenText(term)[0])
+}
@@ -11,9 +11,13 @@ import (
 
 	"github.com/lacymorrow/lash/internal/cmd"
 	"github.com/lacymorrow/lash/internal/log"
+	"github.com/lacymorrow/lash/internal/sandbox"
 )
 
 func main() {
+	// Sandboxed commands are started through lash itself.
+	sandbox.Main()
+
 	defer log.RecoverPanic("main", func() {
 		slog.Error("Application terminated due to unhandled panic")
 	})